@@ -0,0 +1,186 @@
+// Package breaker implements a circuit breaker for the clients of the
+// downstream services. The breaker opens after a number of consecutive
+// failures and rejects the calls with Unavailable status errors with
+// RetryInfo until it's open timeout passes, so the failures of a downstream
+// service degrade the callers gracefully instead of piling up their latency.
+// Then it lets a few trial calls through and closes again when they succeed.
+//
+// The breaker is installed into the HTTP clients by
+// httpkit.CircuitBreakerTransport and into the gRPC clients by
+// grpckit.UnaryClientCircuitBreaker and grpckit.StreamClientCircuitBreaker.
+package breaker
+
+import (
+	"sync"
+	"time"
+
+	gerrdetails "google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// State is the state of a Breaker.
+type State int
+
+const (
+	// Closed lets all calls through.
+	Closed State = iota
+	// Open rejects all calls.
+	Open
+	// HalfOpen lets a limited number of trial calls through.
+	HalfOpen
+)
+
+func (s State) String() string {
+	switch s {
+	case Open:
+		return "open"
+	case HalfOpen:
+		return "half-open"
+	}
+	return "closed"
+}
+
+// Option sets an optional parameter for the Breaker.
+type Option func(*Breaker)
+
+// FailureThreshold sets the number of consecutive failures after which the
+// breaker opens. It's 5 by default.
+func FailureThreshold(n int) Option {
+	return func(b *Breaker) { b.threshold = n }
+}
+
+// OpenTimeout sets how long the breaker stays open before it lets the trial
+// calls through. It's 30 seconds by default.
+func OpenTimeout(d time.Duration) Option {
+	return func(b *Breaker) { b.timeout = d }
+}
+
+// HalfOpenCalls sets the number of the trial calls of the half-open
+// breaker that must succeed for it to close. It's 1 by default.
+func HalfOpenCalls(n int) Option {
+	return func(b *Breaker) { b.halfOpenCalls = n }
+}
+
+// Breaker is a circuit breaker that is safe for concurrent use.
+type Breaker struct {
+	threshold     int
+	timeout       time.Duration
+	halfOpenCalls int
+
+	mu        sync.Mutex
+	state     State
+	failures  int
+	trials    int
+	successes int
+	opened    time.Time
+	// generation is incremented on every state transition, so the outcomes
+	// of the calls that were let through in a previous state are ignored.
+	generation uint64
+}
+
+// New creates a new closed Breaker.
+func New(options ...Option) *Breaker {
+	b := &Breaker{threshold: 5, timeout: 30 * time.Second, halfOpenCalls: 1}
+	for _, option := range options {
+		option(b)
+	}
+	return b
+}
+
+// State returns the current state of the breaker.
+func (b *Breaker) State() State {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.current(time.Now())
+}
+
+// Allow reports whether a call can be made. When it can, the returned
+// function must be called with the outcome of the call. Otherwise the
+// returned error is the Unavailable status error of the open breaker with
+// RetryInfo set to the time until the breaker lets the calls through again.
+func (b *Breaker) Allow() (done func(success bool), err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	switch b.current(now) {
+	case Open:
+		return nil, openError(b.opened.Add(b.timeout).Sub(now))
+	case HalfOpen:
+		if b.state == Open {
+			b.transition(HalfOpen)
+			b.trials, b.successes = 0, 0
+		}
+		if b.trials >= b.halfOpenCalls {
+			return nil, openError(0)
+		}
+		b.trials++
+	}
+	generation := b.generation
+	return func(success bool) { b.done(generation, success) }, nil
+}
+
+// done records the outcome of a call that was let through in the
+// generation. The outcomes of the previous generations are ignored, so the
+// calls that started before the breaker opened don't count as its trials.
+func (b *Breaker) done(generation uint64, success bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if generation != b.generation {
+		return
+	}
+	switch b.state {
+	case Closed:
+		if success {
+			b.failures = 0
+			return
+		}
+		b.failures++
+		if b.failures >= b.threshold {
+			b.open(time.Now())
+		}
+	case HalfOpen:
+		if !success {
+			b.open(time.Now())
+			return
+		}
+		b.successes++
+		if b.successes >= b.halfOpenCalls {
+			b.transition(Closed)
+			b.failures = 0
+		}
+	}
+}
+
+func (b *Breaker) open(now time.Time) {
+	b.transition(Open)
+	b.opened = now
+}
+
+func (b *Breaker) transition(state State) {
+	b.state = state
+	b.generation++
+}
+
+// current returns the state of the breaker at the time, which is half-open
+// when the open timeout has passed.
+func (b *Breaker) current(now time.Time) State {
+	if b.state == Open && now.Sub(b.opened) >= b.timeout {
+		return HalfOpen
+	}
+	return b.state
+}
+
+// openError returns the error of the calls that are rejected by the open
+// breaker.
+func openError(retryAfter time.Duration) error {
+	if retryAfter < time.Second {
+		retryAfter = time.Second
+	}
+	st := status.New(codes.Unavailable, "circuit breaker is open")
+	st, _ = st.WithDetails(&gerrdetails.RetryInfo{RetryDelay: durationpb.New(retryAfter.Round(time.Second))})
+	return st.Err()
+}
@@ -0,0 +1,91 @@
+package breaker_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/clouway/go-genproto/clouwayapis/rpc/breaker"
+	gerrdetails "google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestBreaker(t *testing.T) {
+	b := breaker.New(breaker.FailureThreshold(2), breaker.OpenTimeout(50*time.Millisecond))
+	call := func(success bool) error {
+		done, err := b.Allow()
+		if err != nil {
+			return err
+		}
+		done(success)
+		return nil
+	}
+
+	call(false)
+	call(true)
+	call(false)
+	if b.State() != breaker.Closed {
+		t.Errorf("unexpected state after interleaved failures:\n- want: %v\n-  got: %v", breaker.Closed, b.State())
+	}
+	call(false)
+	if b.State() != breaker.Open {
+		t.Errorf("unexpected state after consecutive failures:\n- want: %v\n-  got: %v", breaker.Open, b.State())
+	}
+
+	err := call(true)
+	st := status.Convert(err)
+	if st.Code() != codes.Unavailable {
+		t.Errorf("unexpected code of open breaker:\n- want: %v\n-  got: %v", codes.Unavailable, st.Code())
+	}
+	if len(st.Details()) != 1 {
+		t.Fatalf("unexpected details of open breaker: %v", st.Details())
+	}
+	if retry, ok := st.Details()[0].(*gerrdetails.RetryInfo); !ok || retry.RetryDelay.AsDuration() != time.Second {
+		t.Errorf("unexpected retry info:\n- want: %v\n-  got: %v", time.Second, st.Details()[0])
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	if b.State() != breaker.HalfOpen {
+		t.Errorf("unexpected state after open timeout:\n- want: %v\n-  got: %v", breaker.HalfOpen, b.State())
+	}
+	done, err := b.Allow()
+	if err != nil {
+		t.Fatalf("unexpected error of trial call: %v", err)
+	}
+	if _, err := b.Allow(); status.Code(err) != codes.Unavailable {
+		t.Errorf("unexpected code of concurrent trial call:\n- want: %v\n-  got: %v", codes.Unavailable, status.Code(err))
+	}
+	done(true)
+	if b.State() != breaker.Closed {
+		t.Errorf("unexpected state after successful trial:\n- want: %v\n-  got: %v", breaker.Closed, b.State())
+	}
+}
+
+func TestBreakerIgnoresStaleCalls(t *testing.T) {
+	b := breaker.New(breaker.FailureThreshold(1), breaker.OpenTimeout(50*time.Millisecond))
+
+	stale, err := b.Allow()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	done, _ := b.Allow()
+	done(false)
+	time.Sleep(60 * time.Millisecond)
+
+	trial, err := b.Allow()
+	if err != nil {
+		t.Fatalf("unexpected error of trial call: %v", err)
+	}
+	stale(true)
+	if b.State() != breaker.HalfOpen {
+		t.Errorf("unexpected state after stale call:\n- want: %v\n-  got: %v", breaker.HalfOpen, b.State())
+	}
+	trial(true)
+	if b.State() != breaker.Closed {
+		t.Errorf("unexpected state after successful trial:\n- want: %v\n-  got: %v", breaker.Closed, b.State())
+	}
+	stale(false)
+	if b.State() != breaker.Closed {
+		t.Errorf("unexpected state after stale failure:\n- want: %v\n-  got: %v", breaker.Closed, b.State())
+	}
+}
@@ -0,0 +1,217 @@
+// Package delta implements the differential sync of the list endpoints with
+// changed_since parameter, so the mobile apps download only what changed
+// since their last sync.
+//
+// The server issues an opaque change token with every response. The next
+// request passes the token back and gets only the resources that changed
+// after it, along with the tombstones of the deleted resources. When the
+// tombstones of the token are no longer retained, the request fails with an
+// OutOfRange status error and the client syncs everything again.
+package delta
+
+import (
+	"context"
+	"encoding/base64"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+// tokenVersion is the version of the token format.
+const tokenVersion = "v1"
+
+// Issuer issues and parses the change tokens of a single dataset. The
+// tokens carry the version of the dataset, which is any value that grows
+// with every change, e.g. a sequence or the time of the change in
+// nanoseconds.
+type Issuer struct {
+	// Scope identifies the dataset, e.g. "customers/42/devices", so the
+	// tokens of one dataset are rejected by the others.
+	Scope string
+}
+
+// Token returns the change token of the passed version of the dataset.
+func (i Issuer) Token(version int64) string {
+	raw := tokenVersion + ":" + i.Scope + ":" + strconv.FormatInt(version, 10)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// Parse returns the version of the change token. The empty token requests a
+// full sync and has version 0. The tokens that are older than the oldest
+// retained tombstone fail with an OutOfRange status error, as the deletions
+// after them are no longer known.
+func (i Issuer) Parse(token string, oldest int64) (int64, error) {
+	if token == "" {
+		return 0, nil
+	}
+	b, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return 0, status.Error(codes.InvalidArgument, "invalid change token")
+	}
+	parts := strings.Split(string(b), ":")
+	if len(parts) < 3 || parts[0] != tokenVersion {
+		return 0, status.Error(codes.InvalidArgument, "invalid change token")
+	}
+	version, err := strconv.ParseInt(parts[len(parts)-1], 10, 64)
+	if err != nil {
+		return 0, status.Error(codes.InvalidArgument, "invalid change token")
+	}
+	if scope := strings.Join(parts[1:len(parts)-1], ":"); scope != i.Scope {
+		return 0, status.Error(codes.InvalidArgument, "change token of another dataset")
+	}
+	if version < oldest {
+		return 0, status.Error(codes.OutOfRange, "change token expired, full sync is required")
+	}
+	return version, nil
+}
+
+// Tombstone records the deletion of a resource.
+type Tombstone struct {
+	// Name is the name of the deleted resource.
+	Name string
+
+	// Version is the version of the dataset after the deletion.
+	Version int64
+
+	// DeleteTime is the time of the deletion.
+	DeleteTime time.Time
+}
+
+// Delta is the response of a changed_since request.
+type Delta[T proto.Message] struct {
+	// Changed are the resources that were created or updated.
+	Changed []T
+
+	// Removed are the names of the deleted resources.
+	Removed []string
+
+	// Full is true when the delta contains the whole dataset, so the
+	// resources that are not in it should be dropped.
+	Full bool
+
+	// Token is the change token for the next request.
+	Token string
+}
+
+// NewDelta builds the delta of the changes after the passed version from the
+// resources and the tombstones that were changed after it. The tombstones of
+// the resources that were created again after their deletion are dropped,
+// as the resources are in the changed ones. The version of the next token
+// is the highest version of the changes, or the passed version when nothing
+// changed.
+func NewDelta[T proto.Message](issuer Issuer, since int64, changed []T, versionOf func(T) int64, nameOf func(T) string, tombstones []Tombstone) Delta[T] {
+	d := Delta[T]{Full: since == 0}
+	latest := since
+	versions := make(map[string]int64)
+	for _, item := range changed {
+		if v := versionOf(item); v > since {
+			d.Changed = append(d.Changed, item)
+			versions[nameOf(item)] = v
+			if v > latest {
+				latest = v
+			}
+		}
+	}
+	for _, t := range tombstones {
+		if t.Version > since {
+			if v, ok := versions[t.Name]; !d.Full && (!ok || v < t.Version) {
+				d.Removed = append(d.Removed, t.Name)
+			}
+			if t.Version > latest {
+				latest = t.Version
+			}
+		}
+	}
+	d.Token = issuer.Token(latest)
+	return d
+}
+
+// Store is the local store of the client that the deltas are merged into.
+type Store[T proto.Message] interface {
+	Put(key string, item T)
+	Delete(key string)
+	Reset()
+}
+
+// Merge merges the delta into the store. The store is reset before the full
+// deltas. The removed resources are deleted before the changed ones are
+// stored, so a resource that was deleted and created again is kept.
+func Merge[T proto.Message](store Store[T], d Delta[T], key func(T) string) {
+	if d.Full {
+		store.Reset()
+	}
+	for _, name := range d.Removed {
+		store.Delete(name)
+	}
+	for _, item := range d.Changed {
+		store.Put(key(item), item)
+	}
+}
+
+// FetchFunc fetches the delta after the passed change token.
+type FetchFunc[T proto.Message] func(ctx context.Context, token string) (Delta[T], error)
+
+// Sync fetches the delta after the token and merges it into the store. When
+// the token has expired, the whole dataset is fetched again. It returns the
+// token for the next sync.
+func Sync[T proto.Message](ctx context.Context, store Store[T], token string, fetch FetchFunc[T], key func(T) string) (string, error) {
+	d, err := fetch(ctx, token)
+	if status.Code(err) == codes.OutOfRange && token != "" {
+		d, err = fetch(ctx, "")
+	}
+	if err != nil {
+		return token, err
+	}
+	Merge(store, d, key)
+	return d.Token, nil
+}
+
+// MapStore is an in-memory Store that is safe for concurrent use.
+type MapStore[T proto.Message] struct {
+	mu    sync.RWMutex
+	items map[string]T
+}
+
+// Put stores the item with the passed key.
+func (s *MapStore[T]) Put(key string, item T) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.items == nil {
+		s.items = make(map[string]T)
+	}
+	s.items[key] = item
+}
+
+// Delete deletes the item with the passed key.
+func (s *MapStore[T]) Delete(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.items, key)
+}
+
+// Reset deletes all items.
+func (s *MapStore[T]) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.items = nil
+}
+
+// Get returns the item with the passed key.
+func (s *MapStore[T]) Get(key string) (T, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	item, ok := s.items[key]
+	return item, ok
+}
+
+// Len returns the number of the stored items.
+func (s *MapStore[T]) Len() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.items)
+}
@@ -0,0 +1,82 @@
+package delta_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/clouway/go-genproto/clouwayapis/rpc/delta"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/typepb"
+)
+
+// The test resources are fields with their number as version.
+func versionOf(f *typepb.Field) int64 { return int64(f.Number) }
+func keyOf(f *typepb.Field) string    { return f.Name }
+
+func TestIssuer(t *testing.T) {
+	issuer := delta.Issuer{Scope: "customers/42/devices"}
+
+	version, err := issuer.Parse(issuer.Token(17), 10)
+	if err != nil || version != 17 {
+		t.Errorf("unexpected version:\n- want: %v\n-  got: %v (%v)", 17, version, err)
+	}
+	if _, err := issuer.Parse(issuer.Token(5), 10); status.Code(err) != codes.OutOfRange {
+		t.Errorf("unexpected error of expired token:\n- want: %v\n-  got: %v", codes.OutOfRange, err)
+	}
+	if _, err := (delta.Issuer{Scope: "customers/43/devices"}).Parse(issuer.Token(17), 0); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("unexpected error of foreign token:\n- want: %v\n-  got: %v", codes.InvalidArgument, err)
+	}
+	if _, err := issuer.Parse("garbage!", 0); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("unexpected error of invalid token:\n- want: %v\n-  got: %v", codes.InvalidArgument, err)
+	}
+}
+
+func TestSync(t *testing.T) {
+	issuer := delta.Issuer{Scope: "fields"}
+	fields := []*typepb.Field{{Name: "a", Number: 1}, {Name: "b", Number: 2}}
+	var tombstones []delta.Tombstone
+	oldest := int64(0)
+	fetch := func(_ context.Context, token string) (delta.Delta[*typepb.Field], error) {
+		since, err := issuer.Parse(token, oldest)
+		if err != nil {
+			return delta.Delta[*typepb.Field]{}, err
+		}
+		return delta.NewDelta(issuer, since, fields, versionOf, keyOf, tombstones), nil
+	}
+	store := &delta.MapStore[*typepb.Field]{}
+
+	token, err := delta.Sync[*typepb.Field](context.Background(), store, "", fetch, keyOf)
+	if err != nil || store.Len() != 2 {
+		t.Fatalf("unexpected full sync: %d items (%v)", store.Len(), err)
+	}
+
+	fields = []*typepb.Field{{Name: "b", Number: 2}, {Name: "c", Number: 4}}
+	tombstones = []delta.Tombstone{{Name: "a", Version: 3}}
+	if token, err = delta.Sync[*typepb.Field](context.Background(), store, token, fetch, keyOf); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok := store.Get("a"); ok || store.Len() != 2 {
+		t.Errorf("unexpected store after the delta: %d items", store.Len())
+	}
+
+	// The field a is created again after its deletion.
+	fields = append(fields, &typepb.Field{Name: "a", Number: 6})
+	tombstones = []delta.Tombstone{{Name: "a", Version: 5}}
+	if token, err = delta.Sync[*typepb.Field](context.Background(), store, token, fetch, keyOf); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok := store.Get("a"); !ok || store.Len() != 3 {
+		t.Errorf("unexpected store after the re-creation: %d items", store.Len())
+	}
+
+	// The tombstones before version 7 are no longer retained.
+	oldest = 7
+	fields = append(fields, &typepb.Field{Name: "d", Number: 7})
+	if _, err = delta.Sync[*typepb.Field](context.Background(), store, token, fetch, keyOf); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if store.Len() != 4 {
+		t.Errorf("unexpected items after the resync:\n- want: %v\n-  got: %v", 4, store.Len())
+	}
+}
@@ -0,0 +1,67 @@
+// Package errreport defines the integration point of error reporting
+// services such as Sentry or Cloud Error Reporting. The reporter is invoked
+// by the httpkit error encoder and by the grpckit recovery interceptors for
+// errors with severity above the configured one, so that the unexpected
+// errors are reported without reporting calls in the handlers. A Sentry
+// adapter is as simple as:
+//
+//	reporter := errreport.ReporterFunc(func(ctx context.Context, err error) {
+//		sentry.CaptureException(err)
+//	})
+package errreport
+
+import (
+	"context"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Reporter reports errors to an error reporting service.
+type Reporter interface {
+	Report(ctx context.Context, err error)
+}
+
+// ReporterFunc is an adapter to allow the use of ordinary functions as
+// Reporter.
+type ReporterFunc func(ctx context.Context, err error)
+
+// Report calls f(ctx, err).
+func (f ReporterFunc) Report(ctx context.Context, err error) {
+	f(ctx, err)
+}
+
+// Severity is the severity of an error.
+type Severity int
+
+const (
+	// SeverityInfo is the severity of the errors caused by the clients, such
+	// as NotFound or InvalidArgument.
+	SeverityInfo Severity = iota
+	// SeverityWarning is the severity of the errors that are expected to
+	// happen from time to time, such as Unavailable or DeadlineExceeded.
+	SeverityWarning
+	// SeverityError is the severity of the unexpected errors, such as
+	// Internal or Unknown.
+	SeverityError
+)
+
+// SeverityOf returns the severity of the status code.
+func SeverityOf(code codes.Code) Severity {
+	switch code {
+	case codes.Unknown, codes.Internal, codes.DataLoss:
+		return SeverityError
+	case codes.DeadlineExceeded, codes.Unavailable, codes.Unimplemented:
+		return SeverityWarning
+	}
+	return SeverityInfo
+}
+
+// Reportable reports whether the error has severity at or above the passed
+// one. Errors that are not status errors are considered Unknown.
+func Reportable(err error, min Severity) bool {
+	if err == nil {
+		return false
+	}
+	return SeverityOf(status.Code(err)) >= min
+}
@@ -0,0 +1,32 @@
+package errreport_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/clouway/go-genproto/clouwayapis/rpc/errreport"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestReportable(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		min  errreport.Severity
+		want bool
+	}{
+		{name: "internal", err: status.Error(codes.Internal, "internal"), min: errreport.SeverityError, want: true},
+		{name: "plain error", err: errors.New("failure"), min: errreport.SeverityError, want: true},
+		{name: "not found", err: status.Error(codes.NotFound, "not found"), min: errreport.SeverityError, want: false},
+		{name: "unavailable above warning", err: status.Error(codes.Unavailable, "unavailable"), min: errreport.SeverityWarning, want: true},
+		{name: "nil", err: nil, min: errreport.SeverityInfo, want: false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := errreport.Reportable(test.err, test.min); got != test.want {
+				t.Errorf("unexpected reportable:\n- want: %v\n-  got: %v", test.want, got)
+			}
+		})
+	}
+}
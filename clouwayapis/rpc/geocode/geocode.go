@@ -0,0 +1,242 @@
+// Package geocode defines the adapter interface for forward and reverse
+// geocoding providers together with the middlewares that are shared by all
+// of them, so that the services such as the field-service scheduling don't
+// embed the SDKs of the providers directly. The results use the standard
+// google.type.LatLng and google.type.PostalAddress messages.
+package geocode
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/genproto/googleapis/type/latlng"
+	"google.golang.org/genproto/googleapis/type/postaladdress"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+// Geocoder is implemented by the adapters of the geocoding providers.
+type Geocoder interface {
+	// Geocode returns the locations that match the passed address.
+	Geocode(ctx context.Context, address string) ([]*Result, error)
+
+	// ReverseGeocode returns the addresses of the passed location.
+	ReverseGeocode(ctx context.Context, location *latlng.LatLng) ([]*Result, error)
+}
+
+// Result is a single match of the geocoding provider.
+type Result struct {
+	// Location is the geographic point of the match.
+	Location *latlng.LatLng
+
+	// Address is the structured postal address of the match.
+	Address *postaladdress.PostalAddress
+
+	// FormattedAddress is the human readable address as returned by the
+	// provider.
+	FormattedAddress string
+
+	// PlaceID is the identifier of the place at the provider.
+	PlaceID string
+
+	// Partial is true when the provider matched only a part of the address.
+	Partial bool
+}
+
+// clone returns a deep copy of the result, so the cached results cannot be
+// changed by the callers.
+func (r *Result) clone() *Result {
+	c := *r
+	if r.Location != nil {
+		c.Location = proto.Clone(r.Location).(*latlng.LatLng)
+	}
+	if r.Address != nil {
+		c.Address = proto.Clone(r.Address).(*postaladdress.PostalAddress)
+	}
+	return &c
+}
+
+// Middleware is a chainable behavior modifier of the Geocoders.
+type Middleware func(Geocoder) Geocoder
+
+// Chain decorates the geocoder with the passed middlewares. The first
+// middleware is the outermost one.
+func Chain(g Geocoder, middlewares ...Middleware) Geocoder {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		g = middlewares[i](g)
+	}
+	return g
+}
+
+// CacheOption sets an optional parameter for the Caching middleware.
+type CacheOption func(*cache)
+
+// CacheTTL sets for how long the results are cached. The results are cached
+// for 24 hours by default.
+func CacheTTL(ttl time.Duration) CacheOption {
+	return func(c *cache) { c.ttl = ttl }
+}
+
+// CacheSize sets the maximum number of cached queries. The least recently
+// stored queries are evicted first. The limit is 10000 by default.
+func CacheSize(n int) CacheOption {
+	return func(c *cache) { c.size = n }
+}
+
+// CachePrecision sets the number of decimal places of the coordinates that
+// are used as the key of the reverse geocoding queries. The default of 5
+// places is about a meter at the equator.
+func CachePrecision(places int) CacheOption {
+	return func(c *cache) { c.precision = math.Pow10(places) }
+}
+
+// Caching returns a middleware that caches the results of the successful
+// queries in memory. The addresses are compared case-insensitively and with
+// normalized white space, and the locations are compared after rounding.
+func Caching(options ...CacheOption) Middleware {
+	return func(next Geocoder) Geocoder {
+		c := &cache{
+			next:      next,
+			ttl:       24 * time.Hour,
+			size:      10000,
+			precision: math.Pow10(5),
+			entries:   make(map[string]*cacheEntry),
+			now:       time.Now,
+		}
+		for _, option := range options {
+			option(c)
+		}
+		return c
+	}
+}
+
+type cache struct {
+	next      Geocoder
+	ttl       time.Duration
+	size      int
+	precision float64
+	now       func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]*cacheEntry
+	order   []string
+}
+
+type cacheEntry struct {
+	results []*Result
+	expires time.Time
+}
+
+func (c *cache) Geocode(ctx context.Context, address string) ([]*Result, error) {
+	key := "a:" + strings.ToLower(strings.Join(strings.Fields(address), " "))
+	return c.lookup(key, func() ([]*Result, error) { return c.next.Geocode(ctx, address) })
+}
+
+func (c *cache) ReverseGeocode(ctx context.Context, location *latlng.LatLng) ([]*Result, error) {
+	key := fmt.Sprintf("l:%v,%v",
+		math.Round(location.GetLatitude()*c.precision)/c.precision,
+		math.Round(location.GetLongitude()*c.precision)/c.precision,
+	)
+	return c.lookup(key, func() ([]*Result, error) { return c.next.ReverseGeocode(ctx, location) })
+}
+
+func (c *cache) lookup(key string, query func() ([]*Result, error)) ([]*Result, error) {
+	c.mu.Lock()
+	e, ok := c.entries[key]
+	c.mu.Unlock()
+	if ok && c.now().Before(e.expires) {
+		return cloneResults(e.results), nil
+	}
+
+	results, err := query()
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.entries[key]; !ok {
+		c.order = append(c.order, key)
+	}
+	c.entries[key] = &cacheEntry{results: cloneResults(results), expires: c.now().Add(c.ttl)}
+	for len(c.order) > c.size {
+		delete(c.entries, c.order[0])
+		c.order = c.order[1:]
+	}
+	return results, nil
+}
+
+func cloneResults(results []*Result) []*Result {
+	clones := make([]*Result, len(results))
+	for i, r := range results {
+		clones[i] = r.clone()
+	}
+	return clones
+}
+
+// RateLimit returns a middleware that limits the queries toward the provider
+// to the passed rate with bursts of up to burst queries. The queries wait for
+// their turn until the context is done, in which case a ResourceExhausted
+// status error is returned.
+func RateLimit(every time.Duration, burst int) Middleware {
+	return func(next Geocoder) Geocoder {
+		return &rateLimiter{next: next, every: every, burst: burst, tokens: float64(burst), last: time.Now()}
+	}
+}
+
+type rateLimiter struct {
+	next  Geocoder
+	every time.Duration
+	burst int
+
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+func (l *rateLimiter) Geocode(ctx context.Context, address string) ([]*Result, error) {
+	if err := l.wait(ctx); err != nil {
+		return nil, err
+	}
+	return l.next.Geocode(ctx, address)
+}
+
+func (l *rateLimiter) ReverseGeocode(ctx context.Context, location *latlng.LatLng) ([]*Result, error) {
+	if err := l.wait(ctx); err != nil {
+		return nil, err
+	}
+	return l.next.ReverseGeocode(ctx, location)
+}
+
+// wait takes a token from the bucket, waiting for it when the bucket is
+// empty.
+func (l *rateLimiter) wait(ctx context.Context) error {
+	l.mu.Lock()
+	now := time.Now()
+	l.tokens = math.Min(float64(l.burst), l.tokens+float64(now.Sub(l.last))/float64(l.every))
+	l.last = now
+	l.tokens--
+	delay := time.Duration(-l.tokens * float64(l.every))
+	l.mu.Unlock()
+	if delay <= 0 {
+		return nil
+	}
+
+	t := time.NewTimer(delay)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		// Return the token that was not used.
+		l.mu.Lock()
+		l.tokens++
+		l.mu.Unlock()
+		return status.Errorf(codes.ResourceExhausted, "geocoding rate limit exceeded: %v", ctx.Err())
+	}
+}
@@ -0,0 +1,91 @@
+package geocode_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/clouway/go-genproto/clouwayapis/rpc/geocode"
+	"google.golang.org/genproto/googleapis/type/latlng"
+	"google.golang.org/genproto/googleapis/type/postaladdress"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type fakeGeocoder struct {
+	calls int
+}
+
+func (f *fakeGeocoder) Geocode(_ context.Context, address string) ([]*geocode.Result, error) {
+	f.calls++
+	return []*geocode.Result{{
+		Location:         &latlng.LatLng{Latitude: 43.2141, Longitude: 27.9147},
+		Address:          &postaladdress.PostalAddress{RegionCode: "BG", Locality: "Varna"},
+		FormattedAddress: address,
+	}}, nil
+}
+
+func (f *fakeGeocoder) ReverseGeocode(_ context.Context, location *latlng.LatLng) ([]*geocode.Result, error) {
+	f.calls++
+	return []*geocode.Result{{Location: location}}, nil
+}
+
+func TestCachingGeocode(t *testing.T) {
+	fake := &fakeGeocoder{}
+	g := geocode.Chain(fake, geocode.Caching())
+
+	first, _ := g.Geocode(context.Background(), "Varna,  Bulgaria")
+	first[0].Address.Locality = "changed"
+	g.Geocode(context.Background(), "Sofia, Bulgaria")
+	second, err := g.Geocode(context.Background(), "VARNA, Bulgaria")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if fake.calls != 2 {
+		t.Errorf("unexpected provider calls:\n- want: %v\n-  got: %v", 2, fake.calls)
+	}
+	if got := second[0].Address.Locality; got != "Varna" {
+		t.Errorf("unexpected cached locality:\n- want: %v\n-  got: %v", "Varna", got)
+	}
+}
+
+func TestCachingReverseGeocode(t *testing.T) {
+	fake := &fakeGeocoder{}
+	g := geocode.Chain(fake, geocode.Caching(geocode.CachePrecision(3)))
+
+	g.ReverseGeocode(context.Background(), &latlng.LatLng{Latitude: 43.21411, Longitude: 27.91472})
+	g.ReverseGeocode(context.Background(), &latlng.LatLng{Latitude: 43.21409, Longitude: 27.91468})
+
+	if fake.calls != 1 {
+		t.Errorf("unexpected provider calls:\n- want: %v\n-  got: %v", 1, fake.calls)
+	}
+}
+
+func TestCacheSize(t *testing.T) {
+	fake := &fakeGeocoder{}
+	g := geocode.Chain(fake, geocode.Caching(geocode.CacheSize(1)))
+
+	g.Geocode(context.Background(), "Sofia")
+	g.Geocode(context.Background(), "Varna")
+	g.Geocode(context.Background(), "Sofia")
+
+	if fake.calls != 3 {
+		t.Errorf("unexpected provider calls:\n- want: %v\n-  got: %v", 3, fake.calls)
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	g := geocode.Chain(&fakeGeocoder{}, geocode.RateLimit(time.Hour, 1))
+
+	if _, err := g.Geocode(context.Background(), "Sofia"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err := g.Geocode(ctx, "Varna")
+	if got := status.Code(err); got != codes.ResourceExhausted {
+		t.Errorf("unexpected status code:\n- want: %v\n-  got: %v", codes.ResourceExhausted, got)
+	}
+}
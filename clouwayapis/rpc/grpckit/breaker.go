@@ -0,0 +1,52 @@
+package grpckit
+
+import (
+	"context"
+
+	"github.com/clouway/go-genproto/clouwayapis/rpc/breaker"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// UnaryClientCircuitBreaker returns a unary client interceptor that sends
+// the calls through the circuit breaker. The calls that fail with
+// Unavailable, DeadlineExceeded, Internal or Unknown are counted as
+// failures, and the calls that are rejected by the open breaker fail with
+// its Unavailable status error with RetryInfo.
+func UnaryClientCircuitBreaker(b *breaker.Breaker) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		done, err := b.Allow()
+		if err != nil {
+			return err
+		}
+		err = invoker(ctx, method, req, reply, cc, opts...)
+		done(!isBreakerFailure(err))
+		return err
+	}
+}
+
+// StreamClientCircuitBreaker is the stream variant of
+// UnaryClientCircuitBreaker. Only the establishing of the streams is
+// counted.
+func StreamClientCircuitBreaker(b *breaker.Breaker) grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		done, err := b.Allow()
+		if err != nil {
+			return nil, err
+		}
+		stream, err := streamer(ctx, desc, cc, method, opts...)
+		done(!isBreakerFailure(err))
+		return stream, err
+	}
+}
+
+// isBreakerFailure reports whether the error indicates a failure of the
+// server rather than of the call.
+func isBreakerFailure(err error) bool {
+	switch status.Code(err) {
+	case codes.Unavailable, codes.DeadlineExceeded, codes.Internal, codes.Unknown:
+		return true
+	}
+	return false
+}
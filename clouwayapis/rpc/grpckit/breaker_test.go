@@ -0,0 +1,33 @@
+package grpckit_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/clouway/go-genproto/clouwayapis/rpc/breaker"
+	"github.com/clouway/go-genproto/clouwayapis/rpc/grpckit"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestUnaryClientCircuitBreaker(t *testing.T) {
+	calls := 0
+	invoker := func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		calls++
+		return status.Error(codes.Unavailable, "connection refused")
+	}
+	interceptor := grpckit.UnaryClientCircuitBreaker(breaker.New(breaker.FailureThreshold(2)))
+
+	var err error
+	for i := 0; i < 3; i++ {
+		err = interceptor(context.Background(), "/clouway.Orders/GetOrder", nil, nil, nil, invoker)
+	}
+
+	if calls != 2 {
+		t.Errorf("unexpected calls:\n- want: %v\n-  got: %v", 2, calls)
+	}
+	if want, got := "circuit breaker is open", status.Convert(err).Message(); want != got {
+		t.Errorf("unexpected error:\n- want: %v\n-  got: %v", want, got)
+	}
+}
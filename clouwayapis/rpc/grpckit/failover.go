@@ -0,0 +1,185 @@
+package grpckit
+
+import (
+	"context"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+)
+
+// Region identifies one of the regional endpoints of a FailoverConn.
+type Region int32
+
+const (
+	// NoRegion means that the FailoverConn is not pinned to any region.
+	NoRegion Region = iota
+	// PrimaryRegion is the region that serves all calls when it is healthy.
+	PrimaryRegion
+	// SecondaryRegion is the region that takes over the reads when the
+	// primary region is unavailable.
+	SecondaryRegion
+)
+
+// FailoverOption sets an optional parameter for FailoverConn.
+type FailoverOption func(*FailoverConn)
+
+// FailoverReadMethods sets the function that decides which of the full
+// method names (/package.Service/Method) are reads that are safe to be
+// sent to the secondary region. By default methods starting with Get, List
+// and Search are considered reads.
+func FailoverReadMethods(isRead func(fullMethod string) bool) FailoverOption {
+	return func(c *FailoverConn) { c.isRead = isRead }
+}
+
+// FailoverHealthService sets the service name that is used in the health
+// checks of the regions. The overall server health is checked by default.
+func FailoverHealthService(service string) FailoverOption {
+	return func(c *FailoverConn) { c.healthService = service }
+}
+
+// FailoverConn is a grpc.ClientConnInterface that sends the calls to the
+// primary regional endpoint and fails over the reads to the secondary one
+// when the primary region is unhealthy or is responding with Unavailable.
+// Writes are always sent to the primary region unless the connection is
+// pinned to the secondary one.
+type FailoverConn struct {
+	primary   grpc.ClientConnInterface
+	secondary grpc.ClientConnInterface
+
+	isRead        func(fullMethod string) bool
+	healthService string
+
+	pinned           int32
+	primaryHealthy   int32
+	secondaryHealthy int32
+}
+
+// NewFailoverConn creates a new FailoverConn over the connections to the
+// primary and the secondary region. Both regions are considered healthy
+// until a health check says otherwise.
+func NewFailoverConn(primary, secondary grpc.ClientConnInterface, options ...FailoverOption) *FailoverConn {
+	c := &FailoverConn{
+		primary:          primary,
+		secondary:        secondary,
+		isRead:           isReadMethod,
+		primaryHealthy:   1,
+		secondaryHealthy: 1,
+	}
+	for _, option := range options {
+		option(c)
+	}
+	return c
+}
+
+// Invoke performs a unary RPC in the active region. Reads that fail with
+// Unavailable are retried in the other region.
+func (c *FailoverConn) Invoke(ctx context.Context, method string, args interface{}, reply interface{}, opts ...grpc.CallOption) error {
+	conn, fallback := c.route(method)
+	err := conn.Invoke(ctx, method, args, reply, opts...)
+	if fallback != nil && status.Code(err) == codes.Unavailable {
+		return fallback.Invoke(ctx, method, args, reply, opts...)
+	}
+	return err
+}
+
+// NewStream begins a streaming RPC in the active region. Read streams that
+// cannot be established because of Unavailable are opened in the other
+// region.
+func (c *FailoverConn) NewStream(ctx context.Context, desc *grpc.StreamDesc, method string, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	conn, fallback := c.route(method)
+	stream, err := conn.NewStream(ctx, desc, method, opts...)
+	if fallback != nil && status.Code(err) == codes.Unavailable {
+		return fallback.NewStream(ctx, desc, method, opts...)
+	}
+	return stream, err
+}
+
+// Pin sends all calls, including the writes, to the passed region until
+// Unpin is called. Pinning to NoRegion is the same as calling Unpin.
+func (c *FailoverConn) Pin(region Region) {
+	atomic.StoreInt32(&c.pinned, int32(region))
+}
+
+// Unpin restores the automatic selection of the region.
+func (c *FailoverConn) Unpin() {
+	atomic.StoreInt32(&c.pinned, int32(NoRegion))
+}
+
+// Pinned returns the region to which the connection is pinned.
+func (c *FailoverConn) Pinned() Region {
+	return Region(atomic.LoadInt32(&c.pinned))
+}
+
+// Healthy returns whether the last health check of the region succeeded.
+func (c *FailoverConn) Healthy(region Region) bool {
+	switch region {
+	case PrimaryRegion:
+		return atomic.LoadInt32(&c.primaryHealthy) == 1
+	case SecondaryRegion:
+		return atomic.LoadInt32(&c.secondaryHealthy) == 1
+	}
+	return false
+}
+
+// CheckHealth checks the health of both regions using the standard gRPC
+// health checking protocol and records the result.
+func (c *FailoverConn) CheckHealth(ctx context.Context) {
+	atomic.StoreInt32(&c.primaryHealthy, c.check(ctx, c.primary))
+	atomic.StoreInt32(&c.secondaryHealthy, c.check(ctx, c.secondary))
+}
+
+// WatchHealth checks the health of both regions on every interval until the
+// context is done.
+func (c *FailoverConn) WatchHealth(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		c.CheckHealth(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (c *FailoverConn) check(ctx context.Context, conn grpc.ClientConnInterface) int32 {
+	resp, err := healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{Service: c.healthService})
+	if err != nil || resp.Status != healthpb.HealthCheckResponse_SERVING {
+		return 0
+	}
+	return 1
+}
+
+// route returns the connection to which the method should be sent and the
+// connection to which it could fail over or nil if it should not fail over.
+func (c *FailoverConn) route(method string) (grpc.ClientConnInterface, grpc.ClientConnInterface) {
+	switch c.Pinned() {
+	case PrimaryRegion:
+		return c.primary, nil
+	case SecondaryRegion:
+		return c.secondary, nil
+	}
+	if !c.isRead(method) {
+		return c.primary, nil
+	}
+	if !c.Healthy(PrimaryRegion) && c.Healthy(SecondaryRegion) {
+		return c.secondary, c.primary
+	}
+	return c.primary, c.secondary
+}
+
+func isReadMethod(fullMethod string) bool {
+	name := fullMethod[strings.LastIndex(fullMethod, "/")+1:]
+	for _, prefix := range []string{"Get", "List", "Search"} {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,86 @@
+package grpckit_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/clouway/go-genproto/clouwayapis/rpc/grpckit"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestFailoverConn(t *testing.T) {
+	tests := []struct {
+		name      string
+		method    string
+		pin       grpckit.Region
+		primary   error
+		wantCalls []string
+		wantErr   codes.Code
+	}{
+		{
+			name:      "read from healthy primary",
+			method:    "/clouway.Orders/GetOrder",
+			wantCalls: []string{"primary"},
+		},
+		{
+			name:      "read fails over on unavailable",
+			method:    "/clouway.Orders/GetOrder",
+			primary:   status.Error(codes.Unavailable, "unavailable"),
+			wantCalls: []string{"primary", "secondary"},
+		},
+		{
+			name:      "write does not fail over",
+			method:    "/clouway.Orders/CreateOrder",
+			primary:   status.Error(codes.Unavailable, "unavailable"),
+			wantCalls: []string{"primary"},
+			wantErr:   codes.Unavailable,
+		},
+		{
+			name:      "pinned to secondary",
+			method:    "/clouway.Orders/CreateOrder",
+			pin:       grpckit.SecondaryRegion,
+			wantCalls: []string{"secondary"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var calls []string
+			primary := &fakeConn{name: "primary", err: test.primary, calls: &calls}
+			secondary := &fakeConn{name: "secondary", calls: &calls}
+
+			conn := grpckit.NewFailoverConn(primary, secondary)
+			conn.Pin(test.pin)
+			err := conn.Invoke(context.Background(), test.method, nil, nil)
+
+			if got := status.Code(err); got != test.wantErr {
+				t.Errorf("unexpected error code:\n- want: %v\n-  got: %v", test.wantErr, got)
+			}
+			if len(calls) != len(test.wantCalls) {
+				t.Fatalf("unexpected calls:\n- want: %v\n-  got: %v", test.wantCalls, calls)
+			}
+			for i := range calls {
+				if calls[i] != test.wantCalls[i] {
+					t.Errorf("unexpected calls:\n- want: %v\n-  got: %v", test.wantCalls, calls)
+				}
+			}
+		})
+	}
+}
+
+type fakeConn struct {
+	name  string
+	err   error
+	calls *[]string
+}
+
+func (c *fakeConn) Invoke(ctx context.Context, method string, args interface{}, reply interface{}, opts ...grpc.CallOption) error {
+	*c.calls = append(*c.calls, c.name)
+	return c.err
+}
+
+func (c *fakeConn) NewStream(ctx context.Context, desc *grpc.StreamDesc, method string, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	*c.calls = append(*c.calls, c.name)
+	return nil, c.err
+}
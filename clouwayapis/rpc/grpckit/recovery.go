@@ -0,0 +1,84 @@
+package grpckit
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+
+	"github.com/clouway/go-genproto/clouwayapis/rpc/errreport"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// RecoveryOption sets an optional parameter for the recovery interceptors.
+type RecoveryOption func(*recovery)
+
+// RecoveryReporter sets the reporter that receives the recovered panics and
+// the errors returned by the handlers with severity at or above the passed
+// one.
+func RecoveryReporter(reporter errreport.Reporter, min errreport.Severity) RecoveryOption {
+	return func(r *recovery) {
+		r.reporter = reporter
+		r.reportSeverity = min
+	}
+}
+
+// UnaryRecoveryInterceptor returns a unary server interceptor that recovers
+// the panics of the handlers and returns them as Internal status errors.
+func UnaryRecoveryInterceptor(options ...RecoveryOption) grpc.UnaryServerInterceptor {
+	r := newRecovery(options...)
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
+		defer func() {
+			if v := recover(); v != nil {
+				resp, err = nil, r.recovered(ctx, info.FullMethod, v)
+			}
+		}()
+		resp, err = handler(ctx, req)
+		r.report(ctx, err)
+		return resp, err
+	}
+}
+
+// StreamRecoveryInterceptor returns a stream server interceptor that
+// recovers the panics of the handlers and returns them as Internal status
+// errors.
+func StreamRecoveryInterceptor(options ...RecoveryOption) grpc.StreamServerInterceptor {
+	r := newRecovery(options...)
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+		defer func() {
+			if v := recover(); v != nil {
+				err = r.recovered(ss.Context(), info.FullMethod, v)
+			}
+		}()
+		err = handler(srv, ss)
+		r.report(ss.Context(), err)
+		return err
+	}
+}
+
+type recovery struct {
+	reporter       errreport.Reporter
+	reportSeverity errreport.Severity
+}
+
+func newRecovery(options ...RecoveryOption) *recovery {
+	r := &recovery{reportSeverity: errreport.SeverityError}
+	for _, option := range options {
+		option(r)
+	}
+	return r
+}
+
+func (r *recovery) recovered(ctx context.Context, method string, v interface{}) error {
+	if r.reporter != nil {
+		r.reporter.Report(ctx, fmt.Errorf("panic in %s: %v\n%s", method, v, debug.Stack()))
+	}
+	return status.Error(codes.Internal, "internal error")
+}
+
+func (r *recovery) report(ctx context.Context, err error) {
+	if r.reporter != nil && errreport.Reportable(err, r.reportSeverity) {
+		r.reporter.Report(ctx, err)
+	}
+}
@@ -0,0 +1,32 @@
+package grpckit_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/clouway/go-genproto/clouwayapis/rpc/errreport"
+	"github.com/clouway/go-genproto/clouwayapis/rpc/grpckit"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestUnaryRecoveryInterceptor(t *testing.T) {
+	var reported []error
+	reporter := errreport.ReporterFunc(func(ctx context.Context, err error) {
+		reported = append(reported, err)
+	})
+	interceptor := grpckit.UnaryRecoveryInterceptor(grpckit.RecoveryReporter(reporter, errreport.SeverityError))
+	info := &grpc.UnaryServerInfo{FullMethod: "/clouway.Orders/GetOrder"}
+
+	_, err := interceptor(context.Background(), nil, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		panic("boom")
+	})
+
+	if got := status.Code(err); got != codes.Internal {
+		t.Errorf("unexpected error code:\n- want: %v\n-  got: %v", codes.Internal, got)
+	}
+	if len(reported) != 1 {
+		t.Errorf("unexpected number of reported errors:\n- want: %v\n-  got: %v", 1, len(reported))
+	}
+}
@@ -0,0 +1,163 @@
+package grpckit
+
+import (
+	"context"
+	"strings"
+
+	"google.golang.org/genproto/googleapis/api/visibility"
+	"google.golang.org/grpc"
+	reflectionpb "google.golang.org/grpc/reflection/grpc_reflection_v1"
+	reflectionalphapb "google.golang.org/grpc/reflection/grpc_reflection_v1alpha"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// ReflectionFilter returns a stream server interceptor that hides services
+// and methods from the gRPC server reflection when it is queried by external
+// principals. The hidden symbols are full service names (clouway.Orders) or
+// full method names (clouway.Orders.DeleteOrder). The services and methods
+// with a restriction in the google.api.api_visibility or
+// google.api.method_visibility option are hidden as well:
+//
+//	rpc DeleteOrder(DeleteOrderRequest) returns (Order) {
+//	  option (google.api.method_visibility).restriction = "INTERNAL";
+//	}
+//
+// The hidden methods are removed from the file descriptors of the responses,
+// so the rest of their services and files can still be resolved. The
+// isInternal function decides whether the caller is internal, e.g by the
+// authenticated principal in the context, and internal callers see all of
+// the services.
+func ReflectionFilter(isInternal func(ctx context.Context) bool, hidden ...string) grpc.StreamServerInterceptor {
+	f := reflectionFilter(hidden)
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if !isReflectionMethod(info.FullMethod) || isInternal(ss.Context()) {
+			return handler(srv, ss)
+		}
+		return handler(srv, &reflectionFilterStream{ServerStream: ss, filter: f})
+	}
+}
+
+func isReflectionMethod(fullMethod string) bool {
+	return fullMethod == "/"+reflectionpb.ServerReflection_ServiceDesc.ServiceName+"/ServerReflectionInfo" ||
+		fullMethod == "/"+reflectionalphapb.ServerReflection_ServiceDesc.ServiceName+"/ServerReflectionInfo"
+}
+
+type reflectionFilterStream struct {
+	grpc.ServerStream
+	filter reflectionFilter
+}
+
+func (s *reflectionFilterStream) SendMsg(m interface{}) error {
+	switch resp := m.(type) {
+	case *reflectionpb.ServerReflectionResponse:
+		s.filter.filterResponse(resp)
+	case *reflectionalphapb.ServerReflectionResponse:
+		// Both versions of the reflection protocol share the wire format, so
+		// the response is filtered as v1 message.
+		b, err := proto.Marshal(resp)
+		if err != nil {
+			return err
+		}
+		v1 := &reflectionpb.ServerReflectionResponse{}
+		if err := proto.Unmarshal(b, v1); err != nil {
+			return err
+		}
+		s.filter.filterResponse(v1)
+		if b, err = proto.Marshal(v1); err != nil {
+			return err
+		}
+		filtered := &reflectionalphapb.ServerReflectionResponse{}
+		if err := proto.Unmarshal(b, filtered); err != nil {
+			return err
+		}
+		m = filtered
+	}
+	return s.ServerStream.SendMsg(m)
+}
+
+type reflectionFilter []string
+
+func (f reflectionFilter) hides(symbol string) bool {
+	for _, h := range f {
+		if symbol == h || strings.HasPrefix(symbol, h+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// hidesService reports whether the service is hidden by the configuration
+// or by the visibility option of its registered descriptor.
+func (f reflectionFilter) hidesService(name string) bool {
+	if f.hides(name) {
+		return true
+	}
+	d, err := protoregistry.GlobalFiles.FindDescriptorByName(protoreflect.FullName(name))
+	if err != nil {
+		return false
+	}
+	sd, ok := d.(protoreflect.ServiceDescriptor)
+	return ok && isRestricted(sd.Options(), visibility.E_ApiVisibility)
+}
+
+// isRestricted reports whether the options have a visibility rule with a
+// restriction.
+func isRestricted(options proto.Message, xt protoreflect.ExtensionType) bool {
+	rule, _ := proto.GetExtension(options, xt).(*visibility.VisibilityRule)
+	return rule.GetRestriction() != ""
+}
+
+func (f reflectionFilter) filterResponse(resp *reflectionpb.ServerReflectionResponse) {
+	switch r := resp.MessageResponse.(type) {
+	case *reflectionpb.ServerReflectionResponse_ListServicesResponse:
+		services := r.ListServicesResponse.Service[:0]
+		for _, s := range r.ListServicesResponse.Service {
+			if !f.hidesService(s.Name) {
+				services = append(services, s)
+			}
+		}
+		r.ListServicesResponse.Service = services
+	case *reflectionpb.ServerReflectionResponse_FileDescriptorResponse:
+		for i, b := range r.FileDescriptorResponse.FileDescriptorProto {
+			r.FileDescriptorResponse.FileDescriptorProto[i] = f.filterFile(b)
+		}
+	}
+}
+
+// filterFile removes the hidden services and methods from the serialized
+// file descriptor. The descriptor is returned unchanged if it cannot be
+// parsed.
+func (f reflectionFilter) filterFile(b []byte) []byte {
+	fd := &descriptorpb.FileDescriptorProto{}
+	if err := proto.Unmarshal(b, fd); err != nil {
+		return b
+	}
+	services := fd.Service[:0]
+	for _, s := range fd.Service {
+		name := s.GetName()
+		if fd.GetPackage() != "" {
+			name = fd.GetPackage() + "." + name
+		}
+		if f.hides(name) || isRestricted(s.GetOptions(), visibility.E_ApiVisibility) {
+			continue
+		}
+		methods := s.Method[:0]
+		for _, m := range s.Method {
+			if !f.hides(name+"."+m.GetName()) && !isRestricted(m.GetOptions(), visibility.E_MethodVisibility) {
+				methods = append(methods, m)
+			}
+		}
+		s.Method = methods
+		services = append(services, s)
+	}
+	fd.Service = services
+
+	filtered, err := proto.Marshal(fd)
+	if err != nil {
+		return b
+	}
+	return filtered
+}
@@ -0,0 +1,119 @@
+package grpckit_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/clouway/go-genproto/clouwayapis/rpc/grpckit"
+	"google.golang.org/genproto/googleapis/api/visibility"
+	"google.golang.org/grpc"
+	reflectionpb "google.golang.org/grpc/reflection/grpc_reflection_v1"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+func TestReflectionFilter(t *testing.T) {
+	tests := []struct {
+		name     string
+		internal bool
+		want     []string
+	}{
+		{name: "external principal", internal: false, want: []string{"clouway.Orders"}},
+		{name: "internal principal", internal: true, want: []string{"clouway.Orders", "clouway.Admin"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			interceptor := grpckit.ReflectionFilter(func(ctx context.Context) bool { return test.internal }, "clouway.Admin")
+			stream := &recordingStream{ctx: context.Background()}
+			info := &grpc.StreamServerInfo{FullMethod: "/grpc.reflection.v1.ServerReflection/ServerReflectionInfo"}
+
+			interceptor(nil, stream, info, func(srv interface{}, ss grpc.ServerStream) error {
+				return ss.SendMsg(&reflectionpb.ServerReflectionResponse{
+					MessageResponse: &reflectionpb.ServerReflectionResponse_ListServicesResponse{
+						ListServicesResponse: &reflectionpb.ListServiceResponse{
+							Service: []*reflectionpb.ServiceResponse{{Name: "clouway.Orders"}, {Name: "clouway.Admin"}},
+						},
+					},
+				})
+			})
+
+			var got []string
+			for _, s := range stream.sent[0].(*reflectionpb.ServerReflectionResponse).GetListServicesResponse().Service {
+				got = append(got, s.Name)
+			}
+			if !reflect.DeepEqual(test.want, got) {
+				t.Errorf("unexpected services:\n- want: %v\n-  got: %v", test.want, got)
+			}
+		})
+	}
+}
+
+func TestReflectionFilterFileContainingSymbol(t *testing.T) {
+	internal := &descriptorpb.MethodOptions{}
+	proto.SetExtension(internal, visibility.E_MethodVisibility, &visibility.VisibilityRule{Restriction: "INTERNAL"})
+	admin := &descriptorpb.ServiceOptions{}
+	proto.SetExtension(admin, visibility.E_ApiVisibility, &visibility.VisibilityRule{Restriction: "INTERNAL"})
+	file, _ := proto.Marshal(&descriptorpb.FileDescriptorProto{
+		Name:    proto.String("clouway/orders.proto"),
+		Package: proto.String("clouway"),
+		Service: []*descriptorpb.ServiceDescriptorProto{
+			{Name: proto.String("Orders"), Method: []*descriptorpb.MethodDescriptorProto{
+				{Name: proto.String("GetOrder")},
+				{Name: proto.String("CancelOrder")},
+				{Name: proto.String("DeleteOrder"), Options: internal},
+			}},
+			{Name: proto.String("Admin"), Options: admin},
+		},
+	})
+
+	interceptor := grpckit.ReflectionFilter(func(ctx context.Context) bool { return false }, "clouway.Orders.CancelOrder")
+	stream := &recordingStream{ctx: context.Background()}
+	info := &grpc.StreamServerInfo{FullMethod: "/grpc.reflection.v1.ServerReflection/ServerReflectionInfo"}
+	interceptor(nil, stream, info, func(srv interface{}, ss grpc.ServerStream) error {
+		return ss.SendMsg(&reflectionpb.ServerReflectionResponse{
+			OriginalRequest: &reflectionpb.ServerReflectionRequest{
+				MessageRequest: &reflectionpb.ServerReflectionRequest_FileContainingSymbol{FileContainingSymbol: "clouway.Orders.DeleteOrder"},
+			},
+			MessageResponse: &reflectionpb.ServerReflectionResponse_FileDescriptorResponse{
+				FileDescriptorResponse: &reflectionpb.FileDescriptorResponse{FileDescriptorProto: [][]byte{file}},
+			},
+		})
+	})
+
+	files := stream.sent[0].(*reflectionpb.ServerReflectionResponse).GetFileDescriptorResponse().GetFileDescriptorProto()
+	if len(files) != 1 {
+		t.Fatalf("unexpected file descriptors: %v", files)
+	}
+	fd := &descriptorpb.FileDescriptorProto{}
+	if err := proto.Unmarshal(files[0], fd); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var got []string
+	for _, s := range fd.Service {
+		for _, m := range s.Method {
+			got = append(got, s.GetName()+"."+m.GetName())
+		}
+	}
+	if want := []string{"Orders.GetOrder"}; !reflect.DeepEqual(want, got) {
+		t.Errorf("unexpected methods:\n- want: %v\n-  got: %v", want, got)
+	}
+	if len(fd.Service) != 1 {
+		t.Errorf("unexpected services: %v", fd.Service)
+	}
+}
+
+type recordingStream struct {
+	grpc.ServerStream
+	ctx  context.Context
+	sent []interface{}
+}
+
+func (s *recordingStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *recordingStream) SendMsg(m interface{}) error {
+	s.sent = append(s.sent, m)
+	return nil
+}
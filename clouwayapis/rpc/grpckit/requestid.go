@@ -0,0 +1,39 @@
+package grpckit
+
+import (
+	"context"
+
+	"github.com/clouway/go-genproto/clouwayapis/rpc/request"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// UnaryClientRequestID returns a unary client interceptor that propagates
+// the request id of the context to the outgoing metadata as x-request-id, so
+// the calls to the other services are correlated with the incoming request.
+// The servers restore it by MetadataToContext.
+func UnaryClientRequestID() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		return invoker(outgoingRequestID(ctx), method, req, reply, cc, opts...)
+	}
+}
+
+// StreamClientRequestID is the stream variant of UnaryClientRequestID.
+func StreamClientRequestID() grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		return streamer(outgoingRequestID(ctx), desc, cc, method, opts...)
+	}
+}
+
+// outgoingRequestID appends the request id of the context to the outgoing
+// metadata unless it's already there.
+func outgoingRequestID(ctx context.Context) context.Context {
+	id := request.RequestIDFromContext(ctx)
+	if id == "" {
+		return ctx
+	}
+	if md, ok := metadata.FromOutgoingContext(ctx); ok && len(md.Get(string(request.RequestIDKey))) > 0 {
+		return ctx
+	}
+	return metadata.AppendToOutgoingContext(ctx, string(request.RequestIDKey), id)
+}
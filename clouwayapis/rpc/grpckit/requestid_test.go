@@ -0,0 +1,31 @@
+package grpckit_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/clouway/go-genproto/clouwayapis/rpc/grpckit"
+	"github.com/clouway/go-genproto/clouwayapis/rpc/request"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestUnaryClientRequestID(t *testing.T) {
+	var got metadata.MD
+	invoker := func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		got, _ = metadata.FromOutgoingContext(ctx)
+		return nil
+	}
+	ctx := request.WithRequestID(context.Background(), "abc-123")
+
+	if err := grpckit.UnaryClientRequestID()(ctx, "/clouway.Orders/GetOrder", nil, nil, nil, invoker); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if want, got := []string{"abc-123"}, got.Get("x-request-id"); len(got) != 1 || got[0] != want[0] {
+		t.Errorf("unexpected x-request-id metadata:\n- want: %v\n-  got: %v", want, got)
+	}
+	if want, got := "abc-123", request.RequestIDFromContext(grpckit.MetadataToContext(context.Background(), got)); want != got {
+		t.Errorf("unexpected request id of the server:\n- want: %v\n-  got: %v", want, got)
+	}
+}
@@ -6,11 +6,15 @@ package grpckit
 // status errors.
 
 import (
+	"reflect"
+	"sort"
 	"strings"
 
 	"github.com/clouway/go-genproto/clouwayapis/rpc/errdetails"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
 )
 
 // ValidateRequest validates the incoming request and returns error object
@@ -42,7 +46,159 @@ func ValidateRequest(req interface{}) error {
 	return st.Err()
 }
 
+// FromValidationError converts the error of a validation library into an
+// InvalidArgument status error with BadRequest details that hold a field
+// violation for every invalid field. The supported errors are these of
+// protoc-gen-validate (including the MultiError of ValidateAll),
+// protovalidate, ozzo-validation and go-playground/validator. Other errors
+// are converted to InvalidArgument status without field violations.
+func FromValidationError(err error) error {
+	if err == nil {
+		return nil
+	}
+	violations := fieldViolations(err, "")
+	message := err.Error()
+	if len(violations) > 0 {
+		message = violations[0].Reason
+	}
+	st := status.New(codes.InvalidArgument, message)
+	st, _ = st.WithDetails(&errdetails.BadRequest{Message: message, Errors: violations})
+	return st.Err()
+}
+
+func fieldViolations(err error, prefix string) []*errdetails.BadRequest_FieldViolation {
+	switch e := err.(type) {
+	case validationError:
+		// protoc-gen-validate
+		return []*errdetails.BadRequest_FieldViolation{{Reason: e.Reason(), Field: joinField(prefix, lowerFirst(e.Field()))}}
+	case interface{ AllErrors() []error }:
+		// protoc-gen-validate MultiError
+		var violations []*errdetails.BadRequest_FieldViolation
+		for _, err := range e.AllErrors() {
+			violations = append(violations, fieldViolations(err, prefix)...)
+		}
+		return violations
+	case interface {
+		Namespace() string
+		Tag() string
+	}:
+		// go-playground/validator FieldError
+		return []*errdetails.BadRequest_FieldViolation{{Reason: err.Error(), Field: joinField(prefix, namespaceField(e.Namespace())), Code: e.Tag()}}
+	}
+
+	if m, ok := toProto(err); ok {
+		// protovalidate ValidationError
+		return protoViolations(m, prefix)
+	}
+
+	v := reflect.ValueOf(err)
+	errorType := reflect.TypeOf((*error)(nil)).Elem()
+	switch {
+	case v.Kind() == reflect.Map && v.Type().Key().Kind() == reflect.String && v.Type().Elem().Implements(errorType):
+		// ozzo-validation Errors, keyed by field name and possibly nested
+		keys := v.MapKeys()
+		sort.Slice(keys, func(i, j int) bool { return keys[i].String() < keys[j].String() })
+		var violations []*errdetails.BadRequest_FieldViolation
+		for _, key := range keys {
+			fieldErr, ok := v.MapIndex(key).Interface().(error)
+			if !ok || fieldErr == nil {
+				continue
+			}
+			field := joinField(prefix, key.String())
+			if nested := fieldViolations(fieldErr, field); len(nested) > 0 {
+				violations = append(violations, nested...)
+				continue
+			}
+			violations = append(violations, &errdetails.BadRequest_FieldViolation{Reason: fieldErr.Error(), Field: field})
+		}
+		return violations
+	case v.Kind() == reflect.Slice && v.Type().Elem().Implements(errorType):
+		// go-playground/validator ValidationErrors
+		var violations []*errdetails.BadRequest_FieldViolation
+		for i := 0; i < v.Len(); i++ {
+			if fieldErr, ok := v.Index(i).Interface().(error); ok && fieldErr != nil {
+				violations = append(violations, fieldViolations(fieldErr, prefix)...)
+			}
+		}
+		return violations
+	}
+	return nil
+}
+
+// toProto calls the ToProto method of the error. It's called by reflection,
+// as the method of protovalidate returns *validate.Violations and not
+// proto.Message.
+func toProto(err error) (proto.Message, bool) {
+	method := reflect.ValueOf(err).MethodByName("ToProto")
+	if !method.IsValid() || method.Type().NumIn() != 0 || method.Type().NumOut() != 1 {
+		return nil, false
+	}
+	m, ok := method.Call(nil)[0].Interface().(proto.Message)
+	return m, ok
+}
+
+// protoViolations reads the field violations from the buf.validate.Violations
+// message of protovalidate.
+func protoViolations(m proto.Message, prefix string) []*errdetails.BadRequest_FieldViolation {
+	if m == nil {
+		return nil
+	}
+	msg := m.ProtoReflect()
+	fd := msg.Descriptor().Fields().ByName("violations")
+	if fd == nil || !fd.IsList() || fd.Message() == nil {
+		return nil
+	}
+	var violations []*errdetails.BadRequest_FieldViolation
+	list := msg.Get(fd).List()
+	for i := 0; i < list.Len(); i++ {
+		violation := list.Get(i).Message()
+		violations = append(violations, &errdetails.BadRequest_FieldViolation{
+			Reason: stringField(violation, "message"),
+			Field:  joinField(prefix, stringField(violation, "field_path")),
+			Code:   stringField(violation, "constraint_id"),
+		})
+	}
+	return violations
+}
+
+func stringField(m protoreflect.Message, name protoreflect.Name) string {
+	fd := m.Descriptor().Fields().ByName(name)
+	if fd == nil || fd.Kind() != protoreflect.StringKind {
+		return ""
+	}
+	return m.Get(fd).String()
+}
+
+// namespaceField converts the namespace of go-playground/validator
+// (User.Address.City) into field path relative to the validated struct
+// (address.city).
+func namespaceField(namespace string) string {
+	parts := strings.Split(namespace, ".")
+	if len(parts) > 1 {
+		parts = parts[1:]
+	}
+	for i, part := range parts {
+		if part != "" {
+			parts[i] = lowerFirst(part)
+		}
+	}
+	return strings.Join(parts, ".")
+}
+
+func joinField(prefix, field string) string {
+	if prefix == "" {
+		return field
+	}
+	if field == "" {
+		return prefix
+	}
+	return prefix + "." + field
+}
+
 func lowerFirst(s string) string {
+	if s == "" {
+		return s
+	}
 	return strings.ToLower(s[:1]) + s[1:]
 }
 
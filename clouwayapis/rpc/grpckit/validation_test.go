@@ -1,13 +1,20 @@
 package grpckit_test
 
 import (
+	"errors"
 	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/clouway/go-genproto/clouwayapis/rpc/errdetails"
 	"github.com/clouway/go-genproto/clouwayapis/rpc/grpckit"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
 )
 
 func TestValidation(t *testing.T) {
@@ -80,3 +87,164 @@ func (f *fieldError) Cause() error {
 func (f *fieldError) ErrorName() string {
 	return ""
 }
+
+func TestFromValidationError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want []*errdetails.BadRequest_FieldViolation
+	}{
+		{
+			name: "protoc-gen-validate multi error",
+			err: multiError{
+				&fieldError{field: "Name", reason: "name is required"},
+				&fieldError{field: "Email", reason: "email is invalid"},
+			},
+			want: []*errdetails.BadRequest_FieldViolation{
+				{Reason: "name is required", Field: "name"},
+				{Reason: "email is invalid", Field: "email"},
+			},
+		},
+		{
+			name: "ozzo-validation errors",
+			err: ozzoErrors{
+				"name":    errors.New("cannot be blank"),
+				"address": ozzoErrors{"city": errors.New("cannot be blank")},
+			},
+			want: []*errdetails.BadRequest_FieldViolation{
+				{Reason: "cannot be blank", Field: "address.city"},
+				{Reason: "cannot be blank", Field: "name"},
+			},
+		},
+		{
+			name: "protovalidate validation error",
+			err:  newProtovalidateError(t, "email", "string.email", "value must be a valid email address"),
+			want: []*errdetails.BadRequest_FieldViolation{
+				{Reason: "value must be a valid email address", Field: "email", Code: "string.email"},
+			},
+		},
+		{
+			name: "go-playground validation errors",
+			err: playgroundErrors{
+				playgroundFieldError{namespace: "User.Address.City", tag: "required"},
+			},
+			want: []*errdetails.BadRequest_FieldViolation{
+				{Reason: "Key: 'User.Address.City' Error:Field validation for 'City' failed on the 'required' tag", Field: "address.city", Code: "required"},
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			st, _ := status.FromError(grpckit.FromValidationError(test.err))
+
+			if st.Code() != codes.InvalidArgument {
+				t.Errorf("unexpected code:\n- want: %v\n-  got: %v", codes.InvalidArgument, st.Code())
+			}
+			got := st.Details()[0].(*errdetails.BadRequest).Errors
+			if len(got) != len(test.want) {
+				t.Fatalf("unexpected field violations:\n- want: %v\n-  got: %v", test.want, got)
+			}
+			for i := range got {
+				if !proto.Equal(test.want[i], got[i]) {
+					t.Errorf("unexpected field violation:\n- want: %v\n-  got: %v", test.want[i], got[i])
+				}
+			}
+		})
+	}
+}
+
+type multiError []error
+
+func (m multiError) Error() string {
+	return "multiple errors"
+}
+
+func (m multiError) AllErrors() []error {
+	return m
+}
+
+type ozzoErrors map[string]error
+
+func (e ozzoErrors) Error() string {
+	return "ozzo errors"
+}
+
+type playgroundErrors []playgroundFieldError
+
+func (e playgroundErrors) Error() string {
+	return "playground errors"
+}
+
+type playgroundFieldError struct {
+	namespace string
+	tag       string
+}
+
+func (e playgroundFieldError) Namespace() string {
+	return e.namespace
+}
+
+func (e playgroundFieldError) Tag() string {
+	return e.tag
+}
+
+func (e playgroundFieldError) Error() string {
+	field := e.namespace[strings.LastIndex(e.namespace, ".")+1:]
+	return "Key: '" + e.namespace + "' Error:Field validation for '" + field + "' failed on the '" + e.tag + "' tag"
+}
+
+// protovalidateError mirrors the ValidationError of protovalidate, whose
+// ToProto method returns the concrete *validate.Violations message.
+type protovalidateError struct {
+	violations *dynamicpb.Message
+}
+
+func (e *protovalidateError) Error() string {
+	return "validation error"
+}
+
+func (e *protovalidateError) ToProto() *dynamicpb.Message {
+	return e.violations
+}
+
+// newProtovalidateError builds the error with the buf.validate.Violations
+// message of a single violation.
+func newProtovalidateError(t *testing.T, fieldPath, constraintID, message string) error {
+	field := func(name string, number int32, label descriptorpb.FieldDescriptorProto_Label, typ descriptorpb.FieldDescriptorProto_Type, typeName string) *descriptorpb.FieldDescriptorProto {
+		fd := &descriptorpb.FieldDescriptorProto{Name: proto.String(name), Number: proto.Int32(number), Label: label.Enum(), Type: typ.Enum()}
+		if typeName != "" {
+			fd.TypeName = proto.String(typeName)
+		}
+		return fd
+	}
+	optional, repeated := descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL, descriptorpb.FieldDescriptorProto_LABEL_REPEATED
+	str := descriptorpb.FieldDescriptorProto_TYPE_STRING
+	file, err := protodesc.NewFile(&descriptorpb.FileDescriptorProto{
+		Name:    proto.String("buf/validate/expression.proto"),
+		Package: proto.String("buf.validate"),
+		Syntax:  proto.String("proto3"),
+		MessageType: []*descriptorpb.DescriptorProto{
+			{Name: proto.String("Violations"), Field: []*descriptorpb.FieldDescriptorProto{
+				field("violations", 1, repeated, descriptorpb.FieldDescriptorProto_TYPE_MESSAGE, ".buf.validate.Violation"),
+			}},
+			{Name: proto.String("Violation"), Field: []*descriptorpb.FieldDescriptorProto{
+				field("field_path", 1, optional, str, ""),
+				field("constraint_id", 2, optional, str, ""),
+				field("message", 3, optional, str, ""),
+			}},
+		},
+	}, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	vmd := file.Messages().ByName("Violation")
+	violation := dynamicpb.NewMessage(vmd)
+	violation.Set(vmd.Fields().ByName("field_path"), protoreflect.ValueOfString(fieldPath))
+	violation.Set(vmd.Fields().ByName("constraint_id"), protoreflect.ValueOfString(constraintID))
+	violation.Set(vmd.Fields().ByName("message"), protoreflect.ValueOfString(message))
+	violations := dynamicpb.NewMessage(file.Messages().ByName("Violations"))
+	list := violations.Mutable(violations.Descriptor().Fields().ByName("violations")).List()
+	list.Append(protoreflect.ValueOfMessage(violation))
+	return &protovalidateError{violations: violations}
+}
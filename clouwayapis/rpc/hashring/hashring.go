@@ -0,0 +1,221 @@
+// Package hashring implements consistent hashing with bounded loads that is
+// used for sticky routing of per-tenant or per-device traffic across a set of
+// replicas. Every node is placed on the ring with a number of virtual nodes
+// proportional to its weight, so that only the keys of the added or removed
+// node move when the set of replicas changes.
+package hashring
+
+import (
+	"hash/fnv"
+	"math"
+	"sort"
+	"strconv"
+	"sync"
+)
+
+// DefaultReplicas is the default number of virtual nodes per unit of weight.
+const DefaultReplicas = 100
+
+// DefaultLoadFactor is the default bound of the load of a single node
+// relative to the average load of the ring.
+const DefaultLoadFactor = 1.25
+
+// Option sets an optional parameter for the Ring.
+type Option func(*Ring)
+
+// Replicas sets the number of virtual nodes that are placed on the ring per
+// unit of weight.
+func Replicas(n int) Option {
+	return func(r *Ring) { r.replicas = n }
+}
+
+// LoadFactor sets the bound of the load of a single node relative to the
+// average load that is used by Acquire. The factor should be greater than 1.
+func LoadFactor(f float64) Option {
+	return func(r *Ring) { r.loadFactor = f }
+}
+
+// Ring is a consistent hashing ring that is safe for concurrent use.
+type Ring struct {
+	mu         sync.RWMutex
+	replicas   int
+	loadFactor float64
+
+	nodes       map[string]*node
+	points      []point
+	totalWeight int
+	totalLoad   int
+}
+
+type node struct {
+	weight int
+	load   int
+}
+
+type point struct {
+	hash uint64
+	node string
+}
+
+// New creates a new empty Ring.
+func New(options ...Option) *Ring {
+	r := &Ring{
+		replicas:   DefaultReplicas,
+		loadFactor: DefaultLoadFactor,
+		nodes:      make(map[string]*node),
+	}
+	for _, option := range options {
+		option(r)
+	}
+	return r
+}
+
+// Add places the node on the ring with the passed weight. Adding an existing
+// node updates its weight and keeps its load.
+func (r *Ring) Add(name string, weight int) {
+	if weight < 1 {
+		weight = 1
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	n, ok := r.nodes[name]
+	if !ok {
+		n = &node{}
+		r.nodes[name] = n
+	}
+	n.weight = weight
+	r.rebuild()
+}
+
+// Remove removes the node from the ring. The keys of the node are moved to
+// the next nodes of the ring.
+func (r *Ring) Remove(name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	n, ok := r.nodes[name]
+	if !ok {
+		return
+	}
+	r.totalLoad -= n.load
+	delete(r.nodes, name)
+	r.rebuild()
+}
+
+// Nodes returns the names of the nodes on the ring in sorted order.
+func (r *Ring) Nodes() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	names := make([]string, 0, len(r.nodes))
+	for name := range r.nodes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Get returns the node that owns the key without taking the loads into
+// account. The returned bool is false when the ring is empty.
+func (r *Ring) Get(key string) (string, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if len(r.points) == 0 {
+		return "", false
+	}
+	return r.points[r.search(key)].node, true
+}
+
+// Acquire returns the first node of the ring, starting from the owner of the
+// key, whose load is below its bound and increments the load of the node.
+// Every successful Acquire should be paired with a Release of the returned
+// node once the work for the key is done.
+func (r *Ring) Acquire(key string) (string, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if len(r.points) == 0 {
+		return "", false
+	}
+	start := r.search(key)
+	for i := 0; i < len(r.points); i++ {
+		p := r.points[(start+i)%len(r.points)]
+		n := r.nodes[p.node]
+		if n.load < r.capacity(n) {
+			n.load++
+			r.totalLoad++
+			return p.node, true
+		}
+	}
+	// Unreachable as the capacities always sum up above the total load, but
+	// the owner is returned to keep the ring usable.
+	n := r.nodes[r.points[start].node]
+	n.load++
+	r.totalLoad++
+	return r.points[start].node, true
+}
+
+// Release decrements the load of the node that was returned by Acquire.
+func (r *Ring) Release(name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if n, ok := r.nodes[name]; ok && n.load > 0 {
+		n.load--
+		r.totalLoad--
+	}
+}
+
+// Load returns the current load of the node.
+func (r *Ring) Load(name string) int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if n, ok := r.nodes[name]; ok {
+		return n.load
+	}
+	return 0
+}
+
+// capacity returns the maximum load of the node when one more key is added
+// to the ring.
+func (r *Ring) capacity(n *node) int {
+	avg := float64(r.totalLoad+1) * float64(n.weight) / float64(r.totalWeight)
+	return int(math.Ceil(avg * r.loadFactor))
+}
+
+// search returns the index of the first point with hash not less than the
+// hash of the key.
+func (r *Ring) search(key string) int {
+	h := hash(key)
+	i := sort.Search(len(r.points), func(i int) bool { return r.points[i].hash >= h })
+	if i == len(r.points) {
+		i = 0
+	}
+	return i
+}
+
+func (r *Ring) rebuild() {
+	r.points = r.points[:0]
+	r.totalWeight = 0
+	for name, n := range r.nodes {
+		r.totalWeight += n.weight
+		for i := 0; i < n.weight*r.replicas; i++ {
+			r.points = append(r.points, point{hash: hash(name + "#" + strconv.Itoa(i)), node: name})
+		}
+	}
+	sort.Slice(r.points, func(i, j int) bool {
+		if r.points[i].hash == r.points[j].hash {
+			return r.points[i].node < r.points[j].node
+		}
+		return r.points[i].hash < r.points[j].hash
+	})
+}
+
+func hash(s string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(s))
+	return h.Sum64()
+}
@@ -0,0 +1,55 @@
+package hashring_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/clouway/go-genproto/clouwayapis/rpc/hashring"
+)
+
+func TestGetIsStickyAcrossReplicaChanges(t *testing.T) {
+	ring := hashring.New()
+	ring.Add("replica-1", 1)
+	ring.Add("replica-2", 1)
+	ring.Add("replica-3", 1)
+
+	before := make(map[string]string)
+	for i := 0; i < 1000; i++ {
+		key := fmt.Sprintf("tenant-%d", i)
+		before[key], _ = ring.Get(key)
+	}
+
+	ring.Remove("replica-2")
+
+	for key, owner := range before {
+		got, _ := ring.Get(key)
+		if owner != "replica-2" && got != owner {
+			t.Fatalf("unexpected owner of %s after removal of another replica:\n- want: %v\n-  got: %v", key, owner, got)
+		}
+	}
+}
+
+func TestAcquireBoundsTheLoad(t *testing.T) {
+	ring := hashring.New(hashring.LoadFactor(1.25))
+	ring.Add("replica-1", 1)
+	ring.Add("replica-2", 1)
+	ring.Add("replica-3", 2)
+
+	for i := 0; i < 100; i++ {
+		// The same key would be owned by a single replica without bounds.
+		ring.Acquire("hot-tenant")
+	}
+
+	want := map[string]int{"replica-1": 32, "replica-2": 32, "replica-3": 63}
+	for name, bound := range want {
+		if got := ring.Load(name); got > bound {
+			t.Errorf("unexpected load of %s:\n- want: <= %v\n-  got: %v", name, bound, got)
+		}
+	}
+}
+
+func TestGetOnEmptyRing(t *testing.T) {
+	if _, ok := hashring.New().Get("key"); ok {
+		t.Errorf("expected no node from empty ring")
+	}
+}
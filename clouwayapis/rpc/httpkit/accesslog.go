@@ -0,0 +1,266 @@
+package httpkit
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/clouway/go-genproto/clouwayapis/rpc/request"
+	"github.com/go-kit/kit/endpoint"
+	"github.com/go-kit/log"
+	"github.com/gorilla/mux"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// Redacted is the value of the redacted string fields.
+const Redacted = "[REDACTED]"
+
+// AccessLogOption sets an optional parameter for the AccessLog middleware.
+type AccessLogOption func(*accessLog)
+
+// AccessLogSizes sets whether the sizes of the request and the response
+// bodies are logged as request_bytes and response_bytes.
+func AccessLogSizes(enabled bool) AccessLogOption {
+	return func(l *accessLog) { l.sizes = enabled }
+}
+
+type accessLog struct {
+	logger log.Logger
+	sizes  bool
+}
+
+// AccessLog is an HTTP middleware that writes a log line for every request
+// with its method, route, status, latency and request id:
+//
+//	method=GET route=/v1/orders/{id} status=200 latency=1.2ms request_id=abc
+//
+// The route is the path template of the matched gorilla/mux route, so the
+// middleware is meant to be used by Router.Use, or the path of the request
+// otherwise. The request id is the one that is stored by RequestID. The
+// payloads of the go-kit endpoints that use AccessLogPayloads are added to
+// the same line.
+func AccessLog(logger log.Logger, options ...AccessLogOption) func(http.Handler) http.Handler {
+	l := &accessLog{logger: logger}
+	for _, option := range options {
+		option(l)
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			entry := &accessEntry{}
+			body := &countingBody{ReadCloser: r.Body}
+			if r.Body != nil {
+				r.Body = body
+			}
+			lw := &loggingWriter{ResponseWriter: w, code: http.StatusOK}
+			defer func() {
+				l.log(r, lw, body, entry, time.Since(start))
+			}()
+			next.ServeHTTP(lw, r.WithContext(context.WithValue(r.Context(), accessEntryKey{}, entry)))
+		})
+	}
+}
+
+func (l *accessLog) log(r *http.Request, w *loggingWriter, body *countingBody, entry *accessEntry, latency time.Duration) {
+	route := r.URL.Path
+	if current := mux.CurrentRoute(r); current != nil {
+		if template, err := current.GetPathTemplate(); err == nil {
+			route = template
+		}
+	}
+	keyvals := []interface{}{
+		"method", r.Method,
+		"route", route,
+		"status", w.code,
+		"latency", latency,
+	}
+	if id := request.RequestIDFromContext(r.Context()); id != "" {
+		keyvals = append(keyvals, "request_id", id)
+	} else if id := w.Header().Get(RequestIDHeader); id != "" {
+		keyvals = append(keyvals, "request_id", id)
+	}
+	if l.sizes {
+		keyvals = append(keyvals, "request_bytes", body.n, "response_bytes", w.n)
+	}
+	entry.mu.Lock()
+	if entry.request != "" {
+		keyvals = append(keyvals, "request", entry.request)
+	}
+	if entry.response != "" {
+		keyvals = append(keyvals, "response", entry.response)
+	}
+	entry.mu.Unlock()
+	l.logger.Log(keyvals...)
+}
+
+type accessEntryKey struct{}
+
+// accessEntry holds the payloads that are added to the log line by
+// AccessLogPayloads.
+type accessEntry struct {
+	mu       sync.Mutex
+	request  string
+	response string
+}
+
+// AccessLogPayloads returns an endpoint middleware that adds the proto
+// requests and responses of the endpoint to the line of AccessLog, encoded
+// as JSON. The fields with the passed names and the fields that are marked
+// with the debug_redact option are redacted at any depth of the messages,
+// e.g. AccessLogPayloads("password", "card_number").
+func AccessLogPayloads(redacted ...string) endpoint.Middleware {
+	return func(next endpoint.Endpoint) endpoint.Endpoint {
+		return func(ctx context.Context, req interface{}) (interface{}, error) {
+			entry, ok := ctx.Value(accessEntryKey{}).(*accessEntry)
+			if !ok {
+				return next(ctx, req)
+			}
+			resp, err := next(ctx, req)
+			request, response := redactedJSON(req, redacted), redactedJSON(resp, redacted)
+			entry.mu.Lock()
+			entry.request, entry.response = request, response
+			entry.mu.Unlock()
+			return resp, err
+		}
+	}
+}
+
+func redactedJSON(v interface{}, fields []string) string {
+	m, ok := v.(proto.Message)
+	if !ok || isNil(v) {
+		return ""
+	}
+	b, err := protojson.Marshal(Redact(m, fields...))
+	if err != nil {
+		return ""
+	}
+	return string(b)
+}
+
+// Redact returns a copy of the message with the fields of the passed names
+// and the fields marked with the debug_redact option redacted at any depth.
+// The string fields are replaced by Redacted and all other fields are
+// cleared.
+func Redact(m proto.Message, fields ...string) proto.Message {
+	m = proto.Clone(m)
+	redact(m.ProtoReflect(), fields)
+	return m
+}
+
+func redact(m protoreflect.Message, fields []string) {
+	var cleared []protoreflect.FieldDescriptor
+	m.Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		if isRedacted(fd, fields) {
+			cleared = append(cleared, fd)
+			return true
+		}
+		switch {
+		case fd.IsList() && fd.Message() != nil:
+			for i := 0; i < v.List().Len(); i++ {
+				redact(v.List().Get(i).Message(), fields)
+			}
+		case fd.IsMap() && fd.MapValue().Message() != nil:
+			v.Map().Range(func(_ protoreflect.MapKey, v protoreflect.Value) bool {
+				redact(v.Message(), fields)
+				return true
+			})
+		case !fd.IsList() && !fd.IsMap() && fd.Message() != nil:
+			redact(v.Message(), fields)
+		}
+		return true
+	})
+	for _, fd := range cleared {
+		if fd.Kind() == protoreflect.StringKind && !fd.IsList() && !fd.IsMap() {
+			m.Set(fd, protoreflect.ValueOfString(Redacted))
+			continue
+		}
+		m.Clear(fd)
+	}
+}
+
+func isRedacted(fd protoreflect.FieldDescriptor, fields []string) bool {
+	if options, ok := fd.Options().(*descriptorpb.FieldOptions); ok && options.GetDebugRedact() {
+		return true
+	}
+	for _, field := range fields {
+		if strings.EqualFold(field, string(fd.Name())) || field == fd.JSONName() {
+			return true
+		}
+	}
+	return false
+}
+
+// countingBody counts the bytes that are read from the request body.
+type countingBody struct {
+	io.ReadCloser
+	n int64
+}
+
+func (b *countingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.n += int64(n)
+	return n, err
+}
+
+// loggingWriter records the status and the size of the response.
+type loggingWriter struct {
+	http.ResponseWriter
+	code        int
+	n           int64
+	wroteHeader bool
+}
+
+func (w *loggingWriter) WriteHeader(code int) {
+	if !w.wroteHeader {
+		w.code, w.wroteHeader = code, true
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *loggingWriter) Write(b []byte) (int, error) {
+	w.wroteHeader = true
+	n, err := w.ResponseWriter.Write(b)
+	w.n += int64(n)
+	return n, err
+}
+
+// Flush implements http.Flusher for the streaming handlers.
+func (w *loggingWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		w.wroteHeader = true
+		f.Flush()
+	}
+}
+
+// Hijack implements http.Hijacker for the websocket upgrades. The hijacked
+// connections are logged as 101 Switching Protocols.
+func (w *loggingWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, rw, err := hijack(w.ResponseWriter)
+	if err == nil && !w.wroteHeader {
+		w.code, w.wroteHeader = http.StatusSwitchingProtocols, true
+	}
+	return conn, rw, err
+}
+
+// Unwrap returns the underlying writer for http.ResponseController.
+func (w *loggingWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// hijack takes over the connection of the writer when it supports it.
+func hijack(w http.ResponseWriter) (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := w.(http.Hijacker)
+	if !ok {
+		return nil, nil, fmt.Errorf("httpkit: %T does not support hijacking", w)
+	}
+	return h.Hijack()
+}
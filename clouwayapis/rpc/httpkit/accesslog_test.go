@@ -0,0 +1,146 @@
+package httpkit_test
+
+import (
+	"bytes"
+	"context"
+	stdlog "log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/clouway/go-genproto/clouwayapis/rpc/httpkit"
+	httptransport "github.com/go-kit/kit/transport/http"
+	"github.com/go-kit/log"
+	"github.com/gorilla/mux"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/apipb"
+	"google.golang.org/protobuf/types/known/sourcecontextpb"
+	"google.golang.org/protobuf/types/known/typepb"
+)
+
+func TestAccessLog(t *testing.T) {
+	var buf bytes.Buffer
+	router := mux.NewRouter()
+	router.Use(httpkit.RequestID, httpkit.AccessLog(log.NewLogfmtLogger(&buf), httpkit.AccessLogSizes(true)))
+	router.Methods(http.MethodPost).Path("/v1/apis/{id}").Handler(httptransport.NewServer(
+		httpkit.AccessLogPayloads("version")(func(_ context.Context, request interface{}) (interface{}, error) {
+			return request, nil
+		}),
+		httpkit.DecodeProtoJSONRequest[*apipb.Api](),
+		httpkit.EncodeProtoJSONResponse,
+	))
+
+	r := httptest.NewRequest(http.MethodPost, "/v1/apis/orders", strings.NewReader(`{"name":"orders","version":"v1"}`))
+	r.Header.Set(httpkit.RequestIDHeader, "abc-123")
+	router.ServeHTTP(httptest.NewRecorder(), r)
+
+	line := buf.String()
+	for _, want := range []string{
+		"method=POST",
+		"route=/v1/apis/{id}",
+		"status=200",
+		"latency=",
+		"request_id=abc-123",
+		"request_bytes=32",
+		"response_bytes=",
+		`request="{`,
+		`[REDACTED]`,
+	} {
+		if !strings.Contains(line, want) {
+			t.Errorf("unexpected log line without %s:\n%s", want, line)
+		}
+	}
+}
+
+func TestAccessLogHijack(t *testing.T) {
+	// The line is logged by the server after the response is received.
+	lines := make(chan string, 1)
+	logger := log.LoggerFunc(func(keyvals ...interface{}) error {
+		var buf bytes.Buffer
+		log.NewLogfmtLogger(&buf).Log(keyvals...)
+		lines <- buf.String()
+		return nil
+	})
+	handler := httpkit.AccessLog(logger)(upgradeHandler(t))
+
+	if code := serveUpgrade(t, handler); code != http.StatusSwitchingProtocols {
+		t.Errorf("unexpected status code:\n- want: %v\n-  got: %v", http.StatusSwitchingProtocols, code)
+	}
+	if line := <-lines; !strings.Contains(line, "status=101") {
+		t.Errorf("unexpected log line without status=101:\n%s", line)
+	}
+}
+
+// upgradeHandler answers the requests with 101 Switching Protocols over the
+// hijacked connection, as the websocket handlers do.
+func upgradeHandler(t *testing.T) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h, ok := w.(http.Hijacker)
+		if !ok {
+			t.Errorf("unexpected writer without http.Hijacker: %T", w)
+			return
+		}
+		conn, rw, err := h.Hijack()
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+			return
+		}
+		defer conn.Close()
+		rw.WriteString("HTTP/1.1 101 Switching Protocols\r\nConnection: Upgrade\r\nUpgrade: websocket\r\n\r\n")
+		rw.Flush()
+	})
+}
+
+// serveUpgrade sends an upgrade request to the handler and returns the
+// status code of the response. The errors that the server logs, e.g. the
+// writes on the hijacked connection, fail the test.
+func serveUpgrade(t *testing.T, handler http.Handler) int {
+	server := httptest.NewUnstartedServer(handler)
+	server.Config.ErrorLog = stdlog.New(testLogWriter{t}, "", 0)
+	server.Start()
+	defer server.Close()
+
+	r, _ := http.NewRequest(http.MethodGet, server.URL, nil)
+	r.Header.Set("Connection", "Upgrade")
+	r.Header.Set("Upgrade", "websocket")
+	resp, err := http.DefaultClient.Do(r)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	resp.Body.Close()
+	return resp.StatusCode
+}
+
+// testLogWriter fails the test on every written log line.
+type testLogWriter struct {
+	t *testing.T
+}
+
+func (w testLogWriter) Write(b []byte) (int, error) {
+	w.t.Errorf("unexpected server log: %s", b)
+	return len(b), nil
+}
+
+func TestRedact(t *testing.T) {
+	m := &apipb.Api{
+		Name:          "orders",
+		Version:       "v1",
+		Options:       []*typepb.Option{{Name: "secret"}},
+		SourceContext: &sourcecontextpb.SourceContext{FileName: "orders.proto"},
+	}
+
+	got := httpkit.Redact(m, "fileName", "options")
+
+	want := &apipb.Api{
+		Name:          "orders",
+		Version:       "v1",
+		SourceContext: &sourcecontextpb.SourceContext{FileName: httpkit.Redacted},
+	}
+	if !proto.Equal(want, got) {
+		t.Errorf("unexpected message:\n- want: %v\n-  got: %v", want, got)
+	}
+	if m.SourceContext.FileName != "orders.proto" {
+		t.Errorf("unexpected change of the original message: %v", m)
+	}
+}
@@ -0,0 +1,66 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+)
+
+// KeyValidator resolves the identity of the client of an API key. The
+// unknown keys are reported by nil identity and nil error, and rejected
+// with 401 Unauthenticated. The status errors of the validator are written
+// as they are, e.g. PermissionDenied for revoked keys is written as 403.
+type KeyValidator interface {
+	ValidateKey(ctx context.Context, key string) (*Identity, error)
+}
+
+// KeyValidatorFunc is an adapter to use functions as KeyValidator.
+type KeyValidatorFunc func(ctx context.Context, key string) (*Identity, error)
+
+// ValidateKey calls f(ctx, key).
+func (f KeyValidatorFunc) ValidateKey(ctx context.Context, key string) (*Identity, error) {
+	return f(ctx, key)
+}
+
+// KeyHeader sets the header with the API key. It's X-Api-Key by default.
+func KeyHeader(name string) Option {
+	return func(o *options) { o.keyHeader = name }
+}
+
+// KeyQueryParameter sets the query parameter with the API key, which is
+// used when the header is missing. It's api_key by default, and the empty
+// name disables the query parameter.
+func KeyQueryParameter(name string) Option {
+	return func(o *options) { o.keyQuery = name }
+}
+
+// APIKey is an HTTP middleware that authenticates the requests by the API
+// key of the X-Api-Key header or the api_key query parameter. The identity
+// that is resolved by the validator is stored in the request context, and
+// its subject is available by request.SubjectFromContext as well.
+func APIKey(validator KeyValidator, opts ...Option) func(http.Handler) http.Handler {
+	o := newOptions(opts...)
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := r.Context()
+			key := r.Header.Get(o.keyHeader)
+			if key == "" && o.keyQuery != "" {
+				key = r.URL.Query().Get(o.keyQuery)
+			}
+			if key == "" {
+				o.errorEncoder(ctx, unauthenticated("missing API key"), w)
+				return
+			}
+
+			identity, err := validator.ValidateKey(ctx, key)
+			if err != nil {
+				o.errorEncoder(ctx, err, w)
+				return
+			}
+			if identity == nil {
+				o.errorEncoder(ctx, unauthenticated("invalid API key"), w)
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(withIdentity(ctx, identity)))
+		})
+	}
+}
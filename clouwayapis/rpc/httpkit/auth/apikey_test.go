@@ -0,0 +1,61 @@
+package auth_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/clouway/go-genproto/clouwayapis/rpc/httpkit/auth"
+	"github.com/clouway/go-genproto/clouwayapis/rpc/request"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestAPIKey(t *testing.T) {
+	validator := auth.KeyValidatorFunc(func(ctx context.Context, key string) (*auth.Identity, error) {
+		switch key {
+		case "valid":
+			return &auth.Identity{Subject: "client-1"}, nil
+		case "revoked":
+			return nil, status.Error(codes.PermissionDenied, "revoked API key")
+		}
+		return nil, nil
+	})
+
+	tests := []struct {
+		name    string
+		target  string
+		header  string
+		status  int
+		subject string
+	}{
+		{name: "header", target: "/", header: "valid", status: http.StatusOK, subject: "client-1"},
+		{name: "query parameter", target: "/?api_key=valid", status: http.StatusOK, subject: "client-1"},
+		{name: "missing", target: "/", status: http.StatusUnauthorized},
+		{name: "unknown", target: "/", header: "unknown", status: http.StatusUnauthorized},
+		{name: "revoked", target: "/", header: "revoked", status: http.StatusForbidden},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var subject string
+			handler := auth.APIKey(validator)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				subject = request.SubjectFromContext(r.Context())
+			}))
+			r := httptest.NewRequest(http.MethodGet, test.target, nil)
+			if test.header != "" {
+				r.Header.Set("X-Api-Key", test.header)
+			}
+
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+
+			if w.Code != test.status {
+				t.Errorf("unexpected status code:\n- want: %v\n-  got: %v", test.status, w.Code)
+			}
+			if subject != test.subject {
+				t.Errorf("unexpected subject:\n- want: %v\n-  got: %v", test.subject, subject)
+			}
+		})
+	}
+}
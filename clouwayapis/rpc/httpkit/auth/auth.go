@@ -0,0 +1,124 @@
+// Package auth provides the authentication middlewares of the HTTP servers.
+// The middlewares resolve the identity of the caller, store it in the
+// request context and reject the unauthenticated requests with status
+// errors that are written by httpkit.ErrorEncoder:
+//
+//	router.Use(auth.APIKey(validator))
+//
+//	func (s *service) GetOrder(ctx context.Context, req *pb.GetOrderRequest) (*pb.Order, error) {
+//		identity, _ := auth.FromContext(ctx)
+//		...
+//	}
+package auth
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/clouway/go-genproto/clouwayapis/rpc/httpkit"
+	"github.com/clouway/go-genproto/clouwayapis/rpc/request"
+	httptransport "github.com/go-kit/kit/transport/http"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Identity is the authenticated caller of a request.
+type Identity struct {
+	// Subject identifies the caller, e.g. the client of an API key or the
+	// subject of a token.
+	Subject string
+	// Scopes are the scopes that are granted to the caller.
+	Scopes []string
+	// Attributes are the other attributes of the caller, e.g. the tenant.
+	Attributes map[string]string
+}
+
+// HasScope reports whether the scope is granted to the caller.
+func (i *Identity) HasScope(scope string) bool {
+	for _, s := range i.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+type identityKey struct{}
+
+// NewContext returns a copy of the context with the identity.
+func NewContext(ctx context.Context, identity *Identity) context.Context {
+	return context.WithValue(ctx, identityKey{}, identity)
+}
+
+// FromContext returns the identity that is stored in the context by the
+// middlewares of the package.
+func FromContext(ctx context.Context) (*Identity, bool) {
+	identity, ok := ctx.Value(identityKey{}).(*Identity)
+	return identity, ok
+}
+
+// withIdentity returns a copy of the context with the identity and its
+// subject for request.SubjectFromContext.
+func withIdentity(ctx context.Context, identity *Identity) context.Context {
+	ctx = NewContext(ctx, identity)
+	return request.WithSubject(ctx, identity.Subject)
+}
+
+// ScopesFromContext returns the scopes of the identity that is stored in
+// the context.
+func ScopesFromContext(ctx context.Context) []string {
+	if identity, ok := FromContext(ctx); ok {
+		return identity.Scopes
+	}
+	return nil
+}
+
+// Option sets an optional parameter for the middlewares.
+type Option func(*options)
+
+// ErrorEncoder sets the encoder of the authentication errors.
+// httpkit.ErrorEncoder is used by default.
+func ErrorEncoder(ee httptransport.ErrorEncoder) Option {
+	return func(o *options) { o.errorEncoder = ee }
+}
+
+type options struct {
+	errorEncoder httptransport.ErrorEncoder
+
+	// keyHeader and keyQuery are the header and the query parameter with
+	// the API key.
+	keyHeader string
+	keyQuery  string
+
+	// issuer, audiences and leeway verify the claims of the bearer
+	// tokens, and realm is the realm of their WWW-Authenticate challenges.
+	issuer    string
+	audiences []string
+	leeway    time.Duration
+	realm     string
+
+	// scopes are the scopes that the tokens must be granted.
+	scopes []string
+
+	// replayWindow is the accepted age of the signed requests.
+	replayWindow time.Duration
+
+	// csrfInsecure and csrfExempt configure the CSRF protection.
+	csrfInsecure bool
+	csrfExempt   func(r *http.Request) bool
+}
+
+func newOptions(opts ...Option) *options {
+	o := &options{errorEncoder: httpkit.ErrorEncoder, keyHeader: "X-Api-Key", keyQuery: "api_key", leeway: time.Minute, replayWindow: 5 * time.Minute}
+	for _, option := range opts {
+		option(o)
+	}
+	return o
+}
+
+// unauthenticated returns the Unauthenticated status error of the requests
+// without valid credentials.
+func unauthenticated(format string, a ...interface{}) error {
+	return status.Errorf(codes.Unauthenticated, format, a...)
+}
@@ -0,0 +1,99 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+
+	"github.com/clouway/go-genproto/clouwayapis/rpc/httpkit"
+)
+
+const (
+	// CSRFCookie is the cookie with the CSRF token.
+	CSRFCookie = "csrf_token"
+	// CSRFHeader is the header with the CSRF token of the unsafe requests.
+	CSRFHeader = "X-CSRF-Token"
+)
+
+// CSRFInsecureCookie sets whether the CSRF cookie can be sent over plain
+// HTTP, e.g. in local development. The cookie is Secure by default.
+func CSRFInsecureCookie(insecure bool) Option {
+	return func(o *options) { o.csrfInsecure = insecure }
+}
+
+// CSRFExempt sets the function that reports whether the request is exempt
+// from the CSRF protection. By default the requests that are
+// authenticated by the Authorization header, an API key or a request
+// signature are exempt, because they aren't sent by the browsers on their
+// own.
+func CSRFExempt(exempt func(r *http.Request) bool) Option {
+	return func(o *options) { o.csrfExempt = exempt }
+}
+
+// NewCSRFTokenHandler returns the handler that issues the CSRF tokens of
+// the web clients. It sets a new token as the CSRF cookie and responds with
+// it, so the clients can echo it in the X-CSRF-Token header of the unsafe
+// requests:
+//
+//	{"token": "..."}
+func NewCSRFTokenHandler(opts ...Option) http.Handler {
+	o := newOptions(opts...)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b := make([]byte, 32)
+		if _, err := rand.Read(b); err != nil {
+			o.errorEncoder(r.Context(), err, w)
+			return
+		}
+		token := base64.RawURLEncoding.EncodeToString(b)
+		http.SetCookie(w, &http.Cookie{
+			Name:     CSRFCookie,
+			Value:    token,
+			Path:     "/",
+			Secure:   !o.csrfInsecure,
+			HttpOnly: true,
+			SameSite: http.SameSiteStrictMode,
+		})
+		w.Header().Set("Cache-Control", "no-store")
+		w.Header().Set("Content-Type", httpkit.JSONContentType)
+		json.NewEncoder(w).Encode(map[string]string{"token": token})
+	})
+}
+
+// CSRF is an HTTP middleware that protects the browser-facing endpoints
+// from cross-site request forgery with double-submit cookies. The unsafe
+// requests, i.e. all but GET, HEAD, OPTIONS and TRACE, must send the token
+// of the CSRF cookie in the X-CSRF-Token header too, which the other sites
+// can't read. The requests without matching tokens are rejected with 403
+// PermissionDenied.
+func CSRF(opts ...Option) func(http.Handler) http.Handler {
+	o := newOptions(opts...)
+	exempt := o.csrfExempt
+	if exempt == nil {
+		exempt = func(r *http.Request) bool {
+			return r.Header.Get("Authorization") != "" || r.Header.Get(o.keyHeader) != "" || r.Header.Get(SignatureHeader) != ""
+		}
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.Method {
+			case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+				next.ServeHTTP(w, r)
+				return
+			}
+			if exempt(r) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			cookie, err := r.Cookie(CSRFCookie)
+			header := r.Header.Get(CSRFHeader)
+			if err != nil || cookie.Value == "" || subtle.ConstantTimeCompare([]byte(cookie.Value), []byte(header)) != 1 {
+				o.errorEncoder(r.Context(), permissionDenied("missing or invalid CSRF token"), w)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,59 @@
+package auth_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/clouway/go-genproto/clouwayapis/rpc/httpkit/auth"
+)
+
+func TestCSRF(t *testing.T) {
+	w := httptest.NewRecorder()
+	auth.NewCSRFTokenHandler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/csrf", nil))
+	var issued struct{ Token string }
+	json.NewDecoder(w.Body).Decode(&issued)
+	cookies := w.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Name != auth.CSRFCookie || cookies[0].Value != issued.Token || !cookies[0].Secure {
+		t.Fatalf("unexpected CSRF cookie: %v", cookies)
+	}
+
+	tests := []struct {
+		name          string
+		method        string
+		cookie        string
+		header        string
+		authorization string
+		status        int
+	}{
+		{name: "safe method", method: http.MethodGet, status: http.StatusOK},
+		{name: "matching token", method: http.MethodPost, cookie: issued.Token, header: issued.Token, status: http.StatusOK},
+		{name: "missing header", method: http.MethodPost, cookie: issued.Token, status: http.StatusForbidden},
+		{name: "missing cookie", method: http.MethodDelete, header: issued.Token, status: http.StatusForbidden},
+		{name: "different token", method: http.MethodPut, cookie: issued.Token, header: "other", status: http.StatusForbidden},
+		{name: "bearer token", method: http.MethodPost, authorization: "Bearer token", status: http.StatusOK},
+	}
+	handler := auth.CSRF()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := httptest.NewRequest(test.method, "/v1/orders", nil)
+			if test.cookie != "" {
+				r.AddCookie(&http.Cookie{Name: auth.CSRFCookie, Value: test.cookie})
+			}
+			if test.header != "" {
+				r.Header.Set(auth.CSRFHeader, test.header)
+			}
+			if test.authorization != "" {
+				r.Header.Set("Authorization", test.authorization)
+			}
+
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+
+			if w.Code != test.status {
+				t.Errorf("unexpected status code:\n- want: %v\n-  got: %v", test.status, w.Code)
+			}
+		})
+	}
+}
@@ -0,0 +1,183 @@
+package auth
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// SignatureKeyIDHeader is the header with the id of the key that signed
+	// the request.
+	SignatureKeyIDHeader = "X-Signature-Key-Id"
+	// SignatureTimestampHeader is the header with the Unix time in seconds
+	// when the request was signed.
+	SignatureTimestampHeader = "X-Signature-Timestamp"
+	// SignatureHeader is the header with the hex encoded HMAC-SHA256
+	// signature of the request.
+	SignatureHeader = "X-Signature"
+)
+
+// SecretStore returns the shared secrets of the partners by their key id.
+// The unknown keys are reported by nil secret and nil error.
+type SecretStore interface {
+	Secret(ctx context.Context, keyID string) ([]byte, error)
+}
+
+// SecretStoreFunc is an adapter to use functions as SecretStore.
+type SecretStoreFunc func(ctx context.Context, keyID string) ([]byte, error)
+
+// Secret calls f(ctx, keyID).
+func (f SecretStoreFunc) Secret(ctx context.Context, keyID string) ([]byte, error) {
+	return f(ctx, keyID)
+}
+
+// ReplayWindow sets how old the signed requests can be. The requests that
+// are signed earlier or later than the window, or that were already seen in
+// it, are rejected. It's five minutes by default.
+func ReplayWindow(d time.Duration) Option {
+	return func(o *options) { o.replayWindow = d }
+}
+
+// SignRequest signs the request with the secret of the key id at the time.
+// It's used by the clients of the endpoints protected by Signature.
+func SignRequest(r *http.Request, keyID string, secret []byte, t time.Time) error {
+	body, err := readBody(r)
+	if err != nil {
+		return err
+	}
+	timestamp := strconv.FormatInt(t.Unix(), 10)
+	r.Header.Set(SignatureKeyIDHeader, keyID)
+	r.Header.Set(SignatureTimestampHeader, timestamp)
+	r.Header.Set(SignatureHeader, hex.EncodeToString(requestMAC(secret, timestamp, r, body)))
+	return nil
+}
+
+// Signature is an HTTP middleware that authenticates the webhook-style
+// requests of the partners by their HMAC-SHA256 signature. The signature
+// covers the timestamp, the method, the path with the query and the body of
+// the request, separated by new lines, and is verified with the secret of
+// the X-Signature-Key-Id header. The identity of the key id is stored in
+// the request context.
+//
+// The requests with missing or invalid signatures, with timestamps outside
+// the replay window or with signatures that were already seen are rejected
+// with 403 PermissionDenied.
+func Signature(secrets SecretStore, opts ...Option) func(http.Handler) http.Handler {
+	o := newOptions(opts...)
+	seen := &seenSignatures{signatures: map[string]time.Time{}}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := r.Context()
+			if err := o.verifySignature(ctx, secrets, seen, r, time.Now()); err != nil {
+				o.errorEncoder(ctx, err, w)
+				return
+			}
+			identity := &Identity{Subject: r.Header.Get(SignatureKeyIDHeader)}
+			next.ServeHTTP(w, r.WithContext(withIdentity(ctx, identity)))
+		})
+	}
+}
+
+// verifySignature verifies the signature of the request.
+func (o *options) verifySignature(ctx context.Context, secrets SecretStore, seen *seenSignatures, r *http.Request, now time.Time) error {
+	keyID := r.Header.Get(SignatureKeyIDHeader)
+	timestamp := r.Header.Get(SignatureTimestampHeader)
+	signature, err := hex.DecodeString(r.Header.Get(SignatureHeader))
+	if keyID == "" || timestamp == "" || err != nil || len(signature) == 0 {
+		return permissionDenied("missing or malformed request signature")
+	}
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return permissionDenied("malformed signature timestamp")
+	}
+	signed := time.Unix(seconds, 0)
+	if signed.Before(now.Add(-o.replayWindow)) || signed.After(now.Add(o.replayWindow)) {
+		return permissionDenied("signature timestamp is outside of the replay window")
+	}
+
+	secret, err := secrets.Secret(ctx, keyID)
+	if err != nil {
+		return err
+	}
+	if secret == nil {
+		return permissionDenied("unknown signature key %q", keyID)
+	}
+	body, err := readBody(r)
+	if err != nil {
+		return err
+	}
+	if !hmac.Equal(signature, requestMAC(secret, timestamp, r, body)) {
+		return permissionDenied("invalid request signature")
+	}
+	if !seen.add(keyID+":"+hex.EncodeToString(signature), signed.Add(o.replayWindow), now) {
+		return permissionDenied("replayed request signature")
+	}
+	return nil
+}
+
+// requestMAC returns the HMAC-SHA256 of the request.
+func requestMAC(secret []byte, timestamp string, r *http.Request, body []byte) []byte {
+	mac := hmac.New(sha256.New, secret)
+	io.WriteString(mac, timestamp+"\n"+r.Method+"\n"+r.URL.RequestURI()+"\n")
+	mac.Write(body)
+	return mac.Sum(nil)
+}
+
+// readBody reads the body of the request and replaces it with a copy, so
+// it can be read again by the handler.
+func readBody(r *http.Request) ([]byte, error) {
+	if r.Body == nil || r.Body == http.NoBody {
+		return nil, nil
+	}
+	body, err := io.ReadAll(r.Body)
+	r.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	return body, nil
+}
+
+// seenSignatures remembers the verified signatures until they leave the
+// replay window.
+type seenSignatures struct {
+	mu         sync.Mutex
+	signatures map[string]time.Time
+	pruned     time.Time
+}
+
+// add adds the signature and reports whether it wasn't seen before.
+func (s *seenSignatures) add(signature string, expires, now time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if now.Sub(s.pruned) >= time.Second {
+		for k, v := range s.signatures {
+			if now.After(v) {
+				delete(s.signatures, k)
+			}
+		}
+		s.pruned = now
+	}
+	if _, ok := s.signatures[signature]; ok {
+		return false
+	}
+	s.signatures[signature] = expires
+	return true
+}
+
+// permissionDenied returns the PermissionDenied status error of the
+// requests with invalid signatures.
+func permissionDenied(format string, a ...interface{}) error {
+	return status.Errorf(codes.PermissionDenied, format, a...)
+}
@@ -0,0 +1,63 @@
+package auth_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/clouway/go-genproto/clouwayapis/rpc/httpkit/auth"
+)
+
+func TestSignature(t *testing.T) {
+	secrets := auth.SecretStoreFunc(func(ctx context.Context, keyID string) ([]byte, error) {
+		if keyID == "partner-1" {
+			return []byte("secret"), nil
+		}
+		return nil, nil
+	})
+	handler := auth.Signature(secrets)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		identity, _ := auth.FromContext(r.Context())
+		body, _ := io.ReadAll(r.Body)
+		io.WriteString(w, identity.Subject+" "+string(body))
+	}))
+	signed := func(keyID string, secret string, at time.Time) *http.Request {
+		r := httptest.NewRequest(http.MethodPost, "/v1/events?source=billing", strings.NewReader(`{"event":"paid"}`))
+		auth.SignRequest(r, keyID, []byte(secret), at)
+		return r
+	}
+	at := time.Now().Add(-time.Second)
+	tampered := signed("partner-1", "secret", time.Now())
+	tampered.Body = io.NopCloser(strings.NewReader(`{"event":"refunded"}`))
+
+	tests := []struct {
+		name   string
+		req    *http.Request
+		status int
+		body   string
+	}{
+		{name: "valid", req: signed("partner-1", "secret", at), status: http.StatusOK, body: `partner-1 {"event":"paid"}`},
+		{name: "replayed", req: signed("partner-1", "secret", at), status: http.StatusForbidden},
+		{name: "expired", req: signed("partner-1", "secret", time.Now().Add(-10*time.Minute)), status: http.StatusForbidden},
+		{name: "tampered", req: tampered, status: http.StatusForbidden},
+		{name: "wrong secret", req: signed("partner-1", "other", time.Now()), status: http.StatusForbidden},
+		{name: "unknown key", req: signed("partner-2", "secret", time.Now()), status: http.StatusForbidden},
+		{name: "unsigned", req: httptest.NewRequest(http.MethodPost, "/v1/events", nil), status: http.StatusForbidden},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, test.req)
+
+			if w.Code != test.status {
+				t.Errorf("unexpected status code:\n- want: %v\n-  got: %v", test.status, w.Code)
+			}
+			if test.body != "" && w.Body.String() != test.body {
+				t.Errorf("unexpected body:\n- want: %v\n-  got: %v", test.body, w.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,255 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Introspection is the response of an OAuth 2.0 token introspection
+// endpoint (RFC 7662).
+type Introspection struct {
+	Active    bool      `json:"active"`
+	Scope     string    `json:"scope,omitempty"`
+	ClientID  string    `json:"client_id,omitempty"`
+	Username  string    `json:"username,omitempty"`
+	Subject   string    `json:"sub,omitempty"`
+	Audience  Audiences `json:"aud,omitempty"`
+	Issuer    string    `json:"iss,omitempty"`
+	ExpiresAt int64     `json:"exp,omitempty"`
+}
+
+// Audiences is the aud claim, which is either a single string or an array
+// of strings.
+type Audiences []string
+
+// UnmarshalJSON decodes both the string and the array form of the claim.
+func (a *Audiences) UnmarshalJSON(b []byte) error {
+	var single string
+	if err := json.Unmarshal(b, &single); err == nil {
+		*a = Audiences{single}
+		return nil
+	}
+	var multiple []string
+	if err := json.Unmarshal(b, &multiple); err != nil {
+		return fmt.Errorf("aud is neither a string nor an array of strings")
+	}
+	*a = multiple
+	return nil
+}
+
+// MarshalJSON encodes the single audience as a string, and the others as
+// an array.
+func (a Audiences) MarshalJSON() ([]byte, error) {
+	if len(a) == 1 {
+		return json.Marshal(a[0])
+	}
+	return json.Marshal([]string(a))
+}
+
+// IntrospectorOption sets an optional parameter for the introspectors.
+type IntrospectorOption func(*Introspector)
+
+// IntrospectorClient sets the HTTP client that calls the introspection
+// endpoint.
+func IntrospectorClient(client *http.Client) IntrospectorOption {
+	return func(i *Introspector) { i.client = client }
+}
+
+// IntrospectorCredentials sets the client credentials that authenticate
+// the calls to the introspection endpoint with HTTP Basic authentication.
+func IntrospectorCredentials(clientID, clientSecret string) IntrospectorOption {
+	return func(i *Introspector) { i.clientID, i.clientSecret = clientID, clientSecret }
+}
+
+// IntrospectorCacheTTL sets how long the introspection responses are
+// cached. The active tokens are never cached beyond their expiration. The
+// responses are cached for a minute by default, and zero disables the cache.
+func IntrospectorCacheTTL(d time.Duration) IntrospectorOption {
+	return func(i *Introspector) { i.ttl = d }
+}
+
+// Introspector resolves opaque access tokens by an OAuth 2.0 token
+// introspection endpoint (RFC 7662) and caches the responses.
+type Introspector struct {
+	url          string
+	client       *http.Client
+	clientID     string
+	clientSecret string
+	ttl          time.Duration
+
+	mu    sync.Mutex
+	cache map[string]cachedIntrospection
+}
+
+type cachedIntrospection struct {
+	introspection *Introspection
+	expires       time.Time
+}
+
+// maxCachedIntrospections is the maximum number of cached responses. When
+// the cache is full, the expired responses are evicted, and when none is
+// expired, the response that expires first is.
+const maxCachedIntrospections = 1024
+
+// NewIntrospector creates an introspector that calls the endpoint with the
+// URL, e.g. https://issuer.example.com/oauth2/introspect
+func NewIntrospector(url string, options ...IntrospectorOption) *Introspector {
+	i := &Introspector{url: url, client: http.DefaultClient, ttl: time.Minute, cache: map[string]cachedIntrospection{}}
+	for _, option := range options {
+		option(i)
+	}
+	return i
+}
+
+// Introspect returns the introspection response of the token.
+func (i *Introspector) Introspect(ctx context.Context, token string) (*Introspection, error) {
+	now := time.Now()
+	i.mu.Lock()
+	cached, ok := i.cache[token]
+	i.mu.Unlock()
+	if ok && now.Before(cached.expires) {
+		return cached.introspection, nil
+	}
+
+	introspection, err := i.call(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	expires := now.Add(i.ttl)
+	if exp := time.Unix(introspection.ExpiresAt, 0); introspection.Active && introspection.ExpiresAt > 0 && exp.Before(expires) {
+		expires = exp
+	}
+	if expires.After(now) {
+		i.mu.Lock()
+		if _, ok := i.cache[token]; !ok && len(i.cache) >= maxCachedIntrospections {
+			i.evict(now)
+		}
+		i.cache[token] = cachedIntrospection{introspection: introspection, expires: expires}
+		i.mu.Unlock()
+	}
+	return introspection, nil
+}
+
+// evict removes the expired responses from the cache, or the response that
+// expires first when none is expired.
+func (i *Introspector) evict(now time.Time) {
+	var first string
+	var firstExpires time.Time
+	for k, v := range i.cache {
+		if !now.Before(v.expires) {
+			delete(i.cache, k)
+			continue
+		}
+		if firstExpires.IsZero() || v.expires.Before(firstExpires) {
+			first, firstExpires = k, v.expires
+		}
+	}
+	if len(i.cache) >= maxCachedIntrospections {
+		delete(i.cache, first)
+	}
+}
+
+// call calls the introspection endpoint.
+func (i *Introspector) call(ctx context.Context, token string) (*Introspection, error) {
+	form := url.Values{"token": {token}, "token_type_hint": {"access_token"}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, i.url, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if i.clientID != "" {
+		req.SetBasicAuth(url.QueryEscape(i.clientID), url.QueryEscape(i.clientSecret))
+	}
+
+	resp, err := i.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected introspection status %d", resp.StatusCode)
+	}
+	introspection := &Introspection{}
+	if err := json.NewDecoder(resp.Body).Decode(introspection); err != nil {
+		return nil, fmt.Errorf("invalid introspection response: %v", err)
+	}
+	return introspection, nil
+}
+
+// RequireScopes requires the tokens to be granted all of the scopes.
+func RequireScopes(scopes ...string) Option {
+	return func(o *options) { o.scopes = scopes }
+}
+
+// Introspect is an HTTP middleware that authenticates the requests by the
+// opaque access token of the Authorization: Bearer header. The token is
+// resolved by the introspector, and its identity is stored in the request
+// context with the subject of the sub claim, or of the username or the
+// client_id when sub is missing, and the scopes of the scope claim. The
+// subject is available by request.SubjectFromContext as well.
+//
+// The requests with missing or inactive tokens are rejected with 401
+// Unauthenticated, and the tokens without the scopes of RequireScopes with
+// 403 PermissionDenied. The failures of the introspection endpoint are
+// answered with 503 Unavailable.
+func Introspect(introspector *Introspector, opts ...Option) func(http.Handler) http.Handler {
+	o := newOptions(opts...)
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := r.Context()
+			token, ok := bearerToken(r)
+			if !ok {
+				w.Header().Set("WWW-Authenticate", o.challenge("", ""))
+				o.errorEncoder(ctx, unauthenticated("missing bearer token"), w)
+				return
+			}
+			introspection, err := introspector.Introspect(ctx, token)
+			if err != nil {
+				o.errorEncoder(ctx, status.Errorf(codes.Unavailable, "token introspection failed: %v", err), w)
+				return
+			}
+			if !introspection.Active {
+				w.Header().Set("WWW-Authenticate", o.challenge("invalid_token", "token is not active"))
+				o.errorEncoder(ctx, unauthenticated("inactive bearer token"), w)
+				return
+			}
+
+			identity := introspection.identity()
+			for _, scope := range o.scopes {
+				if !identity.HasScope(scope) {
+					w.Header().Set("WWW-Authenticate", o.challenge("insufficient_scope", "missing scope "+scope))
+					o.errorEncoder(ctx, status.Errorf(codes.PermissionDenied, "missing scope %q", scope), w)
+					return
+				}
+			}
+			next.ServeHTTP(w, r.WithContext(withIdentity(ctx, identity)))
+		})
+	}
+}
+
+// identity returns the identity of the introspection response.
+func (i *Introspection) identity() *Identity {
+	identity := &Identity{Subject: i.Subject, Scopes: strings.Fields(i.Scope), Attributes: map[string]string{}}
+	if identity.Subject == "" {
+		identity.Subject = i.Username
+	}
+	if identity.Subject == "" {
+		identity.Subject = i.ClientID
+	}
+	for k, v := range map[string]string{"client_id": i.ClientID, "username": i.Username, "aud": strings.Join(i.Audience, " "), "iss": i.Issuer} {
+		if v != "" {
+			identity.Attributes[k] = v
+		}
+	}
+	return identity
+}
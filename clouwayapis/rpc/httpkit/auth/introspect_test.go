@@ -0,0 +1,125 @@
+package auth_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/clouway/go-genproto/clouwayapis/rpc/httpkit/auth"
+)
+
+func TestIntrospect(t *testing.T) {
+	calls := 0
+	endpoint := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if id, secret, _ := r.BasicAuth(); id != "api" || secret != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.PostFormValue("token") {
+		case "reader":
+			json.NewEncoder(w).Encode(auth.Introspection{Active: true, Subject: "user-1", Scope: "orders.read"})
+		case "writer":
+			json.NewEncoder(w).Encode(auth.Introspection{Active: true, ClientID: "client-1", Scope: "orders.read orders.write"})
+		default:
+			json.NewEncoder(w).Encode(auth.Introspection{Active: false})
+		}
+	}))
+	defer endpoint.Close()
+	introspector := auth.NewIntrospector(endpoint.URL, auth.IntrospectorCredentials("api", "secret"))
+
+	tests := []struct {
+		name    string
+		token   string
+		status  int
+		subject string
+		scopes  string
+	}{
+		{name: "writer", token: "writer", status: http.StatusOK, subject: "client-1", scopes: "orders.read orders.write"},
+		{name: "missing scope", token: "reader", status: http.StatusForbidden},
+		{name: "inactive", token: "revoked", status: http.StatusUnauthorized},
+		{name: "missing", status: http.StatusUnauthorized},
+		{name: "cached", token: "writer", status: http.StatusOK, subject: "client-1", scopes: "orders.read orders.write"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var subject, scopes string
+			handler := auth.Introspect(introspector, auth.RequireScopes("orders.write"))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				identity, _ := auth.FromContext(r.Context())
+				subject = identity.Subject
+				scopes = strings.Join(auth.ScopesFromContext(r.Context()), " ")
+			}))
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			if test.token != "" {
+				r.Header.Set("Authorization", "Bearer "+test.token)
+			}
+
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+
+			if w.Code != test.status {
+				t.Errorf("unexpected status code:\n- want: %v\n-  got: %v", test.status, w.Code)
+			}
+			if subject != test.subject {
+				t.Errorf("unexpected subject:\n- want: %v\n-  got: %v", test.subject, subject)
+			}
+			if scopes != test.scopes {
+				t.Errorf("unexpected scopes:\n- want: %v\n-  got: %v", test.scopes, scopes)
+			}
+		})
+	}
+	if calls != 3 {
+		t.Errorf("unexpected introspection calls:\n- want: %v\n-  got: %v", 3, calls)
+	}
+}
+
+func TestIntrospectionAudience(t *testing.T) {
+	tests := []struct {
+		body string
+		want auth.Audiences
+	}{
+		{body: `{"active":true,"aud":"api"}`, want: auth.Audiences{"api"}},
+		{body: `{"active":true,"aud":["api","billing"]}`, want: auth.Audiences{"api", "billing"}},
+		{body: `{"active":true}`},
+	}
+	for _, test := range tests {
+		var got auth.Introspection
+		if err := json.Unmarshal([]byte(test.body), &got); err != nil {
+			t.Fatalf("unexpected error of %s: %v", test.body, err)
+		}
+		if !reflect.DeepEqual(got.Audience, test.want) {
+			t.Errorf("unexpected audience of %s:\n- want: %v\n-  got: %v", test.body, test.want, got.Audience)
+		}
+	}
+}
+
+func TestIntrospectCacheLimit(t *testing.T) {
+	calls := map[string]int{}
+	endpoint := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls[r.PostFormValue("token")]++
+		json.NewEncoder(w).Encode(auth.Introspection{Active: false})
+	}))
+	defer endpoint.Close()
+	introspector := auth.NewIntrospector(endpoint.URL)
+
+	// The first token is evicted by the random tokens that fill the cache.
+	for i := 0; i <= 1024; i++ {
+		if _, err := introspector.Introspect(context.Background(), "token-"+strconv.Itoa(i)); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	introspector.Introspect(context.Background(), "token-0")
+	introspector.Introspect(context.Background(), "token-1024")
+
+	if calls["token-0"] != 2 {
+		t.Errorf("unexpected calls of evicted token:\n- want: %v\n-  got: %v", 2, calls["token-0"])
+	}
+	if calls["token-1024"] != 1 {
+		t.Errorf("unexpected calls of cached token:\n- want: %v\n-  got: %v", 1, calls["token-1024"])
+	}
+}
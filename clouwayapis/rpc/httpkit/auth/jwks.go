@@ -0,0 +1,234 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// KeySet returns the public keys that verify the signatures of the tokens
+// by their key id.
+type KeySet interface {
+	Key(ctx context.Context, kid string) (crypto.PublicKey, error)
+}
+
+// JWKSOption sets an optional parameter for the JWKS key sets.
+type JWKSOption func(*JWKS)
+
+// JWKSClient sets the HTTP client that fetches the key set. The default
+// client times out after 10 seconds.
+func JWKSClient(client *http.Client) JWKSOption {
+	return func(s *JWKS) { s.client = client }
+}
+
+// JWKSRefresh sets how long the fetched keys are cached. The keys are
+// cached for an hour by default.
+func JWKSRefresh(d time.Duration) JWKSOption {
+	return func(s *JWKS) { s.refresh = d }
+}
+
+// JWKS is a KeySet that fetches the JSON Web Key Set (RFC 7517) of the
+// issuer and caches it. The set is fetched again when it expires, or when a
+// token is signed with an unknown key, e.g. after key rotation, but not more
+// often than once a minute. The concurrent requests share a single fetch,
+// and while the issuer is not reachable the expired keys are still used and
+// the fetch is retried once a minute.
+type JWKS struct {
+	url     string
+	client  *http.Client
+	refresh time.Duration
+
+	mu      sync.Mutex
+	keys    map[string]crypto.PublicKey
+	fetched time.Time
+	// fetching is closed when the fetch in flight completes.
+	fetching chan struct{}
+	// failed is the time of the last failed fetch and err is its error.
+	failed time.Time
+	err    error
+}
+
+// minRefetchInterval limits the fetching of the key set because of tokens
+// with unknown keys or after failed fetches.
+const minRefetchInterval = time.Minute
+
+// NewJWKS creates a key set that is fetched from the URL, e.g.
+// https://issuer.example.com/.well-known/jwks.json
+func NewJWKS(url string, options ...JWKSOption) *JWKS {
+	s := &JWKS{url: url, client: &http.Client{Timeout: 10 * time.Second}, refresh: time.Hour}
+	for _, option := range options {
+		option(s)
+	}
+	return s
+}
+
+// Key returns the public key with the key id.
+func (s *JWKS) Key(ctx context.Context, kid string) (crypto.PublicKey, error) {
+	for {
+		s.mu.Lock()
+		key, ok := s.keys[kid]
+		if ok && time.Since(s.fetched) < s.refresh {
+			s.mu.Unlock()
+			return key, nil
+		}
+		if wait := s.fetching; wait != nil {
+			s.mu.Unlock()
+			if ok {
+				return key, nil
+			}
+			select {
+			case <-wait:
+				continue
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		}
+		if !ok && s.keys != nil && time.Since(s.fetched) < minRefetchInterval {
+			s.mu.Unlock()
+			return nil, fmt.Errorf("unknown key %q", kid)
+		}
+		if !s.failed.IsZero() && time.Since(s.failed) < minRefetchInterval {
+			err := s.err
+			s.mu.Unlock()
+			if ok {
+				return key, nil
+			}
+			return nil, err
+		}
+		done := make(chan struct{})
+		s.fetching = done
+		s.mu.Unlock()
+
+		// The fetch is shared by the waiting requests, so it's not
+		// cancelled with the context of this one.
+		keys, err := s.fetch(context.Background())
+
+		s.mu.Lock()
+		s.fetching = nil
+		close(done)
+		if err != nil {
+			s.failed, s.err = time.Now(), err
+			s.mu.Unlock()
+			// The cached keys are used while the issuer is not reachable.
+			if ok {
+				return key, nil
+			}
+			return nil, err
+		}
+		s.keys, s.fetched, s.failed, s.err = keys, time.Now(), time.Time{}, nil
+		s.mu.Unlock()
+		if key, ok = keys[kid]; !ok {
+			return nil, fmt.Errorf("unknown key %q", kid)
+		}
+		return key, nil
+	}
+}
+
+func (s *JWKS) fetch(ctx context.Context) (map[string]crypto.PublicKey, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetching of %s failed with status %d", s.url, resp.StatusCode)
+	}
+
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&set); err != nil {
+		return nil, fmt.Errorf("invalid key set: %v", err)
+	}
+	keys := make(map[string]crypto.PublicKey, len(set.Keys))
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		// The keys of unsupported types are skipped, so they don't
+		// break the verification with the other keys.
+		if key, err := k.publicKey(); err == nil {
+			keys[k.Kid] = key
+		}
+	}
+	return keys, nil
+}
+
+// jwk is a JSON Web Key with the parameters of the RSA, EC and OKP public
+// keys.
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+func (k jwk) publicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	case "OKP":
+		if k.Crv != "Ed25519" {
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil || len(x) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("invalid Ed25519 key")
+		}
+		return ed25519.PublicKey(x), nil
+	}
+	return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+}
+
+func decodeBigInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil || len(b) == 0 {
+		return nil, fmt.Errorf("invalid key parameter")
+	}
+	return new(big.Int).SetBytes(b), nil
+}
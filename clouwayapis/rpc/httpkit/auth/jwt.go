@@ -0,0 +1,252 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"math/big"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Issuer requires the iss claim of the tokens to be the passed issuer.
+func Issuer(issuer string) Option {
+	return func(o *options) { o.issuer = issuer }
+}
+
+// Audience requires the aud claim of the tokens to contain one of the passed
+// audiences.
+func Audience(audiences ...string) Option {
+	return func(o *options) { o.audiences = audiences }
+}
+
+// Leeway sets the tolerated clock skew of the exp and nbf claims. It's one
+// minute by default.
+func Leeway(d time.Duration) Option {
+	return func(o *options) { o.leeway = d }
+}
+
+// Realm sets the realm of the WWW-Authenticate header of the rejected
+// requests.
+func Realm(realm string) Option {
+	return func(o *options) { o.realm = realm }
+}
+
+// Claims are the claims of a JSON Web Token.
+type Claims map[string]interface{}
+
+// Bearer is an HTTP middleware that authenticates the requests by the JSON
+// Web Token (RFC 7519) of the Authorization: Bearer header. The tokens must
+// be signed with RS256, RS384, RS512, ES256, ES384 or EdDSA by a key of the
+// key set, and not expired. The identity of the token is stored in the
+// request context, with the subject and the scopes of the sub and the
+// scope or scp claims and the string claims as attributes. The subject is
+// available by request.SubjectFromContext as well.
+//
+// The rejected requests are answered with 401 Unauthenticated and the
+// WWW-Authenticate header that describes the error as RFC 6750 requires.
+func Bearer(keys KeySet, opts ...Option) func(http.Handler) http.Handler {
+	o := newOptions(opts...)
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := r.Context()
+			token, ok := bearerToken(r)
+			if !ok {
+				w.Header().Set("WWW-Authenticate", o.challenge("", ""))
+				o.errorEncoder(ctx, unauthenticated("missing bearer token"), w)
+				return
+			}
+			claims, err := o.verify(ctx, keys, token, time.Now())
+			if err != nil {
+				w.Header().Set("WWW-Authenticate", o.challenge("invalid_token", err.Error()))
+				o.errorEncoder(ctx, unauthenticated("invalid bearer token: %v", err), w)
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(withIdentity(ctx, claims.identity())))
+		})
+	}
+}
+
+// bearerToken returns the token of the Authorization header.
+func bearerToken(r *http.Request) (string, bool) {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") || token == "" {
+		return "", false
+	}
+	return strings.TrimSpace(token), true
+}
+
+// challenge returns the WWW-Authenticate header of the error.
+func (o *options) challenge(code, description string) string {
+	var params []string
+	if o.realm != "" {
+		params = append(params, fmt.Sprintf("realm=%q", o.realm))
+	}
+	if code != "" {
+		params = append(params, fmt.Sprintf("error=%q", code))
+	}
+	if description != "" {
+		params = append(params, fmt.Sprintf("error_description=%q", description))
+	}
+	if len(params) == 0 {
+		return "Bearer"
+	}
+	return "Bearer " + strings.Join(params, ", ")
+}
+
+type jwtHeader struct {
+	Algorithm string `json:"alg"`
+	KeyID     string `json:"kid"`
+}
+
+// verify verifies the signature and the claims of the token.
+func (o *options) verify(ctx context.Context, keys KeySet, token string, now time.Time) (Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("malformed token")
+	}
+	var header jwtHeader
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, fmt.Errorf("malformed header")
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("malformed signature")
+	}
+	key, err := keys.Key(ctx, header.KeyID)
+	if err != nil {
+		return nil, err
+	}
+	if err := verifySignature(header.Algorithm, key, []byte(parts[0]+"."+parts[1]), sig); err != nil {
+		return nil, err
+	}
+
+	var claims Claims
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, fmt.Errorf("malformed claims")
+	}
+	if exp, ok := claims.time("exp"); ok && now.After(exp.Add(o.leeway)) {
+		return nil, fmt.Errorf("token is expired")
+	}
+	if nbf, ok := claims.time("nbf"); ok && now.Add(o.leeway).Before(nbf) {
+		return nil, fmt.Errorf("token is not valid yet")
+	}
+	if o.issuer != "" && claims["iss"] != o.issuer {
+		return nil, fmt.Errorf("unexpected issuer")
+	}
+	if len(o.audiences) > 0 && !claims.hasAudience(o.audiences) {
+		return nil, fmt.Errorf("unexpected audience")
+	}
+	return claims, nil
+}
+
+func decodeSegment(s string, v interface{}) error {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, v)
+}
+
+// verifySignature verifies the signature with the key. The algorithm must
+// match the type of the key, so the tokens can't downgrade it.
+func verifySignature(algorithm string, key crypto.PublicKey, input, sig []byte) error {
+	var h hash.Hash
+	var hashID crypto.Hash
+	switch algorithm {
+	case "RS256", "ES256":
+		h, hashID = sha256.New(), crypto.SHA256
+	case "RS384", "ES384":
+		h, hashID = sha512.New384(), crypto.SHA384
+	case "RS512":
+		h, hashID = sha512.New(), crypto.SHA512
+	case "EdDSA":
+	default:
+		return fmt.Errorf("unsupported algorithm %q", algorithm)
+	}
+
+	valid := false
+	switch k := key.(type) {
+	case *rsa.PublicKey:
+		if algorithm[:2] == "RS" {
+			h.Write(input)
+			valid = rsa.VerifyPKCS1v15(k, hashID, h.Sum(nil), sig) == nil
+		}
+	case *ecdsa.PublicKey:
+		size := (k.Curve.Params().BitSize + 7) / 8
+		if algorithm[:2] == "ES" && len(sig) == 2*size {
+			h.Write(input)
+			r, s := new(big.Int).SetBytes(sig[:size]), new(big.Int).SetBytes(sig[size:])
+			valid = ecdsa.Verify(k, h.Sum(nil), r, s)
+		}
+	case ed25519.PublicKey:
+		valid = algorithm == "EdDSA" && ed25519.Verify(k, input, sig)
+	}
+	if !valid {
+		return fmt.Errorf("invalid signature")
+	}
+	return nil
+}
+
+// time returns the time of the NumericDate claim.
+func (c Claims) time(name string) (time.Time, bool) {
+	v, ok := c[name].(float64)
+	if !ok {
+		return time.Time{}, false
+	}
+	return time.Unix(int64(v), 0), true
+}
+
+// hasAudience reports whether the aud claim contains one of the audiences.
+func (c Claims) hasAudience(audiences []string) bool {
+	var aud []string
+	switch v := c["aud"].(type) {
+	case string:
+		aud = []string{v}
+	case []interface{}:
+		for _, a := range v {
+			if s, ok := a.(string); ok {
+				aud = append(aud, s)
+			}
+		}
+	}
+	for _, a := range aud {
+		for _, want := range audiences {
+			if a == want {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// identity returns the identity of the claims.
+func (c Claims) identity() *Identity {
+	identity := &Identity{Attributes: map[string]string{}}
+	identity.Subject, _ = c["sub"].(string)
+	if scope, ok := c["scope"].(string); ok {
+		identity.Scopes = strings.Fields(scope)
+	}
+	if scp, ok := c["scp"].([]interface{}); ok {
+		for _, s := range scp {
+			if s, ok := s.(string); ok {
+				identity.Scopes = append(identity.Scopes, s)
+			}
+		}
+	}
+	for k, v := range c {
+		if s, ok := v.(string); ok {
+			identity.Attributes[k] = s
+		}
+	}
+	return identity
+}
@@ -0,0 +1,154 @@
+package auth_test
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/clouway/go-genproto/clouwayapis/rpc/httpkit/auth"
+	"github.com/clouway/go-genproto/clouwayapis/rpc/request"
+)
+
+func TestBearer(t *testing.T) {
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	b64 := base64.RawURLEncoding.EncodeToString
+	jwks := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{"keys": []map[string]string{
+			{"kty": "RSA", "kid": "rsa", "n": b64(rsaKey.N.Bytes()), "e": b64(big.NewInt(int64(rsaKey.E)).Bytes())},
+			{"kty": "EC", "kid": "ec", "crv": "P-256", "x": b64(ecKey.X.FillBytes(make([]byte, 32))), "y": b64(ecKey.Y.FillBytes(make([]byte, 32)))},
+		}})
+	}))
+	defer jwks.Close()
+
+	sign := func(alg, kid string, claims map[string]interface{}) string {
+		header, _ := json.Marshal(map[string]string{"alg": alg, "kid": kid})
+		payload, _ := json.Marshal(claims)
+		input := b64(header) + "." + b64(payload)
+		digest := sha256.Sum256([]byte(input))
+		var sig []byte
+		switch alg {
+		case "RS256":
+			sig, _ = rsa.SignPKCS1v15(rand.Reader, rsaKey, crypto.SHA256, digest[:])
+		case "ES256":
+			r, s, _ := ecdsa.Sign(rand.Reader, ecKey, digest[:])
+			sig = append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...)
+		}
+		return input + "." + b64(sig)
+	}
+	now := time.Now().Unix()
+	claims := func(overrides map[string]interface{}) map[string]interface{} {
+		c := map[string]interface{}{"sub": "user-1", "iss": "https://issuer", "aud": "api", "exp": now + 60, "scope": "read write"}
+		for k, v := range overrides {
+			c[k] = v
+		}
+		return c
+	}
+
+	tests := []struct {
+		name      string
+		token     string
+		status    int
+		subject   string
+		challenge string
+	}{
+		{name: "rsa", token: sign("RS256", "rsa", claims(nil)), status: http.StatusOK, subject: "user-1"},
+		{name: "ec", token: sign("ES256", "ec", claims(map[string]interface{}{"aud": []string{"other", "api"}})), status: http.StatusOK, subject: "user-1"},
+		{name: "missing", status: http.StatusUnauthorized, challenge: `Bearer realm="api"`},
+		{name: "expired", token: sign("RS256", "rsa", claims(map[string]interface{}{"exp": now - 120})), status: http.StatusUnauthorized, challenge: `Bearer realm="api", error="invalid_token", error_description="token is expired"`},
+		{name: "issuer", token: sign("RS256", "rsa", claims(map[string]interface{}{"iss": "https://other"})), status: http.StatusUnauthorized, challenge: `Bearer realm="api", error="invalid_token", error_description="unexpected issuer"`},
+		{name: "audience", token: sign("RS256", "rsa", claims(map[string]interface{}{"aud": "other"})), status: http.StatusUnauthorized, challenge: `Bearer realm="api", error="invalid_token", error_description="unexpected audience"`},
+		{name: "algorithm mismatch", token: sign("ES256", "rsa", claims(nil)), status: http.StatusUnauthorized, challenge: `Bearer realm="api", error="invalid_token", error_description="invalid signature"`},
+		{name: "tampered", token: sign("RS256", "rsa", claims(nil))[:40] + "x", status: http.StatusUnauthorized},
+	}
+	keys := auth.NewJWKS(jwks.URL)
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var subject, contextSubject string
+			var scopes []string
+			handler := auth.Bearer(keys, auth.Issuer("https://issuer"), auth.Audience("api"), auth.Realm("api"))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				identity, _ := auth.FromContext(r.Context())
+				subject, scopes = identity.Subject, identity.Scopes
+				contextSubject = request.SubjectFromContext(r.Context())
+			}))
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			if test.token != "" {
+				r.Header.Set("Authorization", "Bearer "+test.token)
+			}
+
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+
+			if w.Code != test.status {
+				t.Errorf("unexpected status code:\n- want: %v\n-  got: %v", test.status, w.Code)
+			}
+			if subject != test.subject || contextSubject != test.subject {
+				t.Errorf("unexpected subject:\n- want: %v\n-  got: %v, %v", test.subject, subject, contextSubject)
+			}
+			if test.subject != "" && strings.Join(scopes, " ") != "read write" {
+				t.Errorf("unexpected scopes:\n- want: %v\n-  got: %v", "read write", scopes)
+			}
+			if test.challenge != "" && w.Header().Get("WWW-Authenticate") != test.challenge {
+				t.Errorf("unexpected WWW-Authenticate header:\n- want: %v\n-  got: %v", test.challenge, w.Header().Get("WWW-Authenticate"))
+			}
+		})
+	}
+}
+
+func TestJWKSIssuerDown(t *testing.T) {
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	b64 := base64.RawURLEncoding.EncodeToString
+	var fetches, down int32
+	jwks := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&fetches, 1)
+		if atomic.LoadInt32(&down) == 1 {
+			time.Sleep(20 * time.Millisecond)
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"keys": []map[string]string{
+			{"kty": "EC", "kid": "ec", "crv": "P-256", "x": b64(ecKey.X.FillBytes(make([]byte, 32))), "y": b64(ecKey.Y.FillBytes(make([]byte, 32)))},
+		}})
+	}))
+	defer jwks.Close()
+	keys := auth.NewJWKS(jwks.URL, auth.JWKSRefresh(10*time.Millisecond))
+
+	if _, err := keys.Key(context.Background(), "ec"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	atomic.StoreInt32(&down, 1)
+	time.Sleep(20 * time.Millisecond)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if key, err := keys.Key(context.Background(), "ec"); err != nil || key == nil {
+				t.Errorf("unexpected stale key: %v (%v)", key, err)
+			}
+		}()
+	}
+	wg.Wait()
+	if _, err := keys.Key(context.Background(), "ec"); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	if got := atomic.LoadInt32(&fetches); got != 2 {
+		t.Errorf("unexpected fetches:\n- want: %v\n-  got: %v", 2, got)
+	}
+}
@@ -0,0 +1,255 @@
+// Package barcode generates QR codes and Code 128 barcodes as PNG or SVG
+// images for payment slips and asset tags. The codes are encoded by a pure
+// Go encoder, so the services don't need to shell out to external tools.
+package barcode
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/boombuler/barcode"
+	"github.com/boombuler/barcode/code128"
+	"github.com/boombuler/barcode/qr"
+	"github.com/clouway/go-genproto/clouwayapis/rpc/httpkit"
+)
+
+// Type is the symbology of the generated code.
+type Type string
+
+const (
+	// QR is a QR code.
+	QR Type = "qr"
+	// Code128 is a Code 128 linear barcode.
+	Code128 Type = "code128"
+)
+
+// Format is the image format of the generated code.
+type Format string
+
+const (
+	// PNG renders the code as PNG image.
+	PNG Format = "png"
+	// SVG renders the code as SVG document.
+	SVG Format = "svg"
+)
+
+// Spec describes the code that should be generated.
+type Spec struct {
+	// Content is the encoded content.
+	Content string
+	// Type is the symbology of the code. QR is used when empty.
+	Type Type
+	// Format is the image format. PNG is used when empty.
+	Format Format
+	// Width and Height are the size of the image in pixels. The height of
+	// QR codes is the same as the width. They are 256 and 100 when zero.
+	Width, Height int
+	// ErrorCorrection is the error correction level of the QR codes, one of
+	// L, M, Q and H. M is used when empty.
+	ErrorCorrection string
+}
+
+// Encode writes the code described by the spec to w.
+func Encode(w io.Writer, spec Spec) error {
+	bc, err := encode(spec)
+	if err != nil {
+		return err
+	}
+	if spec.Format == SVG {
+		return writeSVG(w, bc)
+	}
+	return png.Encode(w, bc)
+}
+
+// ContentType returns the content type of the images with the format.
+func ContentType(format Format) string {
+	if format == SVG {
+		return "image/svg+xml"
+	}
+	return "image/png"
+}
+
+// The default size of the images in pixels.
+const (
+	defaultWidth  = 256
+	defaultHeight = 100
+)
+
+func encode(spec Spec) (barcode.Barcode, error) {
+	var (
+		bc  barcode.Barcode
+		err error
+	)
+	if spec.Width <= 0 {
+		spec.Width = defaultWidth
+	}
+	if spec.Height <= 0 {
+		spec.Height = defaultHeight
+	}
+	switch spec.Type {
+	case QR, "":
+		level, ok := map[string]qr.ErrorCorrectionLevel{"L": qr.L, "M": qr.M, "Q": qr.Q, "H": qr.H, "": qr.M}[strings.ToUpper(spec.ErrorCorrection)]
+		if !ok {
+			return nil, fmt.Errorf("unknown error correction level '%s'", spec.ErrorCorrection)
+		}
+		bc, err = qr.Encode(spec.Content, level, qr.Auto)
+		spec.Height = spec.Width
+	case Code128:
+		bc, err = code128.Encode(spec.Content)
+	default:
+		return nil, fmt.Errorf("unknown code type '%s'", spec.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if spec.Format == SVG {
+		// The SVG documents are scaled by the view box.
+		return bc, nil
+	}
+	return barcode.Scale(bc, spec.Width, spec.Height)
+}
+
+// writeSVG writes the modules of the unscaled code as rectangles.
+func writeSVG(w io.Writer, bc barcode.Barcode) error {
+	b := bc.Bounds()
+	height := b.Dy()
+	if height == 1 {
+		// Linear codes have a height of a single module.
+		height = b.Dx() / 4
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 %d %d" shape-rendering="crispEdges">`, b.Dx(), height)
+	fmt.Fprintf(&sb, `<rect width="%d" height="%d" fill="#fff"/>`, b.Dx(), height)
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			if !isDark(bc.At(x, y)) {
+				continue
+			}
+			h := 1
+			if b.Dy() == 1 {
+				h = height
+			}
+			fmt.Fprintf(&sb, `<rect x="%d" y="%d" width="1" height="%d"/>`, x-b.Min.X, y-b.Min.Y, h)
+		}
+	}
+	sb.WriteString(`</svg>`)
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+func isDark(c color.Color) bool {
+	r, g, b, _ := c.RGBA()
+	return r+g+b < 3*0x8000
+}
+
+// Option sets an optional parameter for the Handler.
+type Option func(*handler)
+
+// MaxAge sets the max-age of the Cache-Control header of the generated
+// images in seconds. The images are cached for a day by default.
+func MaxAge(seconds int) Option {
+	return func(h *handler) { h.maxAge = seconds }
+}
+
+// MaxSize limits the width and the height of the generated images. The
+// limit is 2048 pixels by default.
+func MaxSize(pixels int) Option {
+	return func(h *handler) { h.maxSize = pixels }
+}
+
+// Handler returns an http.Handler that generates codes from the query
+// parameters of the requests:
+//
+//	GET /barcode?content=BG80BNBG96611020345678&type=qr&format=svg&size=256&ecc=H
+//
+// The supported parameters are content, type (qr, code128), format (png,
+// svg), size or width and height, and ecc (L, M, Q, H). The invalid
+// parameters are answered with InvalidArgument errors by httpkit.ErrorEncoder.
+func Handler(options ...Option) http.Handler {
+	h := &handler{maxAge: 24 * 60 * 60, maxSize: 2048}
+	for _, option := range options {
+		option(h)
+	}
+	return h
+}
+
+type handler struct {
+	maxAge  int
+	maxSize int
+}
+
+func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	spec := Spec{
+		Content:         q.Get("content"),
+		Type:            Type(strings.ToLower(q.Get("type"))),
+		Format:          Format(strings.ToLower(q.Get("format"))),
+		ErrorCorrection: q.Get("ecc"),
+	}
+	if spec.Content == "" {
+		httpkit.ErrorEncoder(r.Context(), httpkit.NewBadRequestError("content is required"), w)
+		return
+	}
+	if spec.Format != "" && spec.Format != PNG && spec.Format != SVG {
+		httpkit.ErrorEncoder(r.Context(), httpkit.NewBadRequestError("unknown format '%s'", spec.Format), w)
+		return
+	}
+	var err error
+	size := q.Get("size")
+	if spec.Width, err = h.dimension(q.Get("width"), size, defaultWidth); err != nil {
+		httpkit.ErrorEncoder(r.Context(), err, w)
+		return
+	}
+	if spec.Height, err = h.dimension(q.Get("height"), size, defaultHeight); err != nil {
+		httpkit.ErrorEncoder(r.Context(), err, w)
+		return
+	}
+
+	etag := etag(spec)
+	w.Header().Set("Cache-Control", "public, max-age="+strconv.Itoa(h.maxAge))
+	w.Header().Set("ETag", etag)
+	if r.Header.Get("If-None-Match") == etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	bc, err := encode(spec)
+	if err != nil {
+		httpkit.ErrorEncoder(r.Context(), httpkit.NewBadRequestError("%v", err), w)
+		return
+	}
+	w.Header().Set("Content-Type", ContentType(spec.Format))
+	if spec.Format == SVG {
+		writeSVG(w, bc)
+		return
+	}
+	png.Encode(w, image.Image(bc))
+}
+
+// dimension parses the first non-empty value as image dimension in pixels.
+func (h *handler) dimension(value, fallback string, def int) (int, error) {
+	if value == "" {
+		value = fallback
+	}
+	if value == "" {
+		return def, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n <= 0 || n > h.maxSize {
+		return 0, httpkit.NewBadRequestError("invalid size '%s', expected a number between 1 and %d", value, h.maxSize)
+	}
+	return n, nil
+}
+
+func etag(spec Spec) string {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s\x00%s\x00%s\x00%d\x00%d\x00%s", spec.Content, spec.Type, spec.Format, spec.Width, spec.Height, spec.ErrorCorrection)))
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
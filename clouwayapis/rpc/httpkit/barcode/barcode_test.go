@@ -0,0 +1,92 @@
+package barcode_test
+
+import (
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/clouway/go-genproto/clouwayapis/rpc/httpkit/barcode"
+)
+
+func TestHandler(t *testing.T) {
+	tests := []struct {
+		name        string
+		query       string
+		status      int
+		contentType string
+	}{
+		{name: "qr png", query: "content=BG80BNBG96611020345678&size=128", status: http.StatusOK, contentType: "image/png"},
+		{name: "code128 svg", query: "content=ASSET-0001&type=code128&format=svg", status: http.StatusOK, contentType: "image/svg+xml"},
+		{name: "missing content", query: "size=128", status: http.StatusBadRequest, contentType: "application/json; charset=utf-8"},
+		{name: "too large", query: "content=x&size=100000", status: http.StatusBadRequest, contentType: "application/json; charset=utf-8"},
+		{name: "unknown type", query: "content=x&type=ean", status: http.StatusBadRequest, contentType: "application/json; charset=utf-8"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			barcode.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/barcode?"+test.query, nil))
+
+			if rec.Code != test.status {
+				t.Errorf("unexpected status code:\n- want: %v\n-  got: %v", test.status, rec.Code)
+			}
+			if got := rec.Header().Get("Content-Type"); got != test.contentType {
+				t.Errorf("unexpected Content-Type header:\n- want: %v\n-  got: %v", test.contentType, got)
+			}
+		})
+	}
+}
+
+func TestEncodePNG(t *testing.T) {
+	var b strings.Builder
+	if err := barcode.Encode(&b, barcode.Spec{Content: "BG80BNBG96611020345678", Width: 200}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	img, err := png.Decode(strings.NewReader(b.String()))
+	if err != nil {
+		t.Fatalf("unexpected error while decoding the image: %v", err)
+	}
+	if got := img.Bounds().Dx(); got != 200 {
+		t.Errorf("unexpected width:\n- want: %v\n-  got: %v", 200, got)
+	}
+}
+
+func TestEncodeDefaultSize(t *testing.T) {
+	tests := []struct {
+		spec          barcode.Spec
+		width, height int
+	}{
+		{spec: barcode.Spec{Content: "BG80BNBG96611020345678"}, width: 256, height: 256},
+		{spec: barcode.Spec{Content: "ASSET-0001", Type: barcode.Code128}, width: 256, height: 100},
+	}
+	for _, test := range tests {
+		var b strings.Builder
+		if err := barcode.Encode(&b, test.spec); err != nil {
+			t.Fatalf("unexpected error of %s: %v", test.spec.Type, err)
+		}
+
+		img, err := png.Decode(strings.NewReader(b.String()))
+		if err != nil {
+			t.Fatalf("unexpected error while decoding the image: %v", err)
+		}
+		if got := img.Bounds(); got.Dx() != test.width || got.Dy() != test.height {
+			t.Errorf("unexpected size of %s:\n- want: %vx%v\n-  got: %vx%v", test.spec.Type, test.width, test.height, got.Dx(), got.Dy())
+		}
+	}
+}
+
+func TestHandlerNotModified(t *testing.T) {
+	rec := httptest.NewRecorder()
+	barcode.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/barcode?content=x", nil))
+
+	req := httptest.NewRequest(http.MethodGet, "/barcode?content=x", nil)
+	req.Header.Set("If-None-Match", rec.Header().Get("ETag"))
+	rec = httptest.NewRecorder()
+	barcode.Handler().ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusNotModified {
+		t.Errorf("unexpected status code:\n- want: %v\n-  got: %v", http.StatusNotModified, rec.Code)
+	}
+}
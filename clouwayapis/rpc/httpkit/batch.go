@@ -0,0 +1,166 @@
+package httpkit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// BatchOption sets an optional parameter for the batch handler.
+type BatchOption func(*batchOptions)
+
+// BatchMaxItems limits the number of the sub-requests of a batch. It's 20
+// by default.
+func BatchMaxItems(n int) BatchOption {
+	return func(o *batchOptions) { o.maxItems = n }
+}
+
+// BatchMaxBytes limits the size of the body of the batch requests. It's
+// 1MiB by default.
+func BatchMaxBytes(limit int64) BatchOption {
+	return func(o *batchOptions) { o.maxBytes = limit }
+}
+
+type batchOptions struct {
+	maxItems int
+	maxBytes int64
+}
+
+// BatchItem is a sub-request of a batch.
+type BatchItem struct {
+	Method string          `json:"method"`
+	Path   string          `json:"path"`
+	Body   json.RawMessage `json:"body,omitempty"`
+}
+
+// batchKey marks the context of the sub-requests of a batch.
+type batchKey struct{}
+
+// BatchResult is the response of a sub-request of a batch. The body of the
+// JSON responses is embedded as it is, and the other bodies as strings.
+type BatchResult struct {
+	Status int             `json:"status"`
+	Body   json.RawMessage `json:"body,omitempty"`
+}
+
+// NewBatchHandler returns a handler that serves a JSON array of
+// sub-requests by dispatching them one after another to the handler, e.g.
+// the router of the service, and responds with the array of their results
+// in the same order:
+//
+//	POST /v1:batch
+//	[{"method": "GET", "path": "/v1/orders/1"}, {"method": "POST", "path": "/v1/orders", "body": {...}}]
+//
+//	200 OK
+//	[{"status": 200, "body": {...}}, {"status": 400, "body": {"message": "..."}}]
+//
+// The sub-requests get the headers of the batch request, so they are
+// authenticated and negotiated in the same way, except for the
+// Idempotency-Key, which gets the index of the item as suffix, e.g.
+// "key/1". The errors of the sub-requests are reported by the status and
+// the body of their results. The batch itself is rejected with 400 Bad
+// Request when it's malformed, has too many items or is itself a
+// sub-request of a batch.
+func NewBatchHandler(handler http.Handler, options ...BatchOption) http.Handler {
+	o := &batchOptions{maxItems: 20, maxBytes: 1 << 20}
+	for _, option := range options {
+		option(o)
+	}
+	decode := newDecodeOptions(DecodeMaxBytes(o.maxBytes))
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		if ctx.Value(batchKey{}) != nil {
+			ErrorEncoder(ctx, NewBadRequestError("nested batch requests are not supported"), w)
+			return
+		}
+		b, err := decode.readBody(r)
+		if err != nil {
+			ErrorEncoder(ctx, err, w)
+			return
+		}
+		var items []BatchItem
+		if err := json.Unmarshal(b, &items); err != nil {
+			ErrorEncoder(ctx, NewBadRequestError("invalid batch: %v", err), w)
+			return
+		}
+		if len(items) > o.maxItems {
+			ErrorEncoder(ctx, NewBadRequestError("batch has %d items, the limit is %d", len(items), o.maxItems), w)
+			return
+		}
+
+		results := make([]BatchResult, len(items))
+		for i, item := range items {
+			results[i] = serveBatchItem(handler, r, i, item)
+		}
+		w.Header().Set("Content-Type", JSONContentType)
+		json.NewEncoder(w).Encode(results)
+	})
+}
+
+// serveBatchItem serves the sub-request of the batch request at the index.
+func serveBatchItem(handler http.Handler, r *http.Request, index int, item BatchItem) BatchResult {
+	if !strings.HasPrefix(item.Path, "/") || item.Method == "" {
+		message, _ := json.Marshal(map[string]string{"message": "invalid batch item"})
+		return BatchResult{Status: http.StatusBadRequest, Body: message}
+	}
+	ctx := context.WithValue(r.Context(), batchKey{}, true)
+	sub, err := http.NewRequestWithContext(ctx, strings.ToUpper(item.Method), item.Path, bytes.NewReader(item.Body))
+	if err != nil {
+		message, _ := json.Marshal(map[string]string{"message": err.Error()})
+		return BatchResult{Status: http.StatusBadRequest, Body: message}
+	}
+	sub.Header = r.Header.Clone()
+	sub.Header.Del("Content-Length")
+	sub.Header.Del("Content-Encoding")
+	sub.Header.Set("Content-Type", JSONContentType)
+	// The idempotency key of the batch is in flight while its items are
+	// served, so the items get their own keys that are derived from it.
+	if key := r.Header.Get(IdempotencyKeyHeader); key != "" {
+		sub.Header.Set(IdempotencyKeyHeader, key+"/"+strconv.Itoa(index))
+	}
+	sub.Host, sub.RemoteAddr = r.Host, r.RemoteAddr
+
+	rw := &bufferedWriter{header: http.Header{}, code: http.StatusOK}
+	handler.ServeHTTP(rw, sub)
+
+	result := BatchResult{Status: rw.code}
+	if rw.body.Len() == 0 {
+		return result
+	}
+	mediaType, _, _ := mime.ParseMediaType(rw.header.Get("Content-Type"))
+	if mediaType == "application/json" && json.Valid(rw.body.Bytes()) {
+		result.Body = bytes.TrimSpace(rw.body.Bytes())
+	} else {
+		result.Body, _ = json.Marshal(rw.body.String())
+	}
+	return result
+}
+
+// bufferedWriter keeps the response in memory.
+type bufferedWriter struct {
+	header      http.Header
+	code        int
+	body        bytes.Buffer
+	wroteHeader bool
+}
+
+func (w *bufferedWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *bufferedWriter) WriteHeader(code int) {
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+	w.code = code
+}
+
+func (w *bufferedWriter) Write(b []byte) (int, error) {
+	w.wroteHeader = true
+	return w.body.Write(b)
+}
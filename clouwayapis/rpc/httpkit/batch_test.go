@@ -0,0 +1,100 @@
+package httpkit_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/clouway/go-genproto/clouwayapis/rpc/httpkit"
+	"github.com/gorilla/mux"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestBatchHandler(t *testing.T) {
+	router := mux.NewRouter()
+	router.Methods(http.MethodGet).Path("/v1/orders/{id}").HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if mux.Vars(r)["id"] != "1" {
+			httpkit.ErrorEncoder(r.Context(), status.Error(codes.NotFound, "order not found"), w)
+			return
+		}
+		w.Header().Set("Content-Type", httpkit.JSONContentType)
+		io.WriteString(w, `{"id":"1","owner":"`+r.Header.Get("X-User")+`"}`)
+	})
+	router.Methods(http.MethodPost).Path("/v1/orders").HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusCreated)
+		w.Write(body)
+	})
+	handler := httpkit.NewBatchHandler(router, httpkit.BatchMaxItems(3))
+	router.Methods(http.MethodPost).Path("/v1:batch").Handler(handler)
+	router.Methods(http.MethodPost).Path("/v2:batch").Handler(handler)
+
+	tests := []struct {
+		name   string
+		body   string
+		status int
+		want   string
+	}{
+		{
+			name:   "batch",
+			body:   `[{"method":"GET","path":"/v1/orders/1"},{"method":"GET","path":"/v1/orders/2"},{"method":"POST","path":"/v1/orders","body":{"item":"book"}}]`,
+			status: http.StatusOK,
+			want:   `[{"status":200,"body":{"id":"1","owner":"user-1"}},{"status":404,"body":{"message":"order not found"}},{"status":201,"body":"{\"item\":\"book\"}"}]`,
+		},
+		{
+			name:   "too many items",
+			body:   `[{"method":"GET","path":"/v1/orders/1"},{"method":"GET","path":"/v1/orders/1"},{"method":"GET","path":"/v1/orders/1"},{"method":"GET","path":"/v1/orders/1"}]`,
+			status: http.StatusBadRequest,
+			want:   `{"message":"batch has 4 items, the limit is 3"}`,
+		},
+		{
+			name:   "nested batch",
+			body:   `[{"method":"POST","path":"/v1:batch"},{"method":"POST","path":"/v2:batch","body":[]}]`,
+			status: http.StatusOK,
+			want:   `[{"status":400,"body":{"message":"nested batch requests are not supported"}},{"status":400,"body":{"message":"nested batch requests are not supported"}}]`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/v1:batch", strings.NewReader(test.body))
+			r.Header.Set("X-User", "user-1")
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+
+			if w.Code != test.status {
+				t.Errorf("unexpected status code:\n- want: %v\n-  got: %v", test.status, w.Code)
+			}
+			if got := compactJSON(w.Body.Bytes()); got != test.want {
+				t.Errorf("unexpected body:\n- want: %v\n-  got: %v", test.want, got)
+			}
+		})
+	}
+}
+
+func TestBatchHandlerWithIdempotency(t *testing.T) {
+	var keys []string
+	router := mux.NewRouter()
+	router.Use(httpkit.Idempotency(httpkit.NewMemoryIdempotencyStore(), time.Hour))
+	router.Methods(http.MethodPost).Path("/v1/orders").HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		keys = append(keys, r.Header.Get(httpkit.IdempotencyKeyHeader))
+		w.WriteHeader(http.StatusCreated)
+	})
+	router.Methods(http.MethodPost).Path("/v1:batch").Handler(httpkit.NewBatchHandler(router))
+
+	r := httptest.NewRequest(http.MethodPost, "/v1:batch", strings.NewReader(`[{"method":"POST","path":"/v1/orders"},{"method":"POST","path":"/v1/orders"}]`))
+	r.Header.Set(httpkit.IdempotencyKeyHeader, "key-1")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, r)
+
+	if want, got := `[{"status":201},{"status":201}]`, compactJSON(w.Body.Bytes()); got != want {
+		t.Errorf("unexpected body:\n- want: %v\n-  got: %v", want, got)
+	}
+	if want := []string{"key-1/0", "key-1/1"}; !reflect.DeepEqual(want, keys) {
+		t.Errorf("unexpected idempotency keys:\n- want: %v\n-  got: %v", want, keys)
+	}
+}
@@ -0,0 +1,35 @@
+package httpkit
+
+import (
+	"net/http"
+
+	"github.com/clouway/go-genproto/clouwayapis/rpc/breaker"
+)
+
+// CircuitBreakerTransport returns an http.RoundTripper that sends the
+// requests through the circuit breaker. The transport errors and the 5xx
+// responses are counted as failures. The requests that are rejected by the
+// open breaker fail with its Unavailable status error, which the callers
+// can inspect with status.Code and propagate to their own clients. When
+// next is nil http.DefaultTransport is used.
+func CircuitBreakerTransport(b *breaker.Breaker, next http.RoundTripper) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		done, err := b.Allow()
+		if err != nil {
+			return nil, err
+		}
+		resp, err := next.RoundTrip(r)
+		done(err == nil && resp.StatusCode < http.StatusInternalServerError)
+		return resp, err
+	})
+}
+
+// roundTripperFunc is an adapter to use functions as http.RoundTripper.
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
@@ -0,0 +1,37 @@
+package httpkit_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/clouway/go-genproto/clouwayapis/rpc/breaker"
+	"github.com/clouway/go-genproto/clouwayapis/rpc/httpkit"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestCircuitBreakerTransport(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+	client := &http.Client{Transport: httpkit.CircuitBreakerTransport(breaker.New(breaker.FailureThreshold(2)), nil)}
+
+	var err error
+	for i := 0; i < 3; i++ {
+		var resp *http.Response
+		if resp, err = client.Get(server.URL); err == nil {
+			resp.Body.Close()
+		}
+	}
+
+	if calls != 2 {
+		t.Errorf("unexpected calls:\n- want: %v\n-  got: %v", 2, calls)
+	}
+	if status.Code(err) != codes.Unavailable {
+		t.Errorf("unexpected code:\n- want: %v\n-  got: %v", codes.Unavailable, status.Code(err))
+	}
+}
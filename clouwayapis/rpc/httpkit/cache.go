@@ -0,0 +1,177 @@
+package httpkit
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	httptransport "github.com/go-kit/kit/transport/http"
+)
+
+// CacheOption sets a directive of the caching policy of the responses.
+type CacheOption func(*cachePolicy)
+
+// CachePublic allows the shared caches such as CDNs to store the responses.
+func CachePublic() CacheOption {
+	return func(p *cachePolicy) { p.visibility = "public" }
+}
+
+// CachePrivate restricts the caching of the responses to the browser of the
+// user, e.g. for the responses with user data.
+func CachePrivate() CacheOption {
+	return func(p *cachePolicy) { p.visibility = "private" }
+}
+
+// CacheMaxAge sets how long the responses are fresh. The Expires header is
+// set accordingly for the HTTP/1.0 caches.
+func CacheMaxAge(d time.Duration) CacheOption {
+	return func(p *cachePolicy) { p.maxAge = d }
+}
+
+// CacheNoCache requires the caches to revalidate the responses, e.g. by
+// their ETag, before they are used.
+func CacheNoCache() CacheOption {
+	return func(p *cachePolicy) { p.noCache = true }
+}
+
+// CacheNoStore forbids the caching of the responses.
+func CacheNoStore() CacheOption {
+	return func(p *cachePolicy) { p.noStore = true }
+}
+
+// CacheVary adds the request headers by which the responses differ, e.g.
+// Accept or Accept-Language.
+func CacheVary(headers ...string) CacheOption {
+	return func(p *cachePolicy) { p.vary = append(p.vary, headers...) }
+}
+
+type cachePolicy struct {
+	visibility string
+	maxAge     time.Duration
+	noCache    bool
+	noStore    bool
+	vary       []string
+}
+
+func newCachePolicy(options ...CacheOption) *cachePolicy {
+	p := &cachePolicy{}
+	for _, option := range options {
+		option(p)
+	}
+	return p
+}
+
+// cacheControl returns the value of the Cache-Control header.
+func (p *cachePolicy) cacheControl() string {
+	if p.noStore {
+		return "no-store"
+	}
+	var directives []string
+	if p.visibility != "" {
+		directives = append(directives, p.visibility)
+	}
+	if p.noCache {
+		directives = append(directives, "no-cache")
+	}
+	if p.maxAge > 0 {
+		directives = append(directives, "max-age="+strconv.FormatInt(int64(p.maxAge/time.Second), 10))
+	}
+	return strings.Join(directives, ", ")
+}
+
+// apply sets the caching headers of a successful response.
+func (p *cachePolicy) apply(h http.Header) {
+	if cc := p.cacheControl(); cc != "" {
+		h.Set("Cache-Control", cc)
+	}
+	if p.maxAge > 0 && !p.noStore {
+		h.Set("Expires", time.Now().Add(p.maxAge).UTC().Format(http.TimeFormat))
+	}
+	for _, header := range p.vary {
+		h.Add("Vary", header)
+	}
+}
+
+// NewCacheControlEncoder returns an EncodeResponseFunc that sets the
+// Cache-Control, Expires and Vary headers of the policy before the response
+// is encoded by next. The errors are not affected, as they are written by
+// the error encoder, which marks them as not cacheable.
+//
+//	encode := httpkit.NewCacheControlEncoder(httpkit.EncodeProtoJSONResponse, httpkit.CachePublic(), httpkit.CacheMaxAge(time.Minute))
+func NewCacheControlEncoder(next httptransport.EncodeResponseFunc, options ...CacheOption) httptransport.EncodeResponseFunc {
+	p := newCachePolicy(options...)
+	return func(ctx context.Context, w http.ResponseWriter, response interface{}) error {
+		p.apply(w.Header())
+		return next(ctx, w, response)
+	}
+}
+
+// CacheControl is an HTTP middleware that sets the caching headers of the
+// policy on the responses of the next handler. The error responses, with
+// status 400 and above, are marked as not cacheable instead. The Vary
+// headers are set on all responses.
+func CacheControl(options ...CacheOption) func(http.Handler) http.Handler {
+	p := newCachePolicy(options...)
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(&cacheWriter{ResponseWriter: w, policy: p}, r)
+		})
+	}
+}
+
+// cacheWriter sets the caching headers once the status of the response is
+// known.
+type cacheWriter struct {
+	http.ResponseWriter
+	policy      *cachePolicy
+	wroteHeader bool
+}
+
+func (w *cacheWriter) WriteHeader(code int) {
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+	h := w.Header()
+	if code >= http.StatusBadRequest {
+		h.Set("Cache-Control", "no-store")
+		h.Del("Expires")
+		for _, header := range w.policy.vary {
+			h.Add("Vary", header)
+		}
+	} else if h.Get("Cache-Control") == "" {
+		w.policy.apply(h)
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *cacheWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// Flush implements http.Flusher for the streaming handlers.
+func (w *cacheWriter) Flush() {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack implements http.Hijacker for the websocket upgrades.
+func (w *cacheWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return hijack(w.ResponseWriter)
+}
+
+// Unwrap returns the underlying writer for http.ResponseController.
+func (w *cacheWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
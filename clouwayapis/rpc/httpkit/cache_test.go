@@ -0,0 +1,116 @@
+package httpkit_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/clouway/go-genproto/clouwayapis/rpc/errdetails"
+	"github.com/clouway/go-genproto/clouwayapis/rpc/httpkit"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestCacheControlEncoder(t *testing.T) {
+	tests := []struct {
+		name    string
+		options []httpkit.CacheOption
+		want    string
+		expires bool
+	}{
+		{name: "public", options: []httpkit.CacheOption{httpkit.CachePublic(), httpkit.CacheMaxAge(time.Minute)}, want: "public, max-age=60", expires: true},
+		{name: "private revalidated", options: []httpkit.CacheOption{httpkit.CachePrivate(), httpkit.CacheNoCache()}, want: "private, no-cache"},
+		{name: "no store", options: []httpkit.CacheOption{httpkit.CacheNoStore(), httpkit.CacheMaxAge(time.Minute)}, want: "no-store"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			encode := httpkit.NewCacheControlEncoder(httpkit.EncodeProtoJSONResponse, append(test.options, httpkit.CacheVary("Accept"))...)
+
+			w := httptest.NewRecorder()
+			if err := encode(context.Background(), w, &errdetails.ErrorInfo{Reason: "Test Reason"}); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if got := w.Header().Get("Cache-Control"); got != test.want {
+				t.Errorf("unexpected Cache-Control header:\n- want: %v\n-  got: %v", test.want, got)
+			}
+			if got := w.Header().Get("Expires") != ""; got != test.expires {
+				t.Errorf("unexpected Expires header:\n- want: %v\n-  got: %v", test.expires, got)
+			}
+			if got := w.Header().Get("Vary"); got != "Accept" {
+				t.Errorf("unexpected Vary header:\n- want: %v\n-  got: %v", "Accept", got)
+			}
+		})
+	}
+}
+
+func TestCacheControl(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		want    string
+	}{
+		{
+			name:    "success",
+			handler: func(w http.ResponseWriter, r *http.Request) { w.Write([]byte("ok")) },
+			want:    "public, max-age=60",
+		},
+		{
+			name: "own policy",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Cache-Control", "private")
+				w.Write([]byte("ok"))
+			},
+			want: "private",
+		},
+		{
+			name: "error",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				http.Error(w, "internal error", http.StatusInternalServerError)
+			},
+			want: "no-store",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			handler := httpkit.CacheControl(httpkit.CachePublic(), httpkit.CacheMaxAge(time.Minute))(test.handler)
+
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+
+			if got := w.Header().Get("Cache-Control"); got != test.want {
+				t.Errorf("unexpected Cache-Control header:\n- want: %v\n-  got: %v", test.want, got)
+			}
+		})
+	}
+}
+
+func TestErrorEncoderIsNotCacheable(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+	}{
+		{name: "status", err: status.Error(codes.NotFound, "not found")},
+		{name: "plain", err: errors.New("failure")},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			w.Header().Set("Cache-Control", "public, max-age=60")
+			httpkit.ErrorEncoder(context.Background(), test.err, w)
+
+			if got := w.Header().Get("Cache-Control"); got != "no-store" {
+				t.Errorf("unexpected Cache-Control header:\n- want: %v\n-  got: %v", "no-store", got)
+			}
+		})
+	}
+}
+
+func TestCacheControlHijack(t *testing.T) {
+	if code := serveUpgrade(t, httpkit.CacheControl(httpkit.CacheMaxAge(time.Minute))(upgradeHandler(t))); code != http.StatusSwitchingProtocols {
+		t.Errorf("unexpected status code:\n- want: %v\n-  got: %v", http.StatusSwitchingProtocols, code)
+	}
+}
@@ -0,0 +1,74 @@
+package httpkit
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+// CapabilitiesPath is the path of the capabilities document that is
+// registered by RegisterCapabilities.
+const CapabilitiesPath = "/.well-known/capabilities"
+
+// Capabilities describes the optional kit capabilities that are enabled by
+// a server, so the clients and the gateways can configure themselves
+// instead of relying on out-of-band knowledge. The document is encoded as:
+//
+//	{
+//	  "contentTypes": ["application/json", "application/x-protobuf"],
+//	  "encodings": ["gzip"],
+//	  "protocols": ["sse", "grpc-web"],
+//	  "authSchemes": ["Bearer"],
+//	  "maxRequestBytes": 4194304,
+//	  "apiVersions": ["v1", "v2"]
+//	}
+type Capabilities struct {
+	// ContentTypes are the media types of the request and response bodies.
+	ContentTypes []string `json:"contentTypes"`
+	// Encodings are the content codings of the request bodies, e.g. "gzip"
+	// when the server uses Decompress.
+	Encodings []string `json:"encodings,omitempty"`
+	// Protocols are the streaming and RPC protocols, e.g. "sse", "ndjson",
+	// "websocket", "grpc-web" or "connect".
+	Protocols []string `json:"protocols,omitempty"`
+	// AuthSchemes are the schemes of the Authorization header.
+	AuthSchemes []string `json:"authSchemes,omitempty"`
+	// MaxRequestBytes is the limit of the request bodies, e.g. of MaxBytes.
+	MaxRequestBytes int64 `json:"maxRequestBytes,omitempty"`
+	// APIVersions are the served versions of the API.
+	APIVersions []string `json:"apiVersions,omitempty"`
+	// Features are the other capabilities of the server by name.
+	Features map[string]bool `json:"features,omitempty"`
+}
+
+// DefaultCapabilities returns the capabilities that are supported by the
+// kit out of the box, i.e. the content types that are negotiated by
+// EncodeNegotiatedResponse and DecodeNegotiatedRequest.
+func DefaultCapabilities() Capabilities {
+	c := Capabilities{ContentTypes: []string{"application/json"}}
+	for _, codec := range bodyCodecs {
+		c.ContentTypes = append(c.ContentTypes, codec.contentTypes[0])
+	}
+	return c
+}
+
+// NewCapabilitiesHandler returns a handler that responds with the JSON
+// document of the capabilities.
+func NewCapabilitiesHandler(c Capabilities) http.Handler {
+	b, err := json.Marshal(c)
+	if err != nil {
+		panic(err)
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", JSONContentType)
+		w.Header().Set("Cache-Control", "public, max-age=300")
+		w.Write(b)
+	})
+}
+
+// RegisterCapabilities registers the handler of the capabilities document
+// for GET requests of CapabilitiesPath.
+func RegisterCapabilities(router *mux.Router, c Capabilities) {
+	router.Methods(http.MethodGet).Path(CapabilitiesPath).Handler(NewCapabilitiesHandler(c))
+}
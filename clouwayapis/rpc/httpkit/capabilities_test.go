@@ -0,0 +1,30 @@
+package httpkit_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/clouway/go-genproto/clouwayapis/rpc/httpkit"
+	"github.com/gorilla/mux"
+)
+
+func TestRegisterCapabilities(t *testing.T) {
+	c := httpkit.DefaultCapabilities()
+	c.Encodings = []string{"gzip"}
+	c.AuthSchemes = []string{"Bearer"}
+	c.MaxRequestBytes = 1 << 20
+	router := mux.NewRouter()
+	httpkit.RegisterCapabilities(router, c)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, httpkit.CapabilitiesPath, nil))
+
+	if w.Code != http.StatusOK {
+		t.Errorf("unexpected status code:\n- want: %v\n-  got: %v", http.StatusOK, w.Code)
+	}
+	want := `{"contentTypes":["application/json","application/x-protobuf","application/msgpack","application/cbor"],"encodings":["gzip"],"authSchemes":["Bearer"],"maxRequestBytes":1048576}`
+	if got := compactJSON(w.Body.Bytes()); got != want {
+		t.Errorf("unexpected body:\n- want: %v\n-  got: %v", want, got)
+	}
+}
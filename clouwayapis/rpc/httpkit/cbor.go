@@ -0,0 +1,37 @@
+package httpkit
+
+import (
+	"reflect"
+
+	"github.com/fxamacker/cbor/v2"
+	"google.golang.org/protobuf/proto"
+)
+
+// CBORContentType is the content type of CBOR messages.
+const CBORContentType = "application/cbor"
+
+var (
+	cborEncMode, _ = cbor.CoreDetEncOptions().EncMode()
+	cborDecMode, _ = cbor.DecOptions{DefaultMapType: reflect.TypeOf(map[string]interface{}(nil))}.DecMode()
+)
+
+// MarshalCBOR encodes the message as deterministic CBOR. Like MarshalMsgpack
+// the document has the shape of the JSON mapping of the message, with the
+// integers and the bytes encoded natively.
+func MarshalCBOR(m proto.Message) ([]byte, error) {
+	v, err := protoToValue(m)
+	if err != nil {
+		return nil, err
+	}
+	return cborEncMode.Marshal(v)
+}
+
+// UnmarshalCBOR decodes the CBOR document into the message. The unknown
+// fields are ignored in the same way as by UnmarshalJSON.
+func UnmarshalCBOR(b []byte, m proto.Message) error {
+	var v interface{}
+	if err := cborDecMode.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	return valueToProto(v, m)
+}
@@ -0,0 +1,83 @@
+package httpkit_test
+
+import (
+	"bytes"
+	"context"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/clouway/go-genproto/clouwayapis/rpc/errdetails"
+	"github.com/clouway/go-genproto/clouwayapis/rpc/httpkit"
+	"github.com/clouway/go-genproto/clouwayapis/rpc/request"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/apipb"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"google.golang.org/protobuf/types/known/typepb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+func TestCBORRoundTrip(t *testing.T) {
+	want := &apipb.Api{Name: "orders", Methods: []*apipb.Method{{Name: "GetOrder"}}, Version: "v1"}
+
+	b, err := httpkit.MarshalCBOR(want)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	got := &apipb.Api{}
+	if err := httpkit.UnmarshalCBOR(b, got); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !proto.Equal(got, want) {
+		t.Errorf("unexpected message:\n- want: %v\n-  got: %v", want, got)
+	}
+}
+
+func TestCBORWellKnownTypes(t *testing.T) {
+	value, _ := structpb.NewStruct(map[string]interface{}{"name": "orders", "count": 2.0})
+	tests := []proto.Message{
+		timestamppb.New(time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)),
+		&wrapperspb.UInt64Value{Value: math.MaxUint64},
+		&wrapperspb.BytesValue{Value: []byte{0, 1, 2}},
+		&typepb.Field{Kind: typepb.Field_TYPE_INT64, Number: -3},
+		value,
+	}
+	for _, want := range tests {
+		b, err := httpkit.MarshalCBOR(want)
+		if err != nil {
+			t.Fatalf("unexpected error of %T: %v", want, err)
+		}
+		got := want.ProtoReflect().New().Interface()
+		if err := httpkit.UnmarshalCBOR(b, got); err != nil {
+			t.Fatalf("unexpected error of %T: %v", want, err)
+		}
+		if !proto.Equal(got, want) {
+			t.Errorf("unexpected message:\n- want: %v\n-  got: %v", want, got)
+		}
+	}
+}
+
+func TestNegotiateCBOR(t *testing.T) {
+	body, _ := httpkit.MarshalCBOR(&errdetails.ErrorInfo{Reason: "cbor"})
+	r := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(body))
+	r.Header.Set("Content-Type", "application/cbor")
+
+	req, err := httpkit.DecodeNegotiatedRequest[*errdetails.ErrorInfo]()(context.Background(), r)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	ctx := context.WithValue(context.Background(), request.ContextKey("accept"), "application/cbor, application/json;q=0.5")
+	w := httptest.NewRecorder()
+	httpkit.EncodeNegotiatedResponse(ctx, w, req)
+
+	if got := w.Header().Get("Content-Type"); got != httpkit.CBORContentType {
+		t.Errorf("unexpected Content-Type header:\n- want: %v\n-  got: %v", httpkit.CBORContentType, got)
+	}
+	if got := w.Body.Bytes(); !bytes.Equal(got, body) {
+		t.Errorf("unexpected body:\n- want: %x\n-  got: %x", body, got)
+	}
+}
@@ -0,0 +1,219 @@
+package httpkit
+
+import (
+	"bytes"
+	"io"
+	"math/rand"
+	"mime"
+	"net/http"
+	"strconv"
+	"time"
+
+	gerrdetails "google.golang.org/genproto/googleapis/rpc/errdetails"
+	spb "google.golang.org/genproto/googleapis/rpc/status"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+// ClientOption sets an optional parameter for the Client.
+type ClientOption func(*Client)
+
+// ClientHTTPClient sets the HTTP client that sends the requests.
+// http.DefaultClient is used by default.
+func ClientHTTPClient(client *http.Client) ClientOption {
+	return func(c *Client) { c.client = client }
+}
+
+// ClientMaxAttempts sets the number of attempts of a request, including the
+// first one. It's 3 by default.
+func ClientMaxAttempts(n int) ClientOption {
+	return func(c *Client) { c.maxAttempts = n }
+}
+
+// ClientBackoff sets the base and the maximum delay of the exponential
+// backoff between the attempts. They are 100ms and 10s by default.
+func ClientBackoff(base, max time.Duration) ClientOption {
+	return func(c *Client) { c.baseDelay, c.maxDelay = base, max }
+}
+
+// ClientRetryBudget limits the total time that a request can spend waiting
+// between its attempts. The request is not retried when the delay would
+// exceed the budget, e.g. because the server asks the client to come back
+// later. It's 30 seconds by default.
+func ClientRetryBudget(d time.Duration) ClientOption {
+	return func(c *Client) { c.budget = d }
+}
+
+// Client is an HTTP client that retries the idempotent requests when the
+// servers are overloaded or unavailable. It implements the HTTPClient
+// interface of transport/http, so it's installed into the go-kit clients
+// with httptransport.SetClient.
+type Client struct {
+	client      *http.Client
+	maxAttempts int
+	baseDelay   time.Duration
+	maxDelay    time.Duration
+	budget      time.Duration
+	hedger      *hedger
+}
+
+// NewClient creates a new Client.
+func NewClient(options ...ClientOption) *Client {
+	c := &Client{
+		client:      http.DefaultClient,
+		maxAttempts: 3,
+		baseDelay:   100 * time.Millisecond,
+		maxDelay:    10 * time.Second,
+		budget:      30 * time.Second,
+	}
+	for _, option := range options {
+		option(c)
+	}
+	return c
+}
+
+// Do sends the request and retries it on 429 Too Many Requests, 503
+// Service Unavailable and 504 Gateway Timeout responses, and on the
+// ResourceExhausted, Unavailable and DeadlineExceeded status errors of the
+// transport, e.g. of CircuitBreakerTransport. Only the requests with
+// idempotent methods or with an Idempotency-Key header are retried, and
+// only when their body can be sent again through GetBody.
+//
+// The delay between the attempts is the one requested by the server with
+// the Retry-After header or the RetryInfo details of the error, or else an
+// exponential backoff with full jitter. The last response or error is
+// returned when the attempts or the retry budget are exhausted.
+func (c *Client) Do(r *http.Request) (*http.Response, error) {
+	retryable := isIdempotent(r) && (r.Body == nil || r.Body == http.NoBody || r.GetBody != nil)
+	var waited time.Duration
+	for attempt := 1; ; attempt++ {
+		resp, err := c.send(r)
+		if !retryable || attempt >= c.maxAttempts {
+			return resp, err
+		}
+		delay, retry := c.retryDelay(attempt, resp, err)
+		if !retry || waited+delay > c.budget {
+			return resp, err
+		}
+		if resp != nil {
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-r.Context().Done():
+			timer.Stop()
+			return nil, r.Context().Err()
+		case <-timer.C:
+		}
+		waited += delay
+		if r.GetBody != nil {
+			body, err := r.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			r.Body = body
+		}
+	}
+}
+
+// retryDelay returns the delay before the next attempt and whether the
+// outcome of the attempt should be retried.
+func (c *Client) retryDelay(attempt int, resp *http.Response, err error) (time.Duration, bool) {
+	if err != nil {
+		st, ok := status.FromError(err)
+		if !ok {
+			return 0, false
+		}
+		switch st.Code() {
+		case codes.ResourceExhausted, codes.Unavailable, codes.DeadlineExceeded:
+		default:
+			return 0, false
+		}
+		if delay, ok := retryInfoDelay(st.Details()); ok {
+			return delay, true
+		}
+		return c.backoff(attempt), true
+	}
+
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+	default:
+		return 0, false
+	}
+	if delay, ok := retryAfter(resp.Header.Get("Retry-After")); ok {
+		return delay, true
+	}
+	if delay, ok := retryInfoDelay(responseDetails(resp)); ok {
+		return delay, true
+	}
+	return c.backoff(attempt), true
+}
+
+// backoff returns the exponential backoff of the attempt with full jitter.
+func (c *Client) backoff(attempt int) time.Duration {
+	delay := c.maxDelay
+	if shift := attempt - 1; shift < 32 && c.baseDelay<<shift < c.maxDelay {
+		delay = c.baseDelay << shift
+	}
+	return time.Duration(rand.Int63n(int64(delay) + 1))
+}
+
+// isIdempotent reports whether the request can be sent more than once.
+func isIdempotent(r *http.Request) bool {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return r.Header.Get("Idempotency-Key") != ""
+}
+
+// retryAfter parses the Retry-After header, which is either a number of
+// seconds or an HTTP date.
+func retryAfter(header string) (time.Duration, bool) {
+	if header == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(header); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if t, err := http.ParseTime(header); err == nil {
+		if d := time.Until(t); d > 0 {
+			return d, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
+
+// retryInfoDelay returns the delay of the RetryInfo details.
+func retryInfoDelay(details []interface{}) (time.Duration, bool) {
+	for _, detail := range details {
+		if info, ok := detail.(*gerrdetails.RetryInfo); ok && info.RetryDelay != nil {
+			return info.RetryDelay.AsDuration(), true
+		}
+	}
+	return 0, false
+}
+
+// responseDetails returns the details of the binary google.rpc.Status body
+// of the response. The body is restored, so it can be read by the caller.
+func responseDetails(resp *http.Response) []interface{} {
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if c, ok := codecOf(mediaType); !ok || c.contentTypes[0] != ProtobufContentType {
+		return nil
+	}
+	b, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	resp.Body = io.NopCloser(bytes.NewReader(b))
+	if err != nil {
+		return nil
+	}
+	st := &spb.Status{}
+	if err := proto.Unmarshal(b, st); err != nil {
+		return nil
+	}
+	return status.FromProto(st).Details()
+}
@@ -0,0 +1,65 @@
+package httpkit_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/clouway/go-genproto/clouwayapis/rpc/httpkit"
+	"github.com/clouway/go-genproto/clouwayapis/rpc/request"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestClientRetries(t *testing.T) {
+	tests := []struct {
+		name     string
+		method   string
+		failures []error
+		options  []httpkit.ClientOption
+		status   int
+		attempts int
+	}{
+		{name: "unavailable", method: http.MethodGet, failures: []error{status.Error(codes.Unavailable, "unavailable")}, status: http.StatusOK, attempts: 2},
+		{name: "rate limited with retry info", method: http.MethodPut, failures: []error{httpkit.NewRateLimitError(10, 0, time.Second)}, options: []httpkit.ClientOption{httpkit.ClientRetryBudget(2 * time.Second)}, status: http.StatusOK, attempts: 2},
+		{name: "over budget", method: http.MethodGet, failures: []error{httpkit.NewRateLimitError(10, 0, time.Minute)}, status: http.StatusTooManyRequests, attempts: 1},
+		{name: "not idempotent", method: http.MethodPost, failures: []error{status.Error(codes.Unavailable, "unavailable")}, status: http.StatusServiceUnavailable, attempts: 1},
+		{name: "not retryable", method: http.MethodGet, failures: []error{status.Error(codes.Internal, "internal")}, status: http.StatusInternalServerError, attempts: 1},
+		{name: "attempts exhausted", method: http.MethodGet, failures: []error{status.Error(codes.DeadlineExceeded, "timeout"), status.Error(codes.DeadlineExceeded, "timeout"), status.Error(codes.DeadlineExceeded, "timeout")}, status: http.StatusGatewayTimeout, attempts: 3},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			attempts := 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				attempts++
+				if body, _ := io.ReadAll(r.Body); r.Method == http.MethodPut && string(body) != "payload" {
+					t.Errorf("unexpected body of attempt %d: %q", attempts, body)
+				}
+				if attempts <= len(test.failures) {
+					ctx := context.WithValue(r.Context(), request.ContextKey("accept"), httpkit.ProtobufContentType)
+					httpkit.ErrorEncoder(ctx, test.failures[attempts-1], w)
+				}
+			}))
+			defer server.Close()
+			client := httpkit.NewClient(append([]httpkit.ClientOption{httpkit.ClientBackoff(time.Millisecond, 10*time.Millisecond)}, test.options...)...)
+
+			r, _ := http.NewRequest(test.method, server.URL, strings.NewReader("payload"))
+			resp, err := client.Do(r)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			resp.Body.Close()
+
+			if resp.StatusCode != test.status {
+				t.Errorf("unexpected status code:\n- want: %v\n-  got: %v", test.status, resp.StatusCode)
+			}
+			if attempts != test.attempts {
+				t.Errorf("unexpected attempts:\n- want: %v\n-  got: %v", test.attempts, attempts)
+			}
+		})
+	}
+}
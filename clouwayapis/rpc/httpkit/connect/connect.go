@@ -0,0 +1,198 @@
+// Package connect serves go-kit endpoints over the unary Connect protocol,
+// so the clients generated by connect-go can call the services that are
+// already exposed over the REST transports of httpkit. The handlers are
+// mounted on the paths of the procedures next to the existing routes:
+//
+//	router.Handle("/clouway.orders.v1.Orders/GetOrder", connect.NewUnaryHandler[*orderspb.GetOrderRequest](endpoints.GetOrder))
+//
+// The errors are answered with the HTTP status codes of httpkit and with the
+// error body of the protocol, which carries the details of the status.
+package connect
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/clouway/go-genproto/clouwayapis/rpc/httpkit"
+	"github.com/go-kit/kit/endpoint"
+	httptransport "github.com/go-kit/kit/transport/http"
+	rpccode "google.golang.org/genproto/googleapis/rpc/code"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	// ProtoContentType is the content type of the binary unary requests.
+	ProtoContentType = "application/proto"
+	// JSONContentType is the content type of the JSON unary requests.
+	JSONContentType = "application/json"
+)
+
+// Option sets an optional parameter for the handlers.
+type Option func(*options)
+
+// ServerBefore adds functions that are executed on the request before it is
+// decoded, e.g. httpkit.HeadersToContext.
+func ServerBefore(before ...httptransport.RequestFunc) Option {
+	return func(o *options) { o.before = append(o.before, before...) }
+}
+
+// MaxBytes limits the size of the request messages. The limit is 4MB by
+// default.
+func MaxBytes(limit int64) Option {
+	return func(o *options) { o.maxBytes = limit }
+}
+
+type options struct {
+	before   []httptransport.RequestFunc
+	maxBytes int64
+}
+
+// NewUnaryHandler returns an http.Handler that decodes the request message
+// of type Req, calls the endpoint and writes its proto.Message response.
+// The timeout of the call that is sent in the Connect-Timeout-Ms header is
+// applied to the context of the endpoint.
+func NewUnaryHandler[Req proto.Message](e endpoint.Endpoint, opts ...Option) http.Handler {
+	o := &options{maxBytes: 4 << 20}
+	for _, option := range opts {
+		option(o)
+	}
+	return &unaryHandler[Req]{endpoint: e, options: o}
+}
+
+type unaryHandler[Req proto.Message] struct {
+	endpoint endpoint.Endpoint
+	*options
+}
+
+func (h *unaryHandler[Req]) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeError(w, http.StatusMethodNotAllowed, status.Errorf(codes.Unimplemented, "method %s is not allowed", r.Method))
+		return
+	}
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType != ProtoContentType && mediaType != JSONContentType {
+		w.Header().Set("Accept-Post", ProtoContentType+", "+JSONContentType)
+		w.WriteHeader(http.StatusUnsupportedMediaType)
+		return
+	}
+	if v := r.Header.Get("Connect-Protocol-Version"); v != "" && v != "1" {
+		writeStatusError(w, status.Errorf(codes.InvalidArgument, "unsupported protocol version '%s'", v))
+		return
+	}
+
+	ctx := r.Context()
+	if v := r.Header.Get("Connect-Timeout-Ms"); v != "" {
+		ms, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || ms < 0 {
+			writeStatusError(w, status.Errorf(codes.InvalidArgument, "invalid timeout '%s'", v))
+			return
+		}
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(ms)*time.Millisecond)
+		defer cancel()
+	}
+	for _, f := range h.before {
+		ctx = f(ctx, r)
+	}
+
+	b, err := io.ReadAll(io.LimitReader(r.Body, h.maxBytes+1))
+	if err != nil {
+		writeStatusError(w, status.Error(codes.Unknown, err.Error()))
+		return
+	}
+	if int64(len(b)) > h.maxBytes {
+		writeStatusError(w, status.Errorf(codes.ResourceExhausted, "request message exceeds the limit of %d bytes", h.maxBytes))
+		return
+	}
+	var zero Req
+	req := zero.ProtoReflect().New().Interface().(Req)
+	if mediaType == JSONContentType {
+		err = protojson.UnmarshalOptions{DiscardUnknown: true}.Unmarshal(b, req)
+	} else {
+		err = proto.Unmarshal(b, req)
+	}
+	if err != nil {
+		writeStatusError(w, status.Errorf(codes.InvalidArgument, "invalid request message: %v", err))
+		return
+	}
+
+	resp, err := h.endpoint(ctx, req)
+	if err != nil {
+		writeStatusError(w, err)
+		return
+	}
+	m, ok := resp.(proto.Message)
+	if !ok {
+		writeStatusError(w, status.Errorf(codes.Internal, "unexpected response of type %T", resp))
+		return
+	}
+	if mediaType == JSONContentType {
+		b, err = protojson.Marshal(m)
+	} else {
+		b, err = proto.Marshal(m)
+	}
+	if err != nil {
+		writeStatusError(w, status.Error(codes.Internal, err.Error()))
+		return
+	}
+	w.Header().Set("Content-Type", mediaType)
+	w.Write(b)
+}
+
+// writeStatusError writes the error with the status code of httpkit.
+func writeStatusError(w http.ResponseWriter, err error) {
+	writeError(w, httpkit.HTTPStatusFromCode(status.Code(err)), err)
+}
+
+type errorBody struct {
+	Code    string        `json:"code"`
+	Message string        `json:"message,omitempty"`
+	Details []errorDetail `json:"details,omitempty"`
+}
+
+type errorDetail struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+// writeError writes the error in the format of the Connect protocol. The
+// errors are always encoded as JSON, regardless of the codec of the call.
+func writeError(w http.ResponseWriter, code int, err error) {
+	st := status.Convert(err)
+	body := errorBody{Code: CodeName(st.Code()), Message: st.Message()}
+	for _, detail := range st.Proto().GetDetails() {
+		body.Details = append(body.Details, errorDetail{
+			Type:  strings.TrimPrefix(detail.GetTypeUrl(), "type.googleapis.com/"),
+			Value: base64.RawStdEncoding.EncodeToString(detail.GetValue()),
+		})
+	}
+	b, _ := json.Marshal(body)
+	w.Header().Set("Content-Type", JSONContentType)
+	w.WriteHeader(code)
+	w.Write(b)
+}
+
+// CodeName returns the name of the code in the Connect protocol, e.g.
+// "not_found".
+func CodeName(code codes.Code) string {
+	if code == codes.Canceled {
+		// The protocol uses the American spelling.
+		return "canceled"
+	}
+	name, ok := rpccode.Code_name[int32(code)]
+	if !ok {
+		name = rpccode.Code_UNKNOWN.String()
+	}
+	return strings.ToLower(name)
+}
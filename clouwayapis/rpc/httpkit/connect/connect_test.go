@@ -0,0 +1,106 @@
+package connect_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/clouway/go-genproto/clouwayapis/rpc/errdetails"
+	"github.com/clouway/go-genproto/clouwayapis/rpc/httpkit/connect"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+func greet(ctx context.Context, request interface{}) (interface{}, error) {
+	name := request.(*wrapperspb.StringValue).Value
+	if name == "" {
+		st, _ := status.New(codes.InvalidArgument, "name is required").WithDetails(&errdetails.ErrorInfo{Reason: "NAME_REQUIRED"})
+		return nil, st.Err()
+	}
+	if _, ok := ctx.Deadline(); !ok {
+		return nil, status.Error(codes.FailedPrecondition, "no deadline")
+	}
+	return wrapperspb.String("Hello, " + name), nil
+}
+
+func call(contentType, body string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodPost, "/clouway.greeter.v1.Greeter/Greet", strings.NewReader(body))
+	r.Header.Set("Content-Type", contentType)
+	r.Header.Set("Connect-Protocol-Version", "1")
+	r.Header.Set("Connect-Timeout-Ms", "1000")
+	rec := httptest.NewRecorder()
+	connect.NewUnaryHandler[*wrapperspb.StringValue](greet).ServeHTTP(rec, r)
+	return rec
+}
+
+func TestUnaryHandlerJSON(t *testing.T) {
+	rec := call("application/json", `"John"`)
+
+	if rec.Code != http.StatusOK {
+		t.Errorf("unexpected status code:\n- want: %v\n-  got: %v", http.StatusOK, rec.Code)
+	}
+	if got := rec.Body.String(); got != `"Hello, John"` {
+		t.Errorf("unexpected body:\n- want: %v\n-  got: %v", `"Hello, John"`, got)
+	}
+}
+
+func TestUnaryHandlerProto(t *testing.T) {
+	b, _ := proto.Marshal(wrapperspb.String("John"))
+	rec := call("application/proto", string(b))
+
+	got := &wrapperspb.StringValue{}
+	if err := proto.Unmarshal(rec.Body.Bytes(), got); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got.Value != "Hello, John" {
+		t.Errorf("unexpected response:\n- want: %v\n-  got: %v", "Hello, John", got.Value)
+	}
+	if got := rec.Header().Get("Content-Type"); got != "application/proto" {
+		t.Errorf("unexpected Content-Type header:\n- want: %v\n-  got: %v", "application/proto", got)
+	}
+}
+
+func TestUnaryHandlerError(t *testing.T) {
+	rec := call("application/json", `""`)
+
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("unexpected status code:\n- want: %v\n-  got: %v", http.StatusBadRequest, rec.Code)
+	}
+	var body struct {
+		Code    string
+		Message string
+		Details []struct{ Type, Value string }
+	}
+	b, _ := io.ReadAll(rec.Body)
+	if err := json.Unmarshal(b, &body); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if body.Code != "invalid_argument" || body.Message != "name is required" {
+		t.Errorf("unexpected error: %s", b)
+	}
+	if len(body.Details) != 1 || body.Details[0].Type != string(proto.MessageName(&errdetails.ErrorInfo{})) {
+		t.Errorf("unexpected details: %s", b)
+	}
+}
+
+func TestUnaryHandlerUnsupportedContentType(t *testing.T) {
+	rec := call("text/plain", `John`)
+
+	if rec.Code != http.StatusUnsupportedMediaType {
+		t.Errorf("unexpected status code:\n- want: %v\n-  got: %v", http.StatusUnsupportedMediaType, rec.Code)
+	}
+}
+
+func TestCodeName(t *testing.T) {
+	for code, want := range map[codes.Code]string{codes.Canceled: "canceled", codes.NotFound: "not_found", codes.Code(99): "unknown"} {
+		if got := connect.CodeName(code); got != want {
+			t.Errorf("unexpected name of %v:\n- want: %v\n-  got: %v", code, want, got)
+		}
+	}
+}
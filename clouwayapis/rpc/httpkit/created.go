@@ -0,0 +1,59 @@
+package httpkit
+
+import (
+	"context"
+	"net/http"
+
+	httptransport "github.com/go-kit/kit/transport/http"
+)
+
+// EncodeCreatedResponse returns an encoder that responds with 201 Created
+// and the created resource as body, encoded by EncodeProtoJSONResponse. The
+// Location header points to the resource and is built by the location
+// function from the name of the resource, or from its id for the resources
+// without name. Location is not set for the resources that have neither.
+func EncodeCreatedResponse(location func(name string) string) httptransport.EncodeResponseFunc {
+	return func(ctx context.Context, w http.ResponseWriter, response interface{}) error {
+		if name := resourceName(response); name != "" && !isNil(response) {
+			w.Header().Set("Location", location(name))
+		}
+		return EncodeProtoJSONResponse(ctx, &statusWriter{ResponseWriter: w, code: http.StatusCreated}, response)
+	}
+}
+
+// resourceName returns the name or the id of the resource.
+func resourceName(response interface{}) string {
+	switch r := response.(type) {
+	case interface{ GetName() string }:
+		return r.GetName()
+	case interface{ GetId() string }:
+		return r.GetId()
+	}
+	return ""
+}
+
+// statusWriter replaces the implicit 200 OK status of the wrapped writer
+// with code.
+type statusWriter struct {
+	http.ResponseWriter
+	code        int
+	wroteHeader bool
+}
+
+func (w *statusWriter) WriteHeader(code int) {
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+	if code == http.StatusOK {
+		code = w.code
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *statusWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.ResponseWriter.Write(b)
+}
@@ -0,0 +1,58 @@
+package httpkit_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/clouway/go-genproto/clouwayapis/rpc/httpkit"
+	"google.golang.org/protobuf/types/known/apipb"
+)
+
+func TestEncodeCreatedResponse(t *testing.T) {
+	encode := httpkit.EncodeCreatedResponse(func(name string) string { return "/v1/" + name })
+
+	rec := httptest.NewRecorder()
+	if err := encode(context.Background(), rec, &apipb.Api{Name: "apis/orders"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if rec.Code != http.StatusCreated {
+		t.Errorf("unexpected status code:\n- want: %v\n-  got: %v", http.StatusCreated, rec.Code)
+	}
+	if want, got := "/v1/apis/orders", rec.Header().Get("Location"); want != got {
+		t.Errorf("unexpected Location header:\n- want: %v\n-  got: %v", want, got)
+	}
+	if want, got := httpkit.JSONContentType, rec.Header().Get("Content-Type"); want != got {
+		t.Errorf("unexpected Content-Type header:\n- want: %v\n-  got: %v", want, got)
+	}
+	want := `{"name":"apis/orders","methods":[],"options":[],"version":"","sourceContext":null,"mixins":[],"syntax":"SYNTAX_PROTO2"}`
+	if got := compactJSON(rec.Body.Bytes()); got != want {
+		t.Errorf("unexpected body:\n- want: %v\n-  got: %v", want, got)
+	}
+}
+
+type createdItem struct {
+	ID string `json:"id"`
+}
+
+func (i *createdItem) GetId() string {
+	return i.ID
+}
+
+func TestEncodeCreatedResponseWithID(t *testing.T) {
+	encode := httpkit.EncodeCreatedResponse(func(id string) string { return "/v1/items/" + id })
+
+	rec := httptest.NewRecorder()
+	if err := encode(context.Background(), rec, &createdItem{ID: "42"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if rec.Code != http.StatusCreated {
+		t.Errorf("unexpected status code:\n- want: %v\n-  got: %v", http.StatusCreated, rec.Code)
+	}
+	if want, got := "/v1/items/42", rec.Header().Get("Location"); want != got {
+		t.Errorf("unexpected Location header:\n- want: %v\n-  got: %v", want, got)
+	}
+}
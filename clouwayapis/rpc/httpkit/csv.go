@@ -0,0 +1,255 @@
+package httpkit
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	httptransport "github.com/go-kit/kit/transport/http"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// CSVContentType is the content type of the CSV responses.
+const CSVContentType = "text/csv; charset=utf-8"
+
+// CSVColumn maps a field of the messages to a column of the CSV response.
+type CSVColumn struct {
+	// Header is the name of the column in the header row.
+	Header string
+
+	// Field is the path of the field, e.g. "customer.name". The fields are
+	// matched by their proto or JSON names.
+	Field string
+}
+
+// CSVOption sets an optional parameter for the CSV encoder.
+type CSVOption func(*csvEncoder)
+
+// CSVColumns sets the columns of the CSV response. By default there is a
+// column for every field of the first message, named after the field.
+func CSVColumns(columns ...CSVColumn) CSVOption {
+	return func(e *csvEncoder) { e.columns = columns }
+}
+
+// CSVFilename sets the name of the file that is offered to the browsers in
+// the Content-Disposition header.
+func CSVFilename(name string) CSVOption {
+	return func(e *csvEncoder) { e.filename = name }
+}
+
+// CSVFlushInterval sets how often the rows are flushed to the client. The
+// rows are flushed every second by default.
+func CSVFlushInterval(d time.Duration) CSVOption {
+	return func(e *csvEncoder) { e.flushInterval = d }
+}
+
+// EncodeCSVResponse is a transport/http.EncodeResponseFunc that streams the
+// response as CSV with a column for every field. See NewCSVEncoder.
+func EncodeCSVResponse(ctx context.Context, w http.ResponseWriter, response interface{}) error {
+	return defaultCSVEncoder(ctx, w, response)
+}
+
+var defaultCSVEncoder = NewCSVEncoder()
+
+// NewCSVEncoder returns an EncodeResponseFunc that writes every message of
+// the response as a row of a CSV document, preceded by a header row. The
+// response is either a MessageStream, a <-chan proto.Message or a
+// []proto.Message.
+//
+// The values of the scalar fields are formatted as in their JSON form but
+// without quotes, enums by their names, and the repeated fields are joined
+// by semicolons. The other messages are written as JSON, except for the well
+// known types such as Timestamp that are written in their string form.
+//
+// The errors that occur before the first row are returned to the go-kit
+// transport. The status of the response is already sent when the stream
+// fails later and CSV has no way to signal errors, so the response is
+// aborted with http.ErrAbortHandler to not leave the client with a silently
+// truncated document.
+func NewCSVEncoder(options ...CSVOption) httptransport.EncodeResponseFunc {
+	e := &csvEncoder{flushInterval: time.Second}
+	for _, option := range options {
+		option(e)
+	}
+	return e.encode
+}
+
+type csvEncoder struct {
+	columns       []CSVColumn
+	filename      string
+	flushInterval time.Duration
+}
+
+func (e *csvEncoder) encode(ctx context.Context, w http.ResponseWriter, response interface{}) error {
+	if messages, ok := response.([]proto.Message); ok {
+		ch := make(chan proto.Message, len(messages))
+		for _, m := range messages {
+			ch <- m
+		}
+		close(ch)
+		response = (<-chan proto.Message)(ch)
+	}
+	next, ok := messageSource(ctx, response)
+	if !ok {
+		return fmt.Errorf("unsupported csv response of type %T", response)
+	}
+
+	m, err := next(func() {})
+	if err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+	columns := e.columns
+	if columns == nil && m != nil {
+		columns = defaultCSVColumns(m.ProtoReflect().Descriptor())
+	}
+
+	w.Header().Set("Content-Type", CSVContentType)
+	if e.filename != "" {
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", e.filename))
+	}
+	w.WriteHeader(http.StatusOK)
+
+	cw := csv.NewWriter(w)
+	flusher, _ := w.(http.Flusher)
+	lastFlush := time.Now()
+	flush := func() {
+		cw.Flush()
+		if flusher != nil {
+			flusher.Flush()
+		}
+		lastFlush = time.Now()
+	}
+
+	row := make([]string, len(columns))
+	for i, c := range columns {
+		row[i] = c.Header
+	}
+	cw.Write(row)
+	for m != nil {
+		for i, c := range columns {
+			row[i] = csvField(m.ProtoReflect(), c.Field)
+		}
+		if err := cw.Write(row); err != nil {
+			return nil
+		}
+		if time.Since(lastFlush) >= e.flushInterval {
+			flush()
+		}
+		m, err = next(flush)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			flush()
+			panic(http.ErrAbortHandler)
+		}
+	}
+	flush()
+	return cw.Error()
+}
+
+// defaultCSVColumns returns a column for every field of the message.
+func defaultCSVColumns(md protoreflect.MessageDescriptor) []CSVColumn {
+	fields := md.Fields()
+	columns := make([]CSVColumn, fields.Len())
+	for i := range columns {
+		name := string(fields.Get(i).Name())
+		columns[i] = CSVColumn{Header: name, Field: name}
+	}
+	return columns
+}
+
+// csvField returns the formatted value of the field at the passed path, or
+// an empty string when the field or any of its parents is not set.
+func csvField(m protoreflect.Message, path string) string {
+	names := strings.Split(path, ".")
+	for i, name := range names {
+		fields := m.Descriptor().Fields()
+		fd := fields.ByName(protoreflect.Name(name))
+		if fd == nil {
+			fd = fields.ByJSONName(name)
+		}
+		if fd == nil {
+			return ""
+		}
+		singular := fd.Message() != nil && !fd.IsList() && !fd.IsMap()
+		if singular && !m.Has(fd) {
+			return ""
+		}
+		if i == len(names)-1 {
+			return csvValue(fd, m.Get(fd))
+		}
+		if !singular {
+			return ""
+		}
+		m = m.Get(fd).Message()
+	}
+	return ""
+}
+
+func csvValue(fd protoreflect.FieldDescriptor, v protoreflect.Value) string {
+	switch {
+	case fd.IsList():
+		list := v.List()
+		values := make([]string, list.Len())
+		for i := range values {
+			values[i] = csvScalar(fd, list.Get(i))
+		}
+		return strings.Join(values, ";")
+	case fd.IsMap():
+		var values []string
+		v.Map().Range(func(k protoreflect.MapKey, v protoreflect.Value) bool {
+			values = append(values, k.String()+"="+csvScalar(fd.MapValue(), v))
+			return true
+		})
+		sort.Strings(values)
+		return strings.Join(values, ";")
+	}
+	return csvScalar(fd, v)
+}
+
+func csvScalar(fd protoreflect.FieldDescriptor, v protoreflect.Value) string {
+	switch fd.Kind() {
+	case protoreflect.BoolKind:
+		return strconv.FormatBool(v.Bool())
+	case protoreflect.EnumKind:
+		if ev := fd.Enum().Values().ByNumber(v.Enum()); ev != nil {
+			return string(ev.Name())
+		}
+		return strconv.Itoa(int(v.Enum()))
+	case protoreflect.BytesKind:
+		return base64.StdEncoding.EncodeToString(v.Bytes())
+	case protoreflect.FloatKind:
+		return strconv.FormatFloat(v.Float(), 'g', -1, 32)
+	case protoreflect.DoubleKind:
+		return strconv.FormatFloat(v.Float(), 'g', -1, 64)
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		b, err := protojson.Marshal(v.Message().Interface())
+		if err != nil {
+			return ""
+		}
+		// The well known types such as Timestamp are encoded as JSON strings.
+		var s string
+		if err := json.Unmarshal(b, &s); err == nil {
+			return s
+		}
+		var out bytes.Buffer
+		if err := json.Compact(&out, b); err != nil {
+			return string(b)
+		}
+		return out.String()
+	}
+	return v.String()
+}
@@ -0,0 +1,84 @@
+package httpkit_test
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/clouway/go-genproto/clouwayapis/rpc/errdetails"
+	"github.com/clouway/go-genproto/clouwayapis/rpc/httpkit"
+	"google.golang.org/genproto/googleapis/type/interval"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/apipb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"google.golang.org/protobuf/types/known/typepb"
+)
+
+func TestEncodeCSVResponse(t *testing.T) {
+	messages := []proto.Message{
+		&errdetails.ErrorInfo{Reason: "NOT_FOUND", Domain: "orders, billing", Metadata: map[string]string{"b": "2", "a": "1"}},
+		&errdetails.ErrorInfo{Reason: `say "hi"`},
+	}
+
+	rec := httptest.NewRecorder()
+	if err := httpkit.EncodeCSVResponse(context.Background(), rec, messages); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if got := rec.Header().Get("Content-Type"); got != httpkit.CSVContentType {
+		t.Errorf("unexpected Content-Type header:\n- want: %v\n-  got: %v", httpkit.CSVContentType, got)
+	}
+	want := "reason,domain,metadata\n" +
+		"NOT_FOUND,\"orders, billing\",a=1;b=2\n" +
+		"\"say \"\"hi\"\"\",,\n"
+	if got := rec.Body.String(); got != want {
+		t.Errorf("unexpected body:\n- want: %q\n-  got: %q", want, got)
+	}
+}
+
+func TestEncodeCSVResponseColumns(t *testing.T) {
+	ch := make(chan proto.Message, 1)
+	ch <- &apipb.Api{
+		Name:    "orders",
+		Methods: []*apipb.Method{{Name: "GetOrder"}},
+		Syntax:  typepb.Syntax_SYNTAX_PROTO3,
+		Options: []*typepb.Option{{Name: "created"}},
+	}
+	close(ch)
+
+	encode := httpkit.NewCSVEncoder(
+		httpkit.CSVFilename("apis.csv"),
+		httpkit.CSVColumns(
+			httpkit.CSVColumn{Header: "Name", Field: "name"},
+			httpkit.CSVColumn{Header: "Syntax", Field: "syntax"},
+			httpkit.CSVColumn{Header: "File", Field: "sourceContext.fileName"},
+		),
+	)
+	rec := httptest.NewRecorder()
+	if err := encode(context.Background(), rec, (<-chan proto.Message)(ch)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := "Name,Syntax,File\norders,SYNTAX_PROTO3,\n"
+	if got := rec.Body.String(); got != want {
+		t.Errorf("unexpected body:\n- want: %q\n-  got: %q", want, got)
+	}
+	if got := rec.Header().Get("Content-Disposition"); got != `attachment; filename="apis.csv"` {
+		t.Errorf("unexpected Content-Disposition header:\n- want: %v\n-  got: %v", `attachment; filename="apis.csv"`, got)
+	}
+}
+
+func TestEncodeCSVResponseWellKnownTypes(t *testing.T) {
+	messages := []proto.Message{&interval.Interval{StartTime: timestamppb.New(time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC))}}
+
+	rec := httptest.NewRecorder()
+	if err := httpkit.EncodeCSVResponse(context.Background(), rec, messages); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := "start_time,end_time\n2024-01-02T03:04:05Z,\n"
+	if got := rec.Body.String(); got != want {
+		t.Errorf("unexpected body:\n- want: %q\n-  got: %q", want, got)
+	}
+}
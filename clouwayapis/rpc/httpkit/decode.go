@@ -0,0 +1,196 @@
+package httpkit
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"mime"
+	"net/http"
+	"regexp"
+	"strconv"
+
+	"github.com/clouway/go-genproto/clouwayapis/rpc/errdetails"
+	httptransport "github.com/go-kit/kit/transport/http"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// DecodeOption sets an optional parameter for the request decoders.
+type DecodeOption func(*decodeOptions)
+
+// DecodeDiscardUnknown sets whether the unknown fields of the request are
+// ignored. The requests with unknown fields are rejected by default.
+func DecodeDiscardUnknown(enabled bool) DecodeOption {
+	return func(o *decodeOptions) { o.unmarshaller.DiscardUnknown = enabled }
+}
+
+// DecodeMaxBytes limits the size of the request body. Larger requests are
+// rejected with the error of NewPayloadTooLargeError.
+func DecodeMaxBytes(limit int64) DecodeOption {
+	return func(o *decodeOptions) { o.maxBytes = limit }
+}
+
+// DecodeContentType requires the request to have one of the passed media
+// types as Content-Type, e.g. "application/json".
+func DecodeContentType(mediaTypes ...string) DecodeOption {
+	return func(o *decodeOptions) { o.contentTypes = mediaTypes }
+}
+
+type decodeOptions struct {
+	unmarshaller protojson.UnmarshalOptions
+	maxBytes     int64
+	contentTypes []string
+}
+
+func newDecodeOptions(options ...DecodeOption) *decodeOptions {
+	o := &decodeOptions{}
+	for _, option := range options {
+		option(o)
+	}
+	return o
+}
+
+// DecodeProtoJSONRequest returns a DecodeRequestFunc that decodes the JSON
+// body of the request into a new message of type T. Unlike UnmarshalJSON the
+// unknown fields are rejected unless DecodeDiscardUnknown is used. The
+// decoding failures are returned as InvalidArgument status errors with
+// BadRequest details that point to the invalid field when it is known.
+func DecodeProtoJSONRequest[T proto.Message](options ...DecodeOption) httptransport.DecodeRequestFunc {
+	o := newDecodeOptions(options...)
+	return func(_ context.Context, r *http.Request) (interface{}, error) {
+		var zero T
+		m := zero.ProtoReflect().New().Interface().(T)
+		if err := o.decode(r, m); err != nil {
+			return nil, err
+		}
+		return m, nil
+	}
+}
+
+// DecodeProtoJSONBody decodes the JSON body of the request into the passed
+// message in the same way as DecodeProtoJSONRequest. It's used by the
+// decoders that don't know the type of the request at compile time.
+func DecodeProtoJSONBody(r *http.Request, m proto.Message, options ...DecodeOption) error {
+	return newDecodeOptions(options...).decode(r, m)
+}
+
+// DecodeProtoJSONField decodes the JSON body of the request as the value of
+// the field of the message, e.g. a string, an array of the repeated field or
+// an object of the map field, with the same options as DecodeProtoJSONBody.
+func DecodeProtoJSONField(r *http.Request, m proto.Message, fd protoreflect.FieldDescriptor, options ...DecodeOption) error {
+	o := newDecodeOptions(options...)
+	b, err := o.checkAndReadBody(r)
+	if err != nil || len(b) == 0 {
+		return err
+	}
+	// The value is unmarshaled as the only field of a JSON object, so it's
+	// decoded by the same rules as in the message.
+	b = append(append([]byte(`{"`+fd.JSONName()+`":`), b...), '}')
+	msg := m.ProtoReflect()
+	v := msg.New()
+	if err := o.unmarshaller.Unmarshal(b, v.Interface()); err != nil {
+		return newDecodeError(b, err)
+	}
+	if v.Has(fd) {
+		msg.Set(fd, v.Get(fd))
+	}
+	return nil
+}
+
+// decode checks the request against the options and unmarshals its body
+// into m.
+func (o *decodeOptions) decode(r *http.Request, m proto.Message) error {
+	b, err := o.checkAndReadBody(r)
+	if err != nil || len(b) == 0 {
+		return err
+	}
+	if err := o.unmarshaller.Unmarshal(b, m); err != nil {
+		return newDecodeError(b, err)
+	}
+	return nil
+}
+
+// checkAndReadBody checks the content type of the request and reads its
+// body.
+func (o *decodeOptions) checkAndReadBody(r *http.Request) ([]byte, error) {
+	if len(o.contentTypes) > 0 {
+		mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		if !contains(o.contentTypes, mediaType) {
+			return nil, NewBadRequestError("unsupported content type '%s'", r.Header.Get("Content-Type"))
+		}
+	}
+	return o.readBody(r)
+}
+
+// readBody reads the body of the request up to the configured limit.
+func (o *decodeOptions) readBody(r *http.Request) ([]byte, error) {
+	body := io.Reader(r.Body)
+	if o.maxBytes > 0 {
+		if r.ContentLength > o.maxBytes {
+			return nil, NewPayloadTooLargeError(o.maxBytes)
+		}
+		body = io.LimitReader(r.Body, o.maxBytes+1)
+	}
+	b, err := io.ReadAll(body)
+	if err != nil {
+		return nil, err
+	}
+	if o.maxBytes > 0 && int64(len(b)) > o.maxBytes {
+		return nil, NewPayloadTooLargeError(o.maxBytes)
+	}
+	return b, nil
+}
+
+var (
+	fieldPattern    = regexp.MustCompile(`field "?([\w.]+)"?`)
+	positionPattern = regexp.MustCompile(`\(line (\d+):(\d+)\)`)
+	keyPattern      = regexp.MustCompile(`"([\w.]+)"\s*:\s*$`)
+)
+
+// newDecodeError converts the protojson error into an InvalidArgument status
+// error with the field that failed to decode as field violation.
+func newDecodeError(b []byte, err error) error {
+	message := "invalid request body: " + err.Error()
+	st := status.New(codes.InvalidArgument, message)
+	if field := decodeErrorField(b, err); field != "" {
+		st, _ = st.WithDetails(&errdetails.BadRequest{
+			Message: message,
+			Errors:  []*errdetails.BadRequest_FieldViolation{{Field: field, Reason: err.Error()}},
+		})
+	}
+	return st.Err()
+}
+
+// decodeErrorField returns the field of the protojson error. The field is
+// taken from the message when it's named there, or otherwise it's the key
+// of the value at the reported position of the error.
+func decodeErrorField(b []byte, err error) string {
+	if match := fieldPattern.FindStringSubmatch(err.Error()); match != nil {
+		return match[1]
+	}
+	match := positionPattern.FindStringSubmatch(err.Error())
+	if match == nil {
+		return ""
+	}
+	line, _ := strconv.Atoi(match[1])
+	column, _ := strconv.Atoi(match[2])
+	offset := 0
+	for i := 1; i < line; i++ {
+		next := bytes.IndexByte(b[offset:], '\n')
+		if next < 0 {
+			return ""
+		}
+		offset += next + 1
+	}
+	offset += column - 1
+	if offset < 0 || offset > len(b) {
+		return ""
+	}
+	if key := keyPattern.FindSubmatch(b[:offset]); key != nil {
+		return string(key[1])
+	}
+	return ""
+}
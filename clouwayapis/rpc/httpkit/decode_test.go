@@ -0,0 +1,68 @@
+package httpkit_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/clouway/go-genproto/clouwayapis/rpc/errdetails"
+	"github.com/clouway/go-genproto/clouwayapis/rpc/httpkit"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestDecodeProtoJSONRequest(t *testing.T) {
+	decode := httpkit.DecodeProtoJSONRequest[*errdetails.ErrorInfo](httpkit.DecodeContentType("application/json"))
+
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"reason":"INVALID","domain":"clouway.com"}`))
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	got, err := decode(context.Background(), req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if want := "INVALID"; got.(*errdetails.ErrorInfo).Reason != want {
+		t.Errorf("unexpected reason:\n- want: %v\n-  got: %v", want, got.(*errdetails.ErrorInfo).Reason)
+	}
+}
+
+func TestDecodeProtoJSONRequestFailures(t *testing.T) {
+	tests := []struct {
+		name        string
+		options     []httpkit.DecodeOption
+		contentType string
+		body        string
+		field       string
+		status      int
+	}{
+		{name: "unknown field", body: `{"reason":"INVALID","unknown":1}`, field: "unknown", status: http.StatusBadRequest},
+		{name: "invalid value", body: `{"reason":1}`, field: "reason", status: http.StatusBadRequest},
+		{name: "content type", options: []httpkit.DecodeOption{httpkit.DecodeContentType("application/json")}, contentType: "text/plain", body: `{}`, status: http.StatusBadRequest},
+		{name: "max bytes", options: []httpkit.DecodeOption{httpkit.DecodeMaxBytes(4)}, body: `{"reason":"INVALID"}`, status: http.StatusRequestEntityTooLarge},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(test.body))
+			req.Header.Set("Content-Type", test.contentType)
+			_, err := httpkit.DecodeProtoJSONRequest[*errdetails.ErrorInfo](test.options...)(context.Background(), req)
+
+			rec := httptest.NewRecorder()
+			httpkit.ErrorEncoder(context.Background(), err, rec)
+			if rec.Code != test.status {
+				t.Errorf("unexpected status code:\n- want: %v\n-  got: %v", test.status, rec.Code)
+			}
+			if test.field == "" {
+				return
+			}
+			st, _ := status.FromError(err)
+			if st.Code() != codes.InvalidArgument {
+				t.Errorf("unexpected code:\n- want: %v\n-  got: %v", codes.InvalidArgument, st.Code())
+			}
+			if got := st.Details()[0].(*errdetails.BadRequest).Errors[0].Field; got != test.field {
+				t.Errorf("unexpected field:\n- want: %v\n-  got: %v", test.field, got)
+			}
+		})
+	}
+}
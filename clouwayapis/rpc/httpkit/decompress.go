@@ -0,0 +1,84 @@
+package httpkit
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"strings"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Decompress is an HTTP middleware that transparently decompresses the
+// request bodies with Content-Encoding gzip before the decoders run. The
+// size of the decompressed body is limited to the passed number of bytes to
+// defend against zip bombs, and reading beyond it fails with the error of
+// NewPayloadTooLargeError.
+//
+// The bodies that are not valid gzip streams are rejected with an
+// InvalidArgument status error, which is also returned by Read when the
+// stream turns out to be corrupted later. The requests with other encodings
+// are rejected with 415 Unsupported Media Type and Accept-Encoding: gzip.
+func Decompress(limit int64) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch encoding := strings.ToLower(strings.TrimSpace(r.Header.Get("Content-Encoding"))); encoding {
+			case "", "identity":
+				next.ServeHTTP(w, r)
+				return
+			case "gzip", "x-gzip":
+			default:
+				ErrorEncoder(r.Context(), NewHttpError(
+					http.StatusUnsupportedMediaType,
+					errorWrapper{Message: "unsupported content encoding '" + encoding + "'"},
+					map[string][]string{"Accept-Encoding": {"gzip"}},
+				), w)
+				return
+			}
+
+			zr, err := gzip.NewReader(r.Body)
+			if err != nil {
+				ErrorEncoder(r.Context(), status.Errorf(codes.InvalidArgument, "invalid gzip request body: %v", err), w)
+				return
+			}
+			r.Body = &gzipBody{zr: zr, body: r.Body, limit: limit}
+			r.Header.Del("Content-Encoding")
+			r.Header.Del("Content-Length")
+			r.ContentLength = -1
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+type gzipBody struct {
+	zr    *gzip.Reader
+	body  io.ReadCloser
+	limit int64
+	read  int64
+}
+
+func (b *gzipBody) Read(p []byte) (int, error) {
+	if b.read >= b.limit {
+		// Check if there is anything beyond the limit before failing.
+		var one [1]byte
+		if n, _ := b.zr.Read(one[:]); n > 0 {
+			return 0, NewPayloadTooLargeError(b.limit)
+		}
+		return 0, io.EOF
+	}
+	if int64(len(p)) > b.limit-b.read {
+		p = p[:b.limit-b.read]
+	}
+	n, err := b.zr.Read(p)
+	b.read += int64(n)
+	if err != nil && err != io.EOF {
+		return n, status.Errorf(codes.InvalidArgument, "invalid gzip request body: %v", err)
+	}
+	return n, err
+}
+
+func (b *gzipBody) Close() error {
+	b.zr.Close()
+	return b.body.Close()
+}
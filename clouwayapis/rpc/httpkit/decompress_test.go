@@ -0,0 +1,64 @@
+package httpkit_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/clouway/go-genproto/clouwayapis/rpc/httpkit"
+)
+
+func gzipped(s string) []byte {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	zw.Write([]byte(s))
+	zw.Close()
+	return buf.Bytes()
+}
+
+func TestDecompress(t *testing.T) {
+	handler := httpkit.Decompress(8)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, err := io.ReadAll(r.Body)
+		if err != nil {
+			httpkit.ErrorEncoder(context.Background(), err, w)
+			return
+		}
+		w.Write(b)
+	}))
+
+	tests := []struct {
+		name     string
+		encoding string
+		body     []byte
+		status   int
+		response string
+	}{
+		{name: "gzip", encoding: "gzip", body: gzipped("12345678"), status: http.StatusOK, response: "12345678"},
+		{name: "identity", encoding: "", body: []byte("plain"), status: http.StatusOK, response: "plain"},
+		{name: "over the limit", encoding: "gzip", body: gzipped(strings.Repeat("0", 1<<20)), status: http.StatusRequestEntityTooLarge, response: `{"message":"request body exceeds the limit of 8 bytes"}`},
+		{name: "invalid gzip", encoding: "gzip", body: []byte("plain"), status: http.StatusBadRequest, response: `{"message":"invalid gzip request body: unexpected EOF"}`},
+		{name: "unsupported encoding", encoding: "br", body: []byte("plain"), status: http.StatusUnsupportedMediaType, response: `{"message":"unsupported content encoding 'br'"}`},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(test.body))
+			if test.encoding != "" {
+				req.Header.Set("Content-Encoding", test.encoding)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != test.status {
+				t.Errorf("unexpected status code:\n- want: %v\n-  got: %v", test.status, rec.Code)
+			}
+			if got := rec.Body.String(); got != test.response {
+				t.Errorf("unexpected body:\n- want: %v\n-  got: %v", test.response, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,145 @@
+package httpkit
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/clouway/go-genproto/clouwayapis/rpc/fileserve"
+	"github.com/clouway/go-genproto/clouwayapis/rpc/request"
+	httptransport "github.com/go-kit/kit/transport/http"
+)
+
+// DownloadOption sets an optional parameter for the download encoders.
+type DownloadOption func(*downloadOptions)
+
+// DownloadInline sets the media types of the files that are displayed by
+// the browsers rather than saved, such as "application/pdf" or "image/*".
+// Only PDF documents are displayed by default.
+func DownloadInline(mediaTypes ...string) DownloadOption {
+	return func(o *downloadOptions) { o.inline = mediaTypes }
+}
+
+type downloadOptions struct {
+	inline []string
+}
+
+// EncodeBinaryFileResponse is a transport/http.EncodeResponseFunc that
+// writes *fileserve.BinaryFile responses as downloads with the encoder of
+// NewBinaryFileEncoder with its default options.
+func EncodeBinaryFileResponse(ctx context.Context, w http.ResponseWriter, response interface{}) error {
+	return defaultBinaryFileEncoder(ctx, w, response)
+}
+
+var defaultBinaryFileEncoder = NewBinaryFileEncoder()
+
+// NewBinaryFileEncoder returns an EncodeResponseFunc that writes the
+// *fileserve.BinaryFile responses as downloads: the content with its
+// Content-Type and Content-Length, and a Content-Disposition that displays
+// the files of the inline media types and saves all other files with their
+// name, encoded as described in ContentDisposition. All other responses are
+// encoded by EncodeProtoJSONResponse.
+//
+// The content is served by http.ServeContent with the ETag of the file, so
+// the Range requests are answered with 206 Partial Content, including the
+// multi-range multipart/byteranges responses, and the If-Range and the
+// other conditional requests are honored. The headers of the request are
+// read from the context, where they are stored by HeadersToContext.
+func NewBinaryFileEncoder(options ...DownloadOption) httptransport.EncodeResponseFunc {
+	o := &downloadOptions{inline: []string{"application/pdf"}}
+	for _, option := range options {
+		option(o)
+	}
+	return func(ctx context.Context, w http.ResponseWriter, response interface{}) error {
+		f, ok := response.(*fileserve.BinaryFile)
+		if !ok {
+			return EncodeProtoJSONResponse(ctx, w, response)
+		}
+		contentType := f.ContentType
+		if contentType == "" {
+			contentType = SniffContentType(f.FileName, f.Content)
+		}
+		disposition := "attachment"
+		if acceptsFileType(o.inline, contentType) {
+			disposition = "inline"
+		}
+
+		etag, err := ETag(f)
+		if err != nil {
+			return err
+		}
+
+		w.Header().Set("Content-Type", contentType)
+		w.Header().Set("Content-Disposition", ContentDisposition(disposition, f.FileName))
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		w.Header().Set("ETag", etag)
+		http.ServeContent(w, requestFromContext(ctx), "", time.Time{}, bytes.NewReader(f.Content))
+		return nil
+	}
+}
+
+// rangeHeaders are the headers of the requests that are handled by
+// http.ServeContent.
+var rangeHeaders = []string{"Range", "If-Range", "If-Match", "If-None-Match", "If-Modified-Since", "If-Unmodified-Since"}
+
+// requestFromContext returns a request with the method and the
+// conditional and range headers that are stored in the context by
+// HeadersToContext and httptransport.PopulateRequestContext, for the
+// encoders that serve the responses by http.ServeContent.
+func requestFromContext(ctx context.Context) *http.Request {
+	r := &http.Request{Method: http.MethodGet, Header: http.Header{}}
+	if method, ok := ctx.Value(httptransport.ContextKeyRequestMethod).(string); ok && method != "" {
+		r.Method = method
+	}
+	for _, h := range rangeHeaders {
+		if v, ok := ctx.Value(request.ContextKey(strings.ToLower(h))).(string); ok && v != "" {
+			r.Header.Set(h, v)
+		}
+	}
+	return r.WithContext(ctx)
+}
+
+// ContentDisposition returns the Content-Disposition header of the file
+// name. The name is set as quoted filename, with the characters that are
+// not printable ASCII replaced by underscores, and as UTF-8 filename* as
+// RFC 6266 and RFC 5987 describe, so all browsers get a usable name:
+//
+//	attachment; filename="_____.pdf"; filename*=UTF-8''%D1%84%D0%B0%D0%BA%D1%82.pdf
+func ContentDisposition(disposition, fileName string) string {
+	if fileName == "" {
+		return disposition
+	}
+	var fallback, encoded strings.Builder
+	for _, r := range fileName {
+		if r == '"' || r == '\\' || r < 0x20 || r > 0x7e {
+			fallback.WriteByte('_')
+		} else {
+			fallback.WriteRune(r)
+		}
+	}
+	for _, b := range []byte(fileName) {
+		if isAttrChar(b) {
+			encoded.WriteByte(b)
+		} else {
+			fmt.Fprintf(&encoded, "%%%02X", b)
+		}
+	}
+	header := disposition + `; filename="` + fallback.String() + `"`
+	if fallback.String() != fileName {
+		header += "; filename*=UTF-8''" + encoded.String()
+	}
+	return header
+}
+
+// isAttrChar reports whether the byte can be left unencoded in the
+// extended parameter values of RFC 5987.
+func isAttrChar(b byte) bool {
+	switch {
+	case 'a' <= b && b <= 'z', 'A' <= b && b <= 'Z', '0' <= b && b <= '9':
+		return true
+	}
+	return strings.IndexByte("!#$&+-.^_`|~", b) >= 0
+}
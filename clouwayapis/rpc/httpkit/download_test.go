@@ -0,0 +1,117 @@
+package httpkit_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/clouway/go-genproto/clouwayapis/rpc/fileserve"
+	"github.com/clouway/go-genproto/clouwayapis/rpc/httpkit"
+	"github.com/clouway/go-genproto/clouwayapis/rpc/request"
+)
+
+func TestEncodeBinaryFileResponse(t *testing.T) {
+	tests := []struct {
+		name        string
+		file        *fileserve.BinaryFile
+		contentType string
+		disposition string
+	}{
+		{
+			name:        "attachment",
+			file:        &fileserve.BinaryFile{ContentType: "text/csv", FileName: "readings.csv", Content: []byte("1,2,3")},
+			contentType: "text/csv",
+			disposition: `attachment; filename="readings.csv"`,
+		},
+		{
+			name:        "inline",
+			file:        &fileserve.BinaryFile{ContentType: "application/pdf", FileName: "invoice.pdf", Content: []byte("1,2,3")},
+			contentType: "application/pdf",
+			disposition: `inline; filename="invoice.pdf"`,
+		},
+		{
+			name:        "non-ascii name",
+			file:        &fileserve.BinaryFile{ContentType: "application/pdf", FileName: "фактура \"1\".pdf", Content: []byte("1,2,3")},
+			contentType: "application/pdf",
+			disposition: `inline; filename="_______ _1_.pdf"; filename*=UTF-8''%D1%84%D0%B0%D0%BA%D1%82%D1%83%D1%80%D0%B0%20%221%22.pdf`,
+		},
+		{
+			name:        "detected type",
+			file:        &fileserve.BinaryFile{Content: []byte("1,2,3")},
+			contentType: "text/plain; charset=utf-8",
+			disposition: "attachment",
+		},
+		{
+			name:        "detected inline type",
+			file:        &fileserve.BinaryFile{FileName: "invoice.pdf", Content: []byte("1,2,3")},
+			contentType: "application/pdf",
+			disposition: `inline; filename="invoice.pdf"`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			if err := httpkit.EncodeBinaryFileResponse(context.Background(), w, test.file); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if got := w.Header().Get("Content-Type"); got != test.contentType {
+				t.Errorf("unexpected Content-Type header:\n- want: %v\n-  got: %v", test.contentType, got)
+			}
+			if got := w.Header().Get("Content-Disposition"); got != test.disposition {
+				t.Errorf("unexpected Content-Disposition header:\n- want: %v\n-  got: %v", test.disposition, got)
+			}
+			if got := w.Header().Get("Content-Length"); got != "5" {
+				t.Errorf("unexpected Content-Length header:\n- want: %v\n-  got: %v", 5, got)
+			}
+			if got := w.Body.String(); got != "1,2,3" {
+				t.Errorf("unexpected body:\n- want: %v\n-  got: %v", "1,2,3", got)
+			}
+		})
+	}
+}
+
+func TestEncodeBinaryFileResponseRanges(t *testing.T) {
+	file := &fileserve.BinaryFile{ContentType: "application/octet-stream", FileName: "firmware.bin", Content: []byte("0123456789")}
+	etag, _ := httpkit.ETag(file)
+
+	tests := []struct {
+		name        string
+		headers     map[string]string
+		status      int
+		body        string
+		contentType string
+	}{
+		{name: "full", status: http.StatusOK, body: "0123456789"},
+		{name: "range", headers: map[string]string{"range": "bytes=2-5"}, status: http.StatusPartialContent, body: "2345"},
+		{name: "suffix range", headers: map[string]string{"range": "bytes=-3"}, status: http.StatusPartialContent, body: "789"},
+		{name: "matching if-range", headers: map[string]string{"range": "bytes=2-5", "if-range": etag}, status: http.StatusPartialContent, body: "2345"},
+		{name: "stale if-range", headers: map[string]string{"range": "bytes=2-5", "if-range": `"stale"`}, status: http.StatusOK, body: "0123456789"},
+		{name: "unsatisfiable", headers: map[string]string{"range": "bytes=20-30"}, status: http.StatusRequestedRangeNotSatisfiable},
+		{name: "multiple ranges", headers: map[string]string{"range": "bytes=0-1,8-9"}, status: http.StatusPartialContent, contentType: "multipart/byteranges"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ctx := context.Background()
+			for k, v := range test.headers {
+				ctx = context.WithValue(ctx, request.ContextKey(k), v)
+			}
+			w := httptest.NewRecorder()
+			if err := httpkit.EncodeBinaryFileResponse(ctx, w, file); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if w.Code != test.status {
+				t.Errorf("unexpected status code:\n- want: %v\n-  got: %v", test.status, w.Code)
+			}
+			if test.body != "" && w.Body.String() != test.body {
+				t.Errorf("unexpected body:\n- want: %v\n-  got: %v", test.body, w.Body.String())
+			}
+			if got := w.Header().Get("Content-Type"); test.contentType != "" && !strings.HasPrefix(got, test.contentType) {
+				t.Errorf("unexpected Content-Type header:\n- want: %v\n-  got: %v", test.contentType, got)
+			}
+		})
+	}
+}
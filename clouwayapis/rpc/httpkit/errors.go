@@ -3,20 +3,50 @@ package httpkit
 import (
 	"context"
 	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"math"
 	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
 
+	"github.com/clouway/go-genproto/clouwayapis/rpc/errdetails"
+	"github.com/clouway/go-genproto/clouwayapis/rpc/errreport"
+	"github.com/clouway/go-genproto/clouwayapis/rpc/request"
 	httptransport "github.com/go-kit/kit/transport/http"
 
+	rpccode "google.golang.org/genproto/googleapis/rpc/code"
+	gerrdetails "google.golang.org/genproto/googleapis/rpc/errdetails"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/encoding/protojson"
 	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/durationpb"
 )
 
 // JSONContentType is the default value of JSON messages recognized
 // by the HTTP clients.
 const JSONContentType = "application/json; charset=utf-8"
 
+// ReasonMethodNotAllowed is the ErrorInfo reason of the errors that are
+// encoded with 405 Method Not Allowed status.
+const ReasonMethodNotAllowed = "METHOD_NOT_ALLOWED"
+
+// ReasonPreconditionFailed is the ErrorInfo reason of the errors that are
+// encoded with 412 Precondition Failed status.
+const ReasonPreconditionFailed = "PRECONDITION_FAILED"
+
+// ReasonRateLimitExceeded is the ErrorInfo reason of the errors created by
+// NewRateLimitError.
+const ReasonRateLimitExceeded = "RATE_LIMIT_EXCEEDED"
+
+// XMLContentType is the content type of XML messages used for clients
+// that are not able to handle JSON responses.
+const XMLContentType = "application/xml; charset=utf-8"
+
 // NewBadRequestError is creating a status error for bad request by formatting
 // the passed format and arguments into a messages. The response of this error
 // will be:
@@ -26,6 +56,155 @@ func NewBadRequestError(format string, a ...interface{}) error {
 	return st.Err()
 }
 
+// NewPayloadTooLargeError creates an error for request bodies that exceed
+// the passed limit of bytes. The error is encoded with 413 Request Entity Too
+// Large status and body:
+//
+//	{"message": "request body exceeds the limit of 1024 bytes"}
+func NewPayloadTooLargeError(limit int64) error {
+	message := fmt.Sprintf("request body exceeds the limit of %d bytes", limit)
+	return &payloadTooLargeError{
+		HttpError: NewHttpError(http.StatusRequestEntityTooLarge, errorWrapper{Message: message}, nil),
+		message:   message,
+	}
+}
+
+// payloadTooLargeError is the HttpError of NewPayloadTooLargeError, whose
+// Error carries the message, so it's kept by the XML errors and the logs.
+type payloadTooLargeError struct {
+	*HttpError
+	message string
+}
+
+func (e *payloadTooLargeError) Error() string {
+	return e.message
+}
+
+// NewMethodNotAllowedError creates a status error for requests with method
+// that is not supported by the requested resource. The error carries
+// ErrorInfo with reason METHOD_NOT_ALLOWED and the allowed methods as
+// metadata, and is encoded with 405 Method Not Allowed status.
+func NewMethodNotAllowedError(method string, allowed []string) error {
+	st := status.Newf(codes.Unimplemented, "method %s is not allowed", method)
+	st, _ = st.WithDetails(&errdetails.ErrorInfo{
+		Reason:   ReasonMethodNotAllowed,
+		Metadata: map[string]string{"allow": strings.Join(allowed, ", ")},
+	})
+	return st.Err()
+}
+
+// NewRateLimitError creates a ResourceExhausted status error for clients
+// that exceeded their rate limit. The error carries ErrorInfo with reason
+// RATE_LIMIT_EXCEEDED and the limit, the remaining requests and the seconds
+// until reset as metadata, and RetryInfo with the reset delay. ErrorEncoder
+// emits them as X-RateLimit-Limit, X-RateLimit-Remaining, X-RateLimit-Reset
+// and Retry-After headers.
+func NewRateLimitError(limit, remaining int, reset time.Duration) error {
+	seconds := int64(math.Ceil(reset.Seconds()))
+	st := status.New(codes.ResourceExhausted, "rate limit exceeded")
+	st, _ = st.WithDetails(
+		&errdetails.ErrorInfo{
+			Reason: ReasonRateLimitExceeded,
+			Metadata: map[string]string{
+				"limit":     strconv.Itoa(limit),
+				"remaining": strconv.Itoa(remaining),
+				"reset":     strconv.FormatInt(seconds, 10),
+			},
+		},
+		&gerrdetails.RetryInfo{RetryDelay: durationpb.New(time.Duration(seconds) * time.Second)},
+	)
+	return st.Err()
+}
+
+// ErrorEncoderOption sets an optional parameter for the error encoders.
+type ErrorEncoderOption func(*errorEncoder)
+
+// ErrorEncoderXML renders all errors as XML regardless of the Accept header
+// of the request. The rendered body has the form:
+//
+//	<error><message>not found</message><code>404</code></error>
+func ErrorEncoderXML() ErrorEncoderOption {
+	return func(e *errorEncoder) { e.xml = true }
+}
+
+// ErrorEncoderUseProtoNames sets whether the error details are encoded with
+// the proto field names instead of the lowerCamelCase JSON names. The proto
+// names are used by default.
+func ErrorEncoderUseProtoNames(enabled bool) ErrorEncoderOption {
+	return func(e *errorEncoder) { e.marshaller.UseProtoNames = enabled }
+}
+
+// ErrorEncoderEmitUnpopulated sets whether the unpopulated fields of the
+// error details are emitted with their zero values.
+func ErrorEncoderEmitUnpopulated(enabled bool) ErrorEncoderOption {
+	return func(e *errorEncoder) { e.marshaller.EmitUnpopulated = enabled }
+}
+
+// ErrorEncoderEnumsAsNumbers sets whether the enum values of the error
+// details are encoded as numbers instead of their names.
+func ErrorEncoderEnumsAsNumbers(enabled bool) ErrorEncoderOption {
+	return func(e *errorEncoder) { e.marshaller.UseEnumNumbers = enabled }
+}
+
+// ErrorEncoderReporter sets the reporter that receives the encoded errors
+// with severity at or above the passed one. Errors that implement
+// StatusCoder with status below 500 are never reported.
+func ErrorEncoderReporter(reporter errreport.Reporter, min errreport.Severity) ErrorEncoderOption {
+	return func(e *errorEncoder) {
+		e.reporter = reporter
+		e.reportSeverity = min
+	}
+}
+
+// ErrorEncoderSchema sets the schema of the JSON body of the errors without
+// details. For example the schema ErrorSchema{MessageKey: "error", CodeKey:
+// "code"} renders the errors as:
+//
+//	{"error": "order not found", "code": 404}
+func ErrorEncoderSchema(schema ErrorSchema) ErrorEncoderOption {
+	return func(e *errorEncoder) { e.schema = schema }
+}
+
+// ErrorEncoderStatusName includes the canonical name of the gRPC status code
+// and the numeric HTTP status code in the body of the errors without
+// details, so that clients can branch on a stable value:
+//
+//	{"message": "order not found", "code": 404, "status": "NOT_FOUND"}
+//
+// The keys of the schema set by ErrorEncoderSchema are kept when present.
+func ErrorEncoderStatusName() ErrorEncoderOption {
+	return func(e *errorEncoder) {
+		if e.schema.CodeKey == "" {
+			e.schema.CodeKey = "code"
+		}
+		if e.schema.StatusKey == "" {
+			e.schema.StatusKey = "status"
+		}
+	}
+}
+
+// ErrorEncoderBearerRealm sets the realm of the WWW-Authenticate header that
+// is required by RFC 6750 for the 401 Unauthorized responses:
+//
+//	WWW-Authenticate: Bearer realm="clouway"
+//
+// The header is not changed when the error has set it through Headerer.
+func ErrorEncoderBearerRealm(realm string) ErrorEncoderOption {
+	return func(e *errorEncoder) { e.realm = realm }
+}
+
+// NewErrorEncoder constructs a new error encoder that is configured with the
+// passed options. See ErrorEncoder for details about the encoding rules.
+func NewErrorEncoder(options ...ErrorEncoderOption) httptransport.ErrorEncoder {
+	e := &errorEncoder{marshaller: protojson.MarshalOptions{UseProtoNames: true}}
+	for _, option := range options {
+		option(e)
+	}
+	return e.encode
+}
+
+var defaultErrorEncoder = NewErrorEncoder()
+
 // ErrorEncoder writes the error to the ResponseWriter, by default a content
 // type of application/json, a body of json with key "message" and the value
 // error.Error(), and a status code of 500. If the error implements Headerer,
@@ -34,8 +213,42 @@ func NewBadRequestError(format string, a ...interface{}) error {
 // as json and will be encoded otherwise json.Marshaler, and the marshaling succeeds, the JSON encoded
 // form of the error will be used. If the error implements StatusCoder, the
 // provided StatusCode will be used instead of 500.
-func ErrorEncoder(_ context.Context, err error, w http.ResponseWriter) {
-	w.Header().Set("Content-Type", JSONContentType)
+//
+// The errors are marked as not cacheable by Cache-Control: no-store, unless
+// the error provides its own Cache-Control header as Headerer.
+//
+// When the Accept header of the request (populated in the context by
+// HeadersToContext or by httptransport.PopulateRequestContext) prefers XML
+// over JSON the error is rendered as XML instead. Status errors are written
+// as binary google.rpc.Status when it prefers application/x-protobuf.
+func ErrorEncoder(ctx context.Context, err error, w http.ResponseWriter) {
+	defaultErrorEncoder(ctx, err, w)
+}
+
+type errorEncoder struct {
+	// marshaller is used for encoding of the error details.
+	marshaller protojson.MarshalOptions
+
+	// xml forces XML rendering of the errors.
+	xml bool
+
+	// schema describes the body of the errors without details.
+	schema ErrorSchema
+
+	// realm is the realm of the WWW-Authenticate header of the 401
+	// responses.
+	realm string
+
+	// reporter receives the errors with severity at or above
+	// reportSeverity.
+	reporter       errreport.Reporter
+	reportSeverity errreport.Severity
+}
+
+func (e *errorEncoder) encode(ctx context.Context, err error, w http.ResponseWriter) {
+	// The errors are not cacheable unless the error itself says so.
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Del("Expires")
 	if headerer, ok := err.(httptransport.Headerer); ok {
 		for k := range headerer.Headers() {
 			w.Header().Set(k, headerer.Headers().Get(k))
@@ -45,35 +258,333 @@ func ErrorEncoder(_ context.Context, err error, w http.ResponseWriter) {
 	if sc, ok := err.(httptransport.StatusCoder); ok {
 		code = sc.StatusCode()
 	}
-	var body []byte
-	if e, ok := status.FromError(err); ok {
-		code = httpStatusFromCode(e.Code())
-		if len(e.Details()) > 0 {
-			marshaller := protojson.MarshalOptions{UseProtoNames: true}
-			jsonBody, _ := marshaller.Marshal(e.Details()[0].(proto.Message))
-			body = jsonBody
+	if e.reporter != nil && code >= http.StatusInternalServerError && errreport.Reportable(err, e.reportSeverity) {
+		e.reporter.Report(ctx, err)
+	}
+	if !e.xml {
+		varyAccept(w.Header())
+	}
+	accept := acceptFromContext(ctx)
+	switch {
+	case e.xml || prefersXML(accept):
+		e.encodeXMLError(err, code, w)
+		return
+	case negotiate(accept, "application/json", ProtobufContentType) == ProtobufContentType:
+		if st, ok := status.FromError(err); ok {
+			e.encodeProtobufError(st, w)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", JSONContentType)
+
+	// The body is encoded in a pooled buffer as the encoder is on the hot
+	// path of the gateways that are answering lots of errors.
+	buf := bufferPool.Get().(*[]byte)
+	body := (*buf)[:0]
+	// pooled reports whether the body is appended to the pooled buffer, as
+	// the bodies of the aggregates and the json.Marshaler errors are not.
+	pooled := true
+	if errs, ok := unwrapAggregate(err); ok {
+		code, body = e.encodeAggregate(errs)
+		pooled = false
+	} else if st, ok := status.FromError(err); ok {
+		details := st.Details()
+		code = httpStatusFromStatus(st.Code(), details)
+		setRateLimitHeaders(w.Header(), details)
+		if len(details) > 0 {
+			if m, ok := details[0].(proto.Message); ok {
+				body, _ = e.marshaller.MarshalAppend(body, m)
+			}
 		} else {
-			body, _ = json.Marshal(errorWrapper{Message: e.Message()})
+			body = e.schema.appendError(body, st.Message(), code, st.Code(), request.RequestIDFromContext(ctx))
 		}
 	} else if marshaler, ok := err.(json.Marshaler); ok {
 		body, _ = marshaler.MarshalJSON()
+		pooled = false
 	} else {
-		body, _ = json.Marshal(errorWrapper{Message: err.Error()})
-		if marshaler, ok := err.(json.Marshaler); ok {
-			if jsonBody, marshalErr := marshaler.MarshalJSON(); marshalErr == nil {
-				body = jsonBody
+		body = e.schema.appendError(body, err.Error(), code, codes.Unknown, request.RequestIDFromContext(ctx))
+	}
+
+	e.writeHeader(w, code)
+	w.Write(body)
+
+	if pooled && cap(body) <= maxPooledBufferSize {
+		*buf = body[:0]
+	}
+	bufferPool.Put(buf)
+}
+
+// maxPooledBufferSize limits the size of the buffers that are returned to
+// the pool, so that a single large error does not stay in memory.
+const maxPooledBufferSize = 64 << 10
+
+var bufferPool = sync.Pool{
+	New: func() interface{} {
+		b := make([]byte, 0, 512)
+		return &b
+	},
+}
+
+// writeHeader writes the status code of the error along with the headers
+// that depend on it.
+func (e *errorEncoder) writeHeader(w http.ResponseWriter, code int) {
+	if code == http.StatusUnauthorized && e.realm != "" && w.Header().Get("WWW-Authenticate") == "" {
+		w.Header().Set("WWW-Authenticate", fmt.Sprintf("Bearer realm=%q", e.realm))
+	}
+	w.WriteHeader(code)
+}
+
+// encodeAggregate encodes the constituents of a joined error as entries of
+// the details array. The response status is the status of the constituents
+// when all of them share it, otherwise the most severe status class is used.
+func (e *errorEncoder) encodeAggregate(errs []error) (int, []byte) {
+	wrapper := aggregateErrorWrapper{
+		Message: fmt.Sprintf("%d errors occurred", len(errs)),
+		Details: make([]aggregateErrorEntry, 0, len(errs)),
+	}
+	code := 0
+	for _, err := range errs {
+		entry := aggregateErrorEntry{Code: http.StatusInternalServerError, Message: err.Error()}
+		if st, ok := status.FromError(err); ok {
+			entry.Code = httpStatusFromStatus(st.Code(), st.Details())
+			entry.Message = st.Message()
+		} else if sc, ok := err.(httptransport.StatusCoder); ok {
+			entry.Code = sc.StatusCode()
+		}
+		wrapper.Details = append(wrapper.Details, entry)
+
+		switch {
+		case code == 0 || code == entry.Code:
+			code = entry.Code
+		case code >= 500 || entry.Code >= 500:
+			code = http.StatusInternalServerError
+		default:
+			code = http.StatusBadRequest
+		}
+	}
+	if code == 0 {
+		code = http.StatusInternalServerError
+	}
+	body, _ := json.Marshal(wrapper)
+	return code, body
+}
+
+// unwrapAggregate returns the constituents of errors created by errors.Join
+// or by the hashicorp/go-multierror package.
+func unwrapAggregate(err error) ([]error, bool) {
+	switch e := err.(type) {
+	case interface{ Unwrap() []error }:
+		return e.Unwrap(), true
+	case interface{ WrappedErrors() []error }:
+		return e.WrappedErrors(), true
+	}
+	return nil, false
+}
+
+// encodeProtobufError writes the status as binary google.rpc.Status message
+// for the clients that prefer protobuf.
+func (e *errorEncoder) encodeProtobufError(st *status.Status, w http.ResponseWriter) {
+	details := st.Details()
+	code := httpStatusFromStatus(st.Code(), details)
+	setRateLimitHeaders(w.Header(), details)
+	body, _ := proto.Marshal(st.Proto())
+
+	w.Header().Set("Content-Type", ProtobufContentType)
+	e.writeHeader(w, code)
+	w.Write(body)
+}
+
+// encodeXMLError writes the error as XML document. Errors that implement
+// xml.Marshaler are encoded by their own marshaler.
+func (e *errorEncoder) encodeXMLError(err error, code int, w http.ResponseWriter) {
+	w.Header().Set("Content-Type", XMLContentType)
+	message := err.Error()
+	if st, ok := status.FromError(err); ok {
+		code = httpStatusFromStatus(st.Code(), st.Details())
+		setRateLimitHeaders(w.Header(), st.Details())
+		message = st.Message()
+		for _, detail := range st.Details() {
+			if br, ok := detail.(*errdetails.BadRequest); ok && message == "" {
+				message = br.Message
 			}
 		}
 	}
+	var body []byte
+	if marshaler, ok := err.(xml.Marshaler); ok {
+		body, _ = xml.Marshal(marshaler)
+	} else {
+		body, _ = xml.Marshal(xmlErrorWrapper{Message: message, Code: code})
+	}
 
-	w.WriteHeader(code)
+	e.writeHeader(w, code)
 	w.Write(body)
 }
 
+// ErrorSchema describes the keys of the JSON body of the errors that have
+// no details, which is {"message": "..."} by default.
+type ErrorSchema struct {
+	// MessageKey is the key of the error message. It's "message" when
+	// empty.
+	MessageKey string
+
+	// CodeKey is the key of the numeric HTTP status code. The code is not
+	// included in the body when the key is empty.
+	CodeKey string
+
+	// StatusKey is the key of the canonical name of the gRPC status code,
+	// such as NOT_FOUND. The name is not included in the body when the key
+	// is empty.
+	StatusKey string
+
+	// RequestIDKey is the key of the id of the request, as it's stored in
+	// the context by RequestID. The id is not included in the body when the
+	// key is empty or the context has no id.
+	RequestIDKey string
+}
+
+// MarshalError returns the JSON body of the error in the schema, as it's
+// written by the error encoder for the errors without details. It's used by
+// the streaming encoders that report the errors after the status is sent.
+func (s ErrorSchema) MarshalError(err error) []byte {
+	st, _ := status.FromError(err)
+	return s.appendError(nil, st.Message(), httpStatusFromStatus(st.Code(), nil), st.Code(), "")
+}
+
+// appendError appends the JSON body of the error with the passed message,
+// HTTP status code, gRPC status code and request id to b. The strings are escaped in the
+// same way as json.Marshal does.
+func (s ErrorSchema) appendError(b []byte, message string, code int, grpcCode codes.Code, requestID string) []byte {
+	messageKey := s.MessageKey
+	if messageKey == "" {
+		messageKey = "message"
+	}
+	b = append(b, '{')
+	b = appendJSONString(b, messageKey)
+	b = append(b, ':')
+	b = appendJSONString(b, message)
+	if s.CodeKey != "" {
+		b = append(b, ',')
+		b = appendJSONString(b, s.CodeKey)
+		b = append(b, ':')
+		b = strconv.AppendInt(b, int64(code), 10)
+	}
+	if s.StatusKey != "" {
+		name, ok := rpccode.Code_name[int32(grpcCode)]
+		if !ok {
+			name = rpccode.Code_UNKNOWN.String()
+		}
+		b = append(b, ',')
+		b = appendJSONString(b, s.StatusKey)
+		b = append(b, ':')
+		b = appendJSONString(b, name)
+	}
+	if s.RequestIDKey != "" && requestID != "" {
+		b = append(b, ',')
+		b = appendJSONString(b, s.RequestIDKey)
+		b = append(b, ':')
+		b = appendJSONString(b, requestID)
+	}
+	return append(b, '}')
+}
+
+const hex = "0123456789abcdef"
+
+// appendJSONString appends s as JSON string to b, escaping it in the same
+// way as json.Marshal does, including the HTML characters.
+func appendJSONString(b []byte, s string) []byte {
+	b = append(b, '"')
+	start := 0
+	for i := 0; i < len(s); {
+		if c := s[i]; c < utf8.RuneSelf {
+			if c >= 0x20 && c != '"' && c != '\\' && c != '<' && c != '>' && c != '&' {
+				i++
+				continue
+			}
+			b = append(b, s[start:i]...)
+			switch c {
+			case '"', '\\':
+				b = append(b, '\\', c)
+			case '\n':
+				b = append(b, '\\', 'n')
+			case '\r':
+				b = append(b, '\\', 'r')
+			case '\t':
+				b = append(b, '\\', 't')
+			default:
+				b = append(b, '\\', 'u', '0', '0', hex[c>>4], hex[c&0xF])
+			}
+			i++
+			start = i
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if r == utf8.RuneError && size == 1 {
+			b = append(b, s[start:i]...)
+			b = append(b, "\ufffd"...)
+			i += size
+			start = i
+			continue
+		}
+		if r == '\u2028' || r == '\u2029' {
+			b = append(b, s[start:i]...)
+			b = append(b, '\\', 'u', '2', '0', '2', hex[r&0xF])
+			i += size
+			start = i
+			continue
+		}
+		i += size
+	}
+	b = append(b, s[start:]...)
+	return append(b, '"')
+}
+
 type errorWrapper struct {
 	Message string `json:"message"`
 }
 
+type aggregateErrorWrapper struct {
+	Message string                `json:"message"`
+	Details []aggregateErrorEntry `json:"details"`
+}
+
+type aggregateErrorEntry struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// prefersXML reports whether the Accept header asks for XML errors. XML is
+// selected only when the client ranks an XML type explicitly above JSON or
+// doesn't accept JSON at all, so the browsers, whose Accept headers list
+// application/xml before */*, still get JSON errors.
+func prefersXML(accept string) bool {
+	xmlQ, jsonQ, acceptsJSON := 0.0, -1.0, false
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, q := parseMediaRange(part)
+		switch mediaType {
+		case "application/xml", "text/xml":
+			if q > xmlQ {
+				xmlQ = q
+			}
+		case "application/json":
+			jsonQ = q
+		}
+		if q > 0 && matchMediaRange(mediaType, "application/json") >= 0 {
+			acceptsJSON = true
+		}
+	}
+	if jsonQ >= 0 {
+		return xmlQ > jsonQ
+	}
+	return xmlQ > 0 && !acceptsJSON
+}
+
+type xmlErrorWrapper struct {
+	XMLName xml.Name `xml:"error"`
+	Message string   `xml:"message"`
+	Code    int      `xml:"code"`
+}
+
 // HttpError satisfies the Headerer and StatusCoder interfaces in
 // package github.com/go-kit/kit/transport/http.
 // It's used to return user defined Error objects
@@ -125,9 +636,53 @@ func (h HttpError) MarshalJSON() ([]byte, error) {
 	return json.Marshal(h.payload)
 }
 
-// httpStatusFromCode converts a gRPC error code into the corresponding HTTP response status.
+// setRateLimitHeaders sets the X-RateLimit headers from the rate limit
+// details of the error. A QuotaFailure means that there are no remaining
+// requests, the ErrorInfo of NewRateLimitError carries all of the values and
+// RetryInfo carries the time until reset.
+func setRateLimitHeaders(h http.Header, details []interface{}) {
+	for _, detail := range details {
+		switch d := detail.(type) {
+		case *errdetails.QuotaFailure:
+			h.Set("X-RateLimit-Remaining", "0")
+		case *errdetails.ErrorInfo:
+			if d.Reason != ReasonRateLimitExceeded {
+				continue
+			}
+			for key, header := range map[string]string{"limit": "X-RateLimit-Limit", "remaining": "X-RateLimit-Remaining", "reset": "X-RateLimit-Reset"} {
+				if v, ok := d.Metadata[key]; ok {
+					h.Set(header, v)
+				}
+			}
+		case *gerrdetails.RetryInfo:
+			seconds := strconv.FormatInt(int64(math.Ceil(d.RetryDelay.AsDuration().Seconds())), 10)
+			h.Set("Retry-After", seconds)
+			if h.Get("X-RateLimit-Reset") == "" {
+				h.Set("X-RateLimit-Reset", seconds)
+			}
+		}
+	}
+}
+
+// httpStatusFromStatus returns the HTTP response status of status error with
+// the passed code and details. The ErrorInfo reasons that have a dedicated
+// HTTP status take precedence over the status code.
+func httpStatusFromStatus(code codes.Code, details []interface{}) int {
+	for _, detail := range details {
+		info, ok := detail.(*errdetails.ErrorInfo)
+		switch {
+		case ok && info.Reason == ReasonMethodNotAllowed:
+			return http.StatusMethodNotAllowed
+		case ok && info.Reason == ReasonPreconditionFailed:
+			return http.StatusPreconditionFailed
+		}
+	}
+	return HTTPStatusFromCode(code)
+}
+
+// HTTPStatusFromCode converts a gRPC error code into the corresponding HTTP response status.
 // See: https://github.com/googleapis/googleapis/blob/master/google/rpc/code.proto
-func httpStatusFromCode(code codes.Code) int {
+func HTTPStatusFromCode(code codes.Code) int {
 	switch code {
 	case codes.OK:
 		return http.StatusOK
@@ -1,14 +1,21 @@
 package httpkit_test
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"reflect"
 	"testing"
+	"time"
 
 	"github.com/clouway/go-genproto/clouwayapis/rpc/errdetails"
+	"github.com/clouway/go-genproto/clouwayapis/rpc/errreport"
 	"github.com/clouway/go-genproto/clouwayapis/rpc/httpkit"
+	"github.com/clouway/go-genproto/clouwayapis/rpc/request"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 )
@@ -63,6 +70,24 @@ func TestEncodeJsonError(t *testing.T) {
 	}
 }
 
+// cachedError returns the same body on every MarshalJSON.
+type cachedError []byte
+
+func (e cachedError) Error() string { return string(e) }
+
+func (e cachedError) MarshalJSON() ([]byte, error) { return e, nil }
+
+func TestEncodeJsonErrorKeepsMarshaledBody(t *testing.T) {
+	err := cachedError(append(make([]byte, 0, 64), `{"message":"cached"}`...))
+
+	httpkit.ErrorEncoder(context.Background(), err, httptest.NewRecorder())
+	httpkit.ErrorEncoder(context.Background(), status.Error(codes.NotFound, "not found"), httptest.NewRecorder())
+
+	if want, got := `{"message":"cached"}`, string(err); want != got {
+		t.Errorf("unexpected body of the error:\n- want: %v\n-  got: %v", want, got)
+	}
+}
+
 func TestEncodeProtoError(t *testing.T) {
 	tests := []test{
 		{
@@ -139,3 +164,346 @@ func TestEncodeProtoError(t *testing.T) {
 		})
 	}
 }
+
+func TestEncodeXMLError(t *testing.T) {
+	tests := []struct {
+		name    string
+		accept  string
+		options []httpkit.ErrorEncoderOption
+		want    string
+	}{
+		{
+			name:   "accept xml",
+			accept: "application/xml",
+			want:   `<error><message>not found</message><code>404</code></error>`,
+		},
+		{
+			name:   "prefers xml over json",
+			accept: "application/json;q=0.5, text/xml",
+			want:   `<error><message>not found</message><code>404</code></error>`,
+		},
+		{
+			name:    "xml option",
+			accept:  "",
+			options: []httpkit.ErrorEncoderOption{httpkit.ErrorEncoderXML()},
+			want:    `<error><message>not found</message><code>404</code></error>`,
+		},
+		{
+			name:   "accept any",
+			accept: "*/*",
+			want:   `{"message":"not found"}`,
+		},
+		{
+			name:   "browser",
+			accept: "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8",
+			want:   `{"message":"not found"}`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ctx := context.WithValue(context.Background(), request.ContextKey("accept"), test.accept)
+			rec := httptest.NewRecorder()
+			httpkit.NewErrorEncoder(test.options...)(ctx, status.Error(codes.NotFound, "not found"), rec)
+
+			if rec.Code != http.StatusNotFound {
+				t.Errorf("unexpected status code:\n- want: %v\n-  got: %v", http.StatusNotFound, rec.Code)
+			}
+			if got := rec.Body.String(); got != test.want {
+				t.Errorf("unexpected body:\n- want: %v\n-  got: %v", test.want, got)
+			}
+		})
+	}
+}
+
+func TestEncodeErrorDetailsWithMarshalOptions(t *testing.T) {
+	st, _ := status.New(codes.InvalidArgument, "invalid").WithDetails(&errdetails.ErrorInfo{Reason: "INVALID_NAME"})
+
+	tests := []struct {
+		name    string
+		options []httpkit.ErrorEncoderOption
+		want    map[string]interface{}
+	}{
+		{
+			name: "defaults",
+			want: map[string]interface{}{"reason": "INVALID_NAME"},
+		},
+		{
+			name:    "emit unpopulated",
+			options: []httpkit.ErrorEncoderOption{httpkit.ErrorEncoderEmitUnpopulated(true)},
+			want:    map[string]interface{}{"reason": "INVALID_NAME", "domain": "", "metadata": map[string]interface{}{}},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			httpkit.NewErrorEncoder(test.options...)(context.Background(), st.Err(), rec)
+
+			got := make(map[string]interface{})
+			if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+				t.Fatalf("unexpected error while decoding body: %v", err)
+			}
+			if !reflect.DeepEqual(test.want, got) {
+				t.Errorf("unexpected body:\n- want: %v\n-  got: %v", test.want, got)
+			}
+		})
+	}
+}
+
+func TestEncodeAggregateError(t *testing.T) {
+	tests := []struct {
+		name   string
+		err    error
+		status int
+		body   string
+	}{
+		{
+			name:   "joined errors with the same code",
+			err:    joinedError{status.Error(codes.NotFound, "item 1 not found"), status.Error(codes.NotFound, "item 2 not found")},
+			status: http.StatusNotFound,
+			body:   `{"message":"2 errors occurred","details":[{"code":404,"message":"item 1 not found"},{"code":404,"message":"item 2 not found"}]}`,
+		},
+		{
+			name:   "joined client errors",
+			err:    joinedError{status.Error(codes.NotFound, "item 1 not found"), status.Error(codes.InvalidArgument, "invalid item 2")},
+			status: http.StatusBadRequest,
+			body:   `{"message":"2 errors occurred","details":[{"code":404,"message":"item 1 not found"},{"code":400,"message":"invalid item 2"}]}`,
+		},
+		{
+			name:   "joined client and server errors",
+			err:    joinedError{status.Error(codes.NotFound, "item 1 not found"), errors.New("connection lost")},
+			status: http.StatusInternalServerError,
+			body:   `{"message":"2 errors occurred","details":[{"code":404,"message":"item 1 not found"},{"code":500,"message":"connection lost"}]}`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			httpkit.ErrorEncoder(context.Background(), test.err, rec)
+
+			if rec.Code != test.status {
+				t.Errorf("unexpected status code:\n- want: %v\n-  got: %v", test.status, rec.Code)
+			}
+			if got := rec.Body.String(); got != test.body {
+				t.Errorf("unexpected body:\n- want: %v\n-  got: %v", test.body, got)
+			}
+		})
+	}
+}
+
+// joinedError mimics the errors returned by errors.Join.
+type joinedError []error
+
+func (e joinedError) Error() string {
+	return "joined"
+}
+
+func (e joinedError) Unwrap() []error {
+	return e
+}
+
+// compactJSON removes the insignificant whitespace that protojson randomly
+// adds to its output.
+func compactJSON(b []byte) string {
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, b); err != nil {
+		return string(b)
+	}
+	return buf.String()
+}
+
+func BenchmarkErrorEncoder(b *testing.B) {
+	withDetails, _ := status.New(codes.InvalidArgument, "invalid").WithDetails(&errdetails.BadRequest{
+		Message: "invalid order",
+		Errors:  []*errdetails.BadRequest_FieldViolation{{Reason: "missing", Field: "itemId"}},
+	})
+	benchmarks := []struct {
+		name string
+		err  error
+	}{
+		{name: "not found", err: status.Error(codes.NotFound, "order not found")},
+		{name: "with details", err: withDetails.Err()},
+	}
+	for _, bm := range benchmarks {
+		b.Run(bm.name, func(b *testing.B) {
+			w := &discardResponseWriter{header: make(http.Header)}
+			ctx := context.Background()
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				httpkit.ErrorEncoder(ctx, bm.err, w)
+			}
+		})
+	}
+}
+
+type discardResponseWriter struct {
+	header http.Header
+}
+
+func (w *discardResponseWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *discardResponseWriter) Write(b []byte) (int, error) {
+	return len(b), nil
+}
+
+func (w *discardResponseWriter) WriteHeader(int) {}
+
+func TestEncodeErrorMessageEscaping(t *testing.T) {
+	messages := []string{
+		`quoted "name" and \ backslash`,
+		"new\nline\ttab\x01control",
+		"<html> & entities",
+		"invalid \xff utf-8 and   separators",
+		"кирилица",
+	}
+	for _, message := range messages {
+		rec := httptest.NewRecorder()
+		httpkit.ErrorEncoder(context.Background(), status.Error(codes.NotFound, message), rec)
+
+		want, _ := json.Marshal(map[string]string{"message": message})
+		if got := rec.Body.String(); got != string(want) {
+			t.Errorf("unexpected body:\n- want: %s\n-  got: %v", want, got)
+		}
+	}
+}
+
+func TestErrorEncoderReporter(t *testing.T) {
+	var reported []error
+	reporter := errreport.ReporterFunc(func(ctx context.Context, err error) {
+		reported = append(reported, err)
+	})
+	encoder := httpkit.NewErrorEncoder(httpkit.ErrorEncoderReporter(reporter, errreport.SeverityError))
+
+	internal := status.Error(codes.Internal, "internal")
+	for _, err := range []error{internal, status.Error(codes.NotFound, "not found")} {
+		encoder(context.Background(), err, httptest.NewRecorder())
+	}
+
+	if len(reported) != 1 || reported[0] != internal {
+		t.Errorf("unexpected reported errors:\n- want: %v\n-  got: %v", []error{internal}, reported)
+	}
+}
+
+func TestEncodeRateLimitHeaders(t *testing.T) {
+	rec := httptest.NewRecorder()
+	httpkit.ErrorEncoder(context.Background(), httpkit.NewRateLimitError(100, 0, 1500*time.Millisecond), rec)
+
+	if rec.Code != http.StatusTooManyRequests {
+		t.Errorf("unexpected status code:\n- want: %v\n-  got: %v", http.StatusTooManyRequests, rec.Code)
+	}
+	want := map[string]string{
+		"X-RateLimit-Limit":     "100",
+		"X-RateLimit-Remaining": "0",
+		"X-RateLimit-Reset":     "2",
+		"Retry-After":           "2",
+	}
+	for name, value := range want {
+		if got := rec.Header().Get(name); got != value {
+			t.Errorf("unexpected %s header:\n- want: %v\n-  got: %v", name, value, got)
+		}
+	}
+}
+
+func TestErrorEncoderBearerRealm(t *testing.T) {
+	encoder := httpkit.NewErrorEncoder(httpkit.ErrorEncoderBearerRealm("clouway"))
+
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{name: "unauthenticated", err: status.Error(codes.Unauthenticated, "missing token"), want: `Bearer realm="clouway"`},
+		{name: "permission denied", err: status.Error(codes.PermissionDenied, "denied"), want: ""},
+		{name: "explicit header", err: httpkit.NewHttpError(http.StatusUnauthorized, nil, map[string][]string{"Www-Authenticate": {"Basic"}}), want: "Basic"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			encoder(context.Background(), test.err, rec)
+
+			if got := rec.Header().Get("WWW-Authenticate"); got != test.want {
+				t.Errorf("unexpected WWW-Authenticate header:\n- want: %v\n-  got: %v", test.want, got)
+			}
+		})
+	}
+}
+
+func TestErrorEncoderSchema(t *testing.T) {
+	tests := []struct {
+		name   string
+		schema httpkit.ErrorSchema
+		err    error
+		want   string
+	}{
+		{
+			name:   "legacy error key",
+			schema: httpkit.ErrorSchema{MessageKey: "error"},
+			err:    status.Error(codes.NotFound, "order not found"),
+			want:   `{"error":"order not found"}`,
+		},
+		{
+			name:   "with code",
+			schema: httpkit.ErrorSchema{MessageKey: "error", CodeKey: "code"},
+			err:    status.Error(codes.NotFound, "order not found"),
+			want:   `{"error":"order not found","code":404}`,
+		},
+		{
+			name:   "plain error with default message key",
+			schema: httpkit.ErrorSchema{CodeKey: "code"},
+			err:    errors.New("failure"),
+			want:   `{"message":"failure","code":500}`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			httpkit.NewErrorEncoder(httpkit.ErrorEncoderSchema(test.schema))(context.Background(), test.err, rec)
+
+			if got := rec.Body.String(); got != test.want {
+				t.Errorf("unexpected body:\n- want: %v\n-  got: %v", test.want, got)
+			}
+		})
+	}
+}
+
+func TestErrorEncoderStatusName(t *testing.T) {
+	tests := []struct {
+		name    string
+		options []httpkit.ErrorEncoderOption
+		err     error
+		want    string
+	}{
+		{
+			name:    "status error",
+			options: []httpkit.ErrorEncoderOption{httpkit.ErrorEncoderStatusName()},
+			err:     status.Error(codes.NotFound, "order not found"),
+			want:    `{"message":"order not found","code":404,"status":"NOT_FOUND"}`,
+		},
+		{
+			name:    "plain error",
+			options: []httpkit.ErrorEncoderOption{httpkit.ErrorEncoderStatusName()},
+			err:     errors.New("failure"),
+			want:    `{"message":"failure","code":500,"status":"UNKNOWN"}`,
+		},
+		{
+			name: "custom schema",
+			options: []httpkit.ErrorEncoderOption{
+				httpkit.ErrorEncoderSchema(httpkit.ErrorSchema{MessageKey: "error", CodeKey: "httpCode"}),
+				httpkit.ErrorEncoderStatusName(),
+			},
+			err:  status.Error(codes.PermissionDenied, "denied"),
+			want: `{"error":"denied","httpCode":403,"status":"PERMISSION_DENIED"}`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			httpkit.NewErrorEncoder(test.options...)(context.Background(), test.err, rec)
+
+			if got := rec.Body.String(); got != test.want {
+				t.Errorf("unexpected body:\n- want: %v\n-  got: %v", test.want, got)
+			}
+		})
+	}
+}
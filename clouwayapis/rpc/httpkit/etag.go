@@ -0,0 +1,85 @@
+package httpkit
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"net/http"
+	"strings"
+
+	"github.com/clouway/go-genproto/clouwayapis/rpc/request"
+	httptransport "github.com/go-kit/kit/transport/http"
+	"google.golang.org/protobuf/proto"
+)
+
+// ETag returns the strong entity tag of the message. The tag is the etag
+// field of the messages that have one, e.g. the resources that follow
+// AIP-154, or the SHA-256 digest of the deterministic binary encoding of the
+// message otherwise.
+func ETag(m proto.Message) (string, error) {
+	if e, ok := m.(interface{ GetEtag() string }); ok && e.GetEtag() != "" {
+		return quoteETag(e.GetEtag()), nil
+	}
+	b, err := proto.MarshalOptions{Deterministic: true}.Marshal(m)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(b)
+	return `"` + base64.RawURLEncoding.EncodeToString(sum[:]) + `"`, nil
+}
+
+// NewETagEncoder returns an EncodeResponseFunc that sets the ETag header of
+// the proto.Message responses and responds with 304 Not Modified without
+// body when the tag matches the If-None-Match header of a GET or HEAD
+// request. The other responses are encoded by next.
+//
+// The If-None-Match header is read from the context, where it's stored by
+// HeadersToContext, and the method of the request by the go-kit
+// httptransport.PopulateRequestContext. The responses of the requests with
+// unknown method are never answered with 304.
+func NewETagEncoder(next httptransport.EncodeResponseFunc) httptransport.EncodeResponseFunc {
+	return func(ctx context.Context, w http.ResponseWriter, response interface{}) error {
+		m, ok := response.(proto.Message)
+		if !ok || isNoContent(response) {
+			return next(ctx, w, response)
+		}
+		etag, err := ETag(m)
+		if err != nil {
+			return err
+		}
+		w.Header().Set("ETag", etag)
+
+		method, _ := ctx.Value(httptransport.ContextKeyRequestMethod).(string)
+		ifNoneMatch, _ := ctx.Value(request.ContextKey("if-none-match")).(string)
+		if (method == http.MethodGet || method == http.MethodHead) && etagMatches(ifNoneMatch, etag, true) {
+			w.WriteHeader(http.StatusNotModified)
+			return nil
+		}
+		return next(ctx, w, response)
+	}
+}
+
+// etagMatches reports whether the list of entity tags of the If-Match or
+// If-None-Match header matches the tag. The weak comparison ignores the W/
+// prefixes, as used by If-None-Match, while the strong comparison of
+// If-Match never matches weak tags.
+func etagMatches(header, etag string, weak bool) bool {
+	if strings.TrimSpace(header) == "*" {
+		return true
+	}
+	if weak {
+		etag = strings.TrimPrefix(etag, "W/")
+	} else if strings.HasPrefix(etag, "W/") {
+		return false
+	}
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if weak {
+			candidate = strings.TrimPrefix(candidate, "W/")
+		}
+		if candidate != "" && candidate == etag {
+			return true
+		}
+	}
+	return false
+}
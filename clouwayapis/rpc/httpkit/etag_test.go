@@ -0,0 +1,93 @@
+package httpkit_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/clouway/go-genproto/clouwayapis/rpc/httpkit"
+	httptransport "github.com/go-kit/kit/transport/http"
+	"google.golang.org/protobuf/types/known/apipb"
+)
+
+type etagResource struct {
+	*apipb.Api
+	etag string
+}
+
+func (r *etagResource) GetEtag() string {
+	return r.etag
+}
+
+func TestETag(t *testing.T) {
+	first, _ := httpkit.ETag(&apipb.Api{Name: "orders"})
+	second, _ := httpkit.ETag(&apipb.Api{Name: "orders"})
+	changed, _ := httpkit.ETag(&apipb.Api{Name: "invoices"})
+
+	if first != second {
+		t.Errorf("unexpected ETag of equal messages:\n- want: %v\n-  got: %v", first, second)
+	}
+	if first == changed {
+		t.Errorf("unexpected ETag of changed message: %v", changed)
+	}
+	if want, got := `"v2"`, mustETag(&etagResource{Api: &apipb.Api{}, etag: "v2"}); want != got {
+		t.Errorf("unexpected ETag of the etag field:\n- want: %v\n-  got: %v", want, got)
+	}
+}
+
+func mustETag(r *etagResource) string {
+	etag, _ := httpkit.ETag(r)
+	return etag
+}
+
+func TestETagEncoder(t *testing.T) {
+	response := &apipb.Api{Name: "orders"}
+	etag, _ := httpkit.ETag(response)
+	encode := httpkit.NewETagEncoder(httpkit.EncodeProtoJSONResponse)
+
+	tests := []struct {
+		name        string
+		method      string
+		ifNoneMatch string
+		status      int
+	}{
+		{name: "without If-None-Match", method: http.MethodGet, status: http.StatusOK},
+		{name: "matching", method: http.MethodGet, ifNoneMatch: etag, status: http.StatusNotModified},
+		{name: "weak match", method: http.MethodGet, ifNoneMatch: `"other", W/` + etag, status: http.StatusNotModified},
+		{name: "any", method: http.MethodHead, ifNoneMatch: "*", status: http.StatusNotModified},
+		{name: "changed", method: http.MethodGet, ifNoneMatch: `"other"`, status: http.StatusOK},
+		{name: "not a read", method: http.MethodPut, ifNoneMatch: etag, status: http.StatusOK},
+		{name: "unknown method", ifNoneMatch: etag, status: http.StatusOK},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/v1/apis/orders", nil)
+			if test.ifNoneMatch != "" {
+				r.Header.Set("If-None-Match", test.ifNoneMatch)
+			}
+			// The method is missing from the context without
+			// PopulateRequestContext.
+			ctx := httpkit.HeadersToContext(context.Background(), r)
+			if test.method != "" {
+				r.Method = test.method
+				ctx = httpkit.HeadersToContext(httptransport.PopulateRequestContext(context.Background(), r), r)
+			}
+
+			w := httptest.NewRecorder()
+			if err := encode(ctx, w, response); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if w.Code != test.status {
+				t.Errorf("unexpected status code:\n- want: %v\n-  got: %v", test.status, w.Code)
+			}
+			if got := w.Header().Get("ETag"); got != etag {
+				t.Errorf("unexpected ETag header:\n- want: %v\n-  got: %v", etag, got)
+			}
+			if test.status == http.StatusNotModified && w.Body.Len() != 0 {
+				t.Errorf("unexpected body: %s", w.Body)
+			}
+		})
+	}
+}
@@ -0,0 +1,157 @@
+package httpkit
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	httptransport "github.com/go-kit/kit/transport/http"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+)
+
+// FieldsParameter is the query parameter with the fields of the partial
+// responses, e.g. ?fields=name,address.city
+const FieldsParameter = "fields"
+
+type fieldMaskKey struct{}
+
+// FieldsToContext is a transport/http.RequestFunc that stores the field mask
+// of the fields query parameter in the context. The paths of the mask are
+// separated by commas and may use either the proto or the JSON names of the
+// fields.
+func FieldsToContext(ctx context.Context, r *http.Request) context.Context {
+	var paths []string
+	for _, v := range r.URL.Query()[FieldsParameter] {
+		for _, path := range strings.Split(v, ",") {
+			if path = strings.TrimSpace(path); path != "" {
+				paths = append(paths, path)
+			}
+		}
+	}
+	if len(paths) == 0 {
+		return ctx
+	}
+	return context.WithValue(ctx, fieldMaskKey{}, &fieldmaskpb.FieldMask{Paths: paths})
+}
+
+// FieldMaskFromContext returns the field mask that is stored by
+// FieldsToContext.
+func FieldMaskFromContext(ctx context.Context) (*fieldmaskpb.FieldMask, bool) {
+	mask, ok := ctx.Value(fieldMaskKey{}).(*fieldmaskpb.FieldMask)
+	return mask, ok
+}
+
+// Fields is an HTTP middleware that stores the field mask of the fields
+// query parameter in the request context for the handlers that are not
+// go-kit servers. The go-kit servers use FieldsToContext as ServerBefore.
+func Fields(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r.WithContext(FieldsToContext(r.Context(), r)))
+	})
+}
+
+// NewFieldMaskEncoder returns an EncodeResponseFunc that prunes the
+// proto.Message responses to the field mask of the context before they are
+// encoded by next. The responses are cloned, so the endpoints may return
+// shared or cached messages. The unknown fields of the mask are rejected
+// with an InvalidArgument status error. The pruned fields are left out of
+// the JSON only by encoders that don't emit the unpopulated fields:
+//
+//	NewFieldMaskEncoder(NewProtoJSONResponseEncoder(protojson.MarshalOptions{}))
+func NewFieldMaskEncoder(next httptransport.EncodeResponseFunc) httptransport.EncodeResponseFunc {
+	return func(ctx context.Context, w http.ResponseWriter, response interface{}) error {
+		mask, ok := FieldMaskFromContext(ctx)
+		m, isMessage := response.(proto.Message)
+		if !ok || !isMessage || isNil(response) {
+			return next(ctx, w, response)
+		}
+		m = proto.Clone(m)
+		if err := PruneFields(m, mask); err != nil {
+			return err
+		}
+		return next(ctx, w, m)
+	}
+}
+
+// PruneFields clears all fields of the message that are not selected by the
+// field mask. The paths that go through repeated or map fields select the
+// fields of all their elements.
+func PruneFields(m proto.Message, mask *fieldmaskpb.FieldMask) error {
+	tree := fieldTree{}
+	md := m.ProtoReflect().Descriptor()
+	for _, path := range mask.GetPaths() {
+		if err := tree.add(md, path); err != nil {
+			return err
+		}
+	}
+	tree.prune(m.ProtoReflect())
+	return nil
+}
+
+// fieldTree holds the selected fields of a message. A nil subtree selects
+// the whole field.
+type fieldTree map[protoreflect.Name]fieldTree
+
+func (t fieldTree) add(md protoreflect.MessageDescriptor, path string) error {
+	node := t
+	names := strings.Split(path, ".")
+	for i, name := range names {
+		if md == nil {
+			return fieldError(FieldsParameter, "unknown field '"+path+"'")
+		}
+		fd := md.Fields().ByName(protoreflect.Name(name))
+		if fd == nil {
+			fd = md.Fields().ByJSONName(name)
+		}
+		if fd == nil {
+			return fieldError(FieldsParameter, "unknown field '"+path+"'")
+		}
+		child, seen := node[fd.Name()]
+		if seen && child == nil {
+			// The whole field is already selected.
+			return nil
+		}
+		if i == len(names)-1 {
+			node[fd.Name()] = nil
+			return nil
+		}
+		if child == nil {
+			child = fieldTree{}
+			node[fd.Name()] = child
+		}
+		node, md = child, fd.Message()
+		if fd.IsMap() {
+			md = fd.MapValue().Message()
+		}
+	}
+	return nil
+}
+
+func (t fieldTree) prune(m protoreflect.Message) {
+	var cleared []protoreflect.FieldDescriptor
+	m.Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		child, ok := t[fd.Name()]
+		switch {
+		case !ok:
+			cleared = append(cleared, fd)
+		case child == nil:
+		case fd.IsList():
+			for i := 0; i < v.List().Len(); i++ {
+				child.prune(v.List().Get(i).Message())
+			}
+		case fd.IsMap():
+			v.Map().Range(func(_ protoreflect.MapKey, v protoreflect.Value) bool {
+				child.prune(v.Message())
+				return true
+			})
+		default:
+			child.prune(v.Message())
+		}
+		return true
+	})
+	for _, fd := range cleared {
+		m.Clear(fd)
+	}
+}
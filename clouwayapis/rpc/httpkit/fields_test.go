@@ -0,0 +1,99 @@
+package httpkit_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/clouway/go-genproto/clouwayapis/rpc/httpkit"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/apipb"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+	"google.golang.org/protobuf/types/known/sourcecontextpb"
+)
+
+func testAPI() *apipb.Api {
+	return &apipb.Api{
+		Name:    "orders",
+		Version: "v1",
+		Methods: []*apipb.Method{
+			{Name: "GetOrder", RequestTypeUrl: "GetOrderRequest", ResponseTypeUrl: "Order"},
+			{Name: "ListOrders", RequestTypeUrl: "ListOrdersRequest", ResponseTypeUrl: "ListOrdersResponse"},
+		},
+		SourceContext: &sourcecontextpb.SourceContext{FileName: "orders.proto"},
+	}
+}
+
+func TestPruneFields(t *testing.T) {
+	tests := []struct {
+		name  string
+		paths []string
+		want  *apipb.Api
+	}{
+		{name: "top level", paths: []string{"name", "version"}, want: &apipb.Api{Name: "orders", Version: "v1"}},
+		{name: "json names", paths: []string{"sourceContext.fileName"}, want: &apipb.Api{SourceContext: &sourcecontextpb.SourceContext{FileName: "orders.proto"}}},
+		{
+			name:  "repeated",
+			paths: []string{"methods.name"},
+			want:  &apipb.Api{Methods: []*apipb.Method{{Name: "GetOrder"}, {Name: "ListOrders"}}},
+		},
+		{name: "whole field wins", paths: []string{"methods.name", "methods"}, want: &apipb.Api{Methods: testAPI().Methods}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := testAPI()
+			if err := httpkit.PruneFields(got, &fieldmaskpb.FieldMask{Paths: test.paths}); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !proto.Equal(test.want, got) {
+				t.Errorf("unexpected message:\n- want: %v\n-  got: %v", test.want, got)
+			}
+		})
+	}
+}
+
+func TestPruneFieldsUnknownField(t *testing.T) {
+	err := httpkit.PruneFields(testAPI(), &fieldmaskpb.FieldMask{Paths: []string{"name.value"}})
+
+	if got := status.Code(err); got != codes.InvalidArgument {
+		t.Errorf("unexpected code:\n- want: %v\n-  got: %v", codes.InvalidArgument, got)
+	}
+}
+
+func TestFieldMaskEncoder(t *testing.T) {
+	encode := httpkit.NewFieldMaskEncoder(httpkit.NewProtoJSONResponseEncoder(protojson.MarshalOptions{}))
+	response := testAPI()
+	ctx := httpkit.FieldsToContext(context.Background(), httptest.NewRequest(http.MethodGet, "/apis/orders?fields=name,%20methods.name", nil))
+
+	w := httptest.NewRecorder()
+	if err := encode(ctx, w, response); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := `{"name":"orders","methods":[{"name":"GetOrder"},{"name":"ListOrders"}]}`
+	if got := compactJSON(w.Body.Bytes()); got != want {
+		t.Errorf("unexpected body:\n- want: %v\n-  got: %v", want, got)
+	}
+	if !proto.Equal(response, testAPI()) {
+		t.Errorf("unexpected change of the response: %v", response)
+	}
+}
+
+func TestFieldMaskEncoderWithoutFields(t *testing.T) {
+	encode := httpkit.NewFieldMaskEncoder(httpkit.NewProtoJSONResponseEncoder(protojson.MarshalOptions{}))
+	ctx := httpkit.FieldsToContext(context.Background(), httptest.NewRequest(http.MethodGet, "/apis/orders?fields=", nil))
+
+	w := httptest.NewRecorder()
+	if err := encode(ctx, w, &apipb.Api{Name: "orders", Version: "v1"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := `{"name":"orders","version":"v1"}`
+	if got := compactJSON(w.Body.Bytes()); got != want {
+		t.Errorf("unexpected body:\n- want: %v\n-  got: %v", want, got)
+	}
+}
@@ -0,0 +1,175 @@
+package httpkit
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// FileInfo describes a file of a FileStorage.
+type FileInfo struct {
+	Name        string
+	ContentType string
+	Size        int64
+	Modified    time.Time
+	// ETag is the entity tag of the file. A weak tag is derived from the
+	// size and the modification time when it's empty.
+	ETag string
+}
+
+// FileStorage opens the files that are served by NewFileHandler, e.g. from
+// a directory, a bucket or a database. The missing files are reported by
+// errors that match fs.ErrNotExist or by NotFound status errors.
+type FileStorage interface {
+	Open(ctx context.Context, name string) (io.ReadSeekCloser, *FileInfo, error)
+}
+
+// FSStorage returns a FileStorage of the file system, e.g. of os.DirFS or
+// of an embed.FS with static assets.
+func FSStorage(fsys fs.FS) FileStorage {
+	return fsStorage{fsys: fsys}
+}
+
+type fsStorage struct {
+	fsys fs.FS
+}
+
+func (s fsStorage) Open(_ context.Context, name string) (io.ReadSeekCloser, *FileInfo, error) {
+	f, err := s.fsys.Open(name)
+	if err != nil {
+		return nil, nil, err
+	}
+	stat, err := f.Stat()
+	if err != nil || stat.IsDir() {
+		f.Close()
+		return nil, nil, fs.ErrNotExist
+	}
+	info := &FileInfo{Name: stat.Name(), Size: stat.Size(), Modified: stat.ModTime()}
+	if rsc, ok := f.(io.ReadSeekCloser); ok {
+		return rsc, info, nil
+	}
+	defer f.Close()
+	b, err := io.ReadAll(f)
+	if err != nil {
+		return nil, nil, err
+	}
+	return nopSeekCloser{bytes.NewReader(b)}, info, nil
+}
+
+type nopSeekCloser struct {
+	io.ReadSeeker
+}
+
+func (nopSeekCloser) Close() error { return nil }
+
+// FileHandlerOption sets an optional parameter for NewFileHandler.
+type FileHandlerOption func(*fileHandler)
+
+// FileCache sets the caching policy of the served files. The files are
+// revalidated by their ETag on every use by default.
+func FileCache(options ...CacheOption) FileHandlerOption {
+	return func(h *fileHandler) { h.cache = newCachePolicy(options...) }
+}
+
+// FileDownload sets the download options of the served files, e.g. which
+// of them are displayed inline. All files are displayed inline by default,
+// as the static assets are.
+func FileDownload(options ...DownloadOption) FileHandlerOption {
+	return func(h *fileHandler) {
+		h.download = &downloadOptions{inline: []string{"application/pdf"}}
+		for _, option := range options {
+			option(h.download)
+		}
+	}
+}
+
+type fileHandler struct {
+	storage  FileStorage
+	cache    *cachePolicy
+	download *downloadOptions
+}
+
+// NewFileHandler returns an http.Handler that serves the GET and HEAD
+// requests with the files of the storage, named by the path of the request
+// without the leading slash, so it's mounted with http.StripPrefix:
+//
+//	router.PathPrefix("/assets/").Handler(http.StripPrefix("/assets/", httpkit.NewFileHandler(httpkit.FSStorage(assets))))
+//
+// The files are served by http.ServeContent with their ETag and
+// Last-Modified, so the conditional and the Range requests are honored,
+// and with the Cache-Control of the FileCache policy. The content type of
+// the files without one is detected by SniffContentType. The
+// errors are written by ErrorEncoder, e.g. the missing files as 404.
+func NewFileHandler(storage FileStorage, options ...FileHandlerOption) http.Handler {
+	h := &fileHandler{storage: storage, cache: newCachePolicy(CacheNoCache())}
+	for _, option := range options {
+		option(h)
+	}
+	return h
+}
+
+func (h *fileHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		allowed := []string{http.MethodGet, http.MethodHead}
+		w.Header().Set("Allow", strings.Join(allowed, ", "))
+		ErrorEncoder(ctx, NewMethodNotAllowedError(r.Method, allowed), w)
+		return
+	}
+	name := strings.TrimPrefix(path.Clean("/"+r.URL.Path), "/")
+	var (
+		content io.ReadSeekCloser
+		info    *FileInfo
+		err     = fs.ErrNotExist
+	)
+	// The root of the mount is not a file, and fs.FS rejects its empty
+	// name as invalid.
+	if name != "" {
+		content, info, err = h.storage.Open(ctx, name)
+	}
+	if errors.Is(err, fs.ErrNotExist) || errors.Is(err, fs.ErrInvalid) {
+		err = status.Errorf(codes.NotFound, "file /%s not found", name)
+	}
+	if err != nil {
+		ErrorEncoder(ctx, err, w)
+		return
+	}
+	defer content.Close()
+
+	etag := info.ETag
+	if etag == "" {
+		etag = fmt.Sprintf(`W/"%x-%x"`, info.Size, info.Modified.UnixNano())
+	}
+	contentType := info.ContentType
+	if contentType == "" {
+		head := make([]byte, 512)
+		n, _ := io.ReadFull(content, head)
+		if _, err := content.Seek(0, io.SeekStart); err != nil {
+			ErrorEncoder(ctx, err, w)
+			return
+		}
+		contentType = SniffContentType(name, head[:n])
+	}
+	w.Header().Set("Content-Type", contentType)
+	if h.download != nil {
+		disposition := "attachment"
+		if acceptsFileType(h.download.inline, contentType) {
+			disposition = "inline"
+		}
+		w.Header().Set("Content-Disposition", ContentDisposition(disposition, path.Base(name)))
+	}
+	w.Header().Set("ETag", etag)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	h.cache.apply(w.Header())
+	http.ServeContent(w, r, name, info.Modified, content)
+}
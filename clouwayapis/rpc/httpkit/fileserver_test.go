@@ -0,0 +1,66 @@
+package httpkit_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/clouway/go-genproto/clouwayapis/rpc/httpkit"
+)
+
+func TestFileHandler(t *testing.T) {
+	modified := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	assets := fstest.MapFS{
+		"css/app.css": {Data: []byte("body{}"), ModTime: modified},
+		"logo":        {Data: []byte("\x89PNG\r\n\x1a\n"), ModTime: modified},
+	}
+	handler := httpkit.NewFileHandler(httpkit.FSStorage(assets), httpkit.FileCache(httpkit.CachePublic(), httpkit.CacheMaxAge(time.Hour)))
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/css/app.css", nil))
+	etag := w.Header().Get("ETag")
+
+	tests := []struct {
+		name        string
+		method      string
+		path        string
+		headers     map[string]string
+		status      int
+		contentType string
+		body        string
+	}{
+		{name: "file", method: http.MethodGet, path: "/css/app.css", status: http.StatusOK, contentType: "text/css; charset=utf-8", body: "body{}"},
+		{name: "sniffed type", method: http.MethodGet, path: "/logo", status: http.StatusOK, contentType: "image/png"},
+		{name: "not modified by etag", method: http.MethodGet, path: "/css/app.css", headers: map[string]string{"If-None-Match": etag}, status: http.StatusNotModified},
+		{name: "not modified since", method: http.MethodGet, path: "/css/app.css", headers: map[string]string{"If-Modified-Since": modified.Format(http.TimeFormat)}, status: http.StatusNotModified},
+		{name: "range", method: http.MethodGet, path: "/css/app.css", headers: map[string]string{"Range": "bytes=0-3"}, status: http.StatusPartialContent, body: "body"},
+		{name: "missing", method: http.MethodGet, path: "/css/missing.css", status: http.StatusNotFound},
+		{name: "directory", method: http.MethodGet, path: "/css", status: http.StatusNotFound},
+		{name: "mount root", method: http.MethodGet, path: "/", status: http.StatusNotFound},
+		{name: "method not allowed", method: http.MethodPost, path: "/css/app.css", status: http.StatusMethodNotAllowed},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := httptest.NewRequest(test.method, test.path, nil)
+			for k, v := range test.headers {
+				r.Header.Set(k, v)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+
+			if w.Code != test.status {
+				t.Errorf("unexpected status code:\n- want: %v\n-  got: %v", test.status, w.Code)
+			}
+			if test.contentType != "" && w.Header().Get("Content-Type") != test.contentType {
+				t.Errorf("unexpected Content-Type header:\n- want: %v\n-  got: %v", test.contentType, w.Header().Get("Content-Type"))
+			}
+			if test.body != "" && w.Body.String() != test.body {
+				t.Errorf("unexpected body:\n- want: %v\n-  got: %v", test.body, w.Body.String())
+			}
+			if test.status < http.StatusBadRequest && w.Header().Get("Cache-Control") != "public, max-age=3600" {
+				t.Errorf("unexpected Cache-Control header:\n- want: %v\n-  got: %v", "public, max-age=3600", w.Header().Get("Cache-Control"))
+			}
+		})
+	}
+}
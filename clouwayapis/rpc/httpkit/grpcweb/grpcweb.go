@@ -0,0 +1,242 @@
+// Package grpcweb implements the gRPC-Web protocol on top of the existing
+// grpc servers, so that the browser applications can call the clouwayapis
+// services without a proxy in front of them. Both the binary and the base64
+// text mode are supported for unary and server-streaming methods.
+package grpcweb
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/clouway/go-genproto/clouwayapis/rpc/httpkit"
+	"google.golang.org/grpc/codes"
+)
+
+const (
+	// ContentType is the content type of the binary gRPC-Web requests.
+	ContentType = "application/grpc-web"
+	// TextContentType is the content type of the base64 encoded gRPC-Web
+	// requests.
+	TextContentType = "application/grpc-web-text"
+)
+
+// trailerFlag marks the frame of the trailers in the response body.
+const trailerFlag = 0x80
+
+// IsGRPCWebRequest reports whether the request is a gRPC-Web call.
+func IsGRPCWebRequest(r *http.Request) bool {
+	return r.Method == http.MethodPost && strings.HasPrefix(r.Header.Get("Content-Type"), ContentType)
+}
+
+// Handler returns an http.Handler that translates the gRPC-Web requests to
+// gRPC calls of the server, which is usually a *grpc.Server. All other
+// requests are passed to next, or answered with 404 when it is nil:
+//
+//	http.ListenAndServe(":8080", grpcweb.Handler(grpcServer, router))
+//
+// The status of the calls that fail before sending any message is also
+// reflected in the HTTP status code of the response with the same mapping
+// that is used by httpkit.ErrorEncoder, e.g. NotFound is answered with 404.
+// For that reason the response headers are sent with the first message.
+func Handler(server http.Handler, next http.Handler) http.Handler {
+	if next == nil {
+		next = http.NotFoundHandler()
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !IsGRPCWebRequest(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		contentType := r.Header.Get("Content-Type")
+		text := strings.HasPrefix(contentType, TextContentType)
+
+		req := r.Clone(r.Context())
+		req.ProtoMajor, req.ProtoMinor, req.Proto = 2, 0, "HTTP/2.0"
+		req.Header.Set("Content-Type", "application/grpc"+contentSubtype(contentType))
+		req.Header.Set("Te", "trailers")
+		req.Header.Del("Content-Length")
+		req.ContentLength = -1
+		if text {
+			req.Body = io.NopCloser(&base64Reader{r: r.Body})
+		}
+
+		ww := &responseWriter{w: w, header: make(http.Header), contentType: contentType, text: text}
+		server.ServeHTTP(ww, req)
+		ww.finish()
+	})
+}
+
+// contentSubtype returns the subtype of the content type including the plus
+// sign, e.g. "+proto".
+func contentSubtype(contentType string) string {
+	contentType = strings.SplitN(contentType, ";", 2)[0]
+	if i := strings.IndexByte(contentType, '+'); i >= 0 {
+		return contentType[i:]
+	}
+	return ""
+}
+
+// responseWriter collects the headers and the trailers that are written by
+// the gRPC server and writes them in the gRPC-Web format.
+type responseWriter struct {
+	w           http.ResponseWriter
+	header      http.Header
+	contentType string
+	text        bool
+	wroteHeader bool
+}
+
+func (w *responseWriter) Header() http.Header {
+	return w.header
+}
+
+// WriteHeader is delayed until the first message, as the status of the call
+// is not known before that. The gRPC server always responds with 200.
+func (w *responseWriter) WriteHeader(int) {}
+
+func (w *responseWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.writeHeader(http.StatusOK, nil)
+	}
+	if w.text {
+		if _, err := io.WriteString(w.w, base64.StdEncoding.EncodeToString(b)); err != nil {
+			return 0, err
+		}
+		return len(b), nil
+	}
+	return w.w.Write(b)
+}
+
+func (w *responseWriter) Flush() {
+	if !w.wroteHeader {
+		return
+	}
+	if f, ok := w.w.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// writeHeader writes the headers of the gRPC server along with the passed
+// trailers.
+func (w *responseWriter) writeHeader(code int, trailers http.Header) {
+	w.wroteHeader = true
+	h := w.w.Header()
+	for k, v := range w.header {
+		if k == "Trailer" || strings.HasPrefix(k, http.TrailerPrefix) {
+			continue
+		}
+		h[k] = v
+	}
+	for k, v := range trailers {
+		h[k] = v
+	}
+	h.Set("Content-Type", strings.Replace(w.header.Get("Content-Type"), "application/grpc", w.webContentType(), 1))
+	h.Del("Content-Length")
+	w.w.WriteHeader(code)
+}
+
+func (w *responseWriter) webContentType() string {
+	if w.text {
+		return TextContentType
+	}
+	return ContentType
+}
+
+// finish writes the trailers of the call. When no message was sent, the
+// trailers are written as headers of a trailers-only response.
+func (w *responseWriter) finish() {
+	trailers := w.trailers()
+	if !w.wroteHeader {
+		code := http.StatusOK
+		if c, err := strconv.Atoi(trailers.Get("Grpc-Status")); err == nil {
+			code = httpkit.HTTPStatusFromCode(codes.Code(c))
+		}
+		if w.header.Get("Content-Type") == "" {
+			w.header.Set("Content-Type", "application/grpc")
+		}
+		w.writeHeader(code, trailers)
+		return
+	}
+
+	keys := make([]string, 0, len(trailers))
+	for k := range trailers {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var b strings.Builder
+	for _, k := range keys {
+		for _, v := range trailers[k] {
+			b.WriteString(strings.ToLower(k))
+			b.WriteString(": ")
+			b.WriteString(v)
+			b.WriteString("\r\n")
+		}
+	}
+	frame := make([]byte, 5, 5+b.Len())
+	frame[0] = trailerFlag
+	binary.BigEndian.PutUint32(frame[1:], uint32(b.Len()))
+	frame = append(frame, b.String()...)
+	w.Write(frame)
+	w.Flush()
+}
+
+// trailers returns the trailers that are declared by the Trailer header or
+// that are set with the http.TrailerPrefix.
+func (w *responseWriter) trailers() http.Header {
+	trailers := make(http.Header)
+	for _, declared := range w.header.Values("Trailer") {
+		for _, k := range strings.Split(declared, ",") {
+			k = http.CanonicalHeaderKey(strings.TrimSpace(k))
+			if v, ok := w.header[k]; ok {
+				trailers[k] = v
+			}
+		}
+	}
+	for k, v := range w.header {
+		if strings.HasPrefix(k, http.TrailerPrefix) {
+			trailers[http.CanonicalHeaderKey(strings.TrimPrefix(k, http.TrailerPrefix))] = v
+		}
+	}
+	return trailers
+}
+
+// base64Reader decodes the base64 text mode requests. The clients may send
+// multiple padded chunks, so the input is decoded in quanta of 4 bytes.
+type base64Reader struct {
+	r       io.Reader
+	pending []byte
+	decoded []byte
+	err     error
+}
+
+func (r *base64Reader) Read(p []byte) (int, error) {
+	for len(r.decoded) == 0 {
+		if r.err != nil {
+			return 0, r.err
+		}
+		buf := make([]byte, 4096)
+		n, err := r.r.Read(buf)
+		r.pending = append(r.pending, buf[:n]...)
+		r.err = err
+		if err == io.EOF && len(r.pending)%4 != 0 {
+			r.err = io.ErrUnexpectedEOF
+		}
+		for len(r.pending) >= 4 {
+			var quantum [3]byte
+			m, err := base64.StdEncoding.Decode(quantum[:], r.pending[:4])
+			if err != nil {
+				return 0, err
+			}
+			r.decoded = append(r.decoded, quantum[:m]...)
+			r.pending = r.pending[4:]
+		}
+	}
+	n := copy(p, r.decoded)
+	r.decoded = r.decoded[n:]
+	return n, nil
+}
@@ -0,0 +1,144 @@
+package grpcweb_test
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/clouway/go-genproto/clouwayapis/rpc/httpkit/grpcweb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/protobuf/proto"
+)
+
+func newServer() *httptest.Server {
+	hs := health.NewServer()
+	hs.SetServingStatus("orders", healthpb.HealthCheckResponse_SERVING)
+	srv := grpc.NewServer()
+	healthpb.RegisterHealthServer(srv, hs)
+	return httptest.NewServer(grpcweb.Handler(srv, nil))
+}
+
+func frame(m proto.Message) []byte {
+	b, _ := proto.Marshal(m)
+	f := make([]byte, 5, 5+len(b))
+	binary.BigEndian.PutUint32(f[1:], uint32(len(b)))
+	return append(f, b...)
+}
+
+// readFrames splits the gRPC-Web response body into its message and trailer
+// frames.
+func readFrames(t *testing.T, body []byte) (messages [][]byte, trailers string) {
+	t.Helper()
+	for len(body) >= 5 {
+		n := binary.BigEndian.Uint32(body[1:5])
+		payload := body[5 : 5+n]
+		if body[0]&0x80 != 0 {
+			trailers = string(payload)
+		} else {
+			messages = append(messages, payload)
+		}
+		body = body[5+n:]
+	}
+	return messages, trailers
+}
+
+func TestUnaryCall(t *testing.T) {
+	srv := newServer()
+	defer srv.Close()
+
+	resp, err := http.Post(srv.URL+"/grpc.health.v1.Health/Check", "application/grpc-web+proto", bytes.NewReader(frame(&healthpb.HealthCheckRequest{Service: "orders"})))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+
+	if got := resp.Header.Get("Content-Type"); got != "application/grpc-web+proto" {
+		t.Errorf("unexpected Content-Type header:\n- want: %v\n-  got: %v", "application/grpc-web+proto", got)
+	}
+	messages, trailers := readFrames(t, body)
+	if len(messages) != 1 {
+		t.Fatalf("unexpected messages:\n- want: %v\n-  got: %v", 1, len(messages))
+	}
+	got := &healthpb.HealthCheckResponse{}
+	proto.Unmarshal(messages[0], got)
+	if got.Status != healthpb.HealthCheckResponse_SERVING {
+		t.Errorf("unexpected health status:\n- want: %v\n-  got: %v", healthpb.HealthCheckResponse_SERVING, got.Status)
+	}
+	if !strings.Contains(trailers, "grpc-status: 0\r\n") {
+		t.Errorf("unexpected trailers: %q", trailers)
+	}
+}
+
+func TestTextMode(t *testing.T) {
+	srv := newServer()
+	defer srv.Close()
+
+	req := base64.StdEncoding.EncodeToString(frame(&healthpb.HealthCheckRequest{Service: "orders"}))
+	resp, err := http.Post(srv.URL+"/grpc.health.v1.Health/Check", "application/grpc-web-text", strings.NewReader(req))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+
+	if got := resp.Header.Get("Content-Type"); got != "application/grpc-web-text" {
+		t.Errorf("unexpected Content-Type header:\n- want: %v\n-  got: %v", "application/grpc-web-text", got)
+	}
+	// Every write is encoded as separately padded chunk.
+	var decoded []byte
+	for _, chunk := range strings.SplitAfter(string(body), "=") {
+		for len(chunk) >= 4 {
+			b, err := base64.StdEncoding.DecodeString(chunk[:4])
+			if err != nil {
+				t.Fatalf("unexpected error while decoding the body: %v", err)
+			}
+			decoded = append(decoded, b...)
+			chunk = chunk[4:]
+		}
+	}
+	messages, trailers := readFrames(t, decoded)
+	if len(messages) != 1 || !strings.Contains(trailers, "grpc-status: 0\r\n") {
+		t.Errorf("unexpected response: %d messages, trailers %q", len(messages), trailers)
+	}
+}
+
+func TestTrailersOnlyResponse(t *testing.T) {
+	srv := newServer()
+	defer srv.Close()
+
+	resp, err := http.Post(srv.URL+"/grpc.health.v1.Health/Check", "application/grpc-web+proto", bytes.NewReader(frame(&healthpb.HealthCheckRequest{Service: "unknown"})))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("unexpected status code:\n- want: %v\n-  got: %v", http.StatusNotFound, resp.StatusCode)
+	}
+	if got := resp.Header.Get("Grpc-Status"); got != "5" {
+		t.Errorf("unexpected Grpc-Status header:\n- want: %v\n-  got: %v", "5", got)
+	}
+}
+
+func TestNonGRPCWebRequest(t *testing.T) {
+	srv := newServer()
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/grpc.health.v1.Health/Check")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("unexpected status code:\n- want: %v\n-  got: %v", http.StatusNotFound, resp.StatusCode)
+	}
+}
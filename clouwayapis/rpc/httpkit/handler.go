@@ -0,0 +1,142 @@
+package httpkit
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/go-kit/kit/endpoint"
+	"github.com/go-kit/kit/transport"
+	httptransport "github.com/go-kit/kit/transport/http"
+	"google.golang.org/protobuf/proto"
+)
+
+// HandlerOption sets an optional parameter for the handlers of Handle.
+type HandlerOption func(*handlerOptions)
+
+// HandlerBefore adds the request functions that are executed on the HTTP
+// request before it's decoded, in the same way as the go-kit ServerBefore,
+// e.g. HeadersToContext or FieldsToContext.
+func HandlerBefore(before ...httptransport.RequestFunc) HandlerOption {
+	return func(o *handlerOptions) { o.before = append(o.before, before...) }
+}
+
+// HandlerAfter adds the response functions that are executed after the
+// method returns and before the response is encoded, in the same way as the
+// go-kit ServerAfter.
+func HandlerAfter(after ...httptransport.ServerResponseFunc) HandlerOption {
+	return func(o *handlerOptions) { o.after = append(o.after, after...) }
+}
+
+// HandlerMiddleware wraps the method by the endpoint middlewares, e.g.
+// RecoverMiddleware or AccessLogPayloads, so the handlers share them with
+// the go-kit servers. The first middleware is the outermost. The requests
+// and responses are boxed only when middlewares are set.
+func HandlerMiddleware(middlewares ...endpoint.Middleware) HandlerOption {
+	return func(o *handlerOptions) { o.middlewares = append(o.middlewares, middlewares...) }
+}
+
+// HandlerErrorHandler sets the handler that receives the errors of the
+// decoding, the method and the encoding, e.g. for logging, in the same way
+// as the go-kit ServerErrorHandler. The errors are not handled by default.
+func HandlerErrorHandler(errorHandler transport.ErrorHandler) HandlerOption {
+	return func(o *handlerOptions) { o.errorHandler = errorHandler }
+}
+
+// HandlerDecodeOptions sets the options of the decoding of the request body.
+func HandlerDecodeOptions(options ...DecodeOption) HandlerOption {
+	return func(o *handlerOptions) { o.decode = newDecodeOptions(options...) }
+}
+
+// HandlerEncoder sets the encoder of the responses. EncodeProtoJSONResponse
+// is used by default.
+func HandlerEncoder(encode httptransport.EncodeResponseFunc) HandlerOption {
+	return func(o *handlerOptions) { o.encode = encode }
+}
+
+// HandlerErrorEncoder sets the encoder of the errors. ErrorEncoder is used
+// by default.
+func HandlerErrorEncoder(ee httptransport.ErrorEncoder) HandlerOption {
+	return func(o *handlerOptions) { o.errorEncoder = ee }
+}
+
+type handlerOptions struct {
+	before       []httptransport.RequestFunc
+	after        []httptransport.ServerResponseFunc
+	middlewares  []endpoint.Middleware
+	decode       *decodeOptions
+	encode       httptransport.EncodeResponseFunc
+	errorEncoder httptransport.ErrorEncoder
+	errorHandler transport.ErrorHandler
+}
+
+// Handle returns an HTTP handler that calls the typed service method
+// directly, without the go-kit endpoint, decoder and encoder indirection and
+// the boxing of the requests and responses. It's meant for the few
+// performance-critical methods such as telemetry ingestion, while the rest
+// of the methods stay go-kit endpoints. See BenchmarkHandle for the cost of
+// the indirection.
+//
+// The handler decodes the JSON body in the same way as DecodeProtoJSONRequest
+// and writes the errors by ErrorEncoder. The options mirror the ones of the
+// go-kit servers, e.g. HandlerBefore, HandlerAfter, HandlerMiddleware and
+// HandlerErrorHandler. The HTTP middlewares such as
+// Recoverer, Timeout or MaxBytes wrap the handler in the same way as the
+// go-kit servers, and the gRPC variants of the methods are registered on the
+// grpc.Server directly, so they pass through its interceptors.
+func Handle[Req, Resp proto.Message](method func(context.Context, Req) (Resp, error), options ...HandlerOption) http.Handler {
+	o := &handlerOptions{decode: newDecodeOptions(), encode: EncodeProtoJSONResponse, errorEncoder: ErrorEncoder}
+	for _, option := range options {
+		option(o)
+	}
+	var zero Req
+	requestType := zero.ProtoReflect().Type()
+	call := method
+	if len(o.middlewares) > 0 {
+		e := endpoint.Endpoint(func(ctx context.Context, request interface{}) (interface{}, error) {
+			return method(ctx, request.(Req))
+		})
+		for i := len(o.middlewares) - 1; i >= 0; i-- {
+			e = o.middlewares[i](e)
+		}
+		call = func(ctx context.Context, req Req) (Resp, error) {
+			var zero Resp
+			resp, err := e(ctx, req)
+			if err != nil {
+				return zero, err
+			}
+			typed, _ := resp.(Resp)
+			return typed, nil
+		}
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		for _, f := range o.before {
+			ctx = f(ctx, r)
+		}
+
+		req := requestType.New().Interface().(Req)
+		if err := o.decode.decode(r, req); err != nil {
+			o.handleError(ctx, err, w)
+			return
+		}
+		resp, err := call(ctx, req)
+		if err != nil {
+			o.handleError(ctx, err, w)
+			return
+		}
+		for _, f := range o.after {
+			ctx = f(ctx, w)
+		}
+		if err := o.encode(ctx, w, resp); err != nil {
+			o.handleError(ctx, err, w)
+		}
+	})
+}
+
+func (o *handlerOptions) handleError(ctx context.Context, err error, w http.ResponseWriter) {
+	if o.errorHandler != nil {
+		o.errorHandler.Handle(ctx, err)
+	}
+	o.errorEncoder(ctx, err, w)
+}
@@ -0,0 +1,150 @@
+package httpkit_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/clouway/go-genproto/clouwayapis/rpc/httpkit"
+	"github.com/clouway/go-genproto/clouwayapis/rpc/request"
+	"github.com/go-kit/kit/transport"
+	httptransport "github.com/go-kit/kit/transport/http"
+	"github.com/go-kit/log"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/typepb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+func TestHandle(t *testing.T) {
+	var gotTenant interface{}
+	handler := httpkit.Handle(func(ctx context.Context, req *typepb.Field) (*wrapperspb.StringValue, error) {
+		gotTenant = ctx.Value(request.ContextKey("x-tenant"))
+		return wrapperspb.String(req.Name), nil
+	}, httpkit.HandlerBefore(httpkit.HeadersToContext))
+
+	r := httptest.NewRequest(http.MethodPost, "/v1/metrics", strings.NewReader(`{"name":"cpu"}`))
+	r.Header.Set("X-Tenant", "acme")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("unexpected status code:\n- want: %v\n-  got: %v", http.StatusOK, w.Code)
+	}
+	if want, got := `"cpu"`, compactJSON(w.Body.Bytes()); want != got {
+		t.Errorf("unexpected body:\n- want: %v\n-  got: %v", want, got)
+	}
+	if gotTenant != "acme" {
+		t.Errorf("unexpected tenant:\n- want: %v\n-  got: %v", "acme", gotTenant)
+	}
+}
+
+func TestHandleErrors(t *testing.T) {
+	handler := httpkit.Handle(func(ctx context.Context, req *typepb.Field) (*wrapperspb.StringValue, error) {
+		return nil, status.Error(codes.NotFound, "metric not found")
+	})
+
+	tests := []struct {
+		name   string
+		body   string
+		status int
+	}{
+		{name: "invalid body", body: `{"unknown":1}`, status: http.StatusBadRequest},
+		{name: "method error", body: `{"name":"cpu"}`, status: http.StatusNotFound},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/v1/metrics", strings.NewReader(test.body)))
+
+			if w.Code != test.status {
+				t.Errorf("unexpected status code:\n- want: %v\n-  got: %v", test.status, w.Code)
+			}
+		})
+	}
+}
+
+func TestHandleMiddlewares(t *testing.T) {
+	var handled []error
+	handler := httpkit.Handle(func(ctx context.Context, req *typepb.Field) (*wrapperspb.StringValue, error) {
+		if req.Name == "panic" {
+			panic("boom")
+		}
+		return wrapperspb.String(req.Name), nil
+	},
+		httpkit.HandlerMiddleware(httpkit.RecoverMiddleware(httpkit.RecovererLogger(log.NewNopLogger()))),
+		httpkit.HandlerAfter(func(ctx context.Context, w http.ResponseWriter) context.Context {
+			w.Header().Set("X-Handled", "true")
+			return ctx
+		}),
+		httpkit.HandlerErrorHandler(transport.ErrorHandlerFunc(func(ctx context.Context, err error) {
+			handled = append(handled, err)
+		})),
+	)
+
+	tests := []struct {
+		name    string
+		body    string
+		status  int
+		after   string
+		handled int
+	}{
+		{name: "success", body: `{"name":"cpu"}`, status: http.StatusOK, after: "true"},
+		{name: "recovered panic", body: `{"name":"panic"}`, status: http.StatusInternalServerError, handled: 1},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			handled = nil
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/v1/metrics", strings.NewReader(test.body)))
+
+			if w.Code != test.status {
+				t.Errorf("unexpected status code:\n- want: %v\n-  got: %v", test.status, w.Code)
+			}
+			if got := w.Header().Get("X-Handled"); got != test.after {
+				t.Errorf("unexpected X-Handled header:\n- want: %v\n-  got: %v", test.after, got)
+			}
+			if len(handled) != test.handled {
+				t.Errorf("unexpected handled errors:\n- want: %v\n-  got: %v", test.handled, handled)
+			}
+		})
+	}
+}
+
+// BenchmarkHandle compares Handle with the go-kit server of the same
+// method, decoder and encoder.
+func BenchmarkHandle(b *testing.B) {
+	method := func(ctx context.Context, req *typepb.Field) (*wrapperspb.StringValue, error) {
+		return wrapperspb.String(req.Name), nil
+	}
+	benchmarks := []struct {
+		name    string
+		handler http.Handler
+	}{
+		{name: "handle", handler: httpkit.Handle(method)},
+		{name: "go-kit server", handler: httptransport.NewServer(
+			func(ctx context.Context, request interface{}) (interface{}, error) {
+				return method(ctx, request.(*typepb.Field))
+			},
+			httpkit.DecodeProtoJSONRequest[*typepb.Field](),
+			httpkit.EncodeProtoJSONResponse,
+			httptransport.ServerErrorEncoder(httpkit.ErrorEncoder),
+		)},
+	}
+	body := []byte(`{"name":"cpu","number":1,"typeUrl":"type.googleapis.com/metrics.Sample"}`)
+	for _, bm := range benchmarks {
+		b.Run(bm.name, func(b *testing.B) {
+			w := &discardResponseWriter{header: make(http.Header)}
+			r := httptest.NewRequest(http.MethodPost, "/v1/metrics", nil)
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				r.Body = io.NopCloser(bytes.NewReader(body))
+				bm.handler.ServeHTTP(w, r)
+			}
+		})
+	}
+}
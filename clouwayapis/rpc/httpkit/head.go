@@ -0,0 +1,57 @@
+package httpkit
+
+import (
+	"net/http"
+	"strconv"
+)
+
+// HeadFromGet is an HTTP middleware that serves the HEAD requests by running
+// the next handler as for GET request and discarding the body of the
+// response. The headers are kept as written by the handler and the
+// Content-Length is set to the length of the discarded body unless the
+// handler has set it explicitly.
+func HeadFromGet(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodHead {
+			next.ServeHTTP(w, r)
+			return
+		}
+		serveHead(next, w, r)
+	})
+}
+
+func serveHead(h http.Handler, w http.ResponseWriter, r *http.Request) {
+	get := r.Clone(r.Context())
+	get.Method = http.MethodGet
+
+	hw := &headWriter{ResponseWriter: w, code: http.StatusOK}
+	h.ServeHTTP(hw, get)
+
+	if w.Header().Get("Content-Length") == "" && hw.code != http.StatusNotModified && hw.code != http.StatusNoContent {
+		w.Header().Set("Content-Length", strconv.FormatInt(hw.length, 10))
+	}
+	w.WriteHeader(hw.code)
+}
+
+// headWriter discards the body of the response and delays the writing of
+// the status code until the length of the body is known.
+type headWriter struct {
+	http.ResponseWriter
+	code        int
+	wroteHeader bool
+	length      int64
+}
+
+func (w *headWriter) WriteHeader(code int) {
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+	w.code = code
+}
+
+func (w *headWriter) Write(b []byte) (int, error) {
+	w.wroteHeader = true
+	w.length += int64(len(b))
+	return len(b), nil
+}
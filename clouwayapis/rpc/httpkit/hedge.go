@@ -0,0 +1,192 @@
+package httpkit
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ClientHedging enables the hedging of the GET and HEAD requests of the
+// Client: when a request doesn't complete within the percentile of the
+// latencies of the recent requests, e.g. 0.95, a second attempt is sent to
+// the next of the backends, and the first successful response is used. The
+// backends are base URLs such as "https://replica-2.example.com", and the
+// second attempt is sent to the same URL as the first one when there are
+// none. The requests are not hedged until enough latencies are observed.
+func ClientHedging(percentile float64, backends ...string) ClientOption {
+	return func(c *Client) { c.hedger = &hedger{percentile: percentile, backends: backends} }
+}
+
+// hedger keeps the recent latencies of the requests and picks the backends
+// of the hedged attempts.
+type hedger struct {
+	percentile float64
+	backends   []string
+
+	mu        sync.Mutex
+	latencies []time.Duration
+	next      int
+	backend   int
+}
+
+const (
+	// maxLatencies is the number of the recent latencies of the hedger.
+	maxLatencies = 256
+	// minLatencies is the number of the latencies that are needed to
+	// hedge the requests.
+	minLatencies = 20
+)
+
+// observe records the latency of a successful attempt.
+func (h *hedger) observe(d time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if len(h.latencies) < maxLatencies {
+		h.latencies = append(h.latencies, d)
+		return
+	}
+	h.latencies[h.next] = d
+	h.next = (h.next + 1) % maxLatencies
+}
+
+// delay returns the delay after which the requests are hedged.
+func (h *hedger) delay() (time.Duration, bool) {
+	h.mu.Lock()
+	latencies := append([]time.Duration(nil), h.latencies...)
+	h.mu.Unlock()
+	if len(latencies) < minLatencies {
+		return 0, false
+	}
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	return latencies[int(h.percentile*float64(len(latencies)-1))], true
+}
+
+// hedge returns the request of the hedged attempt.
+func (h *hedger) hedge(ctx context.Context, r *http.Request) (*http.Request, error) {
+	hedged := r.Clone(ctx)
+	if len(h.backends) == 0 {
+		return hedged, nil
+	}
+	h.mu.Lock()
+	backend := h.backends[h.backend%len(h.backends)]
+	h.backend++
+	h.mu.Unlock()
+
+	base, err := hedged.URL.Parse(backend)
+	if err != nil {
+		return nil, err
+	}
+	hedged.URL.Scheme, hedged.URL.Host, hedged.Host = base.Scheme, base.Host, ""
+	return hedged, nil
+}
+
+type hedgedResult struct {
+	// attempt is the index of the attempt of the result.
+	attempt int
+	resp    *http.Response
+	err     error
+	cancel  context.CancelFunc
+}
+
+func (r hedgedResult) success() bool {
+	return r.err == nil && r.resp.StatusCode < http.StatusInternalServerError
+}
+
+// send sends the request, hedging it when it's enabled.
+func (c *Client) send(r *http.Request) (*http.Response, error) {
+	if c.hedger == nil {
+		return c.client.Do(r)
+	}
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return c.client.Do(r)
+	}
+	delay, ok := c.hedger.delay()
+
+	results := make(chan hedgedResult, 2)
+	// cancels are the cancel functions of the attempts, so the losers are
+	// cancelled as soon as the winner is known.
+	var cancels []context.CancelFunc
+	attempt := func(i int, r *http.Request, cancel context.CancelFunc) {
+		start := time.Now()
+		resp, err := c.client.Do(r)
+		result := hedgedResult{attempt: i, resp: resp, err: err, cancel: cancel}
+		if result.success() {
+			c.hedger.observe(time.Since(start))
+		}
+		results <- result
+	}
+	ctx, cancel := context.WithCancel(r.Context())
+	cancels = append(cancels, cancel)
+	go attempt(0, r.Clone(ctx), cancel)
+	launched := 1
+
+	var timer <-chan time.Time
+	if ok {
+		t := time.NewTimer(delay)
+		defer t.Stop()
+		timer = t.C
+	}
+	var last hedgedResult
+	for received := 0; received < launched; {
+		select {
+		case <-timer:
+			ctx, cancel := context.WithCancel(r.Context())
+			hedged, err := c.hedger.hedge(ctx, r)
+			if err != nil {
+				cancel()
+				continue
+			}
+			cancels = append(cancels, cancel)
+			go attempt(launched, hedged, cancel)
+			launched++
+		case result := <-results:
+			received++
+			if !result.success() && received < launched {
+				discard(result)
+				continue
+			}
+			for i, cancel := range cancels {
+				if i != result.attempt {
+					cancel()
+				}
+			}
+			go func(pending int) {
+				for ; pending > 0; pending-- {
+					discard(<-results)
+				}
+			}(launched - received)
+			last = result
+			received = launched
+		}
+	}
+	if last.err != nil {
+		last.cancel()
+		return nil, last.err
+	}
+	last.resp.Body = &cancelBody{ReadCloser: last.resp.Body, cancel: last.cancel}
+	return last.resp, nil
+}
+
+// discard releases the result of an attempt that is not used.
+func discard(r hedgedResult) {
+	r.cancel()
+	if r.resp != nil {
+		r.resp.Body.Close()
+	}
+}
+
+// cancelBody cancels the context of the request when the body of its
+// response is closed.
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
@@ -0,0 +1,63 @@
+package httpkit_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/clouway/go-genproto/clouwayapis/rpc/httpkit"
+)
+
+func TestClientHedging(t *testing.T) {
+	requests := 0
+	cancelled := make(chan struct{}, 1)
+	primary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests > 20 {
+			select {
+			case <-r.Context().Done():
+				cancelled <- struct{}{}
+			case <-time.After(time.Second):
+			}
+		}
+		io.WriteString(w, "primary")
+	}))
+	defer primary.Close()
+	replica := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "replica")
+	}))
+	defer replica.Close()
+	client := httpkit.NewClient(httpkit.ClientHedging(0.95, replica.URL))
+
+	get := func() (string, time.Duration) {
+		start := time.Now()
+		r, _ := http.NewRequest(http.MethodGet, primary.URL+"/v1/orders", nil)
+		resp, err := client.Do(r)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return string(body), time.Since(start)
+	}
+	for i := 0; i < 20; i++ {
+		if body, _ := get(); body != "primary" {
+			t.Fatalf("unexpected body of request %d: %v", i, body)
+		}
+	}
+
+	body, latency := get()
+	if body != "replica" {
+		t.Errorf("unexpected body of hedged request:\n- want: %v\n-  got: %v", "replica", body)
+	}
+	if latency > 500*time.Millisecond {
+		t.Errorf("unexpected latency of hedged request: %v", latency)
+	}
+	select {
+	case <-cancelled:
+	case <-time.After(500 * time.Millisecond):
+		t.Errorf("the slow attempt was not cancelled")
+	}
+}
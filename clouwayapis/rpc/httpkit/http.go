@@ -2,15 +2,19 @@ package httpkit
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/url"
+	"reflect"
 	"strings"
 
 	"github.com/clouway/go-genproto/clouwayapis/rpc/fileserve"
 	"github.com/clouway/go-genproto/clouwayapis/rpc/request"
+	httptransport "github.com/go-kit/kit/transport/http"
 	"google.golang.org/protobuf/encoding/protojson"
 	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/emptypb"
 )
 
 // UnmarshalJSON decodes the message bytes into a protobuf message.
@@ -21,7 +25,12 @@ func UnmarshalJSON(b []byte, m proto.Message) error {
 
 // EncodeHTTPGenericResponse is a transport/http.EncodeResponseFunc that encodes
 // the response as JSON to the response writer. Primarily useful in a server.
-func EncodeHTTPGenericResponse(_ context.Context, w http.ResponseWriter, response interface{}) error {
+// Nil and *emptypb.Empty responses are written as 204 No Content.
+func EncodeHTTPGenericResponse(ctx context.Context, w http.ResponseWriter, response interface{}) error {
+	if isNoContent(response) {
+		return EncodeNoContentResponse(ctx, w, response)
+	}
+
 	if f, ok := response.(*fileserve.BinaryFile); ok {
 		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%s", url.QueryEscape(f.FileName)))
 		if f.ContentType == "application/pdf" {
@@ -42,6 +51,95 @@ func EncodeHTTPGenericResponse(_ context.Context, w http.ResponseWriter, respons
 	return nil
 }
 
+// EncodeProtoJSONResponse is a transport/http.EncodeResponseFunc that encodes
+// proto.Message responses with protojson, using the same options as
+// EncodeHTTPGenericResponse. Nil and *emptypb.Empty responses are written as
+// 204 No Content.
+func EncodeProtoJSONResponse(ctx context.Context, w http.ResponseWriter, response interface{}) error {
+	return defaultProtoJSONResponseEncoder(ctx, w, response)
+}
+
+var defaultProtoJSONResponseEncoder = NewProtoJSONResponseEncoder(protojson.MarshalOptions{EmitUnpopulated: true, UseProtoNames: false})
+
+// NewProtoJSONResponseEncoder returns an EncodeResponseFunc that encodes the
+// responses as JSON with Content-Type header set to JSONContentType. The
+// proto.Message responses are encoded by the passed marshaller and all other
+// responses by encoding/json. Nil and *emptypb.Empty responses are written
+// as 204 No Content.
+func NewProtoJSONResponseEncoder(marshaller protojson.MarshalOptions) httptransport.EncodeResponseFunc {
+	return func(ctx context.Context, w http.ResponseWriter, response interface{}) error {
+		if isNoContent(response) {
+			return EncodeNoContentResponse(ctx, w, response)
+		}
+
+		var (
+			b   []byte
+			err error
+		)
+		if m, ok := response.(proto.Message); ok {
+			b, err = marshaller.Marshal(m)
+		} else {
+			b, err = json.Marshal(response)
+		}
+		if err != nil {
+			return err
+		}
+		w.Header().Set("Content-Type", JSONContentType)
+		w.Write(b)
+		return nil
+	}
+}
+
+// EncodeNoContentResponse is a transport/http.EncodeResponseFunc that
+// responds with 204 No Content and no body regardless of the response. It's
+// meant for the endpoints such as deletes that return the removed resource,
+// which strict HTTP clients and caches expect without a body.
+func EncodeNoContentResponse(_ context.Context, w http.ResponseWriter, _ interface{}) error {
+	w.Header().Del("Content-Type")
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+// isNoContent reports whether the response has no content, i.e. it's nil or
+// *emptypb.Empty.
+func isNoContent(response interface{}) bool {
+	if _, ok := response.(*emptypb.Empty); ok {
+		return true
+	}
+	return isNil(response)
+}
+
+// isNil reports whether v is nil or a nil pointer.
+func isNil(v interface{}) bool {
+	if v == nil {
+		return true
+	}
+	rv := reflect.ValueOf(v)
+	return rv.Kind() == reflect.Ptr && rv.IsNil()
+}
+
+// WriteError writes the error to the response writer in the same way as the
+// ErrorEncoder of the go-kit HTTP servers. It's useful for plain
+// http.HandlerFunc handlers.
+func WriteError(ctx context.Context, w http.ResponseWriter, err error) {
+	ErrorEncoder(ctx, err, w)
+}
+
+// WriteJSON writes the message as JSON with the passed status code. The
+// message is encoded with the same options as EncodeHTTPGenericResponse.
+func WriteJSON(_ context.Context, w http.ResponseWriter, code int, msg proto.Message) error {
+	marshaller := protojson.MarshalOptions{EmitUnpopulated: true, UseProtoNames: false}
+
+	b, err := marshaller.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	w.Header().Set("Content-Type", JSONContentType)
+	w.WriteHeader(code)
+	w.Write(b)
+	return nil
+}
+
 // HeadersToContext adds all HTTP header values into the passed context.Context. The keys
 // are added with request.ContextKey as and lookups should be performed by using the same
 // type.
@@ -12,6 +12,7 @@ import (
 	"github.com/clouway/go-genproto/clouwayapis/rpc/fileserve"
 	"github.com/clouway/go-genproto/clouwayapis/rpc/httpkit"
 	"github.com/clouway/go-genproto/clouwayapis/rpc/request"
+	"google.golang.org/protobuf/types/known/emptypb"
 )
 
 func TestEncodeHTTPGenericResponse(t *testing.T) {
@@ -27,6 +28,20 @@ func TestEncodeHTTPGenericResponse(t *testing.T) {
 	}
 }
 
+func TestEncodeHTTPGenericResponseWithEmpty(t *testing.T) {
+	w := httptest.NewRecorder()
+	if err := httpkit.EncodeHTTPGenericResponse(context.Background(), w, &emptypb.Empty{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if w.Code != http.StatusNoContent {
+		t.Errorf("unexpected status code:\n- want: %v\n-  got: %v", http.StatusNoContent, w.Code)
+	}
+	if got := w.Body.Len(); got != 0 {
+		t.Errorf("unexpected body length:\n- want: %v\n-  got: %v", 0, got)
+	}
+}
+
 func TestEncodeBinaryFile(t *testing.T) {
 	protoResponse := &fileserve.BinaryFile{ContentType: "image/jpg", FileName: "MyImage.jpg", Content: []byte("::content::")}
 
@@ -147,3 +162,73 @@ func TestCookiesToContext(t *testing.T) {
 		t.Errorf("unexpected cookie value in context :\n- want: %v\n-  got: %v", want, got)
 	}
 }
+
+func TestWriteJSON(t *testing.T) {
+	w := httptest.NewRecorder()
+	err := httpkit.WriteJSON(context.Background(), w, http.StatusCreated, &errdetails.ErrorInfo{Reason: "Test Reason"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if w.Code != http.StatusCreated {
+		t.Errorf("unexpected status code:\n- want: %v\n-  got: %v", http.StatusCreated, w.Code)
+	}
+	if want, got := httpkit.JSONContentType, w.Header().Get("Content-Type"); want != got {
+		t.Errorf("unexpected Content-Type header:\n- want: %v\n-  got: %v", want, got)
+	}
+	if want, got := `{"reason":"Test Reason","domain":"","metadata":{}}`, compactJSON(w.Body.Bytes()); want != got {
+		t.Errorf("unexpected response of WriteJSON:\n- want: %v\n-  got: %v", want, got)
+	}
+}
+
+func TestEncodeProtoJSONResponse(t *testing.T) {
+	tests := []struct {
+		name        string
+		response    interface{}
+		status      int
+		contentType string
+		body        string
+	}{
+		{name: "proto message", response: &errdetails.ErrorInfo{Reason: "Test Reason"}, status: http.StatusOK, contentType: httpkit.JSONContentType, body: `{"reason":"Test Reason","domain":"","metadata":{}}`},
+		{name: "plain value", response: map[string]string{"id": "123"}, status: http.StatusOK, contentType: httpkit.JSONContentType, body: `{"id":"123"}`},
+		{name: "nil message", response: (*errdetails.ErrorInfo)(nil), status: http.StatusNoContent},
+		{name: "nil", response: nil, status: http.StatusNoContent},
+		{name: "empty", response: &emptypb.Empty{}, status: http.StatusNoContent},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			if err := httpkit.EncodeProtoJSONResponse(context.Background(), w, test.response); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if w.Code != test.status {
+				t.Errorf("unexpected status code:\n- want: %v\n-  got: %v", test.status, w.Code)
+			}
+			if got := w.Header().Get("Content-Type"); got != test.contentType {
+				t.Errorf("unexpected Content-Type header:\n- want: %v\n-  got: %v", test.contentType, got)
+			}
+			if got := compactJSON(w.Body.Bytes()); got != test.body {
+				t.Errorf("unexpected body:\n- want: %v\n-  got: %v", test.body, got)
+			}
+		})
+	}
+}
+
+func TestEncodeNoContentResponse(t *testing.T) {
+	w := httptest.NewRecorder()
+	w.Header().Set("Content-Type", httpkit.JSONContentType)
+	if err := httpkit.EncodeNoContentResponse(context.Background(), w, &errdetails.ErrorInfo{Reason: "Test Reason"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if w.Code != http.StatusNoContent {
+		t.Errorf("unexpected status code:\n- want: %v\n-  got: %v", http.StatusNoContent, w.Code)
+	}
+	if got := w.Header().Get("Content-Type"); got != "" {
+		t.Errorf("unexpected Content-Type header:\n- want: %v\n-  got: %v", "", got)
+	}
+	if got := w.Body.Len(); got != 0 {
+		t.Errorf("unexpected body length:\n- want: %v\n-  got: %v", 0, got)
+	}
+}
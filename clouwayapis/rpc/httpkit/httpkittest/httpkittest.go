@@ -0,0 +1,103 @@
+// Package httpkittest provides utilities for testing of the public error
+// contract of services that are using httpkit. The encoded errors are
+// recorded and compared with golden files that are kept next to the tests.
+// The golden files are rewritten with the recorded bodies when the tests are
+// run with the -golden.update flag.
+package httpkittest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	httptransport "github.com/go-kit/kit/transport/http"
+)
+
+var update = flag.Bool("golden.update", false, "update the golden files of httpkittest")
+
+// Recorder is an http.ResponseWriter that records the response and the
+// number of times the status code was written.
+type Recorder struct {
+	*httptest.ResponseRecorder
+
+	// WriteHeaderCalls is the number of WriteHeader calls.
+	WriteHeaderCalls int
+}
+
+// NewRecorder returns an initialized Recorder.
+func NewRecorder() *Recorder {
+	return &Recorder{ResponseRecorder: httptest.NewRecorder()}
+}
+
+// WriteHeader records the status code and counts the calls.
+func (r *Recorder) WriteHeader(code int) {
+	r.WriteHeaderCalls++
+	r.ResponseRecorder.WriteHeader(code)
+}
+
+// EncodeError encodes the error with the passed error encoder and returns
+// the recorded response.
+func EncodeError(ctx context.Context, ee httptransport.ErrorEncoder, err error) *Recorder {
+	rec := NewRecorder()
+	ee(ctx, err, rec)
+	return rec
+}
+
+// AssertStatus fails the test when the recorded status code is not the
+// wanted one or it was written more than once.
+func AssertStatus(t testing.TB, rec *Recorder, want int) {
+	t.Helper()
+	if rec.Code != want {
+		t.Errorf("unexpected status code:\n- want: %v\n-  got: %v", want, rec.Code)
+	}
+	if rec.WriteHeaderCalls > 1 {
+		t.Errorf("unexpected calls of WriteHeader:\n- want: %v\n-  got: %v", 1, rec.WriteHeaderCalls)
+	}
+}
+
+// AssertHeader fails the test when the recorded header is not the wanted
+// one.
+func AssertHeader(t testing.TB, rec *Recorder, name, want string) {
+	t.Helper()
+	if got := rec.Header().Get(name); got != want {
+		t.Errorf("unexpected %s header:\n- want: %v\n-  got: %v", name, want, got)
+	}
+}
+
+// AssertGolden fails the test when the recorded body differs from the
+// content of the golden file. JSON bodies are compared without the
+// insignificant whitespace, as protojson does not guarantee stable output.
+func AssertGolden(t testing.TB, rec *Recorder, path string) {
+	t.Helper()
+	got := rec.Body.Bytes()
+	if *update {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("unable to create golden file directory: %v", err)
+		}
+		if err := os.WriteFile(path, got, 0644); err != nil {
+			t.Fatalf("unable to update golden file: %v", err)
+		}
+		return
+	}
+
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("unable to read golden file: %v", err)
+	}
+	if !bytes.Equal(normalize(want), normalize(got)) {
+		t.Errorf("unexpected body of golden file %s:\n- want: %s\n-  got: %s", path, want, got)
+	}
+}
+
+func normalize(b []byte) []byte {
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, bytes.TrimSpace(b)); err != nil {
+		return bytes.TrimSpace(b)
+	}
+	return buf.Bytes()
+}
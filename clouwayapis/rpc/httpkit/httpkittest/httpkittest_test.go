@@ -0,0 +1,28 @@
+package httpkittest_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/clouway/go-genproto/clouwayapis/rpc/errdetails"
+	"github.com/clouway/go-genproto/clouwayapis/rpc/httpkit"
+	"github.com/clouway/go-genproto/clouwayapis/rpc/httpkit/httpkittest"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestAssertGolden(t *testing.T) {
+	st, _ := status.New(codes.AlreadyExists, "already exists").WithDetails(&errdetails.BadRequest{
+		Message: "item already added",
+		Errors: []*errdetails.BadRequest_FieldViolation{
+			{Reason: "Item with id '123' already exists", Field: "itemId"},
+		},
+	})
+
+	rec := httpkittest.EncodeError(context.Background(), httpkit.ErrorEncoder, st.Err())
+
+	httpkittest.AssertStatus(t, rec, http.StatusConflict)
+	httpkittest.AssertHeader(t, rec, "Content-Type", httpkit.JSONContentType)
+	httpkittest.AssertGolden(t, rec, "testdata/already_exists.json")
+}
@@ -0,0 +1,221 @@
+package httpkit
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/clouway/go-genproto/clouwayapis/rpc/request"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// IdempotencyKeyHeader is the header with the idempotency key of the
+	// request that is set by the clients that retry it.
+	IdempotencyKeyHeader = "Idempotency-Key"
+	// IdempotentReplayedHeader marks the responses that are replayed from
+	// the idempotency store.
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+)
+
+// StoredResponse is a response that is kept in the idempotency store.
+type StoredResponse struct {
+	// Fingerprint identifies the request of the response by its method,
+	// path and body.
+	Fingerprint string
+	StatusCode  int
+	Header      http.Header
+	Body        []byte
+}
+
+// IdempotencyStore keeps the responses of the requests by their
+// idempotency key, e.g. in memory or in Redis for the keys that are shared
+// by the replicas. Get returns nil response and nil error for unknown keys.
+type IdempotencyStore interface {
+	Get(ctx context.Context, key string) (*StoredResponse, error)
+	Put(ctx context.Context, key string, resp *StoredResponse, ttl time.Duration) error
+}
+
+// MemoryIdempotencyStore is an IdempotencyStore that keeps the responses in
+// memory.
+type MemoryIdempotencyStore struct {
+	mu        sync.Mutex
+	responses map[string]storedEntry
+}
+
+type storedEntry struct {
+	resp    *StoredResponse
+	expires time.Time
+}
+
+// NewMemoryIdempotencyStore creates an empty in-memory store.
+func NewMemoryIdempotencyStore() *MemoryIdempotencyStore {
+	return &MemoryIdempotencyStore{responses: map[string]storedEntry{}}
+}
+
+// Get returns the response of the key.
+func (s *MemoryIdempotencyStore) Get(_ context.Context, key string) (*StoredResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.responses[key]
+	if !ok || time.Now().After(e.expires) {
+		return nil, nil
+	}
+	return e.resp, nil
+}
+
+// Put stores the response of the key for the TTL. The expired responses
+// are evicted on every Put.
+func (s *MemoryIdempotencyStore) Put(_ context.Context, key string, resp *StoredResponse, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	for k, e := range s.responses {
+		if now.After(e.expires) {
+			delete(s.responses, k)
+		}
+	}
+	s.responses[key] = storedEntry{resp: resp, expires: now.Add(ttl)}
+	return nil
+}
+
+// IdempotencyOption sets an optional parameter for the Idempotency
+// middleware.
+type IdempotencyOption func(*idempotency)
+
+// IdempotencyScope sets the func that returns the scope of the idempotency
+// keys of the request, so the callers that pick the same keys don't get
+// the responses of each other. It's the subject of the authenticated
+// caller, as it's returned by request.SubjectFromContext, by default.
+func IdempotencyScope(scope func(r *http.Request) string) IdempotencyOption {
+	return func(i *idempotency) { i.scope = scope }
+}
+
+type idempotency struct {
+	scope func(r *http.Request) string
+}
+
+// Idempotency is an HTTP middleware that makes the retries of the POST and
+// PATCH requests with an Idempotency-Key header safe. The response of the
+// first request with the key is kept in the store for the TTL, and the
+// retries get it replayed with the Idempotent-Replayed: true header instead
+// of being handled again. The 5xx responses are not kept, so the retries of
+// the requests that failed are handled again.
+//
+// The keys are scoped by the caller, see IdempotencyScope. The requests that
+// reuse a key with a different method, path or body, and the requests whose
+// key is still being handled are rejected with 409 Aborted errors. The
+// requests without the header are handled as usual.
+func Idempotency(store IdempotencyStore, ttl time.Duration, options ...IdempotencyOption) func(http.Handler) http.Handler {
+	i := &idempotency{scope: func(r *http.Request) string { return request.SubjectFromContext(r.Context()) }}
+	for _, option := range options {
+		option(i)
+	}
+	var mu sync.Mutex
+	inFlight := map[string]bool{}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := r.Header.Get(IdempotencyKeyHeader)
+			if key == "" || (r.Method != http.MethodPost && r.Method != http.MethodPatch) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			ctx := r.Context()
+			body, err := io.ReadAll(r.Body)
+			if err != nil {
+				ErrorEncoder(ctx, err, w)
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+			fingerprint := requestFingerprint(r, body)
+			storeKey := i.scope(r) + "\x00" + key
+
+			// The key is marked in flight before the store is checked, so the
+			// concurrent requests with the key are never handled twice.
+			mu.Lock()
+			if inFlight[storeKey] {
+				mu.Unlock()
+				ErrorEncoder(ctx, status.Errorf(codes.Aborted, "request with idempotency key %q is in progress", key), w)
+				return
+			}
+			inFlight[storeKey] = true
+			mu.Unlock()
+			defer func() {
+				mu.Lock()
+				delete(inFlight, storeKey)
+				mu.Unlock()
+			}()
+
+			stored, err := store.Get(ctx, storeKey)
+			if err != nil {
+				ErrorEncoder(ctx, err, w)
+				return
+			}
+			if stored != nil {
+				if stored.Fingerprint != fingerprint {
+					ErrorEncoder(ctx, status.Errorf(codes.Aborted, "idempotency key %q is used by a different request", key), w)
+					return
+				}
+				for k, v := range stored.Header {
+					w.Header()[k] = v
+				}
+				w.Header().Set(IdempotentReplayedHeader, "true")
+				w.WriteHeader(stored.StatusCode)
+				w.Write(stored.Body)
+				return
+			}
+
+			rw := &recordingWriter{ResponseWriter: w, code: http.StatusOK}
+			next.ServeHTTP(rw, r)
+			if rw.code >= http.StatusInternalServerError {
+				return
+			}
+			store.Put(ctx, storeKey, &StoredResponse{
+				Fingerprint: fingerprint,
+				StatusCode:  rw.code,
+				Header:      w.Header().Clone(),
+				Body:        rw.body.Bytes(),
+			}, ttl)
+		})
+	}
+}
+
+// requestFingerprint returns the hash of the method, the path and the body
+// of the request.
+func requestFingerprint(r *http.Request, body []byte) string {
+	h := sha256.New()
+	io.WriteString(h, r.Method+" "+r.URL.RequestURI()+"\n")
+	h.Write(body)
+	return fmt.Sprintf("%x", h.Sum(nil))
+}
+
+// recordingWriter keeps a copy of the status and the body of the response.
+type recordingWriter struct {
+	http.ResponseWriter
+	code        int
+	body        bytes.Buffer
+	wroteHeader bool
+}
+
+func (w *recordingWriter) WriteHeader(code int) {
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+	w.code = code
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *recordingWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
@@ -0,0 +1,65 @@
+package httpkit_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/clouway/go-genproto/clouwayapis/rpc/httpkit"
+	"github.com/clouway/go-genproto/clouwayapis/rpc/request"
+)
+
+func TestIdempotency(t *testing.T) {
+	calls := 0
+	handler := httpkit.Idempotency(httpkit.NewMemoryIdempotencyStore(), time.Hour)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Location", "/v1/payments/1")
+		w.WriteHeader(http.StatusCreated)
+		w.Write(body)
+	}))
+
+	tests := []struct {
+		name     string
+		key      string
+		subject  string
+		body     string
+		status   int
+		replayed string
+		calls    int
+	}{
+		{name: "first", key: "key-1", body: `{"amount":10}`, status: http.StatusCreated, calls: 1},
+		{name: "retry", key: "key-1", body: `{"amount":10}`, status: http.StatusCreated, replayed: "true", calls: 1},
+		{name: "different payload", key: "key-1", body: `{"amount":20}`, status: http.StatusConflict, calls: 1},
+		{name: "other key", key: "key-2", body: `{"amount":20}`, status: http.StatusCreated, calls: 2},
+		{name: "other caller", key: "key-1", subject: "other", body: `{"amount":20}`, status: http.StatusCreated, calls: 3},
+		{name: "without key", body: `{"amount":10}`, status: http.StatusCreated, calls: 4},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/v1/payments", strings.NewReader(test.body))
+			if test.key != "" {
+				r.Header.Set(httpkit.IdempotencyKeyHeader, test.key)
+			}
+			r = r.WithContext(request.WithSubject(r.Context(), test.subject))
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+
+			if w.Code != test.status {
+				t.Errorf("unexpected status code:\n- want: %v\n-  got: %v", test.status, w.Code)
+			}
+			if got := w.Header().Get(httpkit.IdempotentReplayedHeader); got != test.replayed {
+				t.Errorf("unexpected Idempotent-Replayed header:\n- want: %v\n-  got: %v", test.replayed, got)
+			}
+			if test.status == http.StatusCreated && (w.Body.String() != test.body || w.Header().Get("Location") != "/v1/payments/1") {
+				t.Errorf("unexpected response: %v %v", w.Header(), w.Body.String())
+			}
+			if calls != test.calls {
+				t.Errorf("unexpected calls:\n- want: %v\n-  got: %v", test.calls, calls)
+			}
+		})
+	}
+}
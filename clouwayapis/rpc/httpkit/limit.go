@@ -0,0 +1,38 @@
+package httpkit
+
+import (
+	"io"
+	"net/http"
+)
+
+// MaxBytes is an HTTP middleware that limits the size of the request bodies
+// by wrapping them with http.MaxBytesReader. Reading beyond the limit fails
+// with the error of NewPayloadTooLargeError, so decoders that return the
+// read errors as they are get the request rejected with 413 by ErrorEncoder.
+func MaxBytes(limit int64) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.ContentLength > limit {
+				ErrorEncoder(r.Context(), NewPayloadTooLargeError(limit), w)
+				return
+			}
+			r.Body = &maxBytesBody{ReadCloser: http.MaxBytesReader(w, r.Body, limit), limit: limit}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+type maxBytesBody struct {
+	io.ReadCloser
+	limit int64
+	read  int64
+}
+
+func (b *maxBytesBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.read += int64(n)
+	if err != nil && err != io.EOF && b.read >= b.limit {
+		return n, NewPayloadTooLargeError(b.limit)
+	}
+	return n, err
+}
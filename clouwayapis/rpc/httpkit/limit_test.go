@@ -0,0 +1,68 @@
+package httpkit_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/clouway/go-genproto/clouwayapis/rpc/httpkit"
+)
+
+func TestMaxBytes(t *testing.T) {
+	handler := httpkit.MaxBytes(4)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, err := io.ReadAll(r.Body); err != nil {
+			httpkit.ErrorEncoder(context.Background(), err, w)
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+
+	tests := []struct {
+		name          string
+		body          string
+		contentLength int64
+		status        int
+		response      string
+	}{
+		{name: "within the limit", body: "1234", contentLength: 4, status: http.StatusOK, response: "ok"},
+		{name: "declared length over the limit", body: "12345", contentLength: 5, status: http.StatusRequestEntityTooLarge, response: `{"message":"request body exceeds the limit of 4 bytes"}`},
+		{name: "unknown length over the limit", body: "12345", contentLength: -1, status: http.StatusRequestEntityTooLarge, response: `{"message":"request body exceeds the limit of 4 bytes"}`},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(test.body))
+			req.ContentLength = test.contentLength
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != test.status {
+				t.Errorf("unexpected status code:\n- want: %v\n-  got: %v", test.status, rec.Code)
+			}
+			if got := rec.Body.String(); got != test.response {
+				t.Errorf("unexpected body:\n- want: %v\n-  got: %v", test.response, got)
+			}
+		})
+	}
+}
+
+func TestPayloadTooLargeErrorMessage(t *testing.T) {
+	err := httpkit.NewPayloadTooLargeError(1024)
+	want := "request body exceeds the limit of 1024 bytes"
+	if got := err.Error(); got != want {
+		t.Errorf("unexpected error message:\n- want: %v\n-  got: %v", want, got)
+	}
+
+	rec := httptest.NewRecorder()
+	httpkit.NewErrorEncoder(httpkit.ErrorEncoderXML())(context.Background(), err, rec)
+
+	if rec.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("unexpected status code:\n- want: %v\n-  got: %v", http.StatusRequestEntityTooLarge, rec.Code)
+	}
+	wantBody := `<error><message>` + want + `</message><code>413</code></error>`
+	if got := rec.Body.String(); got != wantBody {
+		t.Errorf("unexpected body:\n- want: %v\n-  got: %v", wantBody, got)
+	}
+}
@@ -0,0 +1,39 @@
+package httpkit
+
+import (
+	"bytes"
+
+	"github.com/vmihailenco/msgpack/v5"
+	"google.golang.org/protobuf/proto"
+)
+
+// MsgpackContentType is the content type of MessagePack messages.
+const MsgpackContentType = "application/msgpack"
+
+// MarshalMsgpack encodes the message as MessagePack. The document has the
+// shape of the JSON mapping of the message, e.g. the fields are in
+// lowerCamelCase and the Timestamps are RFC 3339 strings, while the 64-bit
+// integers and the bytes are encoded natively instead of as strings.
+func MarshalMsgpack(m proto.Message) ([]byte, error) {
+	v, err := protoToValue(m)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	enc := msgpack.NewEncoder(&buf)
+	enc.SetSortMapKeys(true)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// UnmarshalMsgpack decodes the MessagePack document into the message. The
+// unknown fields are ignored in the same way as by UnmarshalJSON.
+func UnmarshalMsgpack(b []byte, m proto.Message) error {
+	var v interface{}
+	if err := msgpack.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	return valueToProto(v, m)
+}
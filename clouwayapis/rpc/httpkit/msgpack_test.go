@@ -0,0 +1,140 @@
+package httpkit_test
+
+import (
+	"bytes"
+	"context"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/clouway/go-genproto/clouwayapis/rpc/errdetails"
+	"github.com/clouway/go-genproto/clouwayapis/rpc/httpkit"
+	"github.com/clouway/go-genproto/clouwayapis/rpc/request"
+	"github.com/vmihailenco/msgpack/v5"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
+	"google.golang.org/protobuf/types/known/apipb"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"google.golang.org/protobuf/types/known/typepb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+func TestMsgpackRoundTrip(t *testing.T) {
+	want := &apipb.Method{Name: "GetOrder", RequestStreaming: true, Syntax: 1}
+
+	b, err := httpkit.MarshalMsgpack(want)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var doc map[string]interface{}
+	msgpack.Unmarshal(b, &doc)
+	if got := doc["requestStreaming"]; got != true {
+		t.Errorf("unexpected requestStreaming:\n- want: %v\n-  got: %v", true, got)
+	}
+
+	got := &apipb.Method{}
+	if err := httpkit.UnmarshalMsgpack(b, got); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !proto.Equal(got, want) {
+		t.Errorf("unexpected message:\n- want: %v\n-  got: %v", want, got)
+	}
+}
+
+func TestMsgpackWellKnownTypes(t *testing.T) {
+	option, _ := anypb.New(&wrapperspb.StringValue{Value: "orders"})
+	value, _ := structpb.NewStruct(map[string]interface{}{"name": "orders", "count": 2.0, "tags": []interface{}{"a", nil}})
+	tests := []proto.Message{
+		&apipb.Api{Name: "orders", Options: []*typepb.Option{{Name: "package", Value: option}}, Mixins: []*apipb.Mixin{{Name: "billing"}}},
+		&typepb.Field{Kind: typepb.Field_TYPE_INT64, Number: -3, Packed: true},
+		timestamppb.New(time.Date(2024, 1, 2, 3, 4, 5, 6000, time.UTC)),
+		durationpb.New(-1500 * time.Millisecond),
+		&fieldmaskpb.FieldMask{Paths: []string{"source_context.file_name", "name"}},
+		&wrapperspb.Int64Value{Value: math.MaxInt64},
+		&wrapperspb.BytesValue{Value: []byte{0, 1, 2}},
+		value,
+	}
+	for _, want := range tests {
+		b, err := httpkit.MarshalMsgpack(want)
+		if err != nil {
+			t.Fatalf("unexpected error of %T: %v", want, err)
+		}
+		got := want.ProtoReflect().New().Interface()
+		if err := httpkit.UnmarshalMsgpack(b, got); err != nil {
+			t.Fatalf("unexpected error of %T: %v", want, err)
+		}
+		if !proto.Equal(got, want) {
+			t.Errorf("unexpected message:\n- want: %v\n-  got: %v", want, got)
+		}
+	}
+}
+
+func TestMsgpackNativeValues(t *testing.T) {
+	tests := []struct {
+		message proto.Message
+		want    interface{}
+	}{
+		{message: &wrapperspb.Int64Value{Value: 5}, want: int64(5)},
+		{message: &wrapperspb.BytesValue{Value: []byte("abc")}, want: []byte("abc")},
+		{message: &typepb.Field{Kind: typepb.Field_TYPE_INT64}, want: map[string]interface{}{"kind": "TYPE_INT64"}},
+	}
+	for _, test := range tests {
+		b, _ := httpkit.MarshalMsgpack(test.message)
+
+		var got interface{}
+		msgpack.Unmarshal(b, &got)
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("unexpected document of %T:\n- want: %#v\n-  got: %#v", test.message, test.want, got)
+		}
+	}
+}
+
+func TestEncodeNegotiatedResponseMsgpack(t *testing.T) {
+	ctx := context.WithValue(context.Background(), request.ContextKey("accept"), "application/msgpack")
+	w := httptest.NewRecorder()
+	if err := httpkit.EncodeNegotiatedResponse(ctx, w, &errdetails.ErrorInfo{Reason: "Test Reason"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if got := w.Header().Get("Content-Type"); got != httpkit.MsgpackContentType {
+		t.Errorf("unexpected Content-Type header:\n- want: %v\n-  got: %v", httpkit.MsgpackContentType, got)
+	}
+	got := &errdetails.ErrorInfo{}
+	if err := httpkit.UnmarshalMsgpack(w.Body.Bytes(), got); err != nil || got.Reason != "Test Reason" {
+		t.Errorf("unexpected response: %v (%v)", got, err)
+	}
+}
+
+func TestDecodeNegotiatedRequest(t *testing.T) {
+	msgpackBody, _ := httpkit.MarshalMsgpack(&errdetails.ErrorInfo{Reason: "msgpack"})
+	protoBody, _ := proto.Marshal(&errdetails.ErrorInfo{Reason: "protobuf"})
+
+	tests := []struct {
+		contentType string
+		body        []byte
+		want        string
+	}{
+		{contentType: "application/json", body: []byte(`{"reason":"json"}`), want: "json"},
+		{contentType: "application/x-msgpack", body: msgpackBody, want: "msgpack"},
+		{contentType: "application/x-protobuf", body: protoBody, want: "protobuf"},
+	}
+	decode := httpkit.DecodeNegotiatedRequest[*errdetails.ErrorInfo]()
+	for _, test := range tests {
+		r := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(test.body))
+		r.Header.Set("Content-Type", test.contentType)
+
+		got, err := decode(context.Background(), r)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if reason := got.(*errdetails.ErrorInfo).Reason; reason != test.want {
+			t.Errorf("unexpected reason of %s:\n- want: %v\n-  got: %v", test.contentType, test.want, reason)
+		}
+	}
+}
@@ -0,0 +1,164 @@
+package httpkit
+
+import (
+	"context"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/clouway/go-genproto/clouwayapis/rpc/fileserve"
+	httptransport "github.com/go-kit/kit/transport/http"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// Upload is a multipart upload that is decoded by DecodeMultipartRequest.
+type Upload[T proto.Message] struct {
+	// File is the uploaded file.
+	File *fileserve.BinaryFile
+	// Request is the message of the JSON metadata part.
+	Request T
+}
+
+// MultipartOption sets an optional parameter for DecodeMultipartRequest.
+type MultipartOption func(*multipartOptions)
+
+// MultipartFilePart sets the name of the part with the file. It's "file"
+// by default.
+func MultipartFilePart(name string) MultipartOption {
+	return func(o *multipartOptions) { o.filePart = name }
+}
+
+// MultipartMetadataPart sets the name of the part with the JSON metadata.
+// It's "metadata" by default.
+func MultipartMetadataPart(name string) MultipartOption {
+	return func(o *multipartOptions) { o.metadataPart = name }
+}
+
+// MultipartMaxFileBytes limits the size of the file. Larger files are
+// rejected with the error of NewPayloadTooLargeError. It's 32MiB by
+// default.
+func MultipartMaxFileBytes(limit int64) MultipartOption {
+	return func(o *multipartOptions) { o.maxFileBytes = limit }
+}
+
+// MultipartFileTypes sets the accepted media types of the file, such as
+// "application/pdf" or "image/*". All types are accepted by default.
+func MultipartFileTypes(mediaTypes ...string) MultipartOption {
+	return func(o *multipartOptions) { o.fileTypes = mediaTypes }
+}
+
+type multipartOptions struct {
+	filePart     string
+	metadataPart string
+	maxFileBytes int64
+	fileTypes    []string
+}
+
+// maxMetadataBytes limits the size of the metadata part.
+const maxMetadataBytes = 1 << 20
+
+// DecodeMultipartRequest returns a DecodeRequestFunc that decodes the
+// multipart/form-data uploads with a file part and an optional JSON
+// metadata part into *Upload[T]:
+//
+//	--boundary
+//	Content-Disposition: form-data; name="metadata"
+//
+//	{"meterId": "123"}
+//	--boundary
+//	Content-Disposition: form-data; name="file"; filename="readings.csv"
+//	Content-Type: text/csv
+//
+//	...
+//
+// The content type of the file is taken from its part, or it's detected by
+// SniffContentType when the part doesn't have one. The parts are read as
+// they are streamed, without temporary files. The uploads without a file
+// and with files of types that are not accepted are rejected as bad
+// requests, and the metadata is decoded in the same way as by
+// DecodeProtoJSONRequest.
+func DecodeMultipartRequest[T proto.Message](options ...MultipartOption) httptransport.DecodeRequestFunc {
+	o := &multipartOptions{filePart: "file", metadataPart: "metadata", maxFileBytes: 32 << 20}
+	for _, option := range options {
+		option(o)
+	}
+	return func(_ context.Context, r *http.Request) (interface{}, error) {
+		var zero T
+		upload := &Upload[T]{Request: zero.ProtoReflect().New().Interface().(T)}
+		reader, err := r.MultipartReader()
+		if err != nil {
+			return nil, NewBadRequestError("invalid multipart request: %v", err)
+		}
+		for {
+			part, err := reader.NextPart()
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if err != nil {
+				return nil, NewBadRequestError("invalid multipart request: %v", err)
+			}
+
+			switch part.FormName() {
+			case o.metadataPart:
+				b, err := readLimited(part, maxMetadataBytes)
+				if err != nil {
+					return nil, err
+				}
+				if err := (protojson.UnmarshalOptions{}).Unmarshal(b, upload.Request); err != nil {
+					return nil, newDecodeError(b, err)
+				}
+			case o.filePart:
+				b, err := readLimited(part, o.maxFileBytes)
+				if err != nil {
+					return nil, err
+				}
+				contentType := part.Header.Get("Content-Type")
+				if contentType == "" || contentType == "application/octet-stream" {
+					contentType = SniffContentType(part.FileName(), b)
+				}
+				if !acceptsFileType(o.fileTypes, contentType) {
+					return nil, NewBadRequestError("unsupported file type '%s'", contentType)
+				}
+				upload.File = &fileserve.BinaryFile{ContentType: contentType, FileName: part.FileName(), Content: b}
+			}
+			part.Close()
+		}
+		if upload.File == nil {
+			return nil, NewBadRequestError("missing file part '%s'", o.filePart)
+		}
+		return upload, nil
+	}
+}
+
+// readLimited reads the reader up to the limit.
+func readLimited(r io.Reader, limit int64) ([]byte, error) {
+	b, err := io.ReadAll(io.LimitReader(r, limit+1))
+	if err != nil {
+		return nil, NewBadRequestError("invalid multipart request: %v", err)
+	}
+	if int64(len(b)) > limit {
+		return nil, NewPayloadTooLargeError(limit)
+	}
+	return b, nil
+}
+
+// acceptsFileType reports whether the content type matches one of the
+// accepted media types.
+func acceptsFileType(accepted []string, contentType string) bool {
+	if len(accepted) == 0 {
+		return true
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	for _, a := range accepted {
+		if a == mediaType || strings.HasSuffix(a, "/*") && strings.HasPrefix(mediaType, strings.TrimSuffix(a, "*")) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,105 @@
+package httpkit_test
+
+import (
+	"bytes"
+	"context"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"testing"
+
+	"github.com/clouway/go-genproto/clouwayapis/rpc/errdetails"
+	"github.com/clouway/go-genproto/clouwayapis/rpc/httpkit"
+)
+
+func TestDecodeMultipartRequest(t *testing.T) {
+	type part struct {
+		name, fileName, contentType, content string
+	}
+	tests := []struct {
+		name        string
+		parts       []part
+		contentType string
+		reason      string
+		status      int
+	}{
+		{
+			name:        "file and metadata",
+			parts:       []part{{name: "metadata", content: `{"reason":"READINGS"}`}, {name: "file", fileName: "readings.csv", contentType: "text/csv", content: "1,2,3"}},
+			contentType: "text/csv",
+			reason:      "READINGS",
+		},
+		{
+			name:        "detected type",
+			parts:       []part{{name: "file", fileName: "readings.txt", content: "1,2,3"}},
+			contentType: "text/plain; charset=utf-8",
+		},
+		{
+			name:   "unsupported type",
+			parts:  []part{{name: "file", fileName: "image.png", contentType: "image/png", content: "..."}},
+			status: http.StatusBadRequest,
+		},
+		{
+			name:   "too large",
+			parts:  []part{{name: "file", fileName: "readings.csv", contentType: "text/csv", content: "1,2,3,4,5,6,7,8,9"}},
+			status: http.StatusRequestEntityTooLarge,
+		},
+		{
+			name:   "missing file",
+			parts:  []part{{name: "metadata", content: `{"reason":"READINGS"}`}},
+			status: http.StatusBadRequest,
+		},
+		{
+			name:   "invalid metadata",
+			parts:  []part{{name: "metadata", content: `{"unknown":"READINGS"}`}, {name: "file", fileName: "readings.csv", contentType: "text/csv", content: "1"}},
+			status: http.StatusBadRequest,
+		},
+	}
+	decode := httpkit.DecodeMultipartRequest[*errdetails.ErrorInfo](httpkit.MultipartMaxFileBytes(16), httpkit.MultipartFileTypes("text/*"))
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			body := &bytes.Buffer{}
+			mw := multipart.NewWriter(body)
+			for _, p := range test.parts {
+				h := textproto.MIMEHeader{}
+				disposition := `form-data; name="` + p.name + `"`
+				if p.fileName != "" {
+					disposition += `; filename="` + p.fileName + `"`
+				}
+				h.Set("Content-Disposition", disposition)
+				if p.contentType != "" {
+					h.Set("Content-Type", p.contentType)
+				}
+				w, _ := mw.CreatePart(h)
+				w.Write([]byte(p.content))
+			}
+			mw.Close()
+			r := httptest.NewRequest(http.MethodPost, "/v1/readings", body)
+			r.Header.Set("Content-Type", mw.FormDataContentType())
+
+			got, err := decode(context.Background(), r)
+			if test.status != 0 {
+				w := httptest.NewRecorder()
+				httpkit.ErrorEncoder(context.Background(), err, w)
+				if err == nil || w.Code != test.status {
+					t.Errorf("unexpected error status:\n- want: %v\n-  got: %v (%v)", test.status, w.Code, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			upload := got.(*httpkit.Upload[*errdetails.ErrorInfo])
+			if upload.File.ContentType != test.contentType {
+				t.Errorf("unexpected content type:\n- want: %v\n-  got: %v", test.contentType, upload.File.ContentType)
+			}
+			if upload.File.FileName != test.parts[len(test.parts)-1].fileName || string(upload.File.Content) != "1,2,3" {
+				t.Errorf("unexpected file: %v", upload.File)
+			}
+			if upload.Request.Reason != test.reason {
+				t.Errorf("unexpected reason:\n- want: %v\n-  got: %v", test.reason, upload.Request.Reason)
+			}
+		})
+	}
+}
@@ -0,0 +1,171 @@
+package httpkit
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	httptransport "github.com/go-kit/kit/transport/http"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// NDJSONContentType is the content type of the newline-delimited JSON
+// responses.
+const NDJSONContentType = "application/x-ndjson"
+
+// MessageStream is a stream of messages that is consumed by the NDJSON
+// encoder. Next returns io.EOF when the stream is completed.
+type MessageStream interface {
+	Next() (proto.Message, error)
+}
+
+// RecvFunc adapts the Recv method of gRPC client streams to MessageStream:
+//
+//	stream, err := client.ExportOrders(ctx, req)
+//	...
+//	return httpkit.RecvFunc(stream.Recv), nil
+type RecvFunc[T proto.Message] func() (T, error)
+
+// Next receives the next message of the stream.
+func (f RecvFunc[T]) Next() (proto.Message, error) {
+	return f()
+}
+
+// NDJSONOption sets an optional parameter for the NDJSON encoder.
+type NDJSONOption func(*ndjsonEncoder)
+
+// NDJSONFlushInterval sets how often the buffered messages are flushed to the
+// client while the stream is producing them. The messages are flushed every
+// second by default and always when the stream has to wait for a message.
+func NDJSONFlushInterval(d time.Duration) NDJSONOption {
+	return func(e *ndjsonEncoder) { e.flushInterval = d }
+}
+
+// NDJSONMarshalOptions sets the options that are used to encode the messages.
+func NDJSONMarshalOptions(marshaller protojson.MarshalOptions) NDJSONOption {
+	return func(e *ndjsonEncoder) { e.marshaller = marshaller }
+}
+
+// EncodeNDJSONResponse is a transport/http.EncodeResponseFunc that streams the
+// response as newline-delimited JSON. See NewNDJSONEncoder.
+func EncodeNDJSONResponse(ctx context.Context, w http.ResponseWriter, response interface{}) error {
+	return defaultNDJSONEncoder(ctx, w, response)
+}
+
+var defaultNDJSONEncoder = NewNDJSONEncoder()
+
+// NewNDJSONEncoder returns an EncodeResponseFunc that writes every message of
+// the response on a separate line, without buffering the whole result. The
+// response is either a MessageStream or a <-chan proto.Message. Responses
+// of other types are encoded by EncodeProtoJSONResponse.
+//
+// The status of the response is already sent when the stream fails, so the
+// error is written as last line in the form
+// {"error":{"message":...,"code":...,"status":...}}
+// and the stream is terminated.
+func NewNDJSONEncoder(options ...NDJSONOption) httptransport.EncodeResponseFunc {
+	e := &ndjsonEncoder{
+		marshaller:    protojson.MarshalOptions{EmitUnpopulated: true, UseProtoNames: false},
+		flushInterval: time.Second,
+	}
+	for _, option := range options {
+		option(e)
+	}
+	return e.encode
+}
+
+type ndjsonEncoder struct {
+	marshaller    protojson.MarshalOptions
+	flushInterval time.Duration
+}
+
+func (e *ndjsonEncoder) encode(ctx context.Context, w http.ResponseWriter, response interface{}) error {
+	next, ok := messageSource(ctx, response)
+	if !ok {
+		return EncodeProtoJSONResponse(ctx, w, response)
+	}
+
+	w.Header().Set("Content-Type", NDJSONContentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(http.StatusOK)
+
+	flusher, _ := w.(http.Flusher)
+	pending, lastFlush := false, time.Now()
+	flush := func() {
+		if pending && flusher != nil {
+			flusher.Flush()
+		}
+		pending, lastFlush = false, time.Now()
+	}
+	defer flush()
+
+	var line []byte
+	for {
+		m, err := next(flush)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			e.writeError(w, err)
+			return nil
+		}
+		if line, err = e.marshaller.MarshalAppend(line[:0], m); err != nil {
+			e.writeError(w, err)
+			return nil
+		}
+		line = append(line, '\n')
+		if _, err := w.Write(line); err != nil {
+			// The client is gone and there is nobody to report to.
+			return nil
+		}
+		pending = true
+		if time.Since(lastFlush) >= e.flushInterval {
+			flush()
+		}
+	}
+}
+
+var ndjsonErrorSchema = ErrorSchema{CodeKey: "code", StatusKey: "status"}
+
+// writeError writes the error of the stream as last line of the response.
+func (e *ndjsonEncoder) writeError(w http.ResponseWriter, err error) {
+	b := append([]byte(`{"error":`), ndjsonErrorSchema.MarshalError(err)...)
+	b = append(b, "}\n"...)
+	w.Write(b)
+}
+
+// messageSource returns a function that receives the next message of the
+// streaming response, which is either a MessageStream or a channel of
+// messages. The wait function is called before blocking on the channel, so
+// the encoders can flush what they have buffered so far.
+func messageSource(ctx context.Context, response interface{}) (func(wait func()) (proto.Message, error), bool) {
+	switch s := response.(type) {
+	case MessageStream:
+		return func(func()) (proto.Message, error) { return s.Next() }, true
+	case <-chan proto.Message:
+		return func(wait func()) (proto.Message, error) {
+			select {
+			case m, ok := <-s:
+				if !ok {
+					return nil, io.EOF
+				}
+				return m, nil
+			default:
+			}
+			wait()
+			select {
+			case m, ok := <-s:
+				if !ok {
+					return nil, io.EOF
+				}
+				return m, nil
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		}, true
+	}
+	return nil, false
+}
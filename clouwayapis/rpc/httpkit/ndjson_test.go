@@ -0,0 +1,94 @@
+package httpkit_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/clouway/go-genproto/clouwayapis/rpc/httpkit"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+func TestEncodeNDJSONResponseFromChannel(t *testing.T) {
+	ch := make(chan proto.Message, 2)
+	ch <- wrapperspb.String("a")
+	ch <- wrapperspb.String("b")
+	close(ch)
+
+	rec := httptest.NewRecorder()
+	if err := httpkit.EncodeNDJSONResponse(context.Background(), rec, (<-chan proto.Message)(ch)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if got, want := rec.Header().Get("Content-Type"), httpkit.NDJSONContentType; got != want {
+		t.Errorf("unexpected Content-Type header:\n- want: %v\n-  got: %v", want, got)
+	}
+	if got, want := compactLines(rec.Body.String()), "\"a\"\n\"b\"\n"; got != want {
+		t.Errorf("unexpected body:\n- want: %q\n-  got: %q", want, got)
+	}
+	if !rec.Flushed {
+		t.Error("expected response to be flushed")
+	}
+}
+
+func TestEncodeNDJSONResponseFromStream(t *testing.T) {
+	messages := []*wrapperspb.Int64Value{wrapperspb.Int64(1), wrapperspb.Int64(2)}
+	recv := func() (*wrapperspb.Int64Value, error) {
+		if len(messages) == 0 {
+			return nil, io.EOF
+		}
+		m := messages[0]
+		messages = messages[1:]
+		return m, nil
+	}
+
+	rec := httptest.NewRecorder()
+	if err := httpkit.EncodeNDJSONResponse(context.Background(), rec, httpkit.RecvFunc[*wrapperspb.Int64Value](recv)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if got, want := compactLines(rec.Body.String()), "\"1\"\n\"2\"\n"; got != want {
+		t.Errorf("unexpected body:\n- want: %q\n-  got: %q", want, got)
+	}
+}
+
+func TestEncodeNDJSONResponseStreamError(t *testing.T) {
+	sent := false
+	recv := func() (*wrapperspb.StringValue, error) {
+		if sent {
+			return nil, status.Error(codes.Unavailable, "backend is gone")
+		}
+		sent = true
+		return wrapperspb.String("a"), nil
+	}
+
+	rec := httptest.NewRecorder()
+	if err := httpkit.EncodeNDJSONResponse(context.Background(), rec, httpkit.RecvFunc[*wrapperspb.StringValue](recv)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if rec.Code != http.StatusOK {
+		t.Errorf("unexpected status code:\n- want: %v\n-  got: %v", http.StatusOK, rec.Code)
+	}
+	want := "\"a\"\n" + `{"error":{"message":"backend is gone","code":503,"status":"UNAVAILABLE"}}` + "\n"
+	if got := compactLines(rec.Body.String()); got != want {
+		t.Errorf("unexpected body:\n- want: %q\n-  got: %q", want, got)
+	}
+}
+
+// compactLines compacts every JSON line of the body.
+func compactLines(body string) string {
+	var out string
+	for _, line := range strings.SplitAfter(body, "\n") {
+		if line != "" {
+			out += compactJSON([]byte(line)) + "\n"
+		}
+	}
+	return out
+}
@@ -0,0 +1,96 @@
+package httpkit
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/clouway/go-genproto/clouwayapis/rpc/request"
+	httptransport "github.com/go-kit/kit/transport/http"
+)
+
+// varyAccept adds Accept to the Vary header of the responses whose
+// representation is negotiated, so the caches keep one per Accept header.
+func varyAccept(h http.Header) {
+	for _, value := range h.Values("Vary") {
+		for _, header := range strings.Split(value, ",") {
+			if header = strings.TrimSpace(header); header == "*" || strings.EqualFold(header, "Accept") {
+				return
+			}
+		}
+	}
+	h.Add("Vary", "Accept")
+}
+
+// negotiate returns the offered media type that is preferred by the passed
+// Accept header. The first offer is returned as default when the header is
+// empty or none of the offers is acceptable.
+func negotiate(accept string, offers ...string) string {
+	if len(offers) == 0 {
+		return ""
+	}
+	if accept == "" {
+		return offers[0]
+	}
+	best, bestQ, bestSpecificity := offers[0], -1.0, -1
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, q := parseMediaRange(part)
+		if mediaType == "" || q <= 0 {
+			continue
+		}
+		for _, offer := range offers {
+			specificity := matchMediaRange(mediaType, offer)
+			if specificity < 0 {
+				continue
+			}
+			if q > bestQ || (q == bestQ && specificity > bestSpecificity) {
+				best, bestQ, bestSpecificity = offer, q, specificity
+			}
+		}
+	}
+	return best
+}
+
+// parseMediaRange parses a single media range of the Accept header and
+// returns the media type in lower case and its quality factor.
+func parseMediaRange(s string) (string, float64) {
+	params := strings.Split(s, ";")
+	mediaType := strings.ToLower(strings.TrimSpace(params[0]))
+	q := 1.0
+	for _, param := range params[1:] {
+		kv := strings.SplitN(strings.TrimSpace(param), "=", 2)
+		if len(kv) != 2 || strings.ToLower(kv[0]) != "q" {
+			continue
+		}
+		if v, err := strconv.ParseFloat(kv[1], 64); err == nil {
+			q = v
+		}
+	}
+	return mediaType, q
+}
+
+// matchMediaRange returns how specific the match of mediaRange against the
+// offer is (2 for exact, 1 for type/*, 0 for */*) or -1 when they don't match.
+func matchMediaRange(mediaRange, offer string) int {
+	switch {
+	case mediaRange == offer:
+		return 2
+	case mediaRange == "*/*":
+		return 0
+	case strings.HasSuffix(mediaRange, "/*") && strings.HasPrefix(offer, strings.TrimSuffix(mediaRange, "*")):
+		return 1
+	}
+	return -1
+}
+
+// acceptFromContext returns the Accept header of the request that is stored
+// in the context either by HeadersToContext or by the go-kit
+// httptransport.PopulateRequestContext.
+func acceptFromContext(ctx context.Context) string {
+	if accept, ok := ctx.Value(request.ContextKey("accept")).(string); ok {
+		return accept
+	}
+	accept, _ := ctx.Value(httptransport.ContextKeyRequestAccept).(string)
+	return accept
+}
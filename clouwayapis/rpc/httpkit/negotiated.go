@@ -0,0 +1,100 @@
+package httpkit
+
+import (
+	"context"
+	"mime"
+	"net/http"
+
+	httptransport "github.com/go-kit/kit/transport/http"
+	"google.golang.org/protobuf/proto"
+)
+
+// ProtobufContentType is the content type of binary protobuf messages.
+const ProtobufContentType = "application/x-protobuf"
+
+// bodyCodec encodes the messages in one of the formats that are negotiated
+// next to JSON.
+type bodyCodec struct {
+	contentTypes []string
+	marshal      func(proto.Message) ([]byte, error)
+	unmarshal    func([]byte, proto.Message) error
+}
+
+var bodyCodecs = []bodyCodec{
+	{contentTypes: []string{ProtobufContentType, "application/protobuf"}, marshal: proto.Marshal, unmarshal: proto.Unmarshal},
+	{contentTypes: []string{MsgpackContentType, "application/x-msgpack"}, marshal: MarshalMsgpack, unmarshal: UnmarshalMsgpack},
+	{contentTypes: []string{CBORContentType}, marshal: MarshalCBOR, unmarshal: UnmarshalCBOR},
+}
+
+// codecOf returns the codec of the media type.
+func codecOf(mediaType string) (bodyCodec, bool) {
+	for _, c := range bodyCodecs {
+		if contains(c.contentTypes, mediaType) {
+			return c, true
+		}
+	}
+	return bodyCodec{}, false
+}
+
+// EncodeNegotiatedResponse is a transport/http.EncodeResponseFunc that
+// encodes proto.Message responses as binary protobuf, MessagePack or CBOR
+// when the Accept header of the request prefers application/x-protobuf,
+// application/msgpack or application/cbor, and as JSON by
+// EncodeProtoJSONResponse otherwise. The Accept header is read from the
+// context in the same way as by ErrorEncoder, and the responses vary by it.
+func EncodeNegotiatedResponse(ctx context.Context, w http.ResponseWriter, response interface{}) error {
+	m, ok := response.(proto.Message)
+	if !ok || isNoContent(response) {
+		return EncodeProtoJSONResponse(ctx, w, response)
+	}
+	varyAccept(w.Header())
+	offers := []string{"application/json"}
+	for _, c := range bodyCodecs {
+		offers = append(offers, c.contentTypes...)
+	}
+	mediaType := negotiate(acceptFromContext(ctx), offers...)
+	c, ok := codecOf(mediaType)
+	if !ok {
+		return EncodeProtoJSONResponse(ctx, w, response)
+	}
+
+	b, err := c.marshal(m)
+	if err != nil {
+		return err
+	}
+	w.Header().Set("Content-Type", mediaType)
+	w.Write(b)
+	return nil
+}
+
+// DecodeNegotiatedRequest returns a DecodeRequestFunc that decodes the body
+// of the request into a new message of type T by its Content-Type: binary
+// protobuf, MessagePack, CBOR, or JSON in the same way as DecodeProtoJSONRequest
+// for all other content types.
+func DecodeNegotiatedRequest[T proto.Message](options ...DecodeOption) httptransport.DecodeRequestFunc {
+	o := newDecodeOptions(options...)
+	return func(_ context.Context, r *http.Request) (interface{}, error) {
+		var zero T
+		m := zero.ProtoReflect().New().Interface().(T)
+		mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		c, ok := codecOf(mediaType)
+		if !ok {
+			if err := o.decode(r, m); err != nil {
+				return nil, err
+			}
+			return m, nil
+		}
+
+		if len(o.contentTypes) > 0 && !contains(o.contentTypes, mediaType) {
+			return nil, NewBadRequestError("unsupported content type '%s'", r.Header.Get("Content-Type"))
+		}
+		b, err := o.readBody(r)
+		if err != nil {
+			return nil, err
+		}
+		if err := c.unmarshal(b, m); err != nil {
+			return nil, NewBadRequestError("invalid request body: %v", err)
+		}
+		return m, nil
+	}
+}
@@ -0,0 +1,71 @@
+package httpkit_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/clouway/go-genproto/clouwayapis/rpc/errdetails"
+	"github.com/clouway/go-genproto/clouwayapis/rpc/httpkit"
+	"github.com/clouway/go-genproto/clouwayapis/rpc/request"
+	spb "google.golang.org/genproto/googleapis/rpc/status"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestEncodeNegotiatedResponse(t *testing.T) {
+	response := &errdetails.ErrorInfo{Reason: "Test Reason"}
+	binary, _ := proto.Marshal(response)
+
+	tests := []struct {
+		name        string
+		accept      string
+		contentType string
+		body        string
+	}{
+		{name: "protobuf", accept: "application/x-protobuf", contentType: httpkit.ProtobufContentType, body: string(binary)},
+		{name: "json", accept: "application/json", contentType: httpkit.JSONContentType, body: `{"reason":"Test Reason","domain":"","metadata":{}}`},
+		{name: "browser", accept: "text/html,*/*;q=0.8", contentType: httpkit.JSONContentType, body: `{"reason":"Test Reason","domain":"","metadata":{}}`},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ctx := context.WithValue(context.Background(), request.ContextKey("accept"), test.accept)
+			w := httptest.NewRecorder()
+			if err := httpkit.EncodeNegotiatedResponse(ctx, w, response); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if got := w.Header().Get("Content-Type"); got != test.contentType {
+				t.Errorf("unexpected Content-Type header:\n- want: %v\n-  got: %v", test.contentType, got)
+			}
+			if got := compactJSON(w.Body.Bytes()); got != test.body {
+				t.Errorf("unexpected body:\n- want: %v\n-  got: %v", test.body, got)
+			}
+			if got := w.Header().Get("Vary"); got != "Accept" {
+				t.Errorf("unexpected Vary header:\n- want: %v\n-  got: %v", "Accept", got)
+			}
+		})
+	}
+}
+
+func TestEncodeProtobufError(t *testing.T) {
+	ctx := context.WithValue(context.Background(), request.ContextKey("accept"), httpkit.ProtobufContentType)
+	rec := httptest.NewRecorder()
+	httpkit.ErrorEncoder(ctx, status.Error(codes.NotFound, "not found"), rec)
+
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("unexpected status code:\n- want: %v\n-  got: %v", http.StatusNotFound, rec.Code)
+	}
+	if got := rec.Header().Values("Vary"); len(got) != 1 || got[0] != "Accept" {
+		t.Errorf("unexpected Vary header:\n- want: %v\n-  got: %v", []string{"Accept"}, got)
+	}
+	got := &spb.Status{}
+	if err := proto.Unmarshal(rec.Body.Bytes(), got); err != nil {
+		t.Fatalf("unexpected error while decoding body: %v", err)
+	}
+	if want := status.New(codes.NotFound, "not found").Proto(); !proto.Equal(want, got) {
+		t.Errorf("unexpected status:\n- want: %v\n-  got: %v", want, got)
+	}
+}
@@ -0,0 +1,60 @@
+package httpkit
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	httptransport "github.com/go-kit/kit/transport/http"
+	"google.golang.org/protobuf/proto"
+)
+
+// operation is implemented by the messages of long-running operations such
+// as google.longrunning.Operation.
+type operation interface {
+	GetName() string
+	GetDone() bool
+}
+
+// EncodeAcceptedResponse returns an encoder that responds with 202 Accepted
+// when the endpoint returns a long-running operation that is not done yet.
+// The Location and Operation-Location headers point to the operation
+// resource that is built by the location function from the operation name.
+// The body of the proto.Message operations is the operation resource itself,
+// encoded by EncodeProtoJSONResponse, so the clients get its metadata without
+// polling. The body of all other operations contains the name of the
+// operation and the interval after which the client should poll it:
+//
+//	{"name": "operations/123", "done": false, "pollAfterSeconds": 5}
+//
+// Operations that are already done and all other responses are encoded by
+// EncodeHTTPGenericResponse.
+func EncodeAcceptedResponse(location func(name string) string, pollAfter time.Duration) httptransport.EncodeResponseFunc {
+	return func(ctx context.Context, w http.ResponseWriter, response interface{}) error {
+		op, ok := response.(operation)
+		if !ok || op.GetDone() {
+			return EncodeHTTPGenericResponse(ctx, w, response)
+		}
+
+		seconds := int64(pollAfter / time.Second)
+		w.Header().Set("Location", location(op.GetName()))
+		w.Header().Set("Operation-Location", location(op.GetName()))
+		if seconds > 0 {
+			w.Header().Set("Retry-After", strconv.FormatInt(seconds, 10))
+		}
+		if _, ok := response.(proto.Message); ok {
+			return EncodeProtoJSONResponse(ctx, &statusWriter{ResponseWriter: w, code: http.StatusAccepted}, response)
+		}
+		w.Header().Set("Content-Type", JSONContentType)
+		w.WriteHeader(http.StatusAccepted)
+		return json.NewEncoder(w).Encode(acceptedOperation{Name: op.GetName(), PollAfterSeconds: seconds})
+	}
+}
+
+type acceptedOperation struct {
+	Name             string `json:"name"`
+	Done             bool   `json:"done"`
+	PollAfterSeconds int64  `json:"pollAfterSeconds,omitempty"`
+}
@@ -0,0 +1,82 @@
+package httpkit_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/clouway/go-genproto/clouwayapis/rpc/httpkit"
+	"google.golang.org/protobuf/types/known/apipb"
+)
+
+func TestEncodeAcceptedResponse(t *testing.T) {
+	encode := httpkit.EncodeAcceptedResponse(func(name string) string { return "/v1/" + name }, 5*time.Second)
+
+	rec := httptest.NewRecorder()
+	if err := encode(context.Background(), rec, &fakeOperation{name: "operations/123"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if rec.Code != http.StatusAccepted {
+		t.Errorf("unexpected status code:\n- want: %v\n-  got: %v", http.StatusAccepted, rec.Code)
+	}
+	if want, got := "/v1/operations/123", rec.Header().Get("Location"); want != got {
+		t.Errorf("unexpected Location header:\n- want: %v\n-  got: %v", want, got)
+	}
+	if want, got := "/v1/operations/123", rec.Header().Get("Operation-Location"); want != got {
+		t.Errorf("unexpected Operation-Location header:\n- want: %v\n-  got: %v", want, got)
+	}
+	if want, got := "5", rec.Header().Get("Retry-After"); want != got {
+		t.Errorf("unexpected Retry-After header:\n- want: %v\n-  got: %v", want, got)
+	}
+	if want, got := `{"name":"operations/123","done":false,"pollAfterSeconds":5}`+"\n", rec.Body.String(); want != got {
+		t.Errorf("unexpected body:\n- want: %v\n-  got: %v", want, got)
+	}
+}
+
+type fakeOperation struct {
+	name string
+	done bool
+}
+
+func (o *fakeOperation) GetName() string {
+	return o.name
+}
+
+func (o *fakeOperation) GetDone() bool {
+	return o.done
+}
+
+// protoOperation is an operation message with the name of the embedded Api.
+type protoOperation struct {
+	*apipb.Api
+}
+
+func (o *protoOperation) GetDone() bool {
+	return false
+}
+
+func TestEncodeAcceptedResponseWithOperationResource(t *testing.T) {
+	encode := httpkit.EncodeAcceptedResponse(func(name string) string { return "/v1/" + name }, 5*time.Second)
+
+	rec := httptest.NewRecorder()
+	if err := encode(context.Background(), rec, &protoOperation{Api: &apipb.Api{Name: "operations/123", Version: "v1"}}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if rec.Code != http.StatusAccepted {
+		t.Errorf("unexpected status code:\n- want: %v\n-  got: %v", http.StatusAccepted, rec.Code)
+	}
+	if want, got := "/v1/operations/123", rec.Header().Get("Operation-Location"); want != got {
+		t.Errorf("unexpected Operation-Location header:\n- want: %v\n-  got: %v", want, got)
+	}
+	if want, got := httpkit.JSONContentType, rec.Header().Get("Content-Type"); want != got {
+		t.Errorf("unexpected Content-Type header:\n- want: %v\n-  got: %v", want, got)
+	}
+	want := `{"name":"operations/123","methods":[],"options":[],"version":"v1","sourceContext":null,"mixins":[],"syntax":"SYNTAX_PROTO2"}`
+	if got := compactJSON(rec.Body.Bytes()); got != want {
+		t.Errorf("unexpected body:\n- want: %v\n-  got: %v", want, got)
+	}
+}
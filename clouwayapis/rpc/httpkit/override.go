@@ -0,0 +1,42 @@
+package httpkit
+
+import (
+	"net/http"
+	"strings"
+)
+
+// MethodOverrideHeader is the header with the method of the POST requests
+// that masquerade as other methods.
+const MethodOverrideHeader = "X-HTTP-Method-Override"
+
+// MethodOverride is an HTTP middleware that serves the POST requests with
+// an X-HTTP-Method-Override header as requests with the method of the
+// header, for the clients behind proxies that pass only GET and POST. Only
+// the passed methods can be overridden, or PUT, PATCH and DELETE when none
+// are passed, and the requests that override other methods are rejected
+// with 400 Bad Request. The middleware must wrap the router, as the routes
+// are matched by the method:
+//
+//	http.ListenAndServe(addr, httpkit.MethodOverride()(router))
+func MethodOverride(allowed ...string) func(http.Handler) http.Handler {
+	if len(allowed) == 0 {
+		allowed = []string{http.MethodPut, http.MethodPatch, http.MethodDelete}
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			method := strings.ToUpper(strings.TrimSpace(r.Header.Get(MethodOverrideHeader)))
+			if method == "" || r.Method != http.MethodPost {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if !contains(allowed, method) {
+				ErrorEncoder(r.Context(), NewBadRequestError("method %s can't be overridden", method), w)
+				return
+			}
+			r = r.Clone(r.Context())
+			r.Method = method
+			r.Header.Del(MethodOverrideHeader)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
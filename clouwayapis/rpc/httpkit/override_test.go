@@ -0,0 +1,48 @@
+package httpkit_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/clouway/go-genproto/clouwayapis/rpc/httpkit"
+	"github.com/gorilla/mux"
+)
+
+func TestMethodOverride(t *testing.T) {
+	router := mux.NewRouter()
+	router.Methods(http.MethodPatch).Path("/v1/orders/1").HandlerFunc(func(w http.ResponseWriter, r *http.Request) { io.WriteString(w, "patched") })
+	router.Methods(http.MethodPost).Path("/v1/orders/1").HandlerFunc(func(w http.ResponseWriter, r *http.Request) { io.WriteString(w, "posted") })
+	handler := httpkit.MethodOverride(http.MethodPatch)(router)
+
+	tests := []struct {
+		name     string
+		method   string
+		override string
+		status   int
+		body     string
+	}{
+		{name: "override", method: http.MethodPost, override: "patch", status: http.StatusOK, body: "patched"},
+		{name: "no override", method: http.MethodPost, status: http.StatusOK, body: "posted"},
+		{name: "not allowed", method: http.MethodPost, override: http.MethodDelete, status: http.StatusBadRequest},
+		{name: "not post", method: http.MethodGet, override: http.MethodPatch, status: http.StatusMethodNotAllowed},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := httptest.NewRequest(test.method, "/v1/orders/1", nil)
+			if test.override != "" {
+				r.Header.Set(httpkit.MethodOverrideHeader, test.override)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+
+			if w.Code != test.status {
+				t.Errorf("unexpected status code:\n- want: %v\n-  got: %v", test.status, w.Code)
+			}
+			if test.body != "" && w.Body.String() != test.body {
+				t.Errorf("unexpected body:\n- want: %v\n-  got: %v", test.body, w.Body.String())
+			}
+		})
+	}
+}
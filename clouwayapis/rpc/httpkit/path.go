@@ -0,0 +1,88 @@
+package httpkit
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	httptransport "github.com/go-kit/kit/transport/http"
+	"github.com/gorilla/mux"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// PathParamFunc returns the value of the named path parameter of the
+// request. chi.URLParam can be used as it is, and MuxVar is the function of
+// gorilla/mux.
+type PathParamFunc func(r *http.Request, name string) string
+
+// MuxVar returns the named path variable of the gorilla/mux route.
+func MuxVar(r *http.Request, name string) string {
+	return mux.Vars(r)[name]
+}
+
+// DecodePathRequest returns a DecodeRequestFunc that populates a new message
+// of type T from the path parameters of the request. Every binding is
+// either the name of a field that has a parameter with the same name, or
+// "field=param" for parameters with other names:
+//
+//	router.Get("/customers/{customerID}/orders/{id}", ...)
+//	httpkit.DecodePathRequest[*orderspb.GetOrderRequest](chi.URLParam, "customer_id=customerID", "id")
+//
+// The values are converted to the types of the fields, e.g. integers and
+// enums, and the invalid or missing values are returned as InvalidArgument
+// status errors with BadRequest details.
+func DecodePathRequest[T proto.Message](param PathParamFunc, bindings ...string) httptransport.DecodeRequestFunc {
+	return func(_ context.Context, r *http.Request) (interface{}, error) {
+		var zero T
+		m := zero.ProtoReflect().New().Interface().(T)
+		if err := BindPathParams(r, m, param, bindings...); err != nil {
+			return nil, err
+		}
+		return m, nil
+	}
+}
+
+// BindPathParams sets the fields of the message from the path parameters of
+// the request. It's meant for decoders that populate the message from the
+// body too. See DecodePathRequest for the form of the bindings.
+func BindPathParams(r *http.Request, m proto.Message, param PathParamFunc, bindings ...string) error {
+	for _, binding := range bindings {
+		field, name := binding, binding
+		if i := strings.IndexByte(binding, '='); i >= 0 {
+			field, name = binding[:i], binding[i+1:]
+		}
+		value := param(r, name)
+		if value == "" {
+			return fieldError(field, "missing path parameter '"+name+"'")
+		}
+		if !hasField(m, field) {
+			return status.Errorf(codes.Internal, "unknown field '%s' of %s", field, m.ProtoReflect().Descriptor().FullName())
+		}
+		if err := PopulateField(m, field, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// hasField reports whether the dotted path refers to a field of the message.
+func hasField(m proto.Message, path string) bool {
+	md := m.ProtoReflect().Descriptor()
+	for _, name := range strings.Split(path, ".") {
+		if md == nil {
+			return false
+		}
+		fd := md.Fields().ByName(protoreflect.Name(name))
+		if fd == nil {
+			fd = md.Fields().ByJSONName(name)
+		}
+		if fd == nil {
+			return false
+		}
+		md = fd.Message()
+	}
+	return true
+}
@@ -0,0 +1,72 @@
+package httpkit_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/clouway/go-genproto/clouwayapis/rpc/errdetails"
+	"github.com/clouway/go-genproto/clouwayapis/rpc/httpkit"
+	"github.com/gorilla/mux"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/typepb"
+)
+
+// chiURLParam mimics chi.URLParam.
+func chiURLParam(params map[string]string) httpkit.PathParamFunc {
+	return func(_ *http.Request, name string) string { return params[name] }
+}
+
+func TestDecodePathRequest(t *testing.T) {
+	decode := httpkit.DecodePathRequest[*typepb.Field](chiURLParam(map[string]string{"fieldName": "id", "number": "7", "kind": "TYPE_INT64"}), "name=fieldName", "number", "kind")
+
+	got, err := decode(context.Background(), httptest.NewRequest(http.MethodGet, "/", nil))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := &typepb.Field{Name: "id", Number: 7, Kind: typepb.Field_TYPE_INT64}
+	if !proto.Equal(got.(proto.Message), want) {
+		t.Errorf("unexpected request:\n- want: %v\n-  got: %v", want, got)
+	}
+}
+
+func TestDecodePathRequestMuxVars(t *testing.T) {
+	var got interface{}
+	router := mux.NewRouter()
+	router.HandleFunc("/fields/{number}", func(w http.ResponseWriter, r *http.Request) {
+		got, _ = httpkit.DecodePathRequest[*typepb.Field](httpkit.MuxVar, "number")(context.Background(), r)
+	})
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/fields/12", nil))
+
+	if got.(*typepb.Field).Number != 12 {
+		t.Errorf("unexpected number:\n- want: %v\n-  got: %v", 12, got.(*typepb.Field).Number)
+	}
+}
+
+func TestDecodePathRequestErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		params map[string]string
+		field  string
+	}{
+		{name: "invalid number", params: map[string]string{"number": "abc"}, field: "number"},
+		{name: "missing parameter", params: map[string]string{}, field: "number"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := httpkit.DecodePathRequest[*typepb.Field](chiURLParam(test.params), "number")(context.Background(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+			st := status.Convert(err)
+			if st.Code() != codes.InvalidArgument {
+				t.Fatalf("unexpected code:\n- want: %v\n-  got: %v", codes.InvalidArgument, st.Code())
+			}
+			if got := st.Details()[0].(*errdetails.BadRequest).Errors[0].Field; got != test.field {
+				t.Errorf("unexpected field:\n- want: %v\n-  got: %v", test.field, got)
+			}
+		})
+	}
+}
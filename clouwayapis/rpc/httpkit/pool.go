@@ -0,0 +1,287 @@
+package httpkit
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"mime"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+
+	httptransport "github.com/go-kit/kit/transport/http"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// MessagePool is an experimental pool of request messages of type T for the
+// high-volume ingestion endpoints. The repeated fields of the released
+// messages are truncated instead of cleared, and the packed repeated scalar
+// fields of the binary protobuf requests are decoded into their retained
+// backing arrays, as the protobuf runtime allocates new arrays for them.
+// The buffers of the request bodies are reused as well.
+//
+// The lifetime of the pooled messages is explicit: the endpoint must Put the
+// request back when it's done with it, and it must not retain the message
+// nor any of its lists after that, as they are overwritten by the next
+// request.
+type MessagePool[T proto.Message] struct {
+	messages sync.Pool
+	buffers  sync.Pool
+	packed   map[protowire.Number]packedField
+}
+
+// packedField is a top-level repeated scalar field of the pooled messages.
+type packedField struct {
+	kind  protoreflect.Kind
+	index []int
+}
+
+// NewMessagePool creates an empty pool of messages of type T.
+func NewMessagePool[T proto.Message]() *MessagePool[T] {
+	var zero T
+	mt := zero.ProtoReflect().Type()
+	return &MessagePool[T]{
+		messages: sync.Pool{New: func() interface{} { return mt.New().Interface() }},
+		buffers:  sync.Pool{New: func() interface{} { return new(bytes.Buffer) }},
+		packed:   packedFields(reflect.TypeOf(zero), mt.Descriptor()),
+	}
+}
+
+// packedFields returns the repeated scalar fields of the generated message
+// type t by their numbers. The enum fields are left to the protobuf runtime,
+// as their Go types are generated.
+func packedFields(t reflect.Type, md protoreflect.MessageDescriptor) map[protowire.Number]packedField {
+	fields := map[protowire.Number]packedField{}
+	if t.Kind() != reflect.Ptr || t.Elem().Kind() != reflect.Struct {
+		return fields
+	}
+	for i := 0; i < t.Elem().NumField(); i++ {
+		sf := t.Elem().Field(i)
+		fd := md.Fields().ByName(protoreflect.Name(protobufTagName(sf.Tag.Get("protobuf"))))
+		if fd == nil || !fd.IsList() || !isPackable(fd.Kind()) {
+			continue
+		}
+		fields[fd.Number()] = packedField{kind: fd.Kind(), index: sf.Index}
+	}
+	return fields
+}
+
+// protobufTagName returns the name of the field from the protobuf struct tag
+// of the generated messages, e.g. "varint,1,rep,packed,name=path".
+func protobufTagName(tag string) string {
+	for _, part := range strings.Split(tag, ",") {
+		if strings.HasPrefix(part, "name=") {
+			return strings.TrimPrefix(part, "name=")
+		}
+	}
+	return ""
+}
+
+func isPackable(kind protoreflect.Kind) bool {
+	switch kind {
+	case protoreflect.EnumKind, protoreflect.StringKind, protoreflect.BytesKind, protoreflect.MessageKind, protoreflect.GroupKind:
+		return false
+	}
+	return true
+}
+
+// Get returns an empty message from the pool.
+func (p *MessagePool[T]) Get() T {
+	return p.messages.Get().(T)
+}
+
+// Put releases the message back to the pool. The message must not be used
+// after Put.
+func (p *MessagePool[T]) Put(m T) {
+	truncate(m.ProtoReflect())
+	p.messages.Put(m)
+}
+
+// truncate clears the message, except the capacity of its repeated fields.
+func truncate(m protoreflect.Message) {
+	var cleared []protoreflect.FieldDescriptor
+	m.Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		if fd.IsList() {
+			v.List().Truncate(0)
+		} else {
+			cleared = append(cleared, fd)
+		}
+		return true
+	})
+	for _, fd := range cleared {
+		m.Clear(fd)
+	}
+	m.SetUnknown(nil)
+}
+
+// DecodePooledRequest returns a DecodeRequestFunc that decodes the binary
+// protobuf body of the request into a message of the pool. The requests
+// with other content types are decoded as JSON in the same way as by
+// DecodeProtoJSONRequest, but into a pooled message as well, so the endpoint
+// can Put all requests back.
+func DecodePooledRequest[T proto.Message](pool *MessagePool[T], options ...DecodeOption) httptransport.DecodeRequestFunc {
+	o := newDecodeOptions(options...)
+	return func(_ context.Context, r *http.Request) (interface{}, error) {
+		m := pool.Get()
+		mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		if c, ok := codecOf(mediaType); !ok || c.contentTypes[0] != ProtobufContentType {
+			if err := o.decode(r, m); err != nil {
+				pool.Put(m)
+				return nil, err
+			}
+			return m, nil
+		}
+
+		if err := pool.decodeProtobuf(o, r, m); err != nil {
+			pool.Put(m)
+			return nil, err
+		}
+		return m, nil
+	}
+}
+
+// decodeProtobuf reads the body into a pooled buffer and decodes it into the
+// truncated message, so the lists are appended to their retained arrays.
+func (p *MessagePool[T]) decodeProtobuf(o *decodeOptions, r *http.Request, m T) error {
+	if len(o.contentTypes) > 0 {
+		mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		if !contains(o.contentTypes, mediaType) {
+			return NewBadRequestError("unsupported content type '%s'", r.Header.Get("Content-Type"))
+		}
+	}
+	buf := p.buffers.Get().(*bytes.Buffer)
+	defer p.buffers.Put(buf)
+	buf.Reset()
+
+	body := io.Reader(r.Body)
+	if o.maxBytes > 0 {
+		if r.ContentLength > o.maxBytes {
+			return NewPayloadTooLargeError(o.maxBytes)
+		}
+		body = io.LimitReader(r.Body, o.maxBytes+1)
+	}
+	if _, err := buf.ReadFrom(body); err != nil {
+		return err
+	}
+	if o.maxBytes > 0 && int64(buf.Len()) > o.maxBytes {
+		return NewPayloadTooLargeError(o.maxBytes)
+	}
+	if err := p.unmarshal(buf.Bytes(), m); err != nil {
+		return NewBadRequestError("invalid request body: %v", err)
+	}
+	return nil
+}
+
+// unmarshal decodes the elements of the packed fields of b, in both the
+// packed and the unpacked encoding, into the retained slices of m and merges
+// the rest of the fields by the protobuf runtime.
+func (p *MessagePool[T]) unmarshal(b []byte, m T) error {
+	if len(p.packed) == 0 {
+		return proto.UnmarshalOptions{Merge: true}.Unmarshal(b, m)
+	}
+	rest := p.buffers.Get().(*bytes.Buffer)
+	defer p.buffers.Put(rest)
+	rest.Reset()
+
+	rv := reflect.ValueOf(m).Elem()
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		if f, ok := p.packed[num]; ok && typ == protowire.BytesType {
+			v, k := protowire.ConsumeBytes(b[n:])
+			if k < 0 {
+				return protowire.ParseError(k)
+			}
+			if err := appendPacked(rv.FieldByIndex(f.index).Addr().Interface(), f.kind, v); err != nil {
+				return err
+			}
+			b = b[n+k:]
+			continue
+		}
+		k := protowire.ConsumeFieldValue(num, typ, b[n:])
+		if k < 0 {
+			return protowire.ParseError(k)
+		}
+		// The unpacked elements of the packed fields are appended as well,
+		// so the elements keep their order when both encodings are mixed.
+		if f, ok := p.packed[num]; ok && typ == elementWireType(f.kind) {
+			if err := appendPacked(rv.FieldByIndex(f.index).Addr().Interface(), f.kind, b[n:n+k]); err != nil {
+				return err
+			}
+			b = b[n+k:]
+			continue
+		}
+		rest.Write(b[:n+k])
+		b = b[n+k:]
+	}
+	return proto.UnmarshalOptions{Merge: true}.Unmarshal(rest.Bytes(), m)
+}
+
+// elementWireType returns the wire type of the unpacked elements of the
+// kind.
+func elementWireType(kind protoreflect.Kind) protowire.Type {
+	switch kind {
+	case protoreflect.Fixed32Kind, protoreflect.Sfixed32Kind, protoreflect.FloatKind:
+		return protowire.Fixed32Type
+	case protoreflect.Fixed64Kind, protoreflect.Sfixed64Kind, protoreflect.DoubleKind:
+		return protowire.Fixed64Type
+	}
+	return protowire.VarintType
+}
+
+// appendPacked appends the packed values of b to the slice that s points to.
+func appendPacked(s interface{}, kind protoreflect.Kind, b []byte) error {
+	for len(b) > 0 {
+		var (
+			v uint64
+			n int
+		)
+		switch kind {
+		case protoreflect.Fixed32Kind, protoreflect.Sfixed32Kind, protoreflect.FloatKind:
+			var v32 uint32
+			v32, n = protowire.ConsumeFixed32(b)
+			v = uint64(v32)
+		case protoreflect.Fixed64Kind, protoreflect.Sfixed64Kind, protoreflect.DoubleKind:
+			v, n = protowire.ConsumeFixed64(b)
+		default:
+			v, n = protowire.ConsumeVarint(b)
+		}
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		b = b[n:]
+
+		switch s := s.(type) {
+		case *[]int32:
+			if kind == protoreflect.Sint32Kind {
+				v = uint64(protowire.DecodeZigZag(v & math.MaxUint32))
+			}
+			*s = append(*s, int32(v))
+		case *[]int64:
+			if kind == protoreflect.Sint64Kind {
+				v = uint64(protowire.DecodeZigZag(v))
+			}
+			*s = append(*s, int64(v))
+		case *[]uint32:
+			*s = append(*s, uint32(v))
+		case *[]uint64:
+			*s = append(*s, v)
+		case *[]float32:
+			*s = append(*s, math.Float32frombits(uint32(v)))
+		case *[]float64:
+			*s = append(*s, math.Float64frombits(v))
+		case *[]bool:
+			*s = append(*s, protowire.DecodeBool(v))
+		default:
+			return fmt.Errorf("unsupported list type %T", s)
+		}
+	}
+	return nil
+}
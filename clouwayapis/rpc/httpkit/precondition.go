@@ -0,0 +1,91 @@
+package httpkit
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/clouway/go-genproto/clouwayapis/rpc/errdetails"
+	"github.com/clouway/go-genproto/clouwayapis/rpc/request"
+	httptransport "github.com/go-kit/kit/transport/http"
+	gerrdetails "google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// PreconditionETag is the type of the PreconditionFailure violations of
+// NewPreconditionFailedError.
+const PreconditionETag = "ETAG"
+
+// NewPreconditionFailedError creates a FailedPrecondition status error for
+// conditional updates of the subject resource with a stale etag. The error
+// carries PreconditionFailure with a violation of type ETAG and ErrorInfo
+// with reason PRECONDITION_FAILED, and is encoded with 412 Precondition
+// Failed status and body:
+//
+//	{"violations": [{"type": "ETAG", "subject": "orders/1", "description": "..."}]}
+func NewPreconditionFailedError(subject, etag string) error {
+	st := status.Newf(codes.FailedPrecondition, "etag of %s does not match", subject)
+	st, _ = st.WithDetails(
+		&gerrdetails.PreconditionFailure{Violations: []*gerrdetails.PreconditionFailure_Violation{{
+			Type:        PreconditionETag,
+			Subject:     subject,
+			Description: "the resource was modified, its current etag is " + etag,
+		}}},
+		&errdetails.ErrorInfo{Reason: ReasonPreconditionFailed, Metadata: map[string]string{"etag": etag}},
+	)
+	return st.Err()
+}
+
+// IfMatchFromContext returns the If-Match header of the request, which is
+// stored in the context by HeadersToContext.
+func IfMatchFromContext(ctx context.Context) string {
+	ifMatch, _ := ctx.Value(request.ContextKey("if-match")).(string)
+	return ifMatch
+}
+
+// CheckIfMatch verifies the If-Match header of the request against the
+// current etag of the subject resource and returns the error of
+// NewPreconditionFailedError when it does not match. The requests without
+// If-Match are unconditional and always pass.
+func CheckIfMatch(ctx context.Context, subject, etag string) error {
+	ifMatch := IfMatchFromContext(ctx)
+	if ifMatch == "" || etagMatches(ifMatch, quoteETag(etag), false) {
+		return nil
+	}
+	return NewPreconditionFailedError(subject, etag)
+}
+
+// DecodeIfMatchRequest wraps the decoder to set the etag field of the decoded
+// message from the If-Match header, so the services can check it in the
+// same way as the etag of the body that follows AIP-154. The etag of the
+// body wins when both are present.
+func DecodeIfMatchRequest(decode httptransport.DecodeRequestFunc) httptransport.DecodeRequestFunc {
+	return func(ctx context.Context, r *http.Request) (interface{}, error) {
+		req, err := decode(ctx, r)
+		if err != nil {
+			return nil, err
+		}
+		ifMatch := r.Header.Get("If-Match")
+		m, ok := req.(proto.Message)
+		if !ok || ifMatch == "" || ifMatch == "*" {
+			return req, nil
+		}
+		fd := m.ProtoReflect().Descriptor().Fields().ByName("etag")
+		if fd == nil || fd.Kind() != protoreflect.StringKind || m.ProtoReflect().Get(fd).String() != "" {
+			return req, nil
+		}
+		m.ProtoReflect().Set(fd, protoreflect.ValueOfString(strings.Trim(ifMatch, `"`)))
+		return req, nil
+	}
+}
+
+// quoteETag quotes the etag unless it's already quoted.
+func quoteETag(etag string) string {
+	if strings.HasPrefix(etag, `"`) || strings.HasPrefix(etag, `W/"`) {
+		return etag
+	}
+	return `"` + etag + `"`
+}
@@ -0,0 +1,109 @@
+package httpkit_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/clouway/go-genproto/clouwayapis/rpc/httpkit"
+	"github.com/clouway/go-genproto/clouwayapis/rpc/request"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+func TestCheckIfMatch(t *testing.T) {
+	tests := []struct {
+		name    string
+		ifMatch string
+		code    codes.Code
+	}{
+		{name: "unconditional", code: codes.OK},
+		{name: "matching", ifMatch: `"v2"`, code: codes.OK},
+		{name: "one of", ifMatch: `"v1", "v2"`, code: codes.OK},
+		{name: "any", ifMatch: "*", code: codes.OK},
+		{name: "stale", ifMatch: `"v1"`, code: codes.FailedPrecondition},
+		{name: "weak", ifMatch: `W/"v2"`, code: codes.FailedPrecondition},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ctx := context.Background()
+			if test.ifMatch != "" {
+				ctx = context.WithValue(ctx, request.ContextKey("if-match"), test.ifMatch)
+			}
+
+			err := httpkit.CheckIfMatch(ctx, "orders/1", "v2")
+
+			if got := status.Code(err); got != test.code {
+				t.Errorf("unexpected code:\n- want: %v\n-  got: %v", test.code, got)
+			}
+		})
+	}
+}
+
+func TestEncodePreconditionFailedError(t *testing.T) {
+	w := httptest.NewRecorder()
+	httpkit.ErrorEncoder(context.Background(), httpkit.NewPreconditionFailedError("orders/1", "v2"), w)
+
+	if w.Code != http.StatusPreconditionFailed {
+		t.Errorf("unexpected status code:\n- want: %v\n-  got: %v", http.StatusPreconditionFailed, w.Code)
+	}
+	want := `{"violations":[{"type":"ETAG","subject":"orders/1","description":"the resource was modified, its current etag is v2"}]}`
+	if got := compactJSON(w.Body.Bytes()); got != want {
+		t.Errorf("unexpected body:\n- want: %v\n-  got: %v", want, got)
+	}
+}
+
+func TestDecodeIfMatchRequest(t *testing.T) {
+	fd, err := protodesc.NewFile(&descriptorpb.FileDescriptorProto{
+		Name:    proto.String("test/order.proto"),
+		Package: proto.String("test"),
+		Syntax:  proto.String("proto3"),
+		MessageType: []*descriptorpb.DescriptorProto{{
+			Name: proto.String("Order"),
+			Field: []*descriptorpb.FieldDescriptorProto{
+				{Name: proto.String("name"), JsonName: proto.String("name"), Number: proto.Int32(1), Type: descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum(), Label: descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum()},
+				{Name: proto.String("etag"), JsonName: proto.String("etag"), Number: proto.Int32(2), Type: descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum(), Label: descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum()},
+			},
+		}},
+	}, protoregistry.GlobalFiles)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	md := fd.Messages().Get(0)
+
+	tests := []struct {
+		name string
+		etag string
+		want string
+	}{
+		{name: "from If-Match", want: "v2"},
+		{name: "body wins", etag: "v1", want: "v1"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			decode := httpkit.DecodeIfMatchRequest(func(_ context.Context, _ *http.Request) (interface{}, error) {
+				m := dynamicpb.NewMessage(md)
+				m.Set(md.Fields().ByName("etag"), protoreflect.ValueOfString(test.etag))
+				return m, nil
+			})
+			r := httptest.NewRequest(http.MethodPatch, "/v1/orders/1", nil)
+			r.Header.Set("If-Match", `"v2"`)
+
+			got, err := decode(context.Background(), r)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if etag := got.(*dynamicpb.Message).Get(md.Fields().ByName("etag")).String(); etag != test.want {
+				t.Errorf("unexpected etag:\n- want: %v\n-  got: %v", test.want, etag)
+			}
+		})
+	}
+}
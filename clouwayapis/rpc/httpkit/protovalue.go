@@ -0,0 +1,520 @@
+package httpkit
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// protoToValue converts the message into a generic value of the shape of
+// its JSON mapping for the binary formats, e.g. MessagePack and CBOR. The
+// fields are keyed by their JSON names and the well known types have their
+// JSON forms, while the integers, the floats and the bytes are kept as
+// they are, as the binary formats encode them natively.
+func protoToValue(m proto.Message) (interface{}, error) {
+	return messageToValue(m.ProtoReflect())
+}
+
+func messageToValue(m protoreflect.Message) (interface{}, error) {
+	md := m.Descriptor()
+	switch md.FullName() {
+	case "google.protobuf.Timestamp":
+		seconds, nanos := timeFields(m)
+		t := time.Unix(seconds, nanos).UTC()
+		return t.Format("2006-01-02T15:04:05") + fraction(nanos) + "Z", nil
+	case "google.protobuf.Duration":
+		seconds, nanos := timeFields(m)
+		sign := ""
+		if seconds < 0 || nanos < 0 {
+			sign, seconds, nanos = "-", -seconds, -nanos
+		}
+		return sign + strconv.FormatInt(seconds, 10) + fraction(nanos) + "s", nil
+	case "google.protobuf.FieldMask":
+		list := m.Get(md.Fields().ByName("paths")).List()
+		paths := make([]string, list.Len())
+		for i := range paths {
+			paths[i] = jsonCamelCase(list.Get(i).String())
+		}
+		return strings.Join(paths, ","), nil
+	case "google.protobuf.Struct":
+		return fieldToValue(md.Fields().ByName("fields"), m.Get(md.Fields().ByName("fields")))
+	case "google.protobuf.ListValue":
+		return fieldToValue(md.Fields().ByName("values"), m.Get(md.Fields().ByName("values")))
+	case "google.protobuf.Value":
+		fd := m.WhichOneof(md.Oneofs().ByName("kind"))
+		if fd == nil {
+			return nil, nil
+		}
+		return singularToValue(fd, m.Get(fd))
+	case "google.protobuf.Any":
+		// The type of the content of Any is resolved by the registry of
+		// protojson, so it's kept in its JSON form.
+		return anyToValue(m.Interface())
+	}
+	if isWrapper(md) {
+		fd := md.Fields().ByNumber(1)
+		return singularToValue(fd, m.Get(fd))
+	}
+
+	obj := map[string]interface{}{}
+	var err error
+	m.Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		if fd.IsExtension() {
+			return true
+		}
+		obj[fd.JSONName()], err = fieldToValue(fd, v)
+		return err == nil
+	})
+	return obj, err
+}
+
+func fieldToValue(fd protoreflect.FieldDescriptor, v protoreflect.Value) (interface{}, error) {
+	switch {
+	case fd.IsList():
+		list := v.List()
+		values := make([]interface{}, list.Len())
+		for i := range values {
+			var err error
+			if values[i], err = singularToValue(fd, list.Get(i)); err != nil {
+				return nil, err
+			}
+		}
+		return values, nil
+	case fd.IsMap():
+		values := map[string]interface{}{}
+		var err error
+		v.Map().Range(func(k protoreflect.MapKey, v protoreflect.Value) bool {
+			values[k.String()], err = singularToValue(fd.MapValue(), v)
+			return err == nil
+		})
+		return values, err
+	}
+	return singularToValue(fd, v)
+}
+
+func singularToValue(fd protoreflect.FieldDescriptor, v protoreflect.Value) (interface{}, error) {
+	switch fd.Kind() {
+	case protoreflect.BoolKind:
+		return v.Bool(), nil
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind,
+		protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		return v.Int(), nil
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind, protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		return v.Uint(), nil
+	case protoreflect.FloatKind, protoreflect.DoubleKind:
+		return v.Float(), nil
+	case protoreflect.StringKind:
+		return v.String(), nil
+	case protoreflect.BytesKind:
+		return v.Bytes(), nil
+	case protoreflect.EnumKind:
+		if fd.Enum().FullName() == "google.protobuf.NullValue" {
+			return nil, nil
+		}
+		if ev := fd.Enum().Values().ByNumber(v.Enum()); ev != nil {
+			return string(ev.Name()), nil
+		}
+		return int64(v.Enum()), nil
+	}
+	return messageToValue(v.Message())
+}
+
+func anyToValue(m proto.Message) (interface{}, error) {
+	b, err := protojson.Marshal(m)
+	if err != nil {
+		return nil, err
+	}
+	var v interface{}
+	err = json.Unmarshal(b, &v)
+	return v, err
+}
+
+// valueToProto populates the message from a generic value of the shape of
+// its JSON mapping, as it's decoded from the binary formats. The fields are
+// matched by their JSON or proto names, and the unknown fields are ignored
+// in the same way as by UnmarshalJSON.
+func valueToProto(v interface{}, m proto.Message) error {
+	return valueToMessage(v, m.ProtoReflect())
+}
+
+func valueToMessage(v interface{}, m protoreflect.Message) error {
+	md := m.Descriptor()
+	switch md.FullName() {
+	case "google.protobuf.Timestamp":
+		s, ok := v.(string)
+		if !ok {
+			return invalidValue(md.FullName(), v)
+		}
+		t, err := time.Parse(time.RFC3339Nano, s)
+		if err != nil {
+			return invalidValue(md.FullName(), v)
+		}
+		setTimeFields(m, t.Unix(), int32(t.Nanosecond()))
+		return nil
+	case "google.protobuf.Duration":
+		s, ok := v.(string)
+		if !ok {
+			return invalidValue(md.FullName(), v)
+		}
+		seconds, nanos, ok := parseDuration(s)
+		if !ok {
+			return invalidValue(md.FullName(), v)
+		}
+		setTimeFields(m, seconds, nanos)
+		return nil
+	case "google.protobuf.FieldMask":
+		s, ok := v.(string)
+		if !ok {
+			return invalidValue(md.FullName(), v)
+		}
+		list := m.Mutable(md.Fields().ByName("paths")).List()
+		for _, path := range strings.Split(s, ",") {
+			if path != "" {
+				list.Append(protoreflect.ValueOfString(jsonSnakeCase(path)))
+			}
+		}
+		return nil
+	case "google.protobuf.Struct":
+		return valueToField(v, m, md.Fields().ByName("fields"))
+	case "google.protobuf.ListValue":
+		return valueToField(v, m, md.Fields().ByName("values"))
+	case "google.protobuf.Value":
+		return valueToStructValue(v, m)
+	case "google.protobuf.Any":
+		b, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		return protojson.UnmarshalOptions{DiscardUnknown: true}.Unmarshal(b, m.Interface())
+	}
+	if isWrapper(md) {
+		return valueToField(v, m, md.Fields().ByNumber(1))
+	}
+
+	obj, ok := v.(map[string]interface{})
+	if !ok {
+		return invalidValue(md.FullName(), v)
+	}
+	fields := md.Fields()
+	for name, value := range obj {
+		fd := fields.ByJSONName(name)
+		if fd == nil {
+			fd = fields.ByName(protoreflect.Name(name))
+		}
+		if fd == nil || (value == nil && !isNullable(fd)) {
+			continue
+		}
+		if err := valueToField(value, m, fd); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func valueToField(v interface{}, m protoreflect.Message, fd protoreflect.FieldDescriptor) error {
+	switch {
+	case fd.IsList():
+		values, ok := v.([]interface{})
+		if !ok {
+			return invalidValue(fd.FullName(), v)
+		}
+		list := m.Mutable(fd).List()
+		for _, value := range values {
+			e, err := valueToSingular(value, fd, list.NewElement)
+			if err != nil {
+				return err
+			}
+			list.Append(e)
+		}
+		return nil
+	case fd.IsMap():
+		values, ok := v.(map[string]interface{})
+		if !ok {
+			return invalidValue(fd.FullName(), v)
+		}
+		mv := m.Mutable(fd).Map()
+		for key, value := range values {
+			k, err := parseScalar(fd.MapKey(), key)
+			if err != nil {
+				return invalidValue(fd.FullName(), key)
+			}
+			e, err := valueToSingular(value, fd.MapValue(), mv.NewValue)
+			if err != nil {
+				return err
+			}
+			mv.Set(k.MapKey(), e)
+		}
+		return nil
+	}
+	e, err := valueToSingular(v, fd, func() protoreflect.Value { return m.NewField(fd) })
+	if err != nil {
+		return err
+	}
+	m.Set(fd, e)
+	return nil
+}
+
+func valueToSingular(v interface{}, fd protoreflect.FieldDescriptor, newValue func() protoreflect.Value) (protoreflect.Value, error) {
+	switch fd.Kind() {
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		e := newValue()
+		return e, valueToMessage(v, e.Message())
+	case protoreflect.StringKind:
+		if s, ok := v.(string); ok {
+			return protoreflect.ValueOfString(s), nil
+		}
+	case protoreflect.BytesKind:
+		switch b := v.(type) {
+		case []byte:
+			return protoreflect.ValueOfBytes(b), nil
+		case string:
+			if e, err := parseScalar(fd, b); err == nil {
+				return e, nil
+			}
+		}
+	case protoreflect.BoolKind:
+		if b, ok := v.(bool); ok {
+			return protoreflect.ValueOfBool(b), nil
+		}
+	case protoreflect.EnumKind:
+		if v == nil && fd.Enum().FullName() == "google.protobuf.NullValue" {
+			return protoreflect.ValueOfEnum(0), nil
+		}
+		if i, ok := toInt64(v); ok && i >= math.MinInt32 && i <= math.MaxInt32 {
+			return protoreflect.ValueOfEnum(protoreflect.EnumNumber(i)), nil
+		}
+		if s, ok := v.(string); ok {
+			if ev := fd.Enum().Values().ByName(protoreflect.Name(s)); ev != nil {
+				return protoreflect.ValueOfEnum(ev.Number()), nil
+			}
+		}
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		if i, ok := toInt64(v); ok && i >= math.MinInt32 && i <= math.MaxInt32 {
+			return protoreflect.ValueOfInt32(int32(i)), nil
+		}
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		if i, ok := toInt64(v); ok {
+			return protoreflect.ValueOfInt64(i), nil
+		}
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		if i, ok := toUint64(v); ok && i <= math.MaxUint32 {
+			return protoreflect.ValueOfUint32(uint32(i)), nil
+		}
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		if i, ok := toUint64(v); ok {
+			return protoreflect.ValueOfUint64(i), nil
+		}
+	case protoreflect.FloatKind:
+		if f, ok := toFloat64(v); ok {
+			return protoreflect.ValueOfFloat32(float32(f)), nil
+		}
+	case protoreflect.DoubleKind:
+		if f, ok := toFloat64(v); ok {
+			return protoreflect.ValueOfFloat64(f), nil
+		}
+	}
+	// The JSON mapping allows the strings of the numbers and the enums.
+	if s, ok := v.(string); ok && fd.Kind() != protoreflect.StringKind && fd.Kind() != protoreflect.BytesKind {
+		if e, err := parseScalar(fd, s); err == nil {
+			return e, nil
+		}
+	}
+	return protoreflect.Value{}, invalidValue(fd.FullName(), v)
+}
+
+// valueToStructValue populates the google.protobuf.Value from the generic
+// value.
+func valueToStructValue(v interface{}, m protoreflect.Message) error {
+	fields := m.Descriptor().Fields()
+	switch v := v.(type) {
+	case nil:
+		m.Set(fields.ByName("null_value"), protoreflect.ValueOfEnum(0))
+		return nil
+	case bool:
+		m.Set(fields.ByName("bool_value"), protoreflect.ValueOfBool(v))
+		return nil
+	case string:
+		m.Set(fields.ByName("string_value"), protoreflect.ValueOfString(v))
+		return nil
+	case map[string]interface{}:
+		return valueToField(v, m, fields.ByName("struct_value"))
+	case []interface{}:
+		return valueToField(v, m, fields.ByName("list_value"))
+	}
+	if f, ok := toFloat64(v); ok {
+		m.Set(fields.ByName("number_value"), protoreflect.ValueOfFloat64(f))
+		return nil
+	}
+	return invalidValue(m.Descriptor().FullName(), v)
+}
+
+func toInt64(v interface{}) (int64, bool) {
+	switch v := v.(type) {
+	case int8:
+		return int64(v), true
+	case int16:
+		return int64(v), true
+	case int32:
+		return int64(v), true
+	case int64:
+		return v, true
+	case int:
+		return int64(v), true
+	case uint8, uint16, uint32, uint64, uint:
+		u, _ := toUint64(v)
+		return int64(u), u <= math.MaxInt64
+	case float32, float64:
+		f, _ := toFloat64(v)
+		return int64(f), f == math.Trunc(f) && f >= math.MinInt64 && f < math.MaxInt64
+	}
+	return 0, false
+}
+
+func toUint64(v interface{}) (uint64, bool) {
+	switch v := v.(type) {
+	case uint8:
+		return uint64(v), true
+	case uint16:
+		return uint64(v), true
+	case uint32:
+		return uint64(v), true
+	case uint64:
+		return v, true
+	case uint:
+		return uint64(v), true
+	case int8, int16, int32, int64, int:
+		i, _ := toInt64(v)
+		return uint64(i), i >= 0
+	case float32, float64:
+		f, _ := toFloat64(v)
+		return uint64(f), f == math.Trunc(f) && f >= 0 && f < math.MaxUint64
+	}
+	return 0, false
+}
+
+func toFloat64(v interface{}) (float64, bool) {
+	switch v := v.(type) {
+	case float32:
+		return float64(v), true
+	case float64:
+		return v, true
+	}
+	if i, ok := toInt64(v); ok {
+		return float64(i), true
+	}
+	if u, ok := toUint64(v); ok {
+		return float64(u), true
+	}
+	return 0, false
+}
+
+func invalidValue(name protoreflect.FullName, v interface{}) error {
+	return fmt.Errorf("httpkit: invalid value %v of %s", v, name)
+}
+
+// isNullable reports whether the null value sets the field, i.e. it's a
+// google.protobuf.Value.
+func isNullable(fd protoreflect.FieldDescriptor) bool {
+	return !fd.IsList() && !fd.IsMap() && fd.Message() != nil && fd.Message().FullName() == "google.protobuf.Value"
+}
+
+// isWrapper reports whether the message is one of the wrappers of the
+// scalar values, e.g. google.protobuf.StringValue.
+func isWrapper(md protoreflect.MessageDescriptor) bool {
+	switch md.FullName() {
+	case "google.protobuf.DoubleValue", "google.protobuf.FloatValue",
+		"google.protobuf.Int64Value", "google.protobuf.UInt64Value",
+		"google.protobuf.Int32Value", "google.protobuf.UInt32Value",
+		"google.protobuf.BoolValue", "google.protobuf.StringValue", "google.protobuf.BytesValue":
+		return true
+	}
+	return false
+}
+
+// timeFields returns the seconds and the nanos of a Timestamp or Duration.
+func timeFields(m protoreflect.Message) (int64, int64) {
+	fields := m.Descriptor().Fields()
+	return m.Get(fields.ByName("seconds")).Int(), m.Get(fields.ByName("nanos")).Int()
+}
+
+func setTimeFields(m protoreflect.Message, seconds int64, nanos int32) {
+	fields := m.Descriptor().Fields()
+	m.Set(fields.ByName("seconds"), protoreflect.ValueOfInt64(seconds))
+	m.Set(fields.ByName("nanos"), protoreflect.ValueOfInt32(nanos))
+}
+
+// fraction formats the nanos with 0, 3, 6 or 9 digits, like protojson.
+func fraction(nanos int64) string {
+	if nanos == 0 {
+		return ""
+	}
+	s := fmt.Sprintf(".%09d", nanos)
+	for strings.HasSuffix(s, "000") {
+		s = s[:len(s)-3]
+	}
+	return s
+}
+
+// parseDuration parses the JSON form of a Duration, e.g. "-1.5s".
+func parseDuration(s string) (int64, int32, bool) {
+	if !strings.HasSuffix(s, "s") {
+		return 0, 0, false
+	}
+	s = strings.TrimSuffix(s, "s")
+	negative := strings.HasPrefix(s, "-")
+	s = strings.TrimPrefix(s, "-")
+	whole, frac, _ := strings.Cut(s, ".")
+	seconds, err := strconv.ParseInt(whole, 10, 64)
+	if err != nil || len(frac) > 9 {
+		return 0, 0, false
+	}
+	var nanos int64
+	if frac != "" {
+		if nanos, err = strconv.ParseInt(frac+strings.Repeat("0", 9-len(frac)), 10, 32); err != nil {
+			return 0, 0, false
+		}
+	}
+	if negative {
+		seconds, nanos = -seconds, -nanos
+	}
+	return seconds, int32(nanos), true
+}
+
+// jsonCamelCase converts the snake_case path of a FieldMask to lowerCamelCase.
+func jsonCamelCase(s string) string {
+	var b strings.Builder
+	upper := false
+	for _, r := range s {
+		switch {
+		case r == '_':
+			upper = true
+		case upper && r >= 'a' && r <= 'z':
+			b.WriteRune(r - 'a' + 'A')
+			upper = false
+		default:
+			b.WriteRune(r)
+			upper = false
+		}
+	}
+	return b.String()
+}
+
+// jsonSnakeCase converts the lowerCamelCase path of a FieldMask to snake_case.
+func jsonSnakeCase(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if r >= 'A' && r <= 'Z' {
+			b.WriteByte('_')
+			r += 'a' - 'A'
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
@@ -0,0 +1,300 @@
+package httpkit
+
+import (
+	"context"
+	"encoding/base64"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/clouway/go-genproto/clouwayapis/rpc/errdetails"
+	httptransport "github.com/go-kit/kit/transport/http"
+	"github.com/gorilla/mux"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// DecodeQueryRequest returns a DecodeRequestFunc that populates a new message
+// of type T from the path variables of the gorilla/mux route and the query
+// parameters of the request, following the rules of the gRPC transcoding:
+//
+//	GET /v1/customers/{customer_id}/orders?filter.status=OPEN&tags=a&tags=b&page_size=10
+//
+// The nested fields are addressed by dots, the repeated fields by repeating
+// the parameter and the map entries as field[key]. The fields are matched by
+// their proto or JSON names, and the parameters that don't match any field
+// are ignored, as are the parameters of the fields that are bound by the
+// path variables, so the query can't replace them. The invalid values are
+// returned as InvalidArgument status errors with BadRequest details.
+func DecodeQueryRequest[T proto.Message]() httptransport.DecodeRequestFunc {
+	return func(_ context.Context, r *http.Request) (interface{}, error) {
+		var zero T
+		m := zero.ProtoReflect().New().Interface().(T)
+		md := m.ProtoReflect().Descriptor()
+		var bound []string
+		for name, value := range mux.Vars(r) {
+			if err := PopulateField(m, name, value); err != nil {
+				return nil, err
+			}
+			if path, ok := FieldPath(md, name); ok {
+				bound = append(bound, path)
+			}
+		}
+		query := url.Values{}
+		for k, v := range r.URL.Query() {
+			if path, ok := FieldPath(md, k); !ok || !isBoundPath(path, bound) {
+				query[k] = v
+			}
+		}
+		if err := PopulateQueryParameters(m, query); err != nil {
+			return nil, err
+		}
+		return m, nil
+	}
+}
+
+// FieldPath resolves the dotted path of proto or JSON field names, as it's
+// accepted by PopulateField, to the path of the proto names of the fields,
+// e.g. "sourceContext.fileName" to "source_context.file_name". The map key
+// of the last field is dropped. It reports false when the path doesn't
+// refer to a field of the message.
+func FieldPath(md protoreflect.MessageDescriptor, path string) (string, bool) {
+	names := strings.Split(path, ".")
+	for i, name := range names {
+		if md == nil {
+			return "", false
+		}
+		if j := strings.IndexByte(name, '['); j > 0 && strings.HasSuffix(name, "]") {
+			name = name[:j]
+		}
+		fields := md.Fields()
+		fd := fields.ByName(protoreflect.Name(name))
+		if fd == nil {
+			fd = fields.ByJSONName(name)
+		}
+		if fd == nil {
+			return "", false
+		}
+		names[i] = string(fd.Name())
+		md = fd.Message()
+	}
+	return strings.Join(names, "."), true
+}
+
+// isBoundPath reports whether the field path is one of the bound fields or
+// a field nested in them.
+func isBoundPath(path string, bound []string) bool {
+	for _, field := range bound {
+		if path == field || strings.HasPrefix(path, field+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// PopulateQueryParameters sets the fields of the message from the query
+// parameters. See DecodeQueryRequest for the supported forms.
+func PopulateQueryParameters(m proto.Message, values url.Values) error {
+	// The parameters are applied in a stable order, so the errors are too.
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if err := PopulateField(m, k, values[k]...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// PopulateField sets the field at the dotted path to the passed values. The
+// unknown fields are ignored. The values are appended to the repeated
+// fields, while the singular fields accept only a single value.
+func PopulateField(m proto.Message, path string, values ...string) error {
+	msg := m.ProtoReflect()
+	names := strings.Split(path, ".")
+	for i, name := range names {
+		key, hasKey := "", false
+		if j := strings.IndexByte(name, '['); j > 0 && strings.HasSuffix(name, "]") {
+			name, key, hasKey = name[:j], name[j+1:len(name)-1], true
+		}
+		fields := msg.Descriptor().Fields()
+		fd := fields.ByName(protoreflect.Name(name))
+		if fd == nil {
+			fd = fields.ByJSONName(name)
+		}
+		if fd == nil {
+			return nil
+		}
+
+		last := i == len(names)-1
+		switch {
+		case hasKey:
+			if !fd.IsMap() || !last {
+				return fieldError(path, "is not a map of scalar values")
+			}
+			return populateMapEntry(msg, fd, path, key, values)
+		case last:
+			return populateValues(msg, fd, path, values)
+		case fd.Message() == nil || fd.IsList() || fd.IsMap() || isWellKnownType(fd.Message()):
+			return fieldError(path, "cannot address nested field of "+string(fd.Name()))
+		}
+		msg = msg.Mutable(fd).Message()
+	}
+	return nil
+}
+
+func populateValues(msg protoreflect.Message, fd protoreflect.FieldDescriptor, path string, values []string) error {
+	if fd.IsMap() {
+		return fieldError(path, "map entries must be passed as "+path+"[key]")
+	}
+	if fd.IsList() {
+		list := msg.Mutable(fd).List()
+		for _, value := range values {
+			v, err := parseValue(fd, list.NewElement, value)
+			if err != nil {
+				return fieldError(path, reasonOf(err, value))
+			}
+			list.Append(v)
+		}
+		return nil
+	}
+	if len(values) != 1 {
+		return fieldError(path, "expected a single value")
+	}
+	v, err := parseValue(fd, func() protoreflect.Value { return msg.NewField(fd) }, values[0])
+	if err != nil {
+		return fieldError(path, reasonOf(err, values[0]))
+	}
+	msg.Set(fd, v)
+	return nil
+}
+
+func populateMapEntry(msg protoreflect.Message, fd protoreflect.FieldDescriptor, path, key string, values []string) error {
+	if len(values) != 1 {
+		return fieldError(path, "expected a single value")
+	}
+	k, err := parseScalar(fd.MapKey(), key)
+	if err != nil {
+		return fieldError(path, reasonOf(err, key))
+	}
+	mv := msg.Mutable(fd).Map()
+	v, err := parseValue(fd.MapValue(), mv.NewValue, values[0])
+	if err != nil {
+		return fieldError(path, reasonOf(err, values[0]))
+	}
+	mv.Set(k.MapKey(), v)
+	return nil
+}
+
+// parseValue parses the value of the field. The messages are limited to the
+// well known types that have a string form, e.g. Timestamp.
+func parseValue(fd protoreflect.FieldDescriptor, newValue func() protoreflect.Value, value string) (protoreflect.Value, error) {
+	if fd.Kind() != protoreflect.MessageKind {
+		return parseScalar(fd, value)
+	}
+	if !isWellKnownType(fd.Message()) {
+		return protoreflect.Value{}, errUnsupportedMessage
+	}
+	v := newValue()
+	m := v.Message().Interface()
+	// The string forms are quoted in JSON, while the wrappers of the
+	// numbers and bools are not.
+	if err := protojson.Unmarshal([]byte(strconv.Quote(value)), m); err != nil {
+		proto.Reset(m)
+		if err := protojson.Unmarshal([]byte(value), m); err != nil {
+			return protoreflect.Value{}, err
+		}
+	}
+	return v, nil
+}
+
+var errUnsupportedMessage = queryError("message fields cannot be set by query parameters")
+
+type queryError string
+
+func (e queryError) Error() string { return string(e) }
+
+// isWellKnownType reports whether the message is one of the well known types
+// with a scalar JSON form.
+func isWellKnownType(md protoreflect.MessageDescriptor) bool {
+	switch md.FullName() {
+	case "google.protobuf.Timestamp", "google.protobuf.Duration", "google.protobuf.FieldMask",
+		"google.protobuf.DoubleValue", "google.protobuf.FloatValue",
+		"google.protobuf.Int64Value", "google.protobuf.UInt64Value",
+		"google.protobuf.Int32Value", "google.protobuf.UInt32Value",
+		"google.protobuf.BoolValue", "google.protobuf.StringValue", "google.protobuf.BytesValue":
+		return true
+	}
+	return false
+}
+
+func parseScalar(fd protoreflect.FieldDescriptor, value string) (protoreflect.Value, error) {
+	switch fd.Kind() {
+	case protoreflect.StringKind:
+		return protoreflect.ValueOfString(value), nil
+	case protoreflect.BoolKind:
+		b, err := strconv.ParseBool(value)
+		return protoreflect.ValueOfBool(b), err
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		i, err := strconv.ParseInt(value, 10, 32)
+		return protoreflect.ValueOfInt32(int32(i)), err
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		i, err := strconv.ParseInt(value, 10, 64)
+		return protoreflect.ValueOfInt64(i), err
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		i, err := strconv.ParseUint(value, 10, 32)
+		return protoreflect.ValueOfUint32(uint32(i)), err
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		i, err := strconv.ParseUint(value, 10, 64)
+		return protoreflect.ValueOfUint64(i), err
+	case protoreflect.FloatKind:
+		f, err := strconv.ParseFloat(value, 32)
+		return protoreflect.ValueOfFloat32(float32(f)), err
+	case protoreflect.DoubleKind:
+		f, err := strconv.ParseFloat(value, 64)
+		return protoreflect.ValueOfFloat64(f), err
+	case protoreflect.EnumKind:
+		if ev := fd.Enum().Values().ByName(protoreflect.Name(value)); ev != nil {
+			return protoreflect.ValueOfEnum(ev.Number()), nil
+		}
+		i, err := strconv.ParseInt(value, 10, 32)
+		if err != nil {
+			return protoreflect.Value{}, queryError("unknown enum value '" + value + "'")
+		}
+		return protoreflect.ValueOfEnum(protoreflect.EnumNumber(i)), nil
+	case protoreflect.BytesKind:
+		b, err := base64.StdEncoding.DecodeString(value)
+		if err != nil {
+			b, err = base64.URLEncoding.DecodeString(value)
+		}
+		return protoreflect.ValueOfBytes(b), err
+	}
+	return protoreflect.Value{}, errUnsupportedMessage
+}
+
+// reasonOf returns the reason of the field violation of the parsing error.
+func reasonOf(err error, value string) string {
+	if qe, ok := err.(queryError); ok {
+		return string(qe)
+	}
+	return "invalid value '" + value + "'"
+}
+
+// fieldError returns an InvalidArgument status error with the field
+// violation of the query parameter.
+func fieldError(path, reason string) error {
+	message := "invalid parameter '" + path + "': " + reason
+	st, _ := status.New(codes.InvalidArgument, message).WithDetails(&errdetails.BadRequest{
+		Message: message,
+		Errors:  []*errdetails.BadRequest_FieldViolation{{Field: path, Reason: reason}},
+	})
+	return st.Err()
+}
@@ -0,0 +1,131 @@
+package httpkit_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/clouway/go-genproto/clouwayapis/rpc/errdetails"
+	"github.com/clouway/go-genproto/clouwayapis/rpc/httpkit"
+	"github.com/gorilla/mux"
+	"google.golang.org/genproto/googleapis/type/interval"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/apipb"
+	"google.golang.org/protobuf/types/known/sourcecontextpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"google.golang.org/protobuf/types/known/typepb"
+)
+
+func TestDecodeQueryRequest(t *testing.T) {
+	var got interface{}
+	router := mux.NewRouter()
+	router.Handle("/apis/{name}", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var err error
+		if got, err = httpkit.DecodeQueryRequest[*apipb.Api]()(context.Background(), r); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}))
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/apis/orders?version=v1&sourceContext.file_name=orders.proto&syntax=SYNTAX_PROTO3&unknown=1", nil))
+
+	want := &apipb.Api{
+		Name:          "orders",
+		Version:       "v1",
+		SourceContext: &sourcecontextpb.SourceContext{FileName: "orders.proto"},
+		Syntax:        typepb.Syntax_SYNTAX_PROTO3,
+	}
+	if !proto.Equal(got.(proto.Message), want) {
+		t.Errorf("unexpected request:\n- want: %v\n-  got: %v", want, got)
+	}
+}
+
+func TestDecodeQueryRequestIgnoresBoundFields(t *testing.T) {
+	var got interface{}
+	router := mux.NewRouter()
+	router.Handle("/apis/{name}/files/{source_context.file_name}", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var err error
+		if got, err = httpkit.DecodeQueryRequest[*apipb.Api]()(context.Background(), r); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}))
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/apis/orders/files/orders.proto?name=billing&sourceContext.fileName=billing.proto&version=v1", nil))
+
+	want := &apipb.Api{
+		Name:          "orders",
+		Version:       "v1",
+		SourceContext: &sourcecontextpb.SourceContext{FileName: "orders.proto"},
+	}
+	if !proto.Equal(got.(proto.Message), want) {
+		t.Errorf("unexpected request:\n- want: %v\n-  got: %v", want, got)
+	}
+}
+
+func TestPopulateQueryParameters(t *testing.T) {
+	tests := []struct {
+		name   string
+		query  string
+		target proto.Message
+		want   proto.Message
+	}{
+		{
+			name:   "map entries",
+			query:  "metadata[region]=eu&metadata[tier]=gold&reason=LIMIT",
+			target: &errdetails.ErrorInfo{},
+			want:   &errdetails.ErrorInfo{Reason: "LIMIT", Metadata: map[string]string{"region": "eu", "tier": "gold"}},
+		},
+		{
+			name:   "well known types",
+			query:  "start_time=2024-01-02T03:04:05Z",
+			target: &interval.Interval{},
+			want:   &interval.Interval{StartTime: timestamppb.New(time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC))},
+		},
+		{
+			name:   "repeated fields",
+			query:  "oneofs=a&oneofs=b",
+			target: &typepb.Type{},
+			want:   &typepb.Type{Oneofs: []string{"a", "b"}},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			values, _ := url.ParseQuery(test.query)
+			if err := httpkit.PopulateQueryParameters(test.target, values); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if !proto.Equal(test.target, test.want) {
+				t.Errorf("unexpected message:\n- want: %v\n-  got: %v", test.want, test.target)
+			}
+		})
+	}
+}
+
+func TestPopulateQueryParametersErrors(t *testing.T) {
+	tests := []struct {
+		query string
+		field string
+	}{
+		{query: "syntax=UNKNOWN", field: "syntax"},
+		{query: "version=v1&version=v2", field: "version"},
+		{query: "methods=GetOrder", field: "methods"},
+	}
+	for _, test := range tests {
+		values, _ := url.ParseQuery(test.query)
+		err := httpkit.PopulateQueryParameters(&apipb.Api{}, values)
+
+		st := status.Convert(err)
+		if st.Code() != codes.InvalidArgument {
+			t.Errorf("unexpected code of %s:\n- want: %v\n-  got: %v", test.query, codes.InvalidArgument, st.Code())
+			continue
+		}
+		if got := st.Details()[0].(*errdetails.BadRequest).Errors[0].Field; got != test.field {
+			t.Errorf("unexpected field of %s:\n- want: %v\n-  got: %v", test.query, test.field, got)
+		}
+	}
+}
@@ -0,0 +1,164 @@
+package httpkit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Rate is the rate limit of a token bucket: Limit requests can be made at
+// once, and the bucket is refilled with Limit tokens per Period.
+type Rate struct {
+	Limit  int
+	Period time.Duration
+}
+
+// validate reports the rates that can't refill a bucket.
+func (r Rate) validate() error {
+	if r.Limit <= 0 || r.Period <= 0 {
+		return fmt.Errorf("httpkit: invalid rate %d per %v", r.Limit, r.Period)
+	}
+	return nil
+}
+
+// RateLimitResult is the result of taking a token from a bucket.
+type RateLimitResult struct {
+	// Allowed reports whether a token was taken.
+	Allowed bool
+	// Remaining is the number of the tokens that are left in the bucket.
+	Remaining int
+	// Reset is the time until the next token when the request is not
+	// allowed, and until the bucket is full again otherwise.
+	Reset time.Duration
+}
+
+// RateLimitStore keeps the token buckets of the rate limited keys, e.g. in
+// memory or in Redis for the limits that are shared by the replicas.
+type RateLimitStore interface {
+	Take(ctx context.Context, key string, rate Rate) (RateLimitResult, error)
+}
+
+// MemoryRateLimitStore is a RateLimitStore that keeps the buckets in
+// memory.
+type MemoryRateLimitStore struct {
+	mu      sync.Mutex
+	buckets map[string]*bucket
+}
+
+type bucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+// maxIdleBuckets is the number of buckets above which the full buckets are
+// evicted from the memory store.
+const maxIdleBuckets = 10000
+
+// NewMemoryRateLimitStore creates an empty in-memory store.
+func NewMemoryRateLimitStore() *MemoryRateLimitStore {
+	return &MemoryRateLimitStore{buckets: map[string]*bucket{}}
+}
+
+// Take takes a token from the bucket of the key. The invalid rates are
+// returned as errors.
+func (s *MemoryRateLimitStore) Take(_ context.Context, key string, rate Rate) (RateLimitResult, error) {
+	if err := rate.validate(); err != nil {
+		return RateLimitResult{}, err
+	}
+	return s.take(key, rate, time.Now()), nil
+}
+
+func (s *MemoryRateLimitStore) take(key string, rate Rate, now time.Time) RateLimitResult {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	perToken := float64(rate.Period) / float64(rate.Limit)
+	refill := func(b *bucket) float64 {
+		return math.Min(float64(rate.Limit), b.tokens+float64(now.Sub(b.updated))/perToken)
+	}
+	b, ok := s.buckets[key]
+	if !ok {
+		if len(s.buckets) >= maxIdleBuckets {
+			for k, b := range s.buckets {
+				if refill(b) >= float64(rate.Limit) {
+					delete(s.buckets, k)
+				}
+			}
+		}
+		b = &bucket{tokens: float64(rate.Limit), updated: now}
+		s.buckets[key] = b
+	}
+	b.tokens, b.updated = refill(b), now
+
+	if b.tokens < 1 {
+		return RateLimitResult{Reset: time.Duration((1 - b.tokens) * perToken)}
+	}
+	b.tokens--
+	return RateLimitResult{
+		Allowed:   true,
+		Remaining: int(b.tokens),
+		Reset:     time.Duration((float64(rate.Limit) - b.tokens) * perToken),
+	}
+}
+
+// RateLimitByHeader returns the key of the requests by the header, e.g. the
+// tenant or the API key of the caller.
+func RateLimitByHeader(name string) func(r *http.Request) string {
+	return func(r *http.Request) string { return r.Header.Get(name) }
+}
+
+// RateLimitByIP returns the key of the requests by the IP address of the
+// client. The address is taken from the connection, so the proxies should
+// set RemoteAddr from X-Forwarded-For before the middleware.
+func RateLimitByIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// RateLimit is an HTTP middleware that limits the requests with the token
+// buckets of the store, one per key of the requests. The requests with
+// empty keys are not limited. The allowed requests get the
+// X-RateLimit-Limit, X-RateLimit-Remaining and X-RateLimit-Reset headers,
+// and the requests over the limit are rejected with the ResourceExhausted
+// error of NewRateLimitError, with RetryInfo and Retry-After set to the
+// time until the next token. The requests are allowed when the store fails,
+// so an outage of the store doesn't take the service down.
+//
+// RateLimit panics when the Limit or the Period of the rate are not
+// positive, as such rates can't refill the buckets.
+func RateLimit(store RateLimitStore, rate Rate, key func(r *http.Request) string) func(http.Handler) http.Handler {
+	if err := rate.validate(); err != nil {
+		panic(err)
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			k := key(r)
+			if k == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			result, err := store.Take(r.Context(), k, rate)
+			if err != nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if !result.Allowed {
+				ErrorEncoder(r.Context(), NewRateLimitError(rate.Limit, 0, result.Reset), w)
+				return
+			}
+
+			w.Header().Set("X-RateLimit-Limit", strconv.Itoa(rate.Limit))
+			w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(result.Remaining))
+			w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(int64(math.Ceil(result.Reset.Seconds())), 10))
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,60 @@
+package httpkit_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/clouway/go-genproto/clouwayapis/rpc/httpkit"
+)
+
+func TestRateLimit(t *testing.T) {
+	handler := httpkit.RateLimit(httpkit.NewMemoryRateLimitStore(), httpkit.Rate{Limit: 2, Period: time.Minute}, httpkit.RateLimitByHeader("X-Tenant"))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	tests := []struct {
+		tenant     string
+		status     int
+		remaining  string
+		retryAfter string
+	}{
+		{tenant: "tenant-1", status: http.StatusOK, remaining: "1"},
+		{tenant: "tenant-1", status: http.StatusOK, remaining: "0"},
+		{tenant: "tenant-1", status: http.StatusTooManyRequests, remaining: "0", retryAfter: "30"},
+		{tenant: "tenant-2", status: http.StatusOK, remaining: "1"},
+		{tenant: "", status: http.StatusOK},
+	}
+	for _, test := range tests {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set("X-Tenant", test.tenant)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+
+		if w.Code != test.status {
+			t.Errorf("unexpected status code of %q:\n- want: %v\n-  got: %v", test.tenant, test.status, w.Code)
+		}
+		if got := w.Header().Get("X-RateLimit-Remaining"); got != test.remaining {
+			t.Errorf("unexpected X-RateLimit-Remaining header of %q:\n- want: %v\n-  got: %v", test.tenant, test.remaining, got)
+		}
+		if got := w.Header().Get("Retry-After"); got != test.retryAfter {
+			t.Errorf("unexpected Retry-After header of %q:\n- want: %v\n-  got: %v", test.tenant, test.retryAfter, got)
+		}
+	}
+}
+
+func TestRateLimitInvalidRate(t *testing.T) {
+	for _, rate := range []httpkit.Rate{{Limit: 0, Period: time.Minute}, {Limit: 10}} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("expected a panic for the rate %v", rate)
+				}
+			}()
+			httpkit.RateLimit(httpkit.NewMemoryRateLimitStore(), rate, httpkit.RateLimitByIP)
+		}()
+		if _, err := httpkit.NewMemoryRateLimitStore().Take(context.Background(), "key", rate); err == nil {
+			t.Errorf("expected an error for the rate %v", rate)
+		}
+	}
+}
@@ -0,0 +1,165 @@
+package httpkit
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"runtime/debug"
+	"strings"
+
+	"github.com/clouway/go-genproto/clouwayapis/rpc/request"
+	"github.com/go-kit/kit/endpoint"
+	httptransport "github.com/go-kit/kit/transport/http"
+	"github.com/go-kit/log"
+	gerrdetails "google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// RecovererOption sets an optional parameter for the panic recovery
+// middlewares.
+type RecovererOption func(*recoverer)
+
+// RecovererLogger sets the logger which receives the recovered panics and
+// their stack traces. The panics are logged to stderr by default.
+func RecovererLogger(logger log.Logger) RecovererOption {
+	return func(r *recoverer) { r.logger = logger }
+}
+
+// RecovererErrorEncoder sets the encoder that writes the Internal status
+// error of the recovered panic. ErrorEncoder is used by default.
+func RecovererErrorEncoder(ee httptransport.ErrorEncoder) RecovererOption {
+	return func(r *recoverer) { r.errorEncoder = ee }
+}
+
+// RecovererDebugInfo attaches the stack trace of the panic as DebugInfo
+// detail of the returned error. It should not be enabled for services that
+// are exposed to external clients.
+func RecovererDebugInfo(enabled bool) RecovererOption {
+	return func(r *recoverer) { r.debugInfo = enabled }
+}
+
+// Recoverer is an HTTP middleware that recovers the panics of the next
+// handler, logs them with their stack trace and responds with an Internal
+// status error encoded by ErrorEncoder.
+func Recoverer(next http.Handler) http.Handler {
+	return NewRecoverer()(next)
+}
+
+// NewRecoverer creates a panic recovery HTTP middleware configured with the
+// passed options.
+func NewRecoverer(options ...RecovererOption) func(http.Handler) http.Handler {
+	r := newRecoverer(options...)
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			defer func() {
+				if v := recover(); v != nil {
+					r.errorEncoder(req.Context(), r.recoveredHandler(req.Context(), v), w)
+				}
+			}()
+			next.ServeHTTP(w, req)
+		})
+	}
+}
+
+// RecoverMiddleware is the go-kit variant of Recoverer. It recovers the
+// panics of the wrapped endpoint and returns them as Internal status error,
+// so they are written by the error encoder of the transport server. The
+// server options cannot intercept the panics of the request decoder and
+// the response encoder, so they are wrapped by RecoverDecoder and
+// RecoverEncoder:
+//
+//	httptransport.NewServer(
+//		httpkit.RecoverMiddleware()(e),
+//		httpkit.RecoverDecoder(decodeRequest),
+//		httpkit.RecoverEncoder(encodeResponse),
+//		httptransport.ServerErrorEncoder(httpkit.ErrorEncoder),
+//	)
+func RecoverMiddleware(options ...RecovererOption) endpoint.Middleware {
+	r := newRecoverer(options...)
+	return func(next endpoint.Endpoint) endpoint.Endpoint {
+		return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+			defer func() {
+				if v := recover(); v != nil {
+					response, err = nil, r.recovered(ctx, v)
+				}
+			}()
+			return next(ctx, request)
+		}
+	}
+}
+
+// RecoverDecoder wraps the request decoder of the go-kit transport server
+// and returns its panics as Internal status errors.
+func RecoverDecoder(dec httptransport.DecodeRequestFunc, options ...RecovererOption) httptransport.DecodeRequestFunc {
+	r := newRecoverer(options...)
+	return func(ctx context.Context, req *http.Request) (request interface{}, err error) {
+		defer func() {
+			if v := recover(); v != nil {
+				request, err = nil, r.recoveredHandler(ctx, v)
+			}
+		}()
+		return dec(ctx, req)
+	}
+}
+
+// RecoverEncoder wraps the response encoder of the go-kit transport server
+// and returns its panics as Internal status errors.
+func RecoverEncoder(enc httptransport.EncodeResponseFunc, options ...RecovererOption) httptransport.EncodeResponseFunc {
+	r := newRecoverer(options...)
+	return func(ctx context.Context, w http.ResponseWriter, response interface{}) (err error) {
+		defer func() {
+			if v := recover(); v != nil {
+				err = r.recoveredHandler(ctx, v)
+			}
+		}()
+		return enc(ctx, w, response)
+	}
+}
+
+type recoverer struct {
+	logger       log.Logger
+	errorEncoder httptransport.ErrorEncoder
+	debugInfo    bool
+}
+
+func newRecoverer(options ...RecovererOption) *recoverer {
+	r := &recoverer{
+		logger:       log.NewLogfmtLogger(log.NewSyncWriter(os.Stderr)),
+		errorEncoder: ErrorEncoder,
+	}
+	for _, option := range options {
+		option(r)
+	}
+	return r
+}
+
+// recoveredHandler is like recovered, but it panics again with
+// http.ErrAbortHandler, so the HTTP server still aborts the request.
+func (r *recoverer) recoveredHandler(ctx context.Context, v interface{}) error {
+	if v == http.ErrAbortHandler {
+		panic(v)
+	}
+	return r.recovered(ctx, v)
+}
+
+// recovered logs the recovered panic value along with the id of the request
+// and converts it to an Internal status error.
+func (r *recoverer) recovered(ctx context.Context, v interface{}) error {
+	stack := string(debug.Stack())
+	keyvals := []interface{}{"msg", "recovered from panic", "panic", fmt.Sprint(v), "stack", stack}
+	if id := request.RequestIDFromContext(ctx); id != "" {
+		keyvals = append(keyvals, "request_id", id)
+	}
+	r.logger.Log(keyvals...)
+
+	st := status.New(codes.Internal, "internal error")
+	if r.debugInfo {
+		st, _ = st.WithDetails(&gerrdetails.DebugInfo{
+			StackEntries: strings.Split(strings.TrimSpace(stack), "\n"),
+			Detail:       fmt.Sprint(v),
+		})
+	}
+	return st.Err()
+}
@@ -0,0 +1,80 @@
+package httpkit_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/clouway/go-genproto/clouwayapis/rpc/httpkit"
+	"github.com/go-kit/kit/endpoint"
+	httptransport "github.com/go-kit/kit/transport/http"
+	"github.com/go-kit/log"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestRecoverer(t *testing.T) {
+	handler := httpkit.NewRecoverer(httpkit.RecovererLogger(log.NewNopLogger()))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("unexpected status code:\n- want: %v\n-  got: %v", http.StatusInternalServerError, rec.Code)
+	}
+	if want, got := `{"message":"internal error"}`, rec.Body.String(); want != got {
+		t.Errorf("unexpected body:\n- want: %v\n-  got: %v", want, got)
+	}
+}
+
+func TestRecoverMiddleware(t *testing.T) {
+	e := httpkit.RecoverMiddleware(httpkit.RecovererLogger(log.NewNopLogger()))(func(ctx context.Context, request interface{}) (interface{}, error) {
+		panic("boom")
+	})
+
+	_, err := e(context.Background(), nil)
+
+	if got := status.Code(err); got != codes.Internal {
+		t.Errorf("unexpected error code:\n- want: %v\n-  got: %v", codes.Internal, got)
+	}
+}
+
+func TestRecoverDecoderAndEncoder(t *testing.T) {
+	logger := httpkit.RecovererLogger(log.NewNopLogger())
+	boom := func(ctx context.Context, r *http.Request) (interface{}, error) { panic("boom") }
+	nop := func(ctx context.Context, r *http.Request) (interface{}, error) { return nil, nil }
+
+	tests := []struct {
+		name   string
+		server *httptransport.Server
+	}{
+		{name: "decoder", server: httptransport.NewServer(
+			endpoint.Nop,
+			httpkit.RecoverDecoder(boom, logger),
+			httptransport.EncodeJSONResponse,
+			httptransport.ServerErrorEncoder(httpkit.ErrorEncoder),
+		)},
+		{name: "encoder", server: httptransport.NewServer(
+			endpoint.Nop,
+			nop,
+			httpkit.RecoverEncoder(func(ctx context.Context, w http.ResponseWriter, response interface{}) error { panic("boom") }, logger),
+			httptransport.ServerErrorEncoder(httpkit.ErrorEncoder),
+		)},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			test.server.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+			if rec.Code != http.StatusInternalServerError {
+				t.Errorf("unexpected status code:\n- want: %v\n-  got: %v", http.StatusInternalServerError, rec.Code)
+			}
+			if want, got := `{"message":"internal error"}`, rec.Body.String(); want != got {
+				t.Errorf("unexpected body:\n- want: %v\n-  got: %v", want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,47 @@
+package httpkit
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"net/http"
+
+	"github.com/clouway/go-genproto/clouwayapis/rpc/request"
+)
+
+// RequestIDHeader is the header with the id of the request.
+const RequestIDHeader = "X-Request-Id"
+
+// RequestID is an HTTP middleware that reads the id of the request from the
+// X-Request-Id header, or generates one when it's missing or invalid, stores
+// it in the context under request.RequestIDKey and sets it as X-Request-Id
+// header of the response. The id is included in the logs of Recoverer and
+// AccessLog, and in the error bodies of the error encoders with the RequestIDKey schema.
+func RequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := RequestIDToContext(r.Context(), r)
+		w.Header().Set(RequestIDHeader, request.RequestIDFromContext(ctx))
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// RequestIDToContext is a transport/http.RequestFunc that stores the id of
+// the request in the context in the same way as RequestID. The valid id of
+// the context is kept, e.g. when RequestID already stored it.
+func RequestIDToContext(ctx context.Context, r *http.Request) context.Context {
+	if id := request.RequestIDFromContext(ctx); id != "" {
+		return ctx
+	}
+	id := r.Header.Get(RequestIDHeader)
+	if !request.ValidRequestID(id) {
+		id = NewRequestID()
+	}
+	return request.WithRequestID(ctx, id)
+}
+
+// NewRequestID generates a random request id of 32 hex characters.
+func NewRequestID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return fmt.Sprintf("%x", b)
+}
@@ -0,0 +1,76 @@
+package httpkit_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/clouway/go-genproto/clouwayapis/rpc/httpkit"
+	"github.com/clouway/go-genproto/clouwayapis/rpc/request"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestRequestID(t *testing.T) {
+	tests := []struct {
+		name     string
+		header   string
+		generate bool
+	}{
+		{name: "from header", header: "abc-123"},
+		{name: "generated", generate: true},
+		{name: "invalid", header: "abc 123", generate: true},
+		{name: "too long", header: strings.Repeat("a", 129), generate: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var got string
+			handler := httpkit.RequestID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = request.RequestIDFromContext(r.Context())
+			}))
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			if test.header != "" {
+				r.Header.Set(httpkit.RequestIDHeader, test.header)
+			}
+
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+
+			if test.generate && (len(got) != 32 || got == test.header) {
+				t.Errorf("unexpected generated request id: %v", got)
+			}
+			if !test.generate && got != test.header {
+				t.Errorf("unexpected request id:\n- want: %v\n-  got: %v", test.header, got)
+			}
+			if header := w.Header().Get(httpkit.RequestIDHeader); header != got {
+				t.Errorf("unexpected X-Request-Id header:\n- want: %v\n-  got: %v", got, header)
+			}
+		})
+	}
+}
+
+func TestRequestIDToContextValidatesHeadersInContext(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set(httpkit.RequestIDHeader, "abc 123")
+	ctx := httpkit.HeadersToContext(context.Background(), r)
+
+	got := request.RequestIDFromContext(httpkit.RequestIDToContext(ctx, r))
+
+	if len(got) != 32 {
+		t.Errorf("unexpected generated request id: %v", got)
+	}
+}
+
+func TestErrorEncoderWithRequestID(t *testing.T) {
+	encode := httpkit.NewErrorEncoder(httpkit.ErrorEncoderSchema(httpkit.ErrorSchema{RequestIDKey: "requestId"}))
+	ctx := request.WithRequestID(context.Background(), "abc-123")
+
+	w := httptest.NewRecorder()
+	encode(ctx, status.Error(codes.NotFound, "not found"), w)
+
+	if want, got := `{"message":"not found","requestId":"abc-123"}`, w.Body.String(); want != got {
+		t.Errorf("unexpected body:\n- want: %v\n-  got: %v", want, got)
+	}
+}
@@ -0,0 +1,114 @@
+package httpkit
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// routeMethods are the methods that are probed when the allowed methods of
+// a path are resolved.
+var routeMethods = []string{
+	http.MethodGet,
+	http.MethodHead,
+	http.MethodPost,
+	http.MethodPut,
+	http.MethodPatch,
+	http.MethodDelete,
+}
+
+// HandleMethods configures the router to answer the OPTIONS requests of the
+// known paths with 204 No Content and an Allow header listing the methods
+// registered for the path. The HEAD requests of paths with GET route are
+// served by the GET handler as described in HeadFromGet. Requests with
+// method that is not registered for a known path are answered with the
+// error of NewMethodNotAllowedError that is encoded by ErrorEncoder as 405
+// Method Not Allowed.
+func HandleMethods(router *mux.Router) {
+	router.MethodNotAllowedHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		allowed := AllowedMethods(router, r)
+		if r.Method == http.MethodHead && contains(allowed, http.MethodGet) {
+			serveHead(router, w, r)
+			return
+		}
+		if r.Method == http.MethodOptions {
+			w.Header().Set("Allow", strings.Join(append(allowed, http.MethodOptions), ", "))
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		w.Header().Set("Allow", strings.Join(allowed, ", "))
+		ErrorEncoder(r.Context(), NewMethodNotAllowedError(r.Method, allowed), w)
+	})
+}
+
+// Methods returns a handler that dispatches the requests of a single path to
+// the handlers of their methods, e.g. to the go-kit servers of the endpoints
+// that are registered in an http.ServeMux without a router. The OPTIONS and
+// HEAD requests and the requests with other methods are answered in the
+// same way as by HandleMethods:
+//
+//	mux.Handle("/v1/orders", httpkit.Methods(map[string]http.Handler{
+//		http.MethodGet:  listOrders,
+//		http.MethodPost: createOrder,
+//	}))
+func Methods(handlers map[string]http.Handler) http.Handler {
+	var allowed []string
+	for _, method := range routeMethods {
+		if handlers[method] != nil || method == http.MethodHead && handlers[http.MethodGet] != nil {
+			allowed = append(allowed, method)
+		}
+	}
+	var other []string
+	for method := range handlers {
+		if !contains(routeMethods, method) && method != http.MethodOptions {
+			other = append(other, method)
+		}
+	}
+	sort.Strings(other)
+	allowed = append(allowed, other...)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if h, ok := handlers[r.Method]; ok {
+			h.ServeHTTP(w, r)
+			return
+		}
+		switch {
+		case r.Method == http.MethodHead && handlers[http.MethodGet] != nil:
+			serveHead(handlers[http.MethodGet], w, r)
+		case r.Method == http.MethodOptions:
+			w.Header().Set("Allow", strings.Join(append(allowed, http.MethodOptions), ", "))
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.Header().Set("Allow", strings.Join(allowed, ", "))
+			ErrorEncoder(r.Context(), NewMethodNotAllowedError(r.Method, allowed), w)
+		}
+	})
+}
+
+// AllowedMethods returns the methods for which the router has a route that
+// matches the path of the request. HEAD is allowed for all paths that have
+// GET route.
+func AllowedMethods(router *mux.Router, r *http.Request) []string {
+	var allowed []string
+	for _, method := range routeMethods {
+		probe := r.Clone(r.Context())
+		probe.Method = method
+		var match mux.RouteMatch
+		if router.Match(probe, &match) && match.MatchErr == nil {
+			allowed = append(allowed, method)
+		} else if method == http.MethodHead && contains(allowed, http.MethodGet) {
+			allowed = append(allowed, method)
+		}
+	}
+	return allowed
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
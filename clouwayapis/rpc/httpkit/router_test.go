@@ -0,0 +1,93 @@
+package httpkit_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/clouway/go-genproto/clouwayapis/rpc/httpkit"
+	"github.com/gorilla/mux"
+)
+
+func TestHandleMethods(t *testing.T) {
+	router := mux.NewRouter()
+	router.HandleFunc("/v1/orders/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "max-age=60")
+		w.Write([]byte(`{"id":"123"}`))
+	}).Methods(http.MethodGet)
+	router.HandleFunc("/v1/orders/{id}", func(w http.ResponseWriter, r *http.Request) {}).Methods(http.MethodDelete)
+	httpkit.HandleMethods(router)
+
+	tests := []struct {
+		name   string
+		method string
+		status int
+		allow  string
+		length string
+		body   string
+	}{
+		{name: "options", method: http.MethodOptions, status: http.StatusNoContent, allow: "GET, HEAD, DELETE, OPTIONS"},
+		{name: "method not allowed", method: http.MethodPost, status: http.StatusMethodNotAllowed, allow: "GET, HEAD, DELETE", body: `{"reason":"METHOD_NOT_ALLOWED","metadata":{"allow":"GET, HEAD, DELETE"}}`},
+		{name: "allowed", method: http.MethodGet, status: http.StatusOK, body: `{"id":"123"}`},
+		{name: "head from get", method: http.MethodHead, status: http.StatusOK, length: "12"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, httptest.NewRequest(test.method, "/v1/orders/123", nil))
+
+			if rec.Code != test.status {
+				t.Errorf("unexpected status code:\n- want: %v\n-  got: %v", test.status, rec.Code)
+			}
+			if got := rec.Header().Get("Allow"); got != test.allow {
+				t.Errorf("unexpected Allow header:\n- want: %v\n-  got: %v", test.allow, got)
+			}
+			if got := rec.Header().Get("Content-Length"); got != test.length {
+				t.Errorf("unexpected Content-Length header:\n- want: %v\n-  got: %v", test.length, got)
+			}
+			if got := compactJSON(rec.Body.Bytes()); got != test.body {
+				t.Errorf("unexpected body:\n- want: %v\n-  got: %v", test.body, got)
+			}
+		})
+	}
+}
+
+func TestMethods(t *testing.T) {
+	handler := httpkit.Methods(map[string]http.Handler{
+		http.MethodGet: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{"id":"123"}`))
+		}),
+		http.MethodPost: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusCreated)
+		}),
+	})
+
+	tests := []struct {
+		name   string
+		method string
+		status int
+		allow  string
+		length string
+	}{
+		{name: "options", method: http.MethodOptions, status: http.StatusNoContent, allow: "GET, HEAD, POST, OPTIONS"},
+		{name: "method not allowed", method: http.MethodDelete, status: http.StatusMethodNotAllowed, allow: "GET, HEAD, POST"},
+		{name: "allowed", method: http.MethodPost, status: http.StatusCreated},
+		{name: "head from get", method: http.MethodHead, status: http.StatusOK, length: "12"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(test.method, "/v1/orders", nil))
+
+			if rec.Code != test.status {
+				t.Errorf("unexpected status code:\n- want: %v\n-  got: %v", test.status, rec.Code)
+			}
+			if got := rec.Header().Get("Allow"); got != test.allow {
+				t.Errorf("unexpected Allow header:\n- want: %v\n-  got: %v", test.allow, got)
+			}
+			if got := rec.Header().Get("Content-Length"); got != test.length {
+				t.Errorf("unexpected Content-Length header:\n- want: %v\n-  got: %v", test.length, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,257 @@
+// Package signature signs the responses of the high-value endpoints, such as
+// invoices and certificates, with detached JSON Web Signatures (RFC 7515,
+// Appendix F), so the clients can prove offline that the documents were not
+// tampered with.
+//
+// The payload of the signatures is the fingerprint of the response message
+// and not its encoding, so the same signature verifies the JSON, protobuf
+// and the other encodings of the message:
+//
+//	signer, _ := signature.NewSigner("2024-01", key)
+//	server := httptransport.NewServer(e, decode, signature.NewEncoder(signer, httpkit.EncodeProtoJSONResponse))
+//
+// and on the client:
+//
+//	verifier := signature.NewVerifier(map[string]interface{}{"2024-01": key.Public()})
+//	err := verifier.Verify(resp.Header.Get(signature.Header), invoice)
+package signature
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+
+	"github.com/clouway/go-genproto/clouwayapis/rpc/fileserve"
+	httptransport "github.com/go-kit/kit/transport/http"
+	"google.golang.org/protobuf/proto"
+)
+
+// Header is the response header with the detached signature.
+const Header = "X-JWS-Signature"
+
+// The supported signature algorithms.
+const (
+	HS256 = "HS256"
+	RS256 = "RS256"
+	ES256 = "ES256"
+	EdDSA = "EdDSA"
+)
+
+// ErrInvalidSignature is returned by the verifier when the signature does
+// not match the message or can't be verified.
+var ErrInvalidSignature = errors.New("signature: invalid signature")
+
+// Fingerprint returns the SHA-256 digest of the deterministic binary
+// encoding of the message. The files are fingerprinted by their content only,
+// so they can be verified by the downloaded bytes.
+func Fingerprint(m proto.Message) ([]byte, error) {
+	if f, ok := m.(*fileserve.BinaryFile); ok {
+		sum := sha256.Sum256(f.Content)
+		return sum[:], nil
+	}
+	b, err := proto.MarshalOptions{Deterministic: true}.Marshal(m)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(b)
+	return sum[:], nil
+}
+
+type header struct {
+	Algorithm string `json:"alg"`
+	KeyID     string `json:"kid,omitempty"`
+}
+
+// Signer signs messages with a private key.
+type Signer struct {
+	keyID     string
+	algorithm string
+	signer    crypto.Signer
+	secret    []byte
+}
+
+// NewSigner creates a signer for the key with the passed id. The key is
+// either a crypto.Signer with RSA, ECDSA P-256 or Ed25519 public key, e.g.
+// a key of a KMS, or a []byte secret for HMAC.
+func NewSigner(keyID string, key interface{}) (*Signer, error) {
+	s := &Signer{keyID: keyID}
+	switch k := key.(type) {
+	case []byte:
+		s.algorithm, s.secret = HS256, k
+		return s, nil
+	case crypto.Signer:
+		s.signer = k
+		algorithm, err := algorithmOf(k.Public())
+		if err != nil {
+			return nil, err
+		}
+		s.algorithm = algorithm
+		return s, nil
+	}
+	return nil, fmt.Errorf("signature: unsupported key type %T", key)
+}
+
+func algorithmOf(key crypto.PublicKey) (string, error) {
+	switch k := key.(type) {
+	case *rsa.PublicKey:
+		return RS256, nil
+	case *ecdsa.PublicKey:
+		if k.Curve != elliptic.P256() {
+			return "", fmt.Errorf("signature: unsupported curve %s", k.Curve.Params().Name)
+		}
+		return ES256, nil
+	case ed25519.PublicKey:
+		return EdDSA, nil
+	}
+	return "", fmt.Errorf("signature: unsupported key type %T", key)
+}
+
+// Sign returns the detached compact serialization of the signature of the
+// message, i.e. the encoded header and signature separated by two dots.
+func (s *Signer) Sign(m proto.Message) (string, error) {
+	fingerprint, err := Fingerprint(m)
+	if err != nil {
+		return "", err
+	}
+	h, err := json.Marshal(header{Algorithm: s.algorithm, KeyID: s.keyID})
+	if err != nil {
+		return "", err
+	}
+	protected := base64.RawURLEncoding.EncodeToString(h)
+	input := protected + "." + base64.RawURLEncoding.EncodeToString(fingerprint)
+
+	sig, err := s.sign([]byte(input))
+	if err != nil {
+		return "", err
+	}
+	return protected + ".." + base64.RawURLEncoding.EncodeToString(sig), nil
+}
+
+func (s *Signer) sign(input []byte) ([]byte, error) {
+	switch s.algorithm {
+	case HS256:
+		mac := hmac.New(sha256.New, s.secret)
+		mac.Write(input)
+		return mac.Sum(nil), nil
+	case EdDSA:
+		return s.signer.Sign(rand.Reader, input, crypto.Hash(0))
+	}
+	digest := sha256.Sum256(input)
+	sig, err := s.signer.Sign(rand.Reader, digest[:], crypto.SHA256)
+	if err != nil || s.algorithm != ES256 {
+		return sig, err
+	}
+	// The ECDSA signers return ASN.1 signatures, while JWS uses the
+	// fixed size concatenation of R and S.
+	var rs struct{ R, S *big.Int }
+	if _, err := asn1.Unmarshal(sig, &rs); err != nil {
+		return nil, err
+	}
+	out := make([]byte, 64)
+	rs.R.FillBytes(out[:32])
+	rs.S.FillBytes(out[32:])
+	return out, nil
+}
+
+// NewEncoder returns an EncodeResponseFunc that sets the signature of the
+// proto.Message responses as Header before they are encoded by next.
+func NewEncoder(signer *Signer, next httptransport.EncodeResponseFunc) httptransport.EncodeResponseFunc {
+	return func(ctx context.Context, w http.ResponseWriter, response interface{}) error {
+		if m, ok := response.(proto.Message); ok && m.ProtoReflect().IsValid() {
+			sig, err := signer.Sign(m)
+			if err != nil {
+				return err
+			}
+			w.Header().Set(Header, sig)
+		}
+		return next(ctx, w, response)
+	}
+}
+
+// Verifier verifies the signatures with the public keys of the signers.
+type Verifier struct {
+	keys map[string]interface{}
+}
+
+// NewVerifier creates a verifier with the keys by their ids. The keys are
+// *rsa.PublicKey, *ecdsa.PublicKey, ed25519.PublicKey or []byte secrets for
+// HMAC.
+func NewVerifier(keys map[string]interface{}) *Verifier {
+	return &Verifier{keys: keys}
+}
+
+// Verify verifies the detached signature of the message. The errors wrap
+// ErrInvalidSignature.
+func (v *Verifier) Verify(signature string, m proto.Message) error {
+	parts := strings.Split(signature, ".")
+	if len(parts) != 3 || parts[1] != "" {
+		return fmt.Errorf("%w: malformed detached signature", ErrInvalidSignature)
+	}
+	b, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return fmt.Errorf("%w: malformed header", ErrInvalidSignature)
+	}
+	var h header
+	if err := json.Unmarshal(b, &h); err != nil {
+		return fmt.Errorf("%w: malformed header", ErrInvalidSignature)
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return fmt.Errorf("%w: malformed signature", ErrInvalidSignature)
+	}
+	key, ok := v.keys[h.KeyID]
+	if !ok {
+		return fmt.Errorf("%w: unknown key %q", ErrInvalidSignature, h.KeyID)
+	}
+
+	fingerprint, err := Fingerprint(m)
+	if err != nil {
+		return err
+	}
+	input := []byte(parts[0] + "." + base64.RawURLEncoding.EncodeToString(fingerprint))
+	if !verify(h.Algorithm, key, input, sig) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// verify checks the signature with the key. The algorithm of the header must
+// match the type of the key, so the public keys can't be used as HMAC
+// secrets.
+func verify(algorithm string, key interface{}, input, sig []byte) bool {
+	digest := sha256.Sum256(input)
+	switch k := key.(type) {
+	case []byte:
+		if algorithm != HS256 {
+			return false
+		}
+		mac := hmac.New(sha256.New, k)
+		mac.Write(input)
+		return hmac.Equal(sig, mac.Sum(nil))
+	case *rsa.PublicKey:
+		return algorithm == RS256 && rsa.VerifyPKCS1v15(k, crypto.SHA256, digest[:], sig) == nil
+	case *ecdsa.PublicKey:
+		if algorithm != ES256 || len(sig) != 64 {
+			return false
+		}
+		r, s := new(big.Int).SetBytes(sig[:32]), new(big.Int).SetBytes(sig[32:])
+		return ecdsa.Verify(k, digest[:], r, s)
+	case ed25519.PublicKey:
+		return algorithm == EdDSA && ed25519.Verify(k, input, sig)
+	}
+	return false
+}
@@ -0,0 +1,105 @@
+package signature_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/clouway/go-genproto/clouwayapis/rpc/fileserve"
+	"github.com/clouway/go-genproto/clouwayapis/rpc/httpkit"
+	"github.com/clouway/go-genproto/clouwayapis/rpc/httpkit/signature"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/types/known/apipb"
+)
+
+func TestSignAndVerify(t *testing.T) {
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	edPublic, edKey, _ := ed25519.GenerateKey(rand.Reader)
+
+	tests := []struct {
+		name   string
+		key    interface{}
+		public interface{}
+	}{
+		{name: "HS256", key: []byte("::secret::"), public: []byte("::secret::")},
+		{name: "RS256", key: rsaKey, public: &rsaKey.PublicKey},
+		{name: "ES256", key: ecKey, public: &ecKey.PublicKey},
+		{name: "EdDSA", key: edKey, public: edPublic},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			signer, err := signature.NewSigner("key-1", test.key)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			invoice := &apipb.Api{Name: "invoice-1", Version: "v1"}
+			sig, err := signer.Sign(invoice)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			verifier := signature.NewVerifier(map[string]interface{}{"key-1": test.public})
+			if err := verifier.Verify(sig, invoice); err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			tampered := &apipb.Api{Name: "invoice-1", Version: "v2"}
+			if err := verifier.Verify(sig, tampered); !errors.Is(err, signature.ErrInvalidSignature) {
+				t.Errorf("unexpected error:\n- want: %v\n-  got: %v", signature.ErrInvalidSignature, err)
+			}
+		})
+	}
+}
+
+func TestVerifyRejectsAlgorithmOfOtherKeyType(t *testing.T) {
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	signer, _ := signature.NewSigner("key-1", []byte("::secret::"))
+	sig, _ := signer.Sign(&apipb.Api{Name: "invoice-1"})
+
+	verifier := signature.NewVerifier(map[string]interface{}{"key-1": &ecKey.PublicKey})
+	if err := verifier.Verify(sig, &apipb.Api{Name: "invoice-1"}); !errors.Is(err, signature.ErrInvalidSignature) {
+		t.Errorf("unexpected error:\n- want: %v\n-  got: %v", signature.ErrInvalidSignature, err)
+	}
+}
+
+func TestEncoder(t *testing.T) {
+	signer, _ := signature.NewSigner("key-1", []byte("::secret::"))
+	encode := signature.NewEncoder(signer, httpkit.EncodeProtoJSONResponse)
+
+	w := httptest.NewRecorder()
+	if err := encode(context.Background(), w, &apipb.Api{Name: "invoice-1"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	got := &apipb.Api{}
+	if err := protojson.Unmarshal(w.Body.Bytes(), got); err != nil {
+		t.Fatalf("unexpected error while decoding body: %v", err)
+	}
+	verifier := signature.NewVerifier(map[string]interface{}{"key-1": []byte("::secret::")})
+	if err := verifier.Verify(w.Header().Get(signature.Header), got); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestEncoderBinaryFile(t *testing.T) {
+	signer, _ := signature.NewSigner("key-1", []byte("::secret::"))
+	encode := signature.NewEncoder(signer, httpkit.EncodeHTTPGenericResponse)
+
+	w := httptest.NewRecorder()
+	file := &fileserve.BinaryFile{ContentType: "application/pdf", FileName: "invoice.pdf", Content: []byte("::content::")}
+	if err := encode(context.Background(), w, file); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	downloaded := &fileserve.BinaryFile{Content: w.Body.Bytes()}
+	verifier := signature.NewVerifier(map[string]interface{}{"key-1": []byte("::secret::")})
+	if err := verifier.Verify(w.Header().Get(signature.Header), downloaded); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,56 @@
+package httpkit
+
+import (
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+	"sync"
+)
+
+var contentTypeOverrides = struct {
+	sync.RWMutex
+	types map[string]string
+}{types: map[string]string{
+	".csv":  "text/csv; charset=utf-8",
+	".json": "application/json",
+	".svg":  "image/svg+xml",
+	".webp": "image/webp",
+	".heic": "image/heic",
+	".apk":  "application/vnd.android.package-archive",
+	".xlsx": "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+	".docx": "application/vnd.openxmlformats-officedocument.wordprocessingml.document",
+}}
+
+// RegisterContentType sets the content type of the files with the
+// extension, e.g. ".bin" for firmware images, which takes precedence over
+// the type that is sniffed from their content.
+func RegisterContentType(ext, contentType string) {
+	contentTypeOverrides.Lock()
+	defer contentTypeOverrides.Unlock()
+	contentTypeOverrides.types[strings.ToLower(ext)] = contentType
+}
+
+// SniffContentType returns the content type of the file with the name and
+// the content, for the files without one, such as the BinaryFile messages
+// and the uploads without Content-Type. The type registered for the
+// extension by RegisterContentType is used first, then the type that is
+// sniffed from the content as http.DetectContentType does, unless it's
+// just generic text or binary data and the extension tells more.
+func SniffContentType(name string, content []byte) string {
+	ext := strings.ToLower(path.Ext(name))
+	contentTypeOverrides.RLock()
+	override := contentTypeOverrides.types[ext]
+	contentTypeOverrides.RUnlock()
+	if override != "" {
+		return override
+	}
+
+	sniffed := http.DetectContentType(content)
+	if sniffed == "application/octet-stream" || strings.HasPrefix(sniffed, "text/plain") {
+		if byExt := mime.TypeByExtension(ext); ext != "" && byExt != "" {
+			return byExt
+		}
+	}
+	return sniffed
+}
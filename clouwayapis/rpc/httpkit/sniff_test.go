@@ -0,0 +1,32 @@
+package httpkit_test
+
+import (
+	"testing"
+
+	"github.com/clouway/go-genproto/clouwayapis/rpc/httpkit"
+)
+
+func TestSniffContentType(t *testing.T) {
+	httpkit.RegisterContentType(".FW", "application/x-firmware")
+
+	tests := []struct {
+		name    string
+		file    string
+		content string
+		want    string
+	}{
+		{name: "sniffed", file: "scan", content: "%PDF-1.7", want: "application/pdf"},
+		{name: "sniffed over extension", file: "scan.txt", content: "\x89PNG\r\n\x1a\n", want: "image/png"},
+		{name: "extension over generic text", file: "readings.csv", content: "1,2,3", want: "text/csv; charset=utf-8"},
+		{name: "extension over generic binary", file: "photo.jpeg", content: "\x00\x01", want: "image/jpeg"},
+		{name: "registered", file: "meter.fw", content: "%PDF-1.7", want: "application/x-firmware"},
+		{name: "unknown", file: "data", content: "\x00\x01", want: "application/octet-stream"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := httpkit.SniffContentType(test.file, []byte(test.content)); got != test.want {
+				t.Errorf("unexpected content type:\n- want: %v\n-  got: %v", test.want, got)
+			}
+		})
+	}
+}
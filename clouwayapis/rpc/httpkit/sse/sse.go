@@ -0,0 +1,278 @@
+// Package sse exposes the gRPC server-streaming methods as Server-Sent Events
+// endpoints, so the browsers can receive the pushed messages natively. Every
+// message of the stream is sent as an event with a JSON data frame:
+//
+//	id: 42
+//	event: notification
+//	data: {"id":"42","title":"..."}
+//
+// The connection is kept alive by heartbeat comments while the stream is
+// idle and the clients are hinted how long to wait before reconnecting.
+package sse
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/clouway/go-genproto/clouwayapis/rpc/httpkit"
+	httptransport "github.com/go-kit/kit/transport/http"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// ContentType is the content type of the event streams.
+const ContentType = "text/event-stream"
+
+type contextKey int
+
+const lastEventIDKey contextKey = iota
+
+// LastEventIDToContext is a go-kit server before func that stores the
+// Last-Event-ID header of the reconnecting clients in the context.
+func LastEventIDToContext(ctx context.Context, r *http.Request) context.Context {
+	id := r.Header.Get("Last-Event-ID")
+	if id == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, lastEventIDKey, id)
+}
+
+// LastEventID returns the id of the last event that was received by the
+// client before it reconnected, or empty string for new clients. The
+// endpoints use it to resume the stream from where it was interrupted.
+func LastEventID(ctx context.Context) string {
+	id, _ := ctx.Value(lastEventIDKey).(string)
+	return id
+}
+
+// Option sets an optional parameter for the encoder.
+type Option func(*encoder)
+
+// Heartbeat sets the interval of the heartbeat comments that are sent while
+// the stream is idle. The heartbeats are sent every 15 seconds by default,
+// and zero or negative intervals disable them.
+func Heartbeat(d time.Duration) Option {
+	return func(e *encoder) { e.heartbeat = d }
+}
+
+// Retry sets the reconnection time that is sent to the clients with the
+// first event. The browsers use their own default when it's not set.
+func Retry(d time.Duration) Option {
+	return func(e *encoder) { e.retry = d }
+}
+
+// EventID sets the function that returns the id of the message event.
+func EventID(f func(proto.Message) string) Option {
+	return func(e *encoder) { e.eventID = f }
+}
+
+// EventName sets the function that returns the name of the message event.
+// The events have no name by default and are received as "message" events.
+func EventName(f func(proto.Message) string) Option {
+	return func(e *encoder) { e.eventName = f }
+}
+
+// MarshalOptions sets the options that are used to encode the messages.
+func MarshalOptions(marshaller protojson.MarshalOptions) Option {
+	return func(e *encoder) { e.marshaller = marshaller }
+}
+
+// EncodeResponse is a transport/http.EncodeResponseFunc that streams the
+// response as Server-Sent Events. See NewEncoder.
+func EncodeResponse(ctx context.Context, w http.ResponseWriter, response interface{}) error {
+	return defaultEncoder(ctx, w, response)
+}
+
+var defaultEncoder = NewEncoder()
+
+// NewEncoder returns an EncodeResponseFunc that sends every message of the
+// response as an event. The response is either an httpkit.MessageStream or
+// a <-chan proto.Message. Responses of other types are encoded by
+// httpkit.EncodeProtoJSONResponse.
+//
+// When the stream fails, the error is sent as an "error" event in the form
+// {"message":...,"code":...,"status":...} and the stream is terminated.
+func NewEncoder(options ...Option) httptransport.EncodeResponseFunc {
+	e := &encoder{
+		marshaller: protojson.MarshalOptions{EmitUnpopulated: true, UseProtoNames: false},
+		heartbeat:  15 * time.Second,
+	}
+	for _, option := range options {
+		option(e)
+	}
+	return e.encode
+}
+
+type encoder struct {
+	marshaller protojson.MarshalOptions
+	heartbeat  time.Duration
+	retry      time.Duration
+	eventID    func(proto.Message) string
+	eventName  func(proto.Message) string
+}
+
+type result struct {
+	m   proto.Message
+	err error
+}
+
+func (e *encoder) encode(ctx context.Context, w http.ResponseWriter, response interface{}) error {
+	var messages <-chan result
+	switch s := response.(type) {
+	case httpkit.MessageStream:
+		messages = receive(ctx, s)
+	case <-chan proto.Message:
+		messages = forward(ctx, s)
+	default:
+		return httpkit.EncodeProtoJSONResponse(ctx, w, response)
+	}
+
+	h := w.Header()
+	h.Set("Content-Type", ContentType)
+	h.Set("Cache-Control", "no-cache")
+	h.Set("Connection", "keep-alive")
+	// Disables the response buffering of nginx.
+	h.Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	flusher, _ := w.(http.Flusher)
+	flush := func() {
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+
+	var frame []byte
+	if e.retry > 0 {
+		frame = append(frame, "retry: "...)
+		frame = strconv.AppendInt(frame, e.retry.Milliseconds(), 10)
+		frame = append(frame, "\n\n"...)
+		w.Write(frame)
+		flush()
+	}
+
+	// The nil channel of the disabled heartbeats never fires.
+	var heartbeat *time.Ticker
+	var heartbeats <-chan time.Time
+	if e.heartbeat > 0 {
+		heartbeat = time.NewTicker(e.heartbeat)
+		defer heartbeat.Stop()
+		heartbeats = heartbeat.C
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-heartbeats:
+			if _, err := io.WriteString(w, ": heartbeat\n\n"); err != nil {
+				return nil
+			}
+			flush()
+		case r, ok := <-messages:
+			if !ok || errors.Is(r.err, io.EOF) {
+				return nil
+			}
+			if r.err != nil {
+				w.Write(errorFrame(r.err))
+				flush()
+				return nil
+			}
+			var err error
+			if frame, err = e.appendFrame(frame[:0], r.m); err != nil {
+				w.Write(errorFrame(err))
+				flush()
+				return nil
+			}
+			if _, err := w.Write(frame); err != nil {
+				// The client is gone and there is nobody to report to.
+				return nil
+			}
+			flush()
+			if heartbeat != nil {
+				heartbeat.Reset(e.heartbeat)
+			}
+		}
+	}
+}
+
+// appendFrame appends the event frame of the message to b.
+func (e *encoder) appendFrame(b []byte, m proto.Message) ([]byte, error) {
+	if e.eventID != nil {
+		if id := e.eventID(m); id != "" {
+			b = appendField(b, "id", id)
+		}
+	}
+	if e.eventName != nil {
+		if name := e.eventName(m); name != "" {
+			b = appendField(b, "event", name)
+		}
+	}
+	b = append(b, "data: "...)
+	b, err := e.marshaller.MarshalAppend(b, m)
+	if err != nil {
+		return nil, err
+	}
+	return append(b, "\n\n"...), nil
+}
+
+// appendField appends a single line field to b. The line breaks of the
+// value would terminate the field, so they are dropped.
+func appendField(b []byte, name, value string) []byte {
+	b = append(b, name...)
+	b = append(b, ": "...)
+	for i := 0; i < len(value); i++ {
+		if value[i] != '\n' && value[i] != '\r' {
+			b = append(b, value[i])
+		}
+	}
+	return append(b, '\n')
+}
+
+var errorSchema = httpkit.ErrorSchema{CodeKey: "code", StatusKey: "status"}
+
+// errorFrame returns the "error" event of the stream error.
+func errorFrame(err error) []byte {
+	b := []byte("event: error\ndata: ")
+	b = append(b, errorSchema.MarshalError(err)...)
+	return append(b, "\n\n"...)
+}
+
+// receive pumps the messages of the stream to a channel, so they can be
+// awaited together with the heartbeats.
+func receive(ctx context.Context, s httpkit.MessageStream) <-chan result {
+	ch := make(chan result)
+	go func() {
+		defer close(ch)
+		for {
+			m, err := s.Next()
+			select {
+			case ch <- result{m: m, err: err}:
+			case <-ctx.Done():
+				return
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+	return ch
+}
+
+func forward(ctx context.Context, s <-chan proto.Message) <-chan result {
+	ch := make(chan result)
+	go func() {
+		defer close(ch)
+		for m := range s {
+			select {
+			case ch <- result{m: m}:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return ch
+}
@@ -0,0 +1,99 @@
+package sse_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/clouway/go-genproto/clouwayapis/rpc/httpkit"
+	"github.com/clouway/go-genproto/clouwayapis/rpc/httpkit/sse"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+func TestEncodeResponse(t *testing.T) {
+	ch := make(chan proto.Message, 2)
+	ch <- wrapperspb.String("a")
+	ch <- wrapperspb.String("b")
+	close(ch)
+
+	encode := sse.NewEncoder(
+		sse.Retry(3*time.Second),
+		sse.EventID(func(m proto.Message) string { return m.(*wrapperspb.StringValue).Value }),
+		sse.EventName(func(proto.Message) string { return "notification" }),
+	)
+	rec := httptest.NewRecorder()
+	if err := encode(context.Background(), rec, (<-chan proto.Message)(ch)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if got := rec.Header().Get("Content-Type"); got != sse.ContentType {
+		t.Errorf("unexpected Content-Type header:\n- want: %v\n-  got: %v", sse.ContentType, got)
+	}
+	want := "retry: 3000\n\n" +
+		"id: a\nevent: notification\ndata: \"a\"\n\n" +
+		"id: b\nevent: notification\ndata: \"b\"\n\n"
+	if got := rec.Body.String(); got != want {
+		t.Errorf("unexpected body:\n- want: %q\n-  got: %q", want, got)
+	}
+}
+
+func TestEncodeResponseHeartbeat(t *testing.T) {
+	ch := make(chan proto.Message)
+	go func() {
+		time.Sleep(30 * time.Millisecond)
+		close(ch)
+	}()
+
+	rec := httptest.NewRecorder()
+	sse.NewEncoder(sse.Heartbeat(10*time.Millisecond))(context.Background(), rec, (<-chan proto.Message)(ch))
+
+	if !strings.HasPrefix(rec.Body.String(), ": heartbeat\n\n") {
+		t.Errorf("expected heartbeat, got: %q", rec.Body.String())
+	}
+}
+
+func TestEncodeResponseWithoutHeartbeat(t *testing.T) {
+	ch := make(chan proto.Message)
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		close(ch)
+	}()
+
+	rec := httptest.NewRecorder()
+	sse.NewEncoder(sse.Heartbeat(0))(context.Background(), rec, (<-chan proto.Message)(ch))
+
+	if rec.Body.Len() != 0 {
+		t.Errorf("unexpected body: %q", rec.Body.String())
+	}
+}
+
+func TestEncodeResponseStreamError(t *testing.T) {
+	recv := func() (*wrapperspb.StringValue, error) {
+		return nil, status.Error(codes.PermissionDenied, "not allowed")
+	}
+
+	rec := httptest.NewRecorder()
+	sse.EncodeResponse(context.Background(), rec, httpkit.RecvFunc[*wrapperspb.StringValue](recv))
+
+	want := "event: error\ndata: {\"message\":\"not allowed\",\"code\":403,\"status\":\"PERMISSION_DENIED\"}\n\n"
+	if got := rec.Body.String(); got != want {
+		t.Errorf("unexpected body:\n- want: %q\n-  got: %q", want, got)
+	}
+}
+
+func TestLastEventID(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/events", nil)
+	r.Header.Set("Last-Event-ID", "42")
+
+	ctx := sse.LastEventIDToContext(context.Background(), r)
+
+	if got := sse.LastEventID(ctx); got != "42" {
+		t.Errorf("unexpected last event id:\n- want: %v\n-  got: %v", "42", got)
+	}
+}
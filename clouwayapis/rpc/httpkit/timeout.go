@@ -0,0 +1,116 @@
+package httpkit
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	httptransport "github.com/go-kit/kit/transport/http"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// TimeoutOption sets an optional parameter for the Timeout middleware.
+type TimeoutOption func(*timeoutHandler)
+
+// TimeoutErrorEncoder sets the encoder that writes the DeadlineExceeded
+// status error. ErrorEncoder is used by default.
+func TimeoutErrorEncoder(ee httptransport.ErrorEncoder) TimeoutOption {
+	return func(h *timeoutHandler) { h.errorEncoder = ee }
+}
+
+// Timeout is an HTTP middleware that runs the next handler with a request
+// context that is cancelled after the passed timeout. When the handler does
+// not complete in time, its response is discarded and a DeadlineExceeded
+// status error with the configured budget is written instead, which is
+// encoded as 504 Gateway Timeout.
+func Timeout(timeout time.Duration, options ...TimeoutOption) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		h := &timeoutHandler{next: next, timeout: timeout, errorEncoder: ErrorEncoder}
+		for _, option := range options {
+			option(h)
+		}
+		return h
+	}
+}
+
+type timeoutHandler struct {
+	next         http.Handler
+	timeout      time.Duration
+	errorEncoder httptransport.ErrorEncoder
+}
+
+func (h *timeoutHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), h.timeout)
+	defer cancel()
+
+	tw := &timeoutWriter{header: make(http.Header), code: http.StatusOK}
+	done := make(chan struct{})
+	panicked := make(chan interface{}, 1)
+	go func() {
+		defer func() {
+			if v := recover(); v != nil {
+				panicked <- v
+			}
+		}()
+		h.next.ServeHTTP(tw, r.WithContext(ctx))
+		close(done)
+	}()
+
+	select {
+	case v := <-panicked:
+		panic(v)
+	case <-done:
+		tw.mu.Lock()
+		defer tw.mu.Unlock()
+		for k, v := range tw.header {
+			w.Header()[k] = v
+		}
+		w.WriteHeader(tw.code)
+		w.Write(tw.body.Bytes())
+	case <-ctx.Done():
+		tw.mu.Lock()
+		defer tw.mu.Unlock()
+		tw.timedOut = true
+		if ctx.Err() == context.DeadlineExceeded {
+			h.errorEncoder(ctx, status.Errorf(codes.DeadlineExceeded, "request exceeded the timeout of %v", h.timeout), w)
+		}
+	}
+}
+
+// timeoutWriter buffers the response of the handler until it completes, so
+// that nothing is written to the client when the timeout is reached.
+type timeoutWriter struct {
+	mu          sync.Mutex
+	header      http.Header
+	body        bytes.Buffer
+	code        int
+	wroteHeader bool
+	timedOut    bool
+}
+
+func (tw *timeoutWriter) Header() http.Header {
+	return tw.header
+}
+
+func (tw *timeoutWriter) Write(b []byte) (int, error) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.timedOut {
+		return 0, http.ErrHandlerTimeout
+	}
+	tw.wroteHeader = true
+	return tw.body.Write(b)
+}
+
+func (tw *timeoutWriter) WriteHeader(code int) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.timedOut || tw.wroteHeader {
+		return
+	}
+	tw.wroteHeader = true
+	tw.code = code
+}
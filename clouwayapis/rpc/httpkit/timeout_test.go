@@ -0,0 +1,48 @@
+package httpkit_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/clouway/go-genproto/clouwayapis/rpc/httpkit"
+)
+
+func TestTimeout(t *testing.T) {
+	handler := httpkit.Timeout(10 * time.Millisecond)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+		w.Write([]byte("too late"))
+	}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if rec.Code != http.StatusGatewayTimeout {
+		t.Errorf("unexpected status code:\n- want: %v\n-  got: %v", http.StatusGatewayTimeout, rec.Code)
+	}
+	if want, got := `{"message":"request exceeded the timeout of 10ms"}`, rec.Body.String(); want != got {
+		t.Errorf("unexpected body:\n- want: %v\n-  got: %v", want, got)
+	}
+}
+
+func TestTimeoutNotReached(t *testing.T) {
+	handler := httpkit.Timeout(time.Second)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Test", "value")
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte("created"))
+	}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if rec.Code != http.StatusCreated {
+		t.Errorf("unexpected status code:\n- want: %v\n-  got: %v", http.StatusCreated, rec.Code)
+	}
+	if want, got := "value", rec.Header().Get("X-Test"); want != got {
+		t.Errorf("unexpected header:\n- want: %v\n-  got: %v", want, got)
+	}
+	if want, got := "created", rec.Body.String(); want != got {
+		t.Errorf("unexpected body:\n- want: %v\n-  got: %v", want, got)
+	}
+}
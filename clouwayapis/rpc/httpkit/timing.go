@@ -0,0 +1,150 @@
+package httpkit
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ServerTimingHeader is the header with the timings of the request.
+const ServerTimingHeader = "Server-Timing"
+
+type timingsKey struct{}
+
+// timings are the spans that are recorded during the handling of a request.
+type timings struct {
+	mu    sync.Mutex
+	start time.Time
+	spans []timingSpan
+}
+
+type timingSpan struct {
+	name        string
+	description string
+	duration    time.Duration
+}
+
+// ServerTiming is an HTTP middleware that collects the timing spans that
+// are recorded by RecordTiming and StartTiming during the handling of the
+// requests and emits them with the total duration in the Server-Timing
+// header, so the browser devtools and the synthetic monitors can attribute
+// the latency:
+//
+//	Server-Timing: db;dur=12.5, upstream;desc="orders";dur=40.1, total;dur=55.2
+//
+// The header is written with the status of the response, so only the spans
+// that are recorded before the response is written are emitted.
+func ServerTiming(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t := &timings{start: time.Now()}
+		ctx := context.WithValue(r.Context(), timingsKey{}, t)
+		tw := &timingWriter{ResponseWriter: w, timings: t}
+		next.ServeHTTP(tw, r.WithContext(ctx))
+		if !tw.wroteHeader {
+			tw.WriteHeader(http.StatusOK)
+		}
+	})
+}
+
+// RecordTiming records a span with the name, e.g. db, upstream or render,
+// the optional description and the duration. It's a no-op when the
+// context doesn't come from the ServerTiming middleware.
+func RecordTiming(ctx context.Context, name, description string, d time.Duration) {
+	t, ok := ctx.Value(timingsKey{}).(*timings)
+	if !ok {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.spans = append(t.spans, timingSpan{name: name, description: description, duration: d})
+}
+
+// StartTiming starts a span with the name and returns the function that
+// records it when it's done:
+//
+//	defer httpkit.StartTiming(ctx, "db")()
+func StartTiming(ctx context.Context, name string) func() {
+	start := time.Now()
+	return func() { RecordTiming(ctx, name, "", time.Since(start)) }
+}
+
+// header returns the value of the Server-Timing header.
+func (t *timings) header() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	metrics := make([]string, 0, len(t.spans)+1)
+	for _, s := range t.spans {
+		metrics = append(metrics, timingMetric(s.name, s.description, s.duration))
+	}
+	metrics = append(metrics, timingMetric("total", "", time.Since(t.start)))
+	return strings.Join(metrics, ", ")
+}
+
+// timingMetric formats the metric of the Server-Timing header.
+func timingMetric(name, description string, d time.Duration) string {
+	metric := strings.Map(func(r rune) rune {
+		if r > ' ' && r < 0x7f && !strings.ContainsRune(`"(),/:;<=>?@[\]{}`, r) {
+			return r
+		}
+		return '_'
+	}, name)
+	if description != "" {
+		metric += ";desc=" + strconv.Quote(description)
+	}
+	return metric + ";dur=" + strconv.FormatFloat(float64(d)/float64(time.Millisecond), 'f', 1, 64)
+}
+
+// timingWriter sets the Server-Timing header before the status of the
+// response is written.
+type timingWriter struct {
+	http.ResponseWriter
+	timings     *timings
+	wroteHeader bool
+}
+
+func (w *timingWriter) WriteHeader(code int) {
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+	w.Header().Set(ServerTimingHeader, w.timings.header())
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *timingWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// Flush implements http.Flusher for the streaming handlers.
+func (w *timingWriter) Flush() {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack implements http.Hijacker for the websocket upgrades. The status of
+// the hijacked connections is written by the handler, so it's not written
+// by the middleware.
+func (w *timingWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, rw, err := hijack(w.ResponseWriter)
+	if err == nil {
+		w.wroteHeader = true
+	}
+	return conn, rw, err
+}
+
+// Unwrap returns the underlying writer for http.ResponseController.
+func (w *timingWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
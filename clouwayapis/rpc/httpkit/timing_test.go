@@ -0,0 +1,37 @@
+package httpkit_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/clouway/go-genproto/clouwayapis/rpc/httpkit"
+)
+
+func TestServerTiming(t *testing.T) {
+	handler := httpkit.ServerTiming(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		stop := httpkit.StartTiming(ctx, "db")
+		time.Sleep(2 * time.Millisecond)
+		stop()
+		httpkit.RecordTiming(ctx, "upstream", "orders service", 40*time.Millisecond)
+		w.Write([]byte("{}"))
+		httpkit.RecordTiming(ctx, "late", "", time.Millisecond)
+	}))
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	want := regexp.MustCompile(`^db;dur=\d+\.\d, upstream;desc="orders service";dur=40\.0, total;dur=\d+\.\d$`)
+	if got := w.Header().Get(httpkit.ServerTimingHeader); !want.MatchString(got) {
+		t.Errorf("unexpected Server-Timing header:\n- want: %v\n-  got: %v", want, got)
+	}
+}
+
+func TestServerTimingHijack(t *testing.T) {
+	if code := serveUpgrade(t, httpkit.ServerTiming(upgradeHandler(t))); code != http.StatusSwitchingProtocols {
+		t.Errorf("unexpected status code:\n- want: %v\n-  got: %v", http.StatusSwitchingProtocols, code)
+	}
+}
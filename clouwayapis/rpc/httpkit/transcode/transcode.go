@@ -0,0 +1,335 @@
+// Package transcode registers the HTTP routes of the gRPC services from
+// their google.api.http annotations, so the services don't need the
+// hand-written routes and decoders of every method:
+//
+//	service Orders {
+//	  rpc GetOrder(GetOrderRequest) returns (Order) {
+//	    option (google.api.http) = { get: "/v1/{name=customers/*/orders/*}" };
+//	  }
+//	  rpc UpdateOrder(UpdateOrderRequest) returns (Order) {
+//	    option (google.api.http) = { patch: "/v1/{order.name=customers/*/orders/*}" body: "order" };
+//	  }
+//	}
+//
+// The requests are transcoded to the go-kit endpoints of the methods by the
+// rules of the gRPC transcoding: the path variables and the query
+// parameters populate the fields of the request message, and the body is
+// decoded into the whole message or into the field that is named by the
+// rule.
+package transcode
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/clouway/go-genproto/clouwayapis/rpc/httpkit"
+	"github.com/go-kit/kit/endpoint"
+	httptransport "github.com/go-kit/kit/transport/http"
+	"github.com/gorilla/mux"
+	"google.golang.org/genproto/googleapis/api/annotations"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// Option sets an optional parameter for the registered routes.
+type Option func(*options)
+
+// ServerOptions sets the options of the go-kit servers of the routes, e.g.
+// httptransport.ServerErrorEncoder or httptransport.ServerBefore.
+func ServerOptions(serverOptions ...httptransport.ServerOption) Option {
+	return func(o *options) { o.serverOptions = append(o.serverOptions, serverOptions...) }
+}
+
+// Encoder sets the encoder of the responses. httpkit.EncodeProtoJSONResponse
+// is used by default.
+func Encoder(encode httptransport.EncodeResponseFunc) Option {
+	return func(o *options) { o.encode = encode }
+}
+
+// DecodeOptions sets the options of the decoding of the request bodies.
+func DecodeOptions(decodeOptions ...httpkit.DecodeOption) Option {
+	return func(o *options) { o.decodeOptions = decodeOptions }
+}
+
+type options struct {
+	serverOptions []httptransport.ServerOption
+	encode        httptransport.EncodeResponseFunc
+	decodeOptions []httpkit.DecodeOption
+}
+
+// RegisterRoutes registers the routes of the annotated methods of the service
+// on the router. The endpoints are keyed by the full names of the methods,
+// e.g. "clouway.orders.v1.Orders.GetOrder". The annotated methods without an
+// endpoint are skipped, so the services can be migrated gradually.
+func RegisterRoutes(router *mux.Router, service protoreflect.ServiceDescriptor, endpoints map[string]endpoint.Endpoint, opts ...Option) error {
+	o := &options{
+		encode:        httpkit.EncodeProtoJSONResponse,
+		serverOptions: []httptransport.ServerOption{httptransport.ServerErrorEncoder(httpkit.ErrorEncoder)},
+	}
+	for _, option := range opts {
+		option(o)
+	}
+
+	methods := service.Methods()
+	for i := 0; i < methods.Len(); i++ {
+		md := methods.Get(i)
+		e, ok := endpoints[string(md.FullName())]
+		if !ok {
+			continue
+		}
+		rule, ok := proto.GetExtension(md.Options(), annotations.E_Http).(*annotations.HttpRule)
+		if !ok || rule == nil {
+			continue
+		}
+		for _, r := range append([]*annotations.HttpRule{rule}, rule.GetAdditionalBindings()...) {
+			if err := register(router, md, r, e, o); err != nil {
+				return fmt.Errorf("transcode: method %s: %w", md.FullName(), err)
+			}
+		}
+	}
+	return nil
+}
+
+func register(router *mux.Router, md protoreflect.MethodDescriptor, rule *annotations.HttpRule, e endpoint.Endpoint, o *options) error {
+	method, pattern := ruleMethod(rule)
+	if pattern == "" {
+		return fmt.Errorf("missing pattern")
+	}
+	tmpl, err := parseTemplate(pattern)
+	if err != nil {
+		return err
+	}
+
+	input, err := messageType(md.Input())
+	if err != nil {
+		return err
+	}
+	if err := checkField(md.Input(), rule.GetBody()); err != nil {
+		return err
+	}
+	for _, v := range tmpl.vars {
+		if err := checkField(md.Input(), v); err != nil {
+			return err
+		}
+	}
+	d := &decoder{input: input, vars: tmpl.vars, body: rule.GetBody(), decodeOptions: o.decodeOptions}
+
+	encode := o.encode
+	if field := rule.GetResponseBody(); field != "" {
+		if err := checkField(md.Output(), field); err != nil {
+			return err
+		}
+		encode = responseBodyEncoder(field, o.encode)
+	}
+
+	server := httptransport.NewServer(e, d.decodeRequest, encode, o.serverOptions...)
+	router.Methods(method).Path(tmpl.path).Handler(server)
+	return nil
+}
+
+// ruleMethod returns the HTTP method and the path template of the rule.
+func ruleMethod(rule *annotations.HttpRule) (string, string) {
+	switch p := rule.GetPattern().(type) {
+	case *annotations.HttpRule_Get:
+		return http.MethodGet, p.Get
+	case *annotations.HttpRule_Put:
+		return http.MethodPut, p.Put
+	case *annotations.HttpRule_Post:
+		return http.MethodPost, p.Post
+	case *annotations.HttpRule_Delete:
+		return http.MethodDelete, p.Delete
+	case *annotations.HttpRule_Patch:
+		return http.MethodPatch, p.Patch
+	case *annotations.HttpRule_Custom:
+		return p.Custom.GetKind(), p.Custom.GetPath()
+	}
+	return "", ""
+}
+
+// messageType returns the registered type of the message, or a dynamic type
+// when the message is not linked in the binary.
+func messageType(md protoreflect.MessageDescriptor) (protoreflect.MessageType, error) {
+	mt, err := protoregistry.GlobalTypes.FindMessageByName(md.FullName())
+	if err == protoregistry.NotFound {
+		return dynamicpb.NewMessageType(md), nil
+	}
+	return mt, err
+}
+
+// checkField verifies that the dotted path refers to a field of the message
+// and that its parent fields are singular messages. The empty path and "*"
+// are always valid.
+func checkField(md protoreflect.MessageDescriptor, path string) error {
+	if path == "" || path == "*" {
+		return nil
+	}
+	for _, name := range strings.Split(path, ".") {
+		if md == nil {
+			return fmt.Errorf("field %q is not a message", path)
+		}
+		fd := md.Fields().ByName(protoreflect.Name(name))
+		if fd == nil {
+			return fmt.Errorf("unknown field %q of %s", path, md.FullName())
+		}
+		md = fd.Message()
+		if fd.IsList() || fd.IsMap() {
+			md = nil
+		}
+	}
+	return nil
+}
+
+type template struct {
+	// path is the gorilla/mux path template.
+	path string
+	// vars are the field paths of the variables, in the order of their
+	// mux variables v0, v1, ...
+	vars []string
+}
+
+var variablePattern = regexp.MustCompile(`\{([^{}=]+)(?:=([^{}]+))?\}`)
+
+// parseTemplate converts the path template of the rule to a gorilla/mux
+// template. The variables are renamed, as their field paths may contain
+// characters that mux does not allow, and their segment patterns are
+// converted to regular expressions.
+func parseTemplate(pattern string) (template, error) {
+	if !strings.HasPrefix(pattern, "/") {
+		return template{}, fmt.Errorf("invalid pattern %q", pattern)
+	}
+	var t template
+	var b strings.Builder
+	last := 0
+	for _, m := range variablePattern.FindAllStringSubmatchIndex(pattern, -1) {
+		b.WriteString(pattern[last:m[0]])
+		field := pattern[m[2]:m[3]]
+		segments := "*"
+		if m[4] >= 0 {
+			segments = pattern[m[4]:m[5]]
+		}
+		fmt.Fprintf(&b, "{v%d:%s}", len(t.vars), segmentsRegexp(segments))
+		t.vars = append(t.vars, field)
+		last = m[1]
+	}
+	b.WriteString(pattern[last:])
+	t.path = b.String()
+	if strings.ContainsAny(pattern[last:], "{}") {
+		return template{}, fmt.Errorf("invalid pattern %q", pattern)
+	}
+	return t, nil
+}
+
+func segmentsRegexp(segments string) string {
+	parts := strings.Split(segments, "/")
+	for i, s := range parts {
+		switch s {
+		case "*":
+			parts[i] = "[^/]+"
+		case "**":
+			parts[i] = ".+"
+		default:
+			parts[i] = regexp.QuoteMeta(s)
+		}
+	}
+	return strings.Join(parts, "/")
+}
+
+type decoder struct {
+	input         protoreflect.MessageType
+	vars          []string
+	body          string
+	decodeOptions []httpkit.DecodeOption
+}
+
+func (d *decoder) decodeRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	m := d.input.New().Interface()
+	if err := d.decodeBody(r, m); err != nil {
+		return nil, err
+	}
+
+	vars := mux.Vars(r)
+	for i, field := range d.vars {
+		if err := httpkit.PopulateField(m, field, vars[fmt.Sprintf("v%d", i)]); err != nil {
+			return nil, err
+		}
+	}
+
+	if d.body == "*" {
+		return m, nil
+	}
+	query := url.Values{}
+	md := m.ProtoReflect().Descriptor()
+	for k, v := range r.URL.Query() {
+		if !isBound(md, k, d.body, d.vars) {
+			query[k] = v
+		}
+	}
+	if err := httpkit.PopulateQueryParameters(m, query); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// decodeBody decodes the JSON body into the message, or into its field that
+// is named by the rule.
+func (d *decoder) decodeBody(r *http.Request, m proto.Message) error {
+	if d.body == "" {
+		return nil
+	}
+	target := m.ProtoReflect()
+	if d.body == "*" {
+		return httpkit.DecodeProtoJSONBody(r, target.Interface(), d.decodeOptions...)
+	}
+	names := strings.Split(d.body, ".")
+	for _, name := range names[:len(names)-1] {
+		target = target.Mutable(target.Descriptor().Fields().ByName(protoreflect.Name(name))).Message()
+	}
+	fd := target.Descriptor().Fields().ByName(protoreflect.Name(names[len(names)-1]))
+	if fd.Message() == nil || fd.IsList() || fd.IsMap() {
+		// The scalar, repeated and map fields are decoded from their JSON
+		// values.
+		return httpkit.DecodeProtoJSONField(r, target.Interface(), fd, d.decodeOptions...)
+	}
+	return httpkit.DecodeProtoJSONBody(r, target.Mutable(fd).Message().Interface(), d.decodeOptions...)
+}
+
+// isBound reports whether the query parameter refers to a field that is
+// bound by the path or by the body. The parameter is resolved to the proto
+// names of its fields first, as it may use their JSON names.
+func isBound(md protoreflect.MessageDescriptor, param string, body string, vars []string) bool {
+	path, ok := httpkit.FieldPath(md, param)
+	if !ok {
+		return false
+	}
+	for _, field := range append([]string{body}, vars...) {
+		if field != "" && (path == field || strings.HasPrefix(path, field+".")) {
+			return true
+		}
+	}
+	return false
+}
+
+// responseBodyEncoder encodes only the field of the response.
+func responseBodyEncoder(field string, encode httptransport.EncodeResponseFunc) httptransport.EncodeResponseFunc {
+	return func(ctx context.Context, w http.ResponseWriter, response interface{}) error {
+		m, ok := response.(proto.Message)
+		if !ok {
+			return encode(ctx, w, response)
+		}
+		msg := m.ProtoReflect()
+		for _, name := range strings.Split(field, ".") {
+			fd := msg.Descriptor().Fields().ByName(protoreflect.Name(name))
+			if fd.Message() == nil || fd.IsList() || fd.IsMap() {
+				return encode(ctx, w, response)
+			}
+			msg = msg.Get(fd).Message()
+		}
+		return encode(ctx, w, msg.Interface())
+	}
+}
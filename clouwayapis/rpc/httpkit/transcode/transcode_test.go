@@ -0,0 +1,213 @@
+package transcode_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/clouway/go-genproto/clouwayapis/rpc/httpkit/transcode"
+	"github.com/go-kit/kit/endpoint"
+	"github.com/gorilla/mux"
+	"google.golang.org/genproto/googleapis/api/annotations"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	_ "google.golang.org/protobuf/types/known/apipb"
+	"google.golang.org/protobuf/types/known/sourcecontextpb"
+	"google.golang.org/protobuf/types/known/typepb"
+)
+
+// testService builds the descriptor of an annotated service over the
+// well-known types, as protoc is not available to the tests.
+func testService(t *testing.T) protoreflect.ServiceDescriptor {
+	method := func(name, input, output string, rule *annotations.HttpRule) *descriptorpb.MethodDescriptorProto {
+		options := &descriptorpb.MethodOptions{}
+		proto.SetExtension(options, annotations.E_Http, rule)
+		return &descriptorpb.MethodDescriptorProto{Name: proto.String(name), InputType: proto.String(input), OutputType: proto.String(output), Options: options}
+	}
+	file := &descriptorpb.FileDescriptorProto{
+		Name:       proto.String("test/fields.proto"),
+		Package:    proto.String("test"),
+		Syntax:     proto.String("proto3"),
+		Dependency: []string{"google/protobuf/type.proto", "google/protobuf/api.proto", "google/api/annotations.proto"},
+		Service: []*descriptorpb.ServiceDescriptorProto{{
+			Name: proto.String("Fields"),
+			Method: []*descriptorpb.MethodDescriptorProto{
+				method("GetField", ".google.protobuf.Field", ".google.protobuf.Field", &annotations.HttpRule{
+					Pattern: &annotations.HttpRule_Get{Get: "/v1/{name=fields/*}"},
+					AdditionalBindings: []*annotations.HttpRule{
+						{Pattern: &annotations.HttpRule_Get{Get: "/v1/types/{type_url}/fields/{number}"}},
+					},
+				}),
+				method("CreateField", ".google.protobuf.Field", ".google.protobuf.Field", &annotations.HttpRule{
+					Pattern: &annotations.HttpRule_Post{Post: "/v1/fields"},
+					Body:    "*",
+				}),
+				method("CancelField", ".google.protobuf.Field", ".google.protobuf.Field", &annotations.HttpRule{
+					Pattern: &annotations.HttpRule_Post{Post: "/v1/{name=fields/*}:cancel"},
+				}),
+				method("SetDefaultValue", ".google.protobuf.Field", ".google.protobuf.Field", &annotations.HttpRule{
+					Pattern: &annotations.HttpRule_Put{Put: "/v1/{name=fields/*}/defaultValue"},
+					Body:    "default_value",
+				}),
+				method("SetOptions", ".google.protobuf.Field", ".google.protobuf.Field", &annotations.HttpRule{
+					Pattern: &annotations.HttpRule_Put{Put: "/v1/{name=fields/*}/options"},
+					Body:    "options",
+				}),
+				method("UpdateApi", ".google.protobuf.Api", ".google.protobuf.Api", &annotations.HttpRule{
+					Pattern:      &annotations.HttpRule_Patch{Patch: "/v1/{name=apis/**}"},
+					Body:         "source_context",
+					ResponseBody: "source_context",
+				}),
+			},
+		}},
+	}
+	fd, err := protodesc.NewFile(file, protoregistry.GlobalFiles)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return fd.Services().Get(0)
+}
+
+func echo(prefix string) endpoint.Endpoint {
+	return func(_ context.Context, request interface{}) (interface{}, error) {
+		if f, ok := request.(*typepb.Field); ok {
+			f.JsonName = prefix
+		}
+		return request, nil
+	}
+}
+
+func TestRegisterRoutes(t *testing.T) {
+	router := mux.NewRouter()
+	err := transcode.RegisterRoutes(router, testService(t), map[string]endpoint.Endpoint{
+		"test.Fields.GetField":        echo("get"),
+		"test.Fields.CreateField":     echo("create"),
+		"test.Fields.CancelField":     echo("cancel"),
+		"test.Fields.SetDefaultValue": echo("default"),
+		"test.Fields.SetOptions":      echo("options"),
+		"test.Fields.UpdateApi":       echo("update"),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		method string
+		target string
+		body   string
+		want   proto.Message
+	}{
+		{
+			name:   "path variable with query",
+			method: http.MethodGet,
+			target: "/v1/fields/id?number=3&kind=TYPE_STRING",
+			want:   &typepb.Field{Name: "fields/id", Number: 3, Kind: typepb.Field_TYPE_STRING, JsonName: "get"},
+		},
+		{
+			name:   "additional binding",
+			method: http.MethodGet,
+			target: "/v1/types/account/fields/4",
+			want:   &typepb.Field{TypeUrl: "account", Number: 4, JsonName: "get"},
+		},
+		{
+			name:   "json names of bound fields in query",
+			method: http.MethodGet,
+			target: "/v1/types/account/fields/4?typeUrl=other&number=9",
+			want:   &typepb.Field{TypeUrl: "account", Number: 4, JsonName: "get"},
+		},
+		{
+			name:   "whole body",
+			method: http.MethodPost,
+			target: "/v1/fields",
+			body:   `{"name":"id","number":1}`,
+			want:   &typepb.Field{Name: "id", Number: 1, JsonName: "create"},
+		},
+		{
+			name:   "custom verb",
+			method: http.MethodPost,
+			target: "/v1/fields/id:cancel",
+			want:   &typepb.Field{Name: "fields/id", JsonName: "cancel"},
+		},
+		{
+			name:   "scalar field body",
+			method: http.MethodPut,
+			target: "/v1/fields/id/defaultValue",
+			body:   `"none"`,
+			want:   &typepb.Field{Name: "fields/id", DefaultValue: "none", JsonName: "default"},
+		},
+		{
+			name:   "repeated field body",
+			method: http.MethodPut,
+			target: "/v1/fields/id/options",
+			body:   `[{"name":"deprecated"}]`,
+			want:   &typepb.Field{Name: "fields/id", Options: []*typepb.Option{{Name: "deprecated"}}, JsonName: "options"},
+		},
+		{
+			name:   "field body with wildcard path and response body",
+			method: http.MethodPatch,
+			target: "/v1/apis/a/b/c?version=v2",
+			body:   `{"fileName":"api.proto"}`,
+			want:   &sourcecontextpb.SourceContext{FileName: "api.proto"},
+		},
+		{
+			name:   "json names of body fields in query",
+			method: http.MethodPatch,
+			target: "/v1/apis/a/b/c?sourceContext.fileName=other.proto",
+			body:   `{"fileName":"api.proto"}`,
+			want:   &sourcecontextpb.SourceContext{FileName: "api.proto"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, httptest.NewRequest(test.method, test.target, strings.NewReader(test.body)))
+
+			if rec.Code != http.StatusOK {
+				t.Fatalf("unexpected status code:\n- want: %v\n-  got: %v (%s)", http.StatusOK, rec.Code, rec.Body)
+			}
+			want := test.want
+			got := want.ProtoReflect().New().Interface()
+			if err := protojson.Unmarshal(rec.Body.Bytes(), got); err != nil {
+				t.Fatalf("unexpected error while decoding body: %v", err)
+			}
+			if !proto.Equal(want, got) {
+				t.Errorf("unexpected response:\n- want: %v\n-  got: %v", want, got)
+			}
+		})
+	}
+}
+
+func TestRegisterRoutesInvalidQuery(t *testing.T) {
+	router := mux.NewRouter()
+	if err := transcode.RegisterRoutes(router, testService(t), map[string]endpoint.Endpoint{"test.Fields.GetField": echo("get")}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/fields/id?number=abc", nil))
+
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("unexpected status code:\n- want: %v\n-  got: %v", http.StatusBadRequest, rec.Code)
+	}
+}
+
+func TestRegisterRoutesSkipsMethodsWithoutEndpoint(t *testing.T) {
+	router := mux.NewRouter()
+	if err := transcode.RegisterRoutes(router, testService(t), map[string]endpoint.Endpoint{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/fields/id", nil))
+
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("unexpected status code:\n- want: %v\n-  got: %v", http.StatusNotFound, rec.Code)
+	}
+}
@@ -0,0 +1,191 @@
+package httpkit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"mime"
+	"net/http"
+	"sync"
+
+	httptransport "github.com/go-kit/kit/transport/http"
+	spb "google.golang.org/genproto/googleapis/rpc/status"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// BinaryProtocolHeader is the response header with which the servers
+// advertise that they accept binary protobuf request bodies.
+const BinaryProtocolHeader = "X-Accept-Protobuf"
+
+// AdvertiseBinaryProtocol is a transport/http.ServerResponseFunc that
+// advertises the binary protobuf support of the server by
+// BinaryProtocolHeader. It's meant for the servers that decode their
+// requests by DecodeNegotiatedRequest and encode the responses by
+// EncodeNegotiatedResponse, so the internal clients of BinaryNegotiator
+// switch to binary protobuf after the first call, while the external
+// clients keep using JSON.
+func AdvertiseBinaryProtocol(ctx context.Context, w http.ResponseWriter) context.Context {
+	w.Header().Set(BinaryProtocolHeader, "1")
+	return ctx
+}
+
+// binaryAccept is the Accept header of the internal calls, which prefers
+// binary protobuf responses to JSON.
+const binaryAccept = ProtobufContentType + ", application/json;q=0.9"
+
+// BinaryNegotiator upgrades the internal service-to-service calls to binary
+// protobuf. The requests are sent as JSON until the server advertises the
+// binary protocol by BinaryProtocolHeader, and as binary protobuf after
+// that. The responses are requested as binary protobuf and decoded by their
+// Content-Type, so the servers without binary support keep working with
+// JSON.
+//
+// The negotiator is safe for concurrent use and remembers the servers by
+// host, so a single negotiator is shared by all clients of a service:
+//
+//	n := httpkit.NewBinaryNegotiator()
+//	client := httptransport.NewClient(http.MethodPost, u, n.EncodeRequest, httpkit.DecodeBinaryResponse[*pb.Order](n))
+type BinaryNegotiator struct {
+	mu    sync.RWMutex
+	hosts map[string]bool
+}
+
+// NewBinaryNegotiator creates a negotiator that doesn't know any servers yet.
+func NewBinaryNegotiator() *BinaryNegotiator {
+	return &BinaryNegotiator{hosts: make(map[string]bool)}
+}
+
+// Binary reports whether the server on host advertised the binary protocol.
+func (n *BinaryNegotiator) Binary(host string) bool {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+	return n.hosts[host]
+}
+
+func (n *BinaryNegotiator) observe(r *http.Response) {
+	if r.Request == nil {
+		return
+	}
+	binary := r.Header.Get(BinaryProtocolHeader) == "1"
+	if n.Binary(r.Request.URL.Host) == binary {
+		return
+	}
+	n.mu.Lock()
+	n.hosts[r.Request.URL.Host] = binary
+	n.mu.Unlock()
+}
+
+// EncodeRequest is a transport/http.EncodeRequestFunc that encodes the
+// proto.Message requests as binary protobuf for the servers that advertised
+// the binary protocol and as JSON otherwise. Nil requests are sent without
+// body.
+func (n *BinaryNegotiator) EncodeRequest(_ context.Context, r *http.Request, request interface{}) error {
+	r.Header.Set("Accept", binaryAccept)
+	m, ok := request.(proto.Message)
+	if !ok || isNil(request) {
+		return nil
+	}
+
+	var (
+		b   []byte
+		err error
+	)
+	if n.Binary(r.URL.Host) {
+		r.Header.Set("Content-Type", ProtobufContentType)
+		b, err = proto.Marshal(m)
+	} else {
+		r.Header.Set("Content-Type", JSONContentType)
+		b, err = protojson.Marshal(m)
+	}
+	if err != nil {
+		return err
+	}
+	r.Body = io.NopCloser(bytes.NewReader(b))
+	r.ContentLength = int64(len(b))
+	r.GetBody = func() (io.ReadCloser, error) { return io.NopCloser(bytes.NewReader(b)), nil }
+	return nil
+}
+
+// DecodeBinaryResponse returns a transport/http.DecodeResponseFunc that
+// decodes the response into a new message of type T by its Content-Type and
+// remembers whether the server advertised the binary protocol. The error
+// responses are returned as status errors, either decoded from their
+// binary google.rpc.Status body or with the code of the HTTP status and the
+// message of the JSON body.
+func DecodeBinaryResponse[T proto.Message](n *BinaryNegotiator) httptransport.DecodeResponseFunc {
+	return func(_ context.Context, r *http.Response) (interface{}, error) {
+		n.observe(r)
+		b, err := io.ReadAll(r.Body)
+		if err != nil {
+			return nil, err
+		}
+		mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		if r.StatusCode >= http.StatusBadRequest {
+			return nil, decodeErrorResponse(r.StatusCode, mediaType, b)
+		}
+
+		var zero T
+		m := zero.ProtoReflect().New().Interface().(T)
+		if len(b) == 0 {
+			return m, nil
+		}
+		if c, ok := codecOf(mediaType); ok {
+			err = c.unmarshal(b, m)
+		} else {
+			err = protojson.UnmarshalOptions{DiscardUnknown: true}.Unmarshal(b, m)
+		}
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "invalid response body: %v", err)
+		}
+		return m, nil
+	}
+}
+
+// decodeErrorResponse converts the error response to a status error.
+func decodeErrorResponse(code int, mediaType string, b []byte) error {
+	if c, ok := codecOf(mediaType); ok && c.contentTypes[0] == ProtobufContentType {
+		st := &spb.Status{}
+		if err := proto.Unmarshal(b, st); err == nil {
+			return status.ErrorProto(st)
+		}
+	}
+	var body struct {
+		Message string `json:"message"`
+	}
+	if err := json.Unmarshal(b, &body); err != nil || body.Message == "" {
+		body.Message = http.StatusText(code)
+	}
+	return status.Error(codeFromHTTPStatus(code), body.Message)
+}
+
+// codeFromHTTPStatus returns the gRPC code of the HTTP status, reversing
+// HTTPStatusFromCode where the mapping is not ambiguous.
+func codeFromHTTPStatus(code int) codes.Code {
+	switch code {
+	case http.StatusBadRequest:
+		return codes.InvalidArgument
+	case http.StatusUnauthorized:
+		return codes.Unauthenticated
+	case http.StatusForbidden:
+		return codes.PermissionDenied
+	case http.StatusNotFound:
+		return codes.NotFound
+	case http.StatusConflict:
+		return codes.AlreadyExists
+	case http.StatusRequestTimeout:
+		return codes.Canceled
+	case http.StatusTooManyRequests:
+		return codes.ResourceExhausted
+	case http.StatusNotImplemented:
+		return codes.Unimplemented
+	case http.StatusServiceUnavailable:
+		return codes.Unavailable
+	case http.StatusGatewayTimeout:
+		return codes.DeadlineExceeded
+	}
+	return codes.Unknown
+}
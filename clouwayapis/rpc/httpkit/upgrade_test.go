@@ -0,0 +1,94 @@
+package httpkit_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/clouway/go-genproto/clouwayapis/rpc/httpkit"
+	httptransport "github.com/go-kit/kit/transport/http"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/typepb"
+)
+
+func TestBinaryNegotiator(t *testing.T) {
+	tests := []struct {
+		name         string
+		serverOpts   []httptransport.ServerOption
+		contentTypes []string
+	}{
+		{
+			name:         "advertised",
+			serverOpts:   []httptransport.ServerOption{httptransport.ServerAfter(httpkit.AdvertiseBinaryProtocol)},
+			contentTypes: []string{httpkit.JSONContentType, httpkit.ProtobufContentType, httpkit.ProtobufContentType},
+		},
+		{
+			name:         "not advertised",
+			contentTypes: []string{httpkit.JSONContentType, httpkit.JSONContentType, httpkit.JSONContentType},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var contentTypes []string
+			options := append([]httptransport.ServerOption{
+				httptransport.ServerBefore(httptransport.PopulateRequestContext, func(ctx context.Context, r *http.Request) context.Context {
+					contentTypes = append(contentTypes, r.Header.Get("Content-Type"))
+					return ctx
+				}),
+			}, test.serverOpts...)
+			server := httptest.NewServer(httptransport.NewServer(
+				func(_ context.Context, request interface{}) (interface{}, error) { return request, nil },
+				httpkit.DecodeNegotiatedRequest[*typepb.Field](),
+				httpkit.EncodeNegotiatedResponse,
+				options...,
+			))
+			defer server.Close()
+
+			n := httpkit.NewBinaryNegotiator()
+			u, _ := url.Parse(server.URL)
+			client := httptransport.NewClient(http.MethodPost, u, n.EncodeRequest, httpkit.DecodeBinaryResponse[*typepb.Field](n))
+
+			for i := 0; i < 3; i++ {
+				want := &typepb.Field{Name: "id", Number: int32(i)}
+				got, err := client.Endpoint()(context.Background(), want)
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if !proto.Equal(want, got.(proto.Message)) {
+					t.Errorf("unexpected response:\n- want: %v\n-  got: %v", want, got)
+				}
+			}
+			if want, got := test.contentTypes, contentTypes; len(want) != len(got) || want[0] != got[0] || want[1] != got[1] || want[2] != got[2] {
+				t.Errorf("unexpected request content types:\n- want: %v\n-  got: %v", want, got)
+			}
+		})
+	}
+}
+
+func TestDecodeBinaryResponseError(t *testing.T) {
+	server := httptest.NewServer(httptransport.NewServer(
+		func(_ context.Context, request interface{}) (interface{}, error) {
+			return nil, status.Error(codes.NotFound, "field not found")
+		},
+		httpkit.DecodeNegotiatedRequest[*typepb.Field](),
+		httpkit.EncodeNegotiatedResponse,
+		httptransport.ServerBefore(httptransport.PopulateRequestContext),
+		httptransport.ServerErrorEncoder(httpkit.ErrorEncoder),
+	))
+	defer server.Close()
+
+	n := httpkit.NewBinaryNegotiator()
+	u, _ := url.Parse(server.URL)
+	client := httptransport.NewClient(http.MethodPost, u, n.EncodeRequest, httpkit.DecodeBinaryResponse[*typepb.Field](n))
+
+	_, err := client.Endpoint()(context.Background(), &typepb.Field{Name: "id"})
+
+	want := status.New(codes.NotFound, "field not found")
+	if got := status.Convert(err); got.Code() != want.Code() || got.Message() != want.Message() {
+		t.Errorf("unexpected error:\n- want: %v\n-  got: %v", want, got)
+	}
+}
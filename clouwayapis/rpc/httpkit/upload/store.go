@@ -0,0 +1,123 @@
+package upload
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// Upload describes a resumable upload.
+type Upload struct {
+	// ID identifies the upload in its URL.
+	ID string `json:"id"`
+	// Length is the size of the file in bytes.
+	Length int64 `json:"length"`
+	// Offset is the number of the received bytes.
+	Offset int64 `json:"offset"`
+	// FileName and ContentType are taken from the filename and the
+	// filetype of the Upload-Metadata header.
+	FileName    string `json:"fileName,omitempty"`
+	ContentType string `json:"contentType,omitempty"`
+	// Metadata is the whole Upload-Metadata header.
+	Metadata map[string]string `json:"metadata,omitempty"`
+}
+
+// Store keeps the uploads and their received bytes, so the uploads survive
+// the restarts of the server. Get returns nil upload and nil error for
+// unknown uploads.
+type Store interface {
+	Create(ctx context.Context, u *Upload) error
+	Get(ctx context.Context, id string) (*Upload, error)
+	// Append writes the bytes of the reader at the offset of the upload,
+	// which must be its current offset, and returns the new offset. The
+	// bytes that are received before the reader fails are kept.
+	Append(ctx context.Context, id string, offset int64, r io.Reader) (int64, error)
+	// Open returns the content of the upload.
+	Open(ctx context.Context, id string) (io.ReadCloser, error)
+	Delete(ctx context.Context, id string) error
+}
+
+// FileStore is a Store that keeps the uploads as files in a directory: the
+// received bytes in <id>.bin and the description in <id>.json.
+type FileStore struct {
+	dir string
+}
+
+// NewFileStore creates a store in the directory, which must exist.
+func NewFileStore(dir string) *FileStore {
+	return &FileStore{dir: dir}
+}
+
+// Create creates the files of the upload.
+func (s *FileStore) Create(_ context.Context, u *Upload) error {
+	b, err := json.Marshal(u)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(s.path(u.ID, ".json"), b, 0o600); err != nil {
+		return err
+	}
+	return os.WriteFile(s.path(u.ID, ".bin"), nil, 0o600)
+}
+
+// Get returns the upload with offset set to the size of its bytes.
+func (s *FileStore) Get(_ context.Context, id string) (*Upload, error) {
+	b, err := os.ReadFile(s.path(id, ".json"))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	u := &Upload{}
+	if err := json.Unmarshal(b, u); err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(s.path(id, ".bin"))
+	if err != nil {
+		return nil, err
+	}
+	u.Offset = info.Size()
+	return u, nil
+}
+
+// Append appends the bytes to the file of the upload.
+func (s *FileStore) Append(_ context.Context, id string, offset int64, r io.Reader) (int64, error) {
+	f, err := os.OpenFile(s.path(id, ".bin"), os.O_WRONLY, 0)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	size, err := f.Seek(0, io.SeekEnd)
+	if err != nil {
+		return 0, err
+	}
+	if size != offset {
+		return size, errOffsetMismatch
+	}
+	n, err := io.Copy(f, r)
+	return offset + n, err
+}
+
+// Open opens the file of the upload.
+func (s *FileStore) Open(_ context.Context, id string) (io.ReadCloser, error) {
+	return os.Open(s.path(id, ".bin"))
+}
+
+// Delete removes the files of the upload.
+func (s *FileStore) Delete(_ context.Context, id string) error {
+	for _, ext := range []string{".bin", ".json"} {
+		if err := os.Remove(s.path(id, ext)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *FileStore) path(id, ext string) string {
+	return filepath.Join(s.dir, filepath.Base(id)+ext)
+}
@@ -0,0 +1,293 @@
+// Package upload implements resumable uploads over HTTP with the core and
+// the creation and termination extensions of the tus protocol, so large
+// files such as the meter readings survive the interruptions of the
+// network. The client creates the upload with its size, sends the file in
+// PATCH chunks and, when a chunk fails, asks for the offset that the server
+// has received and resumes from there:
+//
+//	POST /uploads          Upload-Length: 104857600      → 201 Location: /uploads/<id>
+//	PATCH /uploads/<id>    Upload-Offset: 0              → 204 Upload-Offset: 52428800
+//	HEAD /uploads/<id>                                   → 200 Upload-Offset: 52428800
+//	PATCH /uploads/<id>    Upload-Offset: 52428800       → 204 Upload-Offset: 104857600
+//
+// The completed uploads are passed to the Completer, e.g. to store them as
+// fileserve.BinaryFile, and are removed from the Store.
+package upload
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/clouway/go-genproto/clouwayapis/rpc/fileserve"
+	"github.com/clouway/go-genproto/clouwayapis/rpc/httpkit"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Version is the version of the tus protocol.
+const Version = "1.0.0"
+
+// ContentType is the content type of the PATCH chunks.
+const ContentType = "application/offset+octet-stream"
+
+var errOffsetMismatch = status.Error(codes.Aborted, "upload offset doesn't match")
+
+// Completer receives the content of the completed uploads.
+type Completer interface {
+	Complete(ctx context.Context, u *Upload, content io.Reader) error
+}
+
+// CompleterFunc is an adapter to use functions as Completer.
+type CompleterFunc func(ctx context.Context, u *Upload, content io.Reader) error
+
+// Complete calls f(ctx, u, content).
+func (f CompleterFunc) Complete(ctx context.Context, u *Upload, content io.Reader) error {
+	return f(ctx, u, content)
+}
+
+// BinaryFile reads the content of the upload into a fileserve.BinaryFile.
+func BinaryFile(u *Upload, content io.Reader) (*fileserve.BinaryFile, error) {
+	b, err := io.ReadAll(content)
+	if err != nil {
+		return nil, err
+	}
+	return &fileserve.BinaryFile{ContentType: u.ContentType, FileName: u.FileName, Content: b}, nil
+}
+
+// Option sets an optional parameter for the handler.
+type Option func(*handler)
+
+// MaxSize limits the size of the uploads. It's 1GiB by default.
+func MaxSize(n int64) Option {
+	return func(h *handler) { h.maxSize = n }
+}
+
+type handler struct {
+	base      string
+	store     Store
+	completer Completer
+	maxSize   int64
+
+	mu    sync.Mutex
+	locks map[string]bool
+}
+
+// NewHandler returns the handler of the uploads under the base path, e.g.
+// "/uploads". It must be registered for the base path and all paths below
+// it:
+//
+//	router.PathPrefix("/uploads").Handler(upload.NewHandler("/uploads", store, completer))
+//
+// The errors are written by httpkit.ErrorEncoder: the unknown uploads as
+// 404, the chunks with wrong offset or to uploads that are already being
+// written as 409, and the uploads over the maximum size as 413.
+func NewHandler(base string, store Store, completer Completer, options ...Option) http.Handler {
+	h := &handler{base: strings.TrimSuffix(base, "/"), store: store, completer: completer, maxSize: 1 << 30, locks: map[string]bool{}}
+	for _, option := range options {
+		option(h)
+	}
+	return h
+}
+
+func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Tus-Resumable", Version)
+	w.Header().Set("Cache-Control", "no-store")
+	id := strings.Trim(strings.TrimPrefix(r.URL.Path, h.base), "/")
+
+	var err error
+	switch {
+	case r.Method == http.MethodOptions:
+		w.Header().Set("Tus-Version", Version)
+		w.Header().Set("Tus-Extension", "creation,termination")
+		w.Header().Set("Tus-Max-Size", strconv.FormatInt(h.maxSize, 10))
+		w.WriteHeader(http.StatusNoContent)
+	case id == "" && r.Method == http.MethodPost:
+		err = h.create(w, r)
+	case id != "" && r.Method == http.MethodHead:
+		err = h.head(w, r, id)
+	case id != "" && r.Method == http.MethodPatch:
+		err = h.patch(w, r, id)
+	case id != "" && r.Method == http.MethodDelete:
+		err = h.delete(w, r, id)
+	default:
+		allowed := []string{http.MethodPost}
+		if id != "" {
+			allowed = []string{http.MethodHead, http.MethodPatch, http.MethodDelete}
+		}
+		w.Header().Set("Allow", strings.Join(allowed, ", "))
+		err = httpkit.NewMethodNotAllowedError(r.Method, allowed)
+	}
+	if err != nil {
+		httpkit.ErrorEncoder(r.Context(), err, w)
+	}
+}
+
+// create creates a new upload.
+func (h *handler) create(w http.ResponseWriter, r *http.Request) error {
+	length, err := strconv.ParseInt(r.Header.Get("Upload-Length"), 10, 64)
+	if err != nil || length < 0 {
+		return httpkit.NewBadRequestError("invalid Upload-Length header '%s'", r.Header.Get("Upload-Length"))
+	}
+	if length > h.maxSize {
+		return httpkit.NewPayloadTooLargeError(h.maxSize)
+	}
+	metadata, err := parseMetadata(r.Header.Get("Upload-Metadata"))
+	if err != nil {
+		return err
+	}
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return err
+	}
+
+	u := &Upload{ID: fmt.Sprintf("%x", b), Length: length, FileName: metadata["filename"], ContentType: metadata["filetype"], Metadata: metadata}
+	if err := h.store.Create(r.Context(), u); err != nil {
+		return err
+	}
+	if length == 0 {
+		if err := h.complete(r.Context(), u); err != nil {
+			return err
+		}
+	}
+	w.Header().Set("Location", h.base+"/"+u.ID)
+	w.WriteHeader(http.StatusCreated)
+	return nil
+}
+
+// head responds with the offset of the upload.
+func (h *handler) head(w http.ResponseWriter, r *http.Request, id string) error {
+	u, err := h.get(r.Context(), id)
+	if err != nil {
+		return err
+	}
+	w.Header().Set("Upload-Offset", strconv.FormatInt(u.Offset, 10))
+	w.Header().Set("Upload-Length", strconv.FormatInt(u.Length, 10))
+	w.WriteHeader(http.StatusOK)
+	return nil
+}
+
+// patch appends the chunk to the upload and completes the upload when all
+// of its bytes are received.
+func (h *handler) patch(w http.ResponseWriter, r *http.Request, id string) error {
+	if r.Header.Get("Content-Type") != ContentType {
+		return httpkit.NewBadRequestError("unsupported content type '%s'", r.Header.Get("Content-Type"))
+	}
+	offset, err := strconv.ParseInt(r.Header.Get("Upload-Offset"), 10, 64)
+	if err != nil || offset < 0 {
+		return httpkit.NewBadRequestError("invalid Upload-Offset header '%s'", r.Header.Get("Upload-Offset"))
+	}
+	if !h.lock(id) {
+		return status.Errorf(codes.Aborted, "upload %s is being written", id)
+	}
+	defer h.unlock(id)
+
+	ctx := r.Context()
+	u, err := h.get(ctx, id)
+	if err != nil {
+		return err
+	}
+	if offset != u.Offset {
+		return errOffsetMismatch
+	}
+	if r.ContentLength > u.Length-offset {
+		return httpkit.NewPayloadTooLargeError(u.Length - offset)
+	}
+	u.Offset, err = h.store.Append(ctx, id, offset, io.LimitReader(r.Body, u.Length-offset))
+	if err != nil && !errors.Is(err, errOffsetMismatch) {
+		// The bytes that are received before the failure are kept and
+		// the client resumes from the offset that it gets by HEAD.
+		return status.Errorf(codes.Unavailable, "upload interrupted at offset %d: %v", u.Offset, err)
+	}
+	if err != nil {
+		return err
+	}
+	if u.Offset == u.Length {
+		if err := h.complete(ctx, u); err != nil {
+			return err
+		}
+	}
+	w.Header().Set("Upload-Offset", strconv.FormatInt(u.Offset, 10))
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+// delete terminates the upload.
+func (h *handler) delete(w http.ResponseWriter, r *http.Request, id string) error {
+	if _, err := h.get(r.Context(), id); err != nil {
+		return err
+	}
+	if err := h.store.Delete(r.Context(), id); err != nil {
+		return err
+	}
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+// get returns the upload or NotFound error when it's unknown.
+func (h *handler) get(ctx context.Context, id string) (*Upload, error) {
+	u, err := h.store.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if u == nil {
+		return nil, status.Errorf(codes.NotFound, "upload %s not found", id)
+	}
+	return u, nil
+}
+
+// complete passes the content of the upload to the completer and removes
+// the upload from the store.
+func (h *handler) complete(ctx context.Context, u *Upload) error {
+	content, err := h.store.Open(ctx, u.ID)
+	if err != nil {
+		return err
+	}
+	defer content.Close()
+	if err := h.completer.Complete(ctx, u, content); err != nil {
+		return err
+	}
+	return h.store.Delete(ctx, u.ID)
+}
+
+func (h *handler) lock(id string) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.locks[id] {
+		return false
+	}
+	h.locks[id] = true
+	return true
+}
+
+func (h *handler) unlock(id string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.locks, id)
+}
+
+// parseMetadata parses the Upload-Metadata header, which is a comma
+// separated list of keys and base64 encoded values.
+func parseMetadata(header string) (map[string]string, error) {
+	metadata := map[string]string{}
+	for _, pair := range strings.Split(header, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		key, value, _ := strings.Cut(pair, " ")
+		b, err := base64.StdEncoding.DecodeString(value)
+		if err != nil {
+			return nil, httpkit.NewBadRequestError("invalid Upload-Metadata value of '%s'", key)
+		}
+		metadata[key] = string(b)
+	}
+	return metadata, nil
+}
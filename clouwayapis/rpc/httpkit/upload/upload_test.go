@@ -0,0 +1,78 @@
+package upload_test
+
+import (
+	"context"
+	"encoding/base64"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/clouway/go-genproto/clouwayapis/rpc/fileserve"
+	"github.com/clouway/go-genproto/clouwayapis/rpc/httpkit/upload"
+)
+
+func TestResumableUpload(t *testing.T) {
+	var completed *fileserve.BinaryFile
+	completer := upload.CompleterFunc(func(ctx context.Context, u *upload.Upload, content io.Reader) (err error) {
+		completed, err = upload.BinaryFile(u, content)
+		return err
+	})
+	handler := upload.NewHandler("/uploads", upload.NewFileStore(t.TempDir()), completer, upload.MaxSize(100))
+	serve := func(method, target string, headers map[string]string, body string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, target, strings.NewReader(body))
+		for k, v := range headers {
+			r.Header.Set(k, v)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w
+	}
+
+	w := serve(http.MethodPost, "/uploads", map[string]string{
+		"Upload-Length":   "10",
+		"Upload-Metadata": "filename " + base64.StdEncoding.EncodeToString([]byte("readings.csv")) + ",filetype " + base64.StdEncoding.EncodeToString([]byte("text/csv")),
+	}, "")
+	if w.Code != http.StatusCreated {
+		t.Fatalf("unexpected status code of creation:\n- want: %v\n-  got: %v", http.StatusCreated, w.Code)
+	}
+	location := w.Header().Get("Location")
+
+	steps := []struct {
+		name    string
+		method  string
+		offset  string
+		body    string
+		status  int
+		resumed string
+	}{
+		{name: "first chunk", method: http.MethodPatch, offset: "0", body: "1,2,3", status: http.StatusNoContent, resumed: "5"},
+		{name: "wrong offset", method: http.MethodPatch, offset: "2", body: "4,5", status: http.StatusConflict},
+		{name: "status", method: http.MethodHead, status: http.StatusOK, resumed: "5"},
+		{name: "last chunk", method: http.MethodPatch, offset: "5", body: ",4,5\n", status: http.StatusNoContent, resumed: "10"},
+		{name: "completed", method: http.MethodHead, status: http.StatusNotFound},
+	}
+	for _, step := range steps {
+		headers := map[string]string{"Content-Type": upload.ContentType}
+		if step.offset != "" {
+			headers["Upload-Offset"] = step.offset
+		}
+		w := serve(step.method, location, headers, step.body)
+
+		if w.Code != step.status {
+			t.Errorf("unexpected status code of %s:\n- want: %v\n-  got: %v", step.name, step.status, w.Code)
+		}
+		if got := w.Header().Get("Upload-Offset"); got != step.resumed {
+			t.Errorf("unexpected Upload-Offset of %s:\n- want: %v\n-  got: %v", step.name, step.resumed, got)
+		}
+	}
+
+	if completed == nil || completed.FileName != "readings.csv" || completed.ContentType != "text/csv" || string(completed.Content) != "1,2,3,4,5\n" {
+		t.Errorf("unexpected completed file: %v", completed)
+	}
+	if w := serve(http.MethodPost, "/uploads", map[string]string{"Upload-Length": strconv.Itoa(101)}, ""); w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("unexpected status code of too large upload:\n- want: %v\n-  got: %v", http.StatusRequestEntityTooLarge, w.Code)
+	}
+}
@@ -0,0 +1,251 @@
+// Package ws exposes the bidirectional gRPC streams to the browsers over
+// WebSocket. The messages are exchanged as protojson text frames, the idle
+// connections are kept alive by pings, and the final status of the stream is
+// reported with the close code of the connection (see CloseCode).
+package ws
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/clouway/go-genproto/clouwayapis/rpc/httpkit"
+	httptransport "github.com/go-kit/kit/transport/http"
+	"github.com/gorilla/websocket"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// BidiStream is the client side of a bidirectional gRPC stream. It's
+// implemented by the stream clients that are generated by protoc-gen-go-grpc.
+type BidiStream[Req, Resp proto.Message] interface {
+	Send(Req) error
+	Recv() (Resp, error)
+	CloseSend() error
+}
+
+// Option sets an optional parameter for the Handler.
+type Option func(*options)
+
+// CheckOrigin sets the function that verifies the Origin header of the
+// upgrade requests. Only the requests from the same host are accepted by
+// default.
+func CheckOrigin(f func(r *http.Request) bool) Option {
+	return func(o *options) { o.upgrader.CheckOrigin = f }
+}
+
+// Subprotocols sets the supported subprotocols in order of preference.
+func Subprotocols(protocols ...string) Option {
+	return func(o *options) { o.upgrader.Subprotocols = protocols }
+}
+
+// PingInterval sets how often the connection is pinged. The connection is
+// closed when the pong is not received within twice the interval. The
+// connection is pinged every 30 seconds by default.
+func PingInterval(d time.Duration) Option {
+	return func(o *options) { o.pingInterval = d }
+}
+
+// ReadLimit sets the maximum size of a message that is read from the client
+// in bytes. The limit is 1MB by default.
+func ReadLimit(limit int64) Option {
+	return func(o *options) { o.readLimit = limit }
+}
+
+// ErrorEncoder sets the encoder of the errors that occur before the
+// connection is upgraded. httpkit.ErrorEncoder is used by default.
+func ErrorEncoder(ee httptransport.ErrorEncoder) Option {
+	return func(o *options) { o.errorEncoder = ee }
+}
+
+// MarshalOptions sets the options that are used to encode the messages.
+func MarshalOptions(marshaller protojson.MarshalOptions) Option {
+	return func(o *options) { o.marshaller = marshaller }
+}
+
+type options struct {
+	upgrader     websocket.Upgrader
+	pingInterval time.Duration
+	readLimit    int64
+	errorEncoder httptransport.ErrorEncoder
+	marshaller   protojson.MarshalOptions
+	unmarshaller protojson.UnmarshalOptions
+}
+
+// Handler returns an http.Handler that opens a stream for every request and
+// upgrades the connection to WebSocket. The frames of the client are decoded
+// as Req messages and sent to the stream, and the Resp messages of the
+// stream are sent back as text frames:
+//
+//	ws.Handler(func(ctx context.Context, r *http.Request) (ws.BidiStream[*pb.TelemetryRequest, *pb.TelemetryResponse], error) {
+//		return client.StreamTelemetry(ctx)
+//	})
+//
+// The stream is opened before the upgrade, so its errors are returned as
+// regular HTTP errors. The context of the stream is cancelled when the
+// connection is lost.
+func Handler[Req, Resp proto.Message](open func(ctx context.Context, r *http.Request) (BidiStream[Req, Resp], error), opts ...Option) http.Handler {
+	o := &options{
+		pingInterval: 30 * time.Second,
+		readLimit:    1 << 20,
+		errorEncoder: httpkit.ErrorEncoder,
+		marshaller:   protojson.MarshalOptions{EmitUnpopulated: true, UseProtoNames: false},
+		unmarshaller: protojson.UnmarshalOptions{DiscardUnknown: true},
+	}
+	for _, option := range opts {
+		option(o)
+	}
+	return &handler[Req, Resp]{open: open, options: o}
+}
+
+type handler[Req, Resp proto.Message] struct {
+	open func(ctx context.Context, r *http.Request) (BidiStream[Req, Resp], error)
+	*options
+}
+
+func (h *handler[Req, Resp]) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+
+	stream, err := h.open(ctx, r)
+	if err != nil {
+		h.errorEncoder(ctx, err, w)
+		return
+	}
+
+	conn, err := h.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// The upgrader has already responded to the client.
+		return
+	}
+	defer conn.Close()
+
+	conn.SetReadLimit(h.readLimit)
+	conn.SetReadDeadline(time.Now().Add(2 * h.pingInterval))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(2 * h.pingInterval))
+	})
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		h.ping(ctx, conn)
+	}()
+	go func() {
+		defer wg.Done()
+		// The stream is cancelled when the client is gone or it sent
+		// an invalid message.
+		defer cancel()
+		if err := h.readLoop(conn, stream); err != nil {
+			h.close(conn, websocket.CloseInvalidFramePayloadData, "invalid message: "+err.Error())
+		}
+	}()
+
+	err = h.writeLoop(conn, stream)
+	if code := CloseCode(err); code == websocket.CloseNormalClosure {
+		h.close(conn, code, "")
+	} else {
+		h.close(conn, code, status.Convert(err).Message())
+	}
+	cancel()
+	wg.Wait()
+}
+
+// readLoop sends the messages of the client to the stream until the
+// connection is closed. Only the errors of the invalid messages are
+// returned.
+func (h *handler[Req, Resp]) readLoop(conn *websocket.Conn, stream BidiStream[Req, Resp]) error {
+	defer stream.CloseSend()
+	for {
+		_, b, err := conn.ReadMessage()
+		if err != nil {
+			return nil
+		}
+		var zero Req
+		m := zero.ProtoReflect().New().Interface().(Req)
+		if err := h.unmarshaller.Unmarshal(b, m); err != nil {
+			return err
+		}
+		if err := stream.Send(m); err != nil {
+			// The status of the stream is returned by Recv.
+			return nil
+		}
+	}
+}
+
+// writeLoop sends the messages of the stream to the client and returns the
+// error that terminated the stream.
+func (h *handler[Req, Resp]) writeLoop(conn *websocket.Conn, stream BidiStream[Req, Resp]) error {
+	var b []byte
+	for {
+		m, err := stream.Recv()
+		if err != nil {
+			return err
+		}
+		if b, err = h.marshaller.MarshalAppend(b[:0], m); err != nil {
+			return status.Error(codes.Internal, err.Error())
+		}
+		if err := conn.WriteMessage(websocket.TextMessage, b); err != nil {
+			return err
+		}
+	}
+}
+
+func (h *handler[Req, Resp]) ping(ctx context.Context, conn *websocket.Conn) {
+	t := time.NewTicker(h.pingInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(h.pingInterval)); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// close sends the close frame with the passed code and reason. The reason is
+// truncated to fit in the control frame.
+func (h *handler[Req, Resp]) close(conn *websocket.Conn, code int, reason string) {
+	if len(reason) > maxCloseReason {
+		reason = reason[:maxCloseReason]
+	}
+	conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, reason), time.Now().Add(time.Second))
+}
+
+// maxCloseReason is the maximum length of the close reason, as the payload of
+// the control frames is limited to 125 bytes including the close code.
+const maxCloseReason = 123
+
+// CloseCodeOffset is added to the gRPC status codes that have no matching
+// close code, e.g. PermissionDenied is reported as 4007. The range 4000-4999
+// is reserved for private use by the WebSocket protocol.
+const CloseCodeOffset = 4000
+
+// CloseCode returns the WebSocket close code of the stream error. The
+// completed streams are closed normally with 1000, Internal, Unknown and
+// DataLoss errors with 1011, Unavailable with 1013 (try again later), and
+// the rest with CloseCodeOffset plus the gRPC status code.
+func CloseCode(err error) int {
+	if err == nil || errors.Is(err, io.EOF) {
+		return websocket.CloseNormalClosure
+	}
+	switch code := status.Code(err); code {
+	case codes.OK:
+		return websocket.CloseNormalClosure
+	case codes.Internal, codes.Unknown, codes.DataLoss:
+		return websocket.CloseInternalServerErr
+	case codes.Unavailable:
+		return websocket.CloseTryAgainLater
+	default:
+		return CloseCodeOffset + int(code)
+	}
+}
@@ -0,0 +1,137 @@
+package ws_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/clouway/go-genproto/clouwayapis/rpc/httpkit/ws"
+	"github.com/gorilla/websocket"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+// echoStream sends back the received messages in upper case and fails with
+// the passed error when the message is "fail".
+type echoStream struct {
+	ch  chan *wrapperspb.StringValue
+	err error
+}
+
+func (s *echoStream) Send(m *wrapperspb.StringValue) error {
+	s.ch <- m
+	return nil
+}
+
+func (s *echoStream) Recv() (*wrapperspb.StringValue, error) {
+	m, ok := <-s.ch
+	if !ok {
+		return nil, io.EOF
+	}
+	if m.Value == "fail" {
+		return nil, s.err
+	}
+	return wrapperspb.String(strings.ToUpper(m.Value)), nil
+}
+
+func (s *echoStream) CloseSend() error {
+	close(s.ch)
+	return nil
+}
+
+func newServer(err error) *httptest.Server {
+	return httptest.NewServer(ws.Handler(func(ctx context.Context, r *http.Request) (ws.BidiStream[*wrapperspb.StringValue, *wrapperspb.StringValue], error) {
+		if r.URL.Query().Get("deny") != "" {
+			return nil, status.Error(codes.PermissionDenied, "not allowed")
+		}
+		return &echoStream{ch: make(chan *wrapperspb.StringValue, 1), err: err}, nil
+	}))
+}
+
+func dial(t *testing.T, url string) *websocket.Conn {
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(url, "http"), nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return conn
+}
+
+func TestHandler(t *testing.T) {
+	srv := newServer(nil)
+	defer srv.Close()
+	conn := dial(t, srv.URL)
+	defer conn.Close()
+
+	conn.WriteMessage(websocket.TextMessage, []byte(`"hello"`))
+	_, b, err := conn.ReadMessage()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if got := string(b); got != `"HELLO"` {
+		t.Errorf("unexpected message:\n- want: %v\n-  got: %v", `"HELLO"`, got)
+	}
+}
+
+func TestHandlerStatusCloseCode(t *testing.T) {
+	srv := newServer(status.Error(codes.PermissionDenied, "not allowed"))
+	defer srv.Close()
+	conn := dial(t, srv.URL)
+	defer conn.Close()
+
+	conn.WriteMessage(websocket.TextMessage, []byte(`"fail"`))
+	_, _, err := conn.ReadMessage()
+
+	if !websocket.IsCloseError(err, ws.CloseCodeOffset+int(codes.PermissionDenied)) {
+		t.Errorf("unexpected close error: %v", err)
+	}
+}
+
+func TestHandlerInvalidMessage(t *testing.T) {
+	srv := newServer(nil)
+	defer srv.Close()
+	conn := dial(t, srv.URL)
+	defer conn.Close()
+
+	conn.WriteMessage(websocket.TextMessage, []byte(`{`))
+	_, _, err := conn.ReadMessage()
+
+	if !websocket.IsCloseError(err, websocket.CloseInvalidFramePayloadData) {
+		t.Errorf("unexpected close error: %v", err)
+	}
+}
+
+func TestHandlerOpenError(t *testing.T) {
+	srv := newServer(nil)
+	defer srv.Close()
+
+	_, resp, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http")+"?deny=1", nil)
+	if err == nil {
+		t.Fatal("expected the upgrade to fail")
+	}
+
+	if resp.StatusCode != http.StatusForbidden {
+		t.Errorf("unexpected status code:\n- want: %v\n-  got: %v", http.StatusForbidden, resp.StatusCode)
+	}
+}
+
+func TestCloseCode(t *testing.T) {
+	tests := []struct {
+		err  error
+		want int
+	}{
+		{err: io.EOF, want: websocket.CloseNormalClosure},
+		{err: status.Error(codes.Internal, "boom"), want: websocket.CloseInternalServerErr},
+		{err: status.Error(codes.Unavailable, "down"), want: websocket.CloseTryAgainLater},
+		{err: status.Error(codes.Unauthenticated, "who"), want: 4016},
+	}
+	for _, test := range tests {
+		if got := ws.CloseCode(test.err); got != test.want {
+			t.Errorf("unexpected close code of %v:\n- want: %v\n-  got: %v", test.err, test.want, got)
+		}
+	}
+}
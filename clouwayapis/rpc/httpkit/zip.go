@@ -0,0 +1,147 @@
+package httpkit
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/clouway/go-genproto/clouwayapis/rpc/fileserve"
+	httptransport "github.com/go-kit/kit/transport/http"
+)
+
+// ZipContentType is the content type of zip archives.
+const ZipContentType = "application/zip"
+
+// ZipEntry is a file of a zip archive.
+type ZipEntry struct {
+	// Name is the path of the file in the archive.
+	Name string
+	// Modified is the modification time of the file. The time of the
+	// download is used when it's zero.
+	Modified time.Time
+	// Content is read until EOF and closed when it's an io.Closer.
+	Content io.Reader
+}
+
+// ZipEntries iterates the files of a zip archive. Next returns io.EOF after
+// the last file.
+type ZipEntries interface {
+	Next(ctx context.Context) (*ZipEntry, error)
+}
+
+// ZipEntriesFunc is an adapter to use functions as ZipEntries.
+type ZipEntriesFunc func(ctx context.Context) (*ZipEntry, error)
+
+// Next calls f(ctx).
+func (f ZipEntriesFunc) Next(ctx context.Context) (*ZipEntry, error) {
+	return f(ctx)
+}
+
+// ZipFiles returns the entries of the binary files.
+func ZipFiles(files ...*fileserve.BinaryFile) ZipEntries {
+	return ZipEntriesFunc(func(context.Context) (*ZipEntry, error) {
+		if len(files) == 0 {
+			return nil, io.EOF
+		}
+		f := files[0]
+		files = files[1:]
+		return &ZipEntry{Name: f.FileName, Content: bytes.NewReader(f.Content)}, nil
+	})
+}
+
+// NewZipEncoder returns an EncodeResponseFunc that streams the ZipEntries
+// responses as a zip archive download with the name, e.g. for the "download
+// all attachments" features. The files are compressed and written as they
+// are iterated, so the archive is never buffered in memory, and the
+// duplicate names get a numbered suffix. The names are kept inside the
+// archive, i.e. the leading slashes and the .. segments are dropped, and
+// the names with drive letters are rejected. All other responses are
+// encoded by EncodeProtoJSONResponse.
+//
+// The status of the response is sent before the first file, so the errors
+// of the iteration can't be reported to the client. They are returned to
+// the go-kit server for logging and the archive is left incomplete, which
+// the clients detect as a corrupted download.
+func NewZipEncoder(name string) httptransport.EncodeResponseFunc {
+	return func(ctx context.Context, w http.ResponseWriter, response interface{}) error {
+		entries, ok := response.(ZipEntries)
+		if !ok {
+			return EncodeProtoJSONResponse(ctx, w, response)
+		}
+		w.Header().Set("Content-Type", ZipContentType)
+		w.Header().Set("Content-Disposition", ContentDisposition("attachment", name))
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		w.WriteHeader(http.StatusOK)
+
+		zw := zip.NewWriter(w)
+		names := map[string]bool{}
+		for {
+			entry, err := entries.Next(ctx)
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return err
+			}
+			name, err := uniqueName(names, entry.Name)
+			if err != nil {
+				return err
+			}
+			if err := writeZipEntry(zw, entry, name); err != nil {
+				return err
+			}
+			zw.Flush()
+			if f, ok := w.(http.Flusher); ok {
+				f.Flush()
+			}
+		}
+		return zw.Close()
+	}
+}
+
+// writeZipEntry writes the entry to the archive with the name.
+func writeZipEntry(zw *zip.Writer, entry *ZipEntry, name string) error {
+	if c, ok := entry.Content.(io.Closer); ok {
+		defer c.Close()
+	}
+	modified := entry.Modified
+	if modified.IsZero() {
+		modified = time.Now()
+	}
+	fw, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: modified})
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(fw, entry.Content)
+	return err
+}
+
+// uniqueName returns the sanitized name with a numbered suffix when it's
+// already used in the archive.
+func uniqueName(names map[string]bool, name string) (string, error) {
+	// The backslashes are separators for the extractors on Windows.
+	name = strings.ReplaceAll(name, "\\", "/")
+	if len(name) >= 2 && name[1] == ':' {
+		return "", fmt.Errorf("httpkit: zip entry name %q has a drive letter", name)
+	}
+	// Cleaning the name as an absolute path drops the .. segments that
+	// would escape the archive, and then the leading slash is dropped too.
+	name = strings.TrimPrefix(path.Clean("/"+name), "/")
+	if name == "" {
+		name = "file"
+	}
+	unique := name
+	ext := path.Ext(name)
+	for i := 2; names[unique]; i++ {
+		unique = strings.TrimSuffix(name, ext) + " (" + strconv.Itoa(i) + ")" + ext
+	}
+	names[unique] = true
+	return unique, nil
+}
@@ -0,0 +1,81 @@
+package httpkit_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"io"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/clouway/go-genproto/clouwayapis/rpc/fileserve"
+	"github.com/clouway/go-genproto/clouwayapis/rpc/httpkit"
+)
+
+func TestZipEncoder(t *testing.T) {
+	files := httpkit.ZipFiles(
+		&fileserve.BinaryFile{FileName: "invoice.pdf", Content: []byte("::invoice::")},
+		&fileserve.BinaryFile{FileName: "invoice.pdf", Content: []byte("::second invoice::")},
+		&fileserve.BinaryFile{FileName: "readings.csv", Content: []byte("1,2,3")},
+	)
+	w := httptest.NewRecorder()
+	if err := httpkit.NewZipEncoder("attachments.zip")(context.Background(), w, files); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if got := w.Header().Get("Content-Type"); got != httpkit.ZipContentType {
+		t.Errorf("unexpected Content-Type header:\n- want: %v\n-  got: %v", httpkit.ZipContentType, got)
+	}
+	if want, got := `attachment; filename="attachments.zip"`, w.Header().Get("Content-Disposition"); want != got {
+		t.Errorf("unexpected Content-Disposition header:\n- want: %v\n-  got: %v", want, got)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(w.Body.Bytes()), int64(w.Body.Len()))
+	if err != nil {
+		t.Fatalf("unexpected error while reading archive: %v", err)
+	}
+	want := map[string]string{"invoice.pdf": "::invoice::", "invoice (2).pdf": "::second invoice::", "readings.csv": "1,2,3"}
+	if len(zr.File) != len(want) {
+		t.Fatalf("unexpected files: %v", zr.File)
+	}
+	for _, f := range zr.File {
+		rc, _ := f.Open()
+		content, _ := io.ReadAll(rc)
+		rc.Close()
+		if string(content) != want[f.Name] {
+			t.Errorf("unexpected content of %s:\n- want: %v\n-  got: %v", f.Name, want[f.Name], string(content))
+		}
+	}
+}
+
+func TestZipEncoderEntryNames(t *testing.T) {
+	files := httpkit.ZipFiles(
+		&fileserve.BinaryFile{FileName: "../../etc/passwd"},
+		&fileserve.BinaryFile{FileName: "/reports/../../readings.csv"},
+		&fileserve.BinaryFile{FileName: `..\invoices\invoice.pdf`},
+		&fileserve.BinaryFile{FileName: ".."},
+	)
+	w := httptest.NewRecorder()
+	if err := httpkit.NewZipEncoder("attachments.zip")(context.Background(), w, files); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(w.Body.Bytes()), int64(w.Body.Len()))
+	if err != nil {
+		t.Fatalf("unexpected error while reading archive: %v", err)
+	}
+	var got []string
+	for _, f := range zr.File {
+		got = append(got, f.Name)
+	}
+	if want := []string{"etc/passwd", "readings.csv", "invoices/invoice.pdf", "file"}; !reflect.DeepEqual(want, got) {
+		t.Errorf("unexpected names:\n- want: %v\n-  got: %v", want, got)
+	}
+}
+
+func TestZipEncoderRejectsDrivePaths(t *testing.T) {
+	files := httpkit.ZipFiles(&fileserve.BinaryFile{FileName: `C:\Windows\win.ini`})
+	if err := httpkit.NewZipEncoder("attachments.zip")(context.Background(), httptest.NewRecorder(), files); err == nil {
+		t.Error("expected an error for the drive path")
+	}
+}
@@ -0,0 +1,210 @@
+// Package paging provides iterators over the List RPCs with page tokens, so
+// the Go consumers don't have to write the pagination loops by hand:
+//
+//	it := paging.New(ctx, func(ctx context.Context, token string) (paging.Page[*orderspb.Order], error) {
+//		resp, err := client.ListOrders(ctx, &orderspb.ListOrdersRequest{PageToken: token})
+//		if err != nil {
+//			return paging.Page[*orderspb.Order]{}, err
+//		}
+//		return paging.Page[*orderspb.Order]{Items: resp.Orders, NextPageToken: resp.NextPageToken, TotalSize: resp.TotalSize}, nil
+//	})
+//	defer it.Stop()
+//	for {
+//		order, err := it.Next()
+//		if err == paging.Done {
+//			break
+//		}
+//		if err != nil {
+//			return err
+//		}
+//		...
+//	}
+package paging
+
+import (
+	"context"
+	"errors"
+)
+
+// Done is returned by Next when the iteration is complete.
+var Done = errors.New("no more items in iterator")
+
+// Page is a single page of the results of a List RPC.
+type Page[T any] struct {
+	// Items are the items of the page.
+	Items []T
+
+	// NextPageToken is the token of the next page. It's empty for the last
+	// page.
+	NextPageToken string
+
+	// TotalSize is the total number of items, when it's returned by the RPC.
+	TotalSize int64
+}
+
+// FetchFunc fetches the page with the passed token. The token of the first
+// page is empty.
+type FetchFunc[T any] func(ctx context.Context, pageToken string) (Page[T], error)
+
+// Option sets an optional parameter for the Iterator.
+type Option func(*options)
+
+// Prefetch sets the number of pages that are fetched in the background ahead
+// of the consumer. The pages are fetched on demand by default.
+func Prefetch(pages int) Option {
+	return func(o *options) { o.prefetch = pages }
+}
+
+// PageToken sets the token of the page to start from, e.g. to resume an
+// interrupted iteration.
+func PageToken(token string) Option {
+	return func(o *options) { o.pageToken = token }
+}
+
+type options struct {
+	prefetch  int
+	pageToken string
+}
+
+// Iterator iterates over the items of all pages. The pages are fetched
+// lazily, so nothing is fetched before the first call of Next. An Iterator
+// is not safe for concurrent use.
+type Iterator[T any] struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	fetch  FetchFunc[T]
+	opts   options
+
+	items     []T
+	token     string
+	last      bool
+	started   bool
+	err       error
+	totalSize int64
+	hasTotal  bool
+	pages     chan pageResult[T]
+}
+
+type pageResult[T any] struct {
+	page Page[T]
+	err  error
+}
+
+// New creates an iterator over the pages that are returned by fetch. The
+// fetching stops when the context is done or Stop is called.
+func New[T any](ctx context.Context, fetch FetchFunc[T], opts ...Option) *Iterator[T] {
+	o := options{}
+	for _, option := range opts {
+		option(&o)
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	return &Iterator[T]{ctx: ctx, cancel: cancel, fetch: fetch, opts: o, token: o.pageToken}
+}
+
+// Next returns the next item. It returns Done when there are no more items,
+// or the error of the fetch or the context otherwise. Once an error is
+// returned, every following call returns the same error.
+func (it *Iterator[T]) Next() (T, error) {
+	var zero T
+	for len(it.items) == 0 {
+		if it.err != nil {
+			return zero, it.err
+		}
+		if it.last {
+			it.err = Done
+			it.cancel()
+			return zero, Done
+		}
+		page, err := it.nextPage()
+		if err != nil {
+			it.err = err
+			it.cancel()
+			return zero, err
+		}
+		it.items = page.Items
+		it.token = page.NextPageToken
+		it.last = page.NextPageToken == ""
+		if page.TotalSize > 0 || !it.hasTotal {
+			it.totalSize, it.hasTotal = page.TotalSize, true
+		}
+	}
+	item := it.items[0]
+	it.items[0] = zero
+	it.items = it.items[1:]
+	return item, nil
+}
+
+func (it *Iterator[T]) nextPage() (Page[T], error) {
+	if err := it.ctx.Err(); err != nil {
+		return Page[T]{}, err
+	}
+	if it.opts.prefetch <= 0 {
+		return it.fetch(it.ctx, it.token)
+	}
+	if !it.started {
+		it.started = true
+		it.pages = make(chan pageResult[T], it.opts.prefetch)
+		go it.prefetch(it.token)
+	}
+	select {
+	case r, ok := <-it.pages:
+		if !ok {
+			return Page[T]{}, it.ctx.Err()
+		}
+		return r.page, r.err
+	case <-it.ctx.Done():
+		return Page[T]{}, it.ctx.Err()
+	}
+}
+
+// prefetch fetches the pages into the buffered channel until the last page
+// or the first error.
+func (it *Iterator[T]) prefetch(token string) {
+	defer close(it.pages)
+	for {
+		page, err := it.fetch(it.ctx, token)
+		select {
+		case it.pages <- pageResult[T]{page: page, err: err}:
+		case <-it.ctx.Done():
+			return
+		}
+		if err != nil || page.NextPageToken == "" {
+			return
+		}
+		token = page.NextPageToken
+	}
+}
+
+// PageToken returns the token of the page after the items that are already
+// fetched. It can be passed to PageToken to resume the iteration, as long as
+// the remaining items of the current page are consumed.
+func (it *Iterator[T]) PageToken() string {
+	return it.token
+}
+
+// TotalSize returns the total number of items as reported by the RPC. The
+// second value is false until the first page is fetched.
+func (it *Iterator[T]) TotalSize() (int64, bool) {
+	return it.totalSize, it.hasTotal
+}
+
+// Stop stops the fetching of the pages in the background. The following
+// calls of Next return the error of the cancelled context.
+func (it *Iterator[T]) Stop() {
+	it.cancel()
+}
+
+// All fetches all items of the iterator.
+func All[T any](it *Iterator[T]) ([]T, error) {
+	var all []T
+	for {
+		item, err := it.Next()
+		if err == Done {
+			return all, nil
+		}
+		if err != nil {
+			return all, err
+		}
+		all = append(all, item)
+	}
+}
@@ -0,0 +1,91 @@
+package paging_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strconv"
+	"sync/atomic"
+	"testing"
+
+	"github.com/clouway/go-genproto/clouwayapis/rpc/paging"
+)
+
+// pages serves the items in pages of two.
+func pages(items []string, fetches *int32) paging.FetchFunc[string] {
+	return func(_ context.Context, token string) (paging.Page[string], error) {
+		atomic.AddInt32(fetches, 1)
+		start, _ := strconv.Atoi(token)
+		end := start + 2
+		if end >= len(items) {
+			return paging.Page[string]{Items: items[start:], TotalSize: int64(len(items))}, nil
+		}
+		return paging.Page[string]{Items: items[start:end], NextPageToken: strconv.Itoa(end), TotalSize: int64(len(items))}, nil
+	}
+}
+
+func TestIterator(t *testing.T) {
+	for _, prefetch := range []int{0, 2} {
+		var fetches int32
+		it := paging.New(context.Background(), pages([]string{"a", "b", "c", "d", "e"}, &fetches), paging.Prefetch(prefetch))
+
+		if _, ok := it.TotalSize(); ok {
+			t.Error("expected no total size before the first page")
+		}
+		got, err := paging.All(it)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if want := []string{"a", "b", "c", "d", "e"}; !reflect.DeepEqual(got, want) {
+			t.Errorf("unexpected items with prefetch %d:\n- want: %v\n-  got: %v", prefetch, want, got)
+		}
+		if total, _ := it.TotalSize(); total != 5 {
+			t.Errorf("unexpected total size:\n- want: %v\n-  got: %v", 5, total)
+		}
+		if fetches != 3 {
+			t.Errorf("unexpected fetches:\n- want: %v\n-  got: %v", 3, fetches)
+		}
+	}
+}
+
+func TestIteratorIsLazy(t *testing.T) {
+	var fetches int32
+	it := paging.New(context.Background(), pages([]string{"a", "b", "c"}, &fetches))
+
+	if fetches != 0 {
+		t.Errorf("unexpected fetches before Next:\n- want: %v\n-  got: %v", 0, fetches)
+	}
+	it.Next()
+	it.Next()
+	if fetches != 1 {
+		t.Errorf("unexpected fetches:\n- want: %v\n-  got: %v", 1, fetches)
+	}
+	if got := it.PageToken(); got != "2" {
+		t.Errorf("unexpected page token:\n- want: %v\n-  got: %v", "2", got)
+	}
+}
+
+func TestIteratorError(t *testing.T) {
+	fail := errors.New("unavailable")
+	it := paging.New(context.Background(), func(context.Context, string) (paging.Page[int], error) {
+		return paging.Page[int]{}, fail
+	}, paging.Prefetch(1))
+
+	if _, err := it.Next(); err != fail {
+		t.Errorf("unexpected error:\n- want: %v\n-  got: %v", fail, err)
+	}
+	if _, err := it.Next(); err != fail {
+		t.Errorf("unexpected error of the next call:\n- want: %v\n-  got: %v", fail, err)
+	}
+}
+
+func TestIteratorStop(t *testing.T) {
+	var fetches int32
+	it := paging.New(context.Background(), pages([]string{"a", "b", "c"}, &fetches))
+	it.Stop()
+
+	if _, err := it.Next(); err != context.Canceled {
+		t.Errorf("unexpected error:\n- want: %v\n-  got: %v", context.Canceled, err)
+	}
+}
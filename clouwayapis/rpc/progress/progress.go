@@ -0,0 +1,280 @@
+// Package progress tracks the progress of long-running operations, such as
+// the bulk jobs, and streams it to the clients, so the UIs get live updates
+// without polling the operation.
+//
+// The updates are google.protobuf.Struct messages in the form:
+//
+//	{
+//	  "name": "operations/123",
+//	  "percent": 42,
+//	  "step": "importing customers",
+//	  "errors": [{"code": 3, "message": "row 17: invalid email"}],
+//	  "done": false
+//	}
+//
+// The last update of the stream is done and carries either the "response"
+// of the operation or its "error".
+package progress
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/clouway/go-genproto/clouwayapis/rpc/httpkit/sse"
+	httptransport "github.com/go-kit/kit/transport/http"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// Event names of the updates that are used by the SSE streams.
+const (
+	EventProgress  = "progress"
+	EventCompleted = "completed"
+	EventFailed    = "failed"
+)
+
+// Option sets an optional parameter for the Tracker.
+type Option func(*Tracker)
+
+// Throttle sets the minimum interval between the updates that are sent to
+// the watchers. The intermediate updates are coalesced, while the terminal
+// update is always sent immediately. The interval is 500ms by default.
+func Throttle(d time.Duration) Option {
+	return func(t *Tracker) { t.throttle = d }
+}
+
+// Tracker tracks the progress of a single operation. It is safe for
+// concurrent use by the job that reports the progress and the watchers.
+type Tracker struct {
+	name     string
+	throttle time.Duration
+
+	mu       sync.Mutex
+	percent  float64
+	step     string
+	errors   []*status.Status
+	done     bool
+	err      error
+	response proto.Message
+	changed  chan struct{}
+	finished chan struct{}
+}
+
+// NewTracker creates a tracker of the operation with the passed name.
+func NewTracker(name string, options ...Option) *Tracker {
+	t := &Tracker{
+		name:     name,
+		throttle: 500 * time.Millisecond,
+		changed:  make(chan struct{}),
+		finished: make(chan struct{}),
+	}
+	for _, option := range options {
+		option(t)
+	}
+	return t
+}
+
+// Update reports the percentage of the completed work and the current step.
+// The percentage is clamped to [0, 100].
+func (t *Tracker) Update(percent float64, step string) {
+	if percent < 0 {
+		percent = 0
+	}
+	if percent > 100 {
+		percent = 100
+	}
+	t.change(func() {
+		t.percent, t.step = percent, step
+	})
+}
+
+// Error reports a partial error that does not stop the operation, e.g. an
+// invalid row of an import.
+func (t *Tracker) Error(err error) {
+	t.change(func() {
+		t.errors = append(t.errors, status.Convert(err))
+	})
+}
+
+// Complete completes the operation with the passed response, which may be
+// nil.
+func (t *Tracker) Complete(response proto.Message) {
+	t.change(func() {
+		t.percent, t.done, t.response = 100, true, response
+	})
+}
+
+// Fail completes the operation with the passed error.
+func (t *Tracker) Fail(err error) {
+	t.change(func() {
+		t.done, t.err = true, err
+	})
+}
+
+// change applies the change and notifies the watchers. The changes after
+// the completion of the operation are ignored.
+func (t *Tracker) change(f func()) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.done {
+		return
+	}
+	f()
+	close(t.changed)
+	t.changed = make(chan struct{})
+	if t.done {
+		close(t.finished)
+	}
+}
+
+// Snapshot returns the current state of the operation.
+func (t *Tracker) Snapshot() *structpb.Struct {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.snapshot()
+}
+
+func (t *Tracker) snapshot() *structpb.Struct {
+	fields := map[string]*structpb.Value{
+		"name":    structpb.NewStringValue(t.name),
+		"percent": structpb.NewNumberValue(t.percent),
+		"done":    structpb.NewBoolValue(t.done),
+	}
+	if t.step != "" {
+		fields["step"] = structpb.NewStringValue(t.step)
+	}
+	if len(t.errors) > 0 {
+		errors := make([]*structpb.Value, len(t.errors))
+		for i, st := range t.errors {
+			errors[i] = statusValue(st)
+		}
+		fields["errors"] = structpb.NewListValue(&structpb.ListValue{Values: errors})
+	}
+	if t.err != nil {
+		fields["error"] = statusValue(status.Convert(t.err))
+	}
+	if t.response != nil {
+		if v, err := messageValue(t.response); err == nil {
+			fields["response"] = v
+		}
+	}
+	return &structpb.Struct{Fields: fields}
+}
+
+func statusValue(st *status.Status) *structpb.Value {
+	return structpb.NewStructValue(&structpb.Struct{Fields: map[string]*structpb.Value{
+		"code":    structpb.NewNumberValue(float64(st.Code())),
+		"message": structpb.NewStringValue(st.Message()),
+	}})
+}
+
+// messageValue converts the message to the value of its JSON mapping.
+func messageValue(m proto.Message) (*structpb.Value, error) {
+	b, err := protojson.Marshal(m)
+	if err != nil {
+		return nil, err
+	}
+	var v interface{}
+	if err := json.Unmarshal(b, &v); err != nil {
+		return nil, err
+	}
+	return structpb.NewValue(v)
+}
+
+// Watch returns a channel of the updates of the operation. The current
+// state is sent first, followed by the throttled updates, and the channel is
+// closed after the terminal update or when the context is done.
+func (t *Tracker) Watch(ctx context.Context) <-chan proto.Message {
+	ch := make(chan proto.Message)
+	go func() {
+		defer close(ch)
+		for {
+			t.mu.Lock()
+			update, done, changed := t.snapshot(), t.done, t.changed
+			t.mu.Unlock()
+
+			select {
+			case ch <- update:
+			case <-ctx.Done():
+				return
+			}
+			if done {
+				return
+			}
+			sent := time.Now()
+
+			select {
+			case <-changed:
+			case <-ctx.Done():
+				return
+			}
+			if wait := t.throttle - time.Since(sent); wait > 0 {
+				timer := time.NewTimer(wait)
+				select {
+				case <-timer.C:
+				case <-t.finished:
+				case <-ctx.Done():
+					timer.Stop()
+					return
+				}
+				timer.Stop()
+			}
+		}
+	}()
+	return ch
+}
+
+// Stream sends the updates of the operation to a gRPC server stream until
+// the operation completes, e.g. from the handler of a WatchOperation method:
+//
+//	return tracker.Stream(stream.Context(), stream.Send)
+//
+// The error of the failed operation is returned after its terminal update,
+// so the stream ends with the status of the operation.
+func (t *Tracker) Stream(ctx context.Context, send func(*structpb.Struct) error) error {
+	for m := range t.Watch(ctx) {
+		if err := send(m.(*structpb.Struct)); err != nil {
+			return err
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return status.FromContextError(err).Err()
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.err
+}
+
+// EventName returns the name of the SSE event of the update: EventCompleted
+// or EventFailed for the terminal updates and EventProgress for the rest. It
+// is meant to be used with the sse.EventName option:
+//
+//	sse.NewEncoder(sse.EventName(progress.EventName))
+func EventName(m proto.Message) string {
+	s, ok := m.(*structpb.Struct)
+	if !ok || !s.Fields["done"].GetBoolValue() {
+		return EventProgress
+	}
+	if _, failed := s.Fields["error"]; failed {
+		return EventFailed
+	}
+	return EventCompleted
+}
+
+// NewSSEEncoder returns an EncodeResponseFunc that streams the updates of the
+// *Tracker responses as Server-Sent Events named by EventName. The other
+// responses are encoded by the sse encoder with the passed options.
+func NewSSEEncoder(options ...sse.Option) httptransport.EncodeResponseFunc {
+	encode := sse.NewEncoder(append([]sse.Option{sse.EventName(EventName)}, options...)...)
+	return func(ctx context.Context, w http.ResponseWriter, response interface{}) error {
+		if t, ok := response.(*Tracker); ok {
+			response = t.Watch(ctx)
+		}
+		return encode(ctx, w, response)
+	}
+}
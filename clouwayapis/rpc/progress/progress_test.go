@@ -0,0 +1,72 @@
+package progress_test
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/clouway/go-genproto/clouwayapis/rpc/progress"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+func TestStream(t *testing.T) {
+	tracker := progress.NewTracker("operations/1", progress.Throttle(time.Hour))
+	updates := make(chan *structpb.Struct, 10)
+	errs := make(chan error)
+	go func() {
+		errs <- tracker.Stream(context.Background(), func(s *structpb.Struct) error {
+			updates <- s
+			return nil
+		})
+	}()
+
+	first := <-updates
+	tracker.Update(10, "reading")
+	tracker.Update(50, "importing")
+	tracker.Error(status.Error(codes.InvalidArgument, "row 17: invalid email"))
+	tracker.Fail(status.Error(codes.Aborted, "import aborted"))
+
+	if err := <-errs; status.Code(err) != codes.Aborted {
+		t.Errorf("unexpected error:\n- want: %v\n-  got: %v", codes.Aborted, err)
+	}
+	if got := first.Fields["percent"].GetNumberValue(); got != 0 {
+		t.Errorf("unexpected initial percent:\n- want: %v\n-  got: %v", 0, got)
+	}
+	// The updates in the throttle interval are coalesced into the terminal one.
+	last := <-updates
+	if len(updates) != 0 {
+		t.Errorf("unexpected number of updates:\n- want: %v\n-  got: %v", 2, len(updates)+2)
+	}
+	if got := last.Fields["step"].GetStringValue(); got != "importing" {
+		t.Errorf("unexpected step:\n- want: %v\n-  got: %v", "importing", got)
+	}
+	if got := len(last.Fields["errors"].GetListValue().GetValues()); got != 1 {
+		t.Errorf("unexpected partial errors:\n- want: %v\n-  got: %v", 1, got)
+	}
+	if got := progress.EventName(last); got != progress.EventFailed {
+		t.Errorf("unexpected event name:\n- want: %v\n-  got: %v", progress.EventFailed, got)
+	}
+}
+
+func TestSSEEncoder(t *testing.T) {
+	tracker := progress.NewTracker("operations/1", progress.Throttle(0))
+	tracker.Complete(wrapperspb.String("imported"))
+
+	rec := httptest.NewRecorder()
+	if err := progress.NewSSEEncoder()(context.Background(), rec, tracker); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	body := rec.Body.String()
+	if !strings.HasPrefix(body, "event: completed\ndata: ") {
+		t.Errorf("unexpected body: %q", body)
+	}
+	if !strings.Contains(body, `"response":"imported"`) && !strings.Contains(body, `"response": "imported"`) {
+		t.Errorf("expected response in body: %q", body)
+	}
+}
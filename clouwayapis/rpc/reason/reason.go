@@ -0,0 +1,134 @@
+// Package reason provides a catalog of stable machine-readable error reasons.
+// The services register their reasons once, e.g:
+//
+//	var PaymentDeclined = reason.Register("PAYMENT_DECLINED", codes.FailedPrecondition, "payment was declined")
+//
+// and return them as status errors carrying ErrorInfo with the reason, so
+// that the clients can match the reason instead of parsing the messages:
+//
+//	if reason.Is(err, PaymentDeclined) { ... }
+package reason
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/clouway/go-genproto/clouwayapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Reason is a registered error reason.
+type Reason struct {
+	// Name is the UPPER_SNAKE_CASE name of the reason which is sent as
+	// ErrorInfo reason.
+	Name string
+
+	// Code is the status code of the errors with this reason.
+	Code codes.Code
+
+	// Message is the default message of the errors with this reason.
+	Message string
+}
+
+var (
+	mu      sync.RWMutex
+	reasons = make(map[string]Reason)
+)
+
+// Register registers the reason with its code and default message. It
+// panics when a reason with the same name is already registered, as the
+// reasons are part of the public contract of the services and should be
+// unique.
+func Register(name string, code codes.Code, message string) Reason {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if _, ok := reasons[name]; ok {
+		panic(fmt.Sprintf("reason: %s is already registered", name))
+	}
+	r := Reason{Name: name, Code: code, Message: message}
+	reasons[name] = r
+	return r
+}
+
+// Lookup returns the registered reason with the passed name.
+func Lookup(name string) (Reason, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	r, ok := reasons[name]
+	return r, ok
+}
+
+// All returns all registered reasons sorted by name.
+func All() []Reason {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	all := make([]Reason, 0, len(reasons))
+	for _, r := range reasons {
+		all = append(all, r)
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].Name < all[j].Name })
+	return all
+}
+
+// Err returns a status error with the default message of the reason.
+func (r Reason) Err() error {
+	return r.err(r.Message, nil)
+}
+
+// Errorf returns a status error with message formatted according to the
+// format specifier.
+func (r Reason) Errorf(format string, a ...interface{}) error {
+	return r.err(fmt.Sprintf(format, a...), nil)
+}
+
+// WithMetadata returns a status error with the default message of the
+// reason and the passed metadata attached to the ErrorInfo.
+func (r Reason) WithMetadata(metadata map[string]string) error {
+	return r.err(r.Message, metadata)
+}
+
+func (r Reason) err(message string, metadata map[string]string) error {
+	st := status.New(r.Code, message)
+	st, _ = st.WithDetails(&errdetails.ErrorInfo{Reason: r.Name, Metadata: metadata})
+	return st.Err()
+}
+
+// Of returns the reason name of the error or empty string when the error
+// does not carry ErrorInfo.
+func Of(err error) string {
+	if info := errorInfo(err); info != nil {
+		return info.Reason
+	}
+	return ""
+}
+
+// Is reports whether the error carries the reason.
+func Is(err error, r Reason) bool {
+	return err != nil && Of(err) == r.Name
+}
+
+// Metadata returns the metadata of the ErrorInfo of the error.
+func Metadata(err error) map[string]string {
+	if info := errorInfo(err); info != nil {
+		return info.Metadata
+	}
+	return nil
+}
+
+func errorInfo(err error) *errdetails.ErrorInfo {
+	st, ok := status.FromError(err)
+	if !ok {
+		return nil
+	}
+	for _, detail := range st.Details() {
+		if info, ok := detail.(*errdetails.ErrorInfo); ok {
+			return info
+		}
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+package reason_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/clouway/go-genproto/clouwayapis/rpc/reason"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var paymentDeclined = reason.Register("PAYMENT_DECLINED", codes.FailedPrecondition, "payment was declined")
+
+func TestReasonErr(t *testing.T) {
+	err := paymentDeclined.WithMetadata(map[string]string{"provider": "epay"})
+
+	st, _ := status.FromError(err)
+	if st.Code() != codes.FailedPrecondition {
+		t.Errorf("unexpected code:\n- want: %v\n-  got: %v", codes.FailedPrecondition, st.Code())
+	}
+	if want, got := "payment was declined", st.Message(); want != got {
+		t.Errorf("unexpected message:\n- want: %v\n-  got: %v", want, got)
+	}
+	if !reason.Is(err, paymentDeclined) {
+		t.Errorf("expected error to carry reason %s", paymentDeclined.Name)
+	}
+	if want, got := "epay", reason.Metadata(err)["provider"]; want != got {
+		t.Errorf("unexpected metadata:\n- want: %v\n-  got: %v", want, got)
+	}
+}
+
+func TestIsWithOtherErrors(t *testing.T) {
+	if reason.Is(errors.New("payment was declined"), paymentDeclined) {
+		t.Errorf("expected plain error not to carry reason")
+	}
+	if reason.Is(status.Error(codes.FailedPrecondition, "payment was declined"), paymentDeclined) {
+		t.Errorf("expected status error without ErrorInfo not to carry reason")
+	}
+}
+
+func TestLookup(t *testing.T) {
+	got, ok := reason.Lookup("PAYMENT_DECLINED")
+	if !ok || got != paymentDeclined {
+		t.Errorf("unexpected reason:\n- want: %v\n-  got: %v", paymentDeclined, got)
+	}
+}
+
+func TestRegisterDuplicate(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Errorf("expected panic on duplicate registration")
+		}
+	}()
+	reason.Register("PAYMENT_DECLINED", codes.Internal, "")
+}
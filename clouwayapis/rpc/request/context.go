@@ -1,5 +1,7 @@
 package request
 
+import "context"
+
 // ContextKey is an type to act as a Key of context values.
 // Here is an example usage:
 // var  contextAuthKey       = request.ContextKey("authorization")
@@ -9,3 +11,59 @@ type ContextKey string
 func (c ContextKey) String() string {
 	return "request " + string(c)
 }
+
+// RequestIDKey is the key of the request id in the context. It's the same
+// key under which the X-Request-Id header and the x-request-id metadata are
+// stored by httpkit.HeadersToContext and grpckit.MetadataToContext.
+const RequestIDKey = ContextKey("x-request-id")
+
+// WithRequestID returns a copy of the context with the request id.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, RequestIDKey, id)
+}
+
+// RequestIDFromContext returns the request id of the context or an empty
+// string when there is none. As the key is shared with the headers and the
+// metadata of the clients, the invalid ids are treated as missing, see
+// ValidRequestID.
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(RequestIDKey).(string)
+	if !ValidRequestID(id) {
+		return ""
+	}
+	return id
+}
+
+// maxRequestIDLength limits the length of the request ids that are accepted
+// from the clients.
+const maxRequestIDLength = 128
+
+// ValidRequestID reports whether the id is safe to be logged and echoed
+// back, i.e. it's printable ASCII of limited length.
+func ValidRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < 0x21 || id[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+type subjectKey struct{}
+
+// WithSubject returns a copy of the context with the subject of the
+// authenticated caller, e.g. the sub claim of its token. The subject is kept
+// under a private key, so it can't be set from a request header.
+func WithSubject(ctx context.Context, subject string) context.Context {
+	return context.WithValue(ctx, subjectKey{}, subject)
+}
+
+// SubjectFromContext returns the subject of the authenticated caller or an
+// empty string when there is none.
+func SubjectFromContext(ctx context.Context) string {
+	subject, _ := ctx.Value(subjectKey{}).(string)
+	return subject
+}
@@ -0,0 +1,296 @@
+// Package watch mirrors the resources of a service locally, similarly to
+// the Kubernetes informers. The resources are listed once and then kept up
+// to date by a stream of changes that is resumed from the last resume token
+// when it breaks. The consumers receive the typed Added, Modified and
+// Removed events of the reconciled local cache.
+package watch
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+// EventType is the type of the change of a resource in the local cache.
+type EventType int
+
+const (
+	// Added is the type of the events of the new resources.
+	Added EventType = iota + 1
+	// Modified is the type of the events of the changed resources.
+	Modified
+	// Removed is the type of the events of the deleted resources.
+	Removed
+)
+
+func (t EventType) String() string {
+	switch t {
+	case Added:
+		return "ADDED"
+	case Modified:
+		return "MODIFIED"
+	case Removed:
+		return "REMOVED"
+	}
+	return "UNKNOWN"
+}
+
+// Event is a change of a resource in the local cache.
+type Event[T proto.Message] struct {
+	Type EventType
+
+	// Object is the current state of the resource, or its last known state
+	// for the Removed events.
+	Object T
+
+	// Previous is the state of the Modified resources before the change.
+	Previous T
+}
+
+// Change is a single change that is received from the watch stream of the
+// service.
+type Change[T proto.Message] struct {
+	// Object is the changed resource. Only the fields of its key need to be
+	// set for the deleted resources.
+	Object T
+
+	// Deleted is true when the resource was deleted.
+	Deleted bool
+
+	// ResumeToken is the token to resume the stream after this change.
+	ResumeToken string
+}
+
+// Stream is the stream of the changes. Recv returns io.EOF when the stream
+// is closed by the service.
+type Stream[T proto.Message] interface {
+	Recv() (Change[T], error)
+}
+
+// ListFunc lists all resources and returns the token to start watching the
+// changes after the listing.
+type ListFunc[T proto.Message] func(ctx context.Context) (items []T, resumeToken string, err error)
+
+// WatchFunc opens the stream of the changes after the resume token.
+type WatchFunc[T proto.Message] func(ctx context.Context, resumeToken string) (Stream[T], error)
+
+// Option sets an optional parameter for the Watcher.
+type Option func(*options)
+
+// Backoff sets the minimum and the maximum delay between the attempts to
+// reopen a broken stream. The delay starts at 100ms and doubles up to 30s by
+// default.
+func Backoff(min, max time.Duration) Option {
+	return func(o *options) { o.minBackoff, o.maxBackoff = min, max }
+}
+
+// ExpiredToken sets the function that tells whether the error of the stream
+// means that its resume token has expired, so the resources need to be
+// listed again. By default these are the OutOfRange status errors.
+func ExpiredToken(f func(error) bool) Option {
+	return func(o *options) { o.expired = f }
+}
+
+type options struct {
+	minBackoff, maxBackoff time.Duration
+	expired                func(error) bool
+}
+
+// Watcher keeps the local cache of the resources in sync with the service.
+type Watcher[T proto.Message] struct {
+	list  ListFunc[T]
+	watch WatchFunc[T]
+	key   func(T) string
+	opts  options
+
+	mu     sync.RWMutex
+	cache  map[string]T
+	synced chan struct{}
+}
+
+// New creates a watcher of the resources that are identified by the key
+// function, e.g. by their names.
+func New[T proto.Message](list ListFunc[T], watch WatchFunc[T], key func(T) string, opts ...Option) *Watcher[T] {
+	o := options{
+		minBackoff: 100 * time.Millisecond,
+		maxBackoff: 30 * time.Second,
+		expired:    func(err error) bool { return status.Code(err) == codes.OutOfRange },
+	}
+	for _, option := range opts {
+		option(&o)
+	}
+	return &Watcher[T]{
+		list:   list,
+		watch:  watch,
+		key:    key,
+		opts:   o,
+		cache:  make(map[string]T),
+		synced: make(chan struct{}),
+	}
+}
+
+// Run lists the resources and watches their changes until the context is
+// done. The events are sent to the returned channel, which is closed when
+// the watcher stops. The cache is updated before the event is sent, and the
+// errors of the service are retried with backoff.
+func (w *Watcher[T]) Run(ctx context.Context) <-chan Event[T] {
+	events := make(chan Event[T])
+	go func() {
+		defer close(events)
+		w.run(ctx, events)
+	}()
+	return events
+}
+
+func (w *Watcher[T]) run(ctx context.Context, events chan<- Event[T]) {
+	backoff := w.opts.minBackoff
+	token, listed := "", false
+	for ctx.Err() == nil {
+		if !listed {
+			items, t, err := w.list(ctx)
+			if err != nil {
+				if !sleep(ctx, &backoff, w.opts.maxBackoff) {
+					return
+				}
+				continue
+			}
+			if !w.replace(ctx, items, events) {
+				return
+			}
+			token, listed = t, true
+		}
+
+		stream, err := w.watch(ctx, token)
+		if err == nil {
+			backoff = w.opts.minBackoff
+			for {
+				var c Change[T]
+				if c, err = stream.Recv(); err != nil {
+					break
+				}
+				if !w.apply(ctx, c, events) {
+					return
+				}
+				if c.ResumeToken != "" {
+					token = c.ResumeToken
+				}
+			}
+		}
+		if err != nil && err != io.EOF && w.opts.expired(err) {
+			listed = false
+		}
+		if !sleep(ctx, &backoff, w.opts.maxBackoff) {
+			return
+		}
+	}
+}
+
+// replace reconciles the cache with the listed resources.
+func (w *Watcher[T]) replace(ctx context.Context, items []T, events chan<- Event[T]) bool {
+	listed := make(map[string]T, len(items))
+	for _, item := range items {
+		listed[w.key(item)] = item
+	}
+	w.mu.RLock()
+	var removed []T
+	for k, old := range w.cache {
+		if _, ok := listed[k]; !ok {
+			removed = append(removed, old)
+		}
+	}
+	w.mu.RUnlock()
+
+	for _, old := range removed {
+		if !w.apply(ctx, Change[T]{Object: old, Deleted: true}, events) {
+			return false
+		}
+	}
+	for _, item := range items {
+		if !w.apply(ctx, Change[T]{Object: item}, events) {
+			return false
+		}
+	}
+	select {
+	case <-w.synced:
+	default:
+		close(w.synced)
+	}
+	return true
+}
+
+// apply applies the change to the cache and sends its event, if any.
+func (w *Watcher[T]) apply(ctx context.Context, c Change[T], events chan<- Event[T]) bool {
+	k := w.key(c.Object)
+	w.mu.Lock()
+	old, exists := w.cache[k]
+	var e Event[T]
+	switch {
+	case c.Deleted && exists:
+		delete(w.cache, k)
+		e = Event[T]{Type: Removed, Object: old}
+	case c.Deleted:
+	case !exists:
+		w.cache[k] = c.Object
+		e = Event[T]{Type: Added, Object: c.Object}
+	case !proto.Equal(old, c.Object):
+		w.cache[k] = c.Object
+		e = Event[T]{Type: Modified, Object: c.Object, Previous: old}
+	}
+	w.mu.Unlock()
+
+	if e.Type == 0 {
+		return true
+	}
+	select {
+	case events <- e:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// Synced returns a channel that is closed after the first listing of the
+// resources is reconciled with the cache.
+func (w *Watcher[T]) Synced() <-chan struct{} {
+	return w.synced
+}
+
+// Get returns the cached resource with the passed key.
+func (w *Watcher[T]) Get(key string) (T, bool) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	item, ok := w.cache[key]
+	return item, ok
+}
+
+// List returns all cached resources in no particular order.
+func (w *Watcher[T]) List() []T {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	items := make([]T, 0, len(w.cache))
+	for _, item := range w.cache {
+		items = append(items, item)
+	}
+	return items
+}
+
+// sleep waits for the backoff and doubles it up to max. It returns false
+// when the context is done.
+func sleep(ctx context.Context, backoff *time.Duration, max time.Duration) bool {
+	t := time.NewTimer(*backoff)
+	defer t.Stop()
+	if *backoff *= 2; *backoff > max {
+		*backoff = max
+	}
+	select {
+	case <-t.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
@@ -0,0 +1,94 @@
+package watch_test
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/clouway/go-genproto/clouwayapis/rpc/watch"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/apipb"
+)
+
+type fakeStream struct {
+	changes []watch.Change[*apipb.Api]
+	err     error
+}
+
+func (s *fakeStream) Recv() (watch.Change[*apipb.Api], error) {
+	if len(s.changes) == 0 {
+		return watch.Change[*apipb.Api]{}, s.err
+	}
+	c := s.changes[0]
+	s.changes = s.changes[1:]
+	return c, nil
+}
+
+func api(name, version string) *apipb.Api {
+	return &apipb.Api{Name: name, Version: version}
+}
+
+func TestWatcher(t *testing.T) {
+	lists := [][]*apipb.Api{
+		{api("orders", "v1"), api("billing", "v1")},
+		{api("orders", "v2"), api("devices", "v1")},
+	}
+	var tokens []string
+	list := func(context.Context) ([]*apipb.Api, string, error) {
+		items := lists[0]
+		lists = lists[1:]
+		return items, "list", nil
+	}
+	streams := []*fakeStream{
+		{changes: []watch.Change[*apipb.Api]{
+			{Object: api("orders", "v2"), ResumeToken: "1"},
+			{Object: api("orders", "v2"), ResumeToken: "2"},
+			{Object: api("billing", ""), Deleted: true, ResumeToken: "3"},
+			{Object: api("catalog", "v1"), ResumeToken: "4"},
+		}, err: io.EOF},
+		{err: status.Error(codes.OutOfRange, "resume token expired")},
+		{err: status.Error(codes.Unavailable, "")},
+	}
+	watchFn := func(_ context.Context, token string) (watch.Stream[*apipb.Api], error) {
+		tokens = append(tokens, token)
+		s := streams[0]
+		if len(streams) > 1 {
+			streams = streams[1:]
+		}
+		return s, nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	w := watch.New(list, watchFn, func(a *apipb.Api) string { return a.Name }, watch.Backoff(time.Millisecond, time.Millisecond))
+	events := w.Run(ctx)
+
+	want := []string{
+		"ADDED orders v1", "ADDED billing v1",
+		"MODIFIED orders v2", "REMOVED billing v1", "ADDED catalog v1",
+		// The stream is listed again after the resume token expired.
+		"REMOVED catalog v1", "ADDED devices v1",
+	}
+	for i, w := range want {
+		e := <-events
+		if got := e.Type.String() + " " + e.Object.Name + " " + e.Object.Version; got != w {
+			t.Errorf("unexpected event %d:\n- want: %v\n-  got: %v", i, w, got)
+		}
+	}
+	select {
+	case <-w.Synced():
+	default:
+		t.Error("expected the watcher to be synced")
+	}
+	if got, _ := w.Get("orders"); got.GetVersion() != "v2" {
+		t.Errorf("unexpected cached version:\n- want: %v\n-  got: %v", "v2", got.GetVersion())
+	}
+	cancel()
+	for range events {
+	}
+	if tokens[0] != "list" || tokens[1] != "4" || tokens[2] != "list" {
+		t.Errorf("unexpected resume tokens: %v", tokens[:3])
+	}
+}
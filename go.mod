@@ -1,10 +1,28 @@
 module github.com/clouway/go-genproto
 
-go 1.15
+go 1.18
 
 require (
+	github.com/boombuler/barcode v1.1.0
+	github.com/fxamacker/cbor/v2 v2.5.0
 	github.com/go-kit/kit v0.12.0
+	github.com/go-kit/log v0.2.0
 	github.com/gorilla/mux v1.7.3
+	github.com/gorilla/websocket v1.5.0
+	github.com/vmihailenco/msgpack/v5 v5.3.5
+	google.golang.org/genproto v0.0.0-20240123012728-ef4313101c80
+	google.golang.org/genproto/googleapis/api v0.0.0-20240123012728-ef4313101c80
+	google.golang.org/genproto/googleapis/rpc v0.0.0-20240123012728-ef4313101c80
 	google.golang.org/grpc v1.62.1
 	google.golang.org/protobuf v1.33.0
 )
+
+require (
+	github.com/go-logfmt/logfmt v0.5.1 // indirect
+	github.com/golang/protobuf v1.5.3 // indirect
+	github.com/vmihailenco/tagparser/v2 v2.0.0 // indirect
+	github.com/x448/float16 v0.8.4 // indirect
+	golang.org/x/net v0.20.0 // indirect
+	golang.org/x/sys v0.16.0 // indirect
+	golang.org/x/text v0.14.0 // indirect
+)